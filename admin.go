@@ -0,0 +1,672 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"database-monitor/pkg/history"
+	"database-monitor/pkg/monitor"
+)
+
+type previewRequest struct {
+	// 通知后端名称，例如 feishu
+	Notifier string `json:"notifier"`
+	// 为 true 时使用最近一轮巡检的报告，忽略 Report
+	Live   bool            `json:"live"`
+	Report *monitor.Report `json:"report"`
+}
+
+// adminServer 管理和查询接口
+type adminServer struct {
+	// 配置重新加载后替换为新的 Monitor
+	m atomic.Pointer[monitor.Monitor]
+}
+
+// 管理接口的超时，避免慢连接长期占用
+const (
+	adminReadHeaderTimeout = 10 * time.Second
+	adminReadTimeout       = 30 * time.Second
+	adminWriteTimeout      = time.Minute
+	adminIdleTimeout       = 2 * time.Minute
+)
+
+func startAdminServer(m *monitor.Monitor) *adminServer {
+	s := &adminServer{}
+	s.m.Store(m)
+	if cfg.AdminAddr == "" {
+		return s
+	}
+	srv := &http.Server{
+		Addr:              cfg.AdminAddr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: adminReadHeaderTimeout,
+		ReadTimeout:       adminReadTimeout,
+		WriteTimeout:      adminWriteTimeout,
+		IdleTimeout:       adminIdleTimeout,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			slog.Error("Admin server stopped", "err", err)
+		}
+	}()
+	return s
+}
+
+func (s *adminServer) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	// 预览和配置接口暴露渲染后的配置和路由，所有请求都需要 AdminToken
+	mux.HandleFunc("/admin/preview", requireToken(s.handlePreview))
+	mux.HandleFunc("/api/v1/config", requireToken(s.handleConfig))
+	mux.HandleFunc("/api/v1/status", s.handleStatus)
+	mux.HandleFunc("/api/v1/clusters", s.handleClusters)
+	mux.HandleFunc("/api/v1/alerts/active", s.handleActiveAlerts)
+	mux.HandleFunc("/api/v1/alerts/resolved", s.handleResolvedAlerts)
+	mux.HandleFunc("/api/v1/debt-namespaces", s.handleDebtNamespaces)
+	mux.HandleFunc("/api/v1/silences", requireTokenForWrites(s.handleSilences))
+	mux.HandleFunc("/api/v1/silences/", requireTokenForWrites(s.handleSilence))
+	mux.HandleFunc("/api/silences", requireTokenForWrites(s.handleSilences))
+	mux.HandleFunc("/api/silences/", requireTokenForWrites(s.handleSilence))
+	mux.HandleFunc("/api/v1/history", s.handleHistory)
+	mux.HandleFunc("/api/v1/history/first-failure", s.handleFirstFailure)
+	mux.HandleFunc("/api/v1/history/transitions", s.handleTransitions)
+	mux.HandleFunc("/api/history", s.handleTransitions)
+	mux.HandleFunc("/api/v1/sla", s.handleSLA)
+	mux.HandleFunc("/api/v1/feishu/callback", s.handleFeishuCallback)
+	gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, &monitorMetrics}
+	if regions != nil {
+		gatherers = append(gatherers, regions)
+	}
+	mux.Handle("/metrics", promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/dashboard/", s.handleDashboard)
+	mux.Handle("/dashboard", http.RedirectHandler("/dashboard/", http.StatusMovedPermanently))
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	return mux
+}
+
+// requireToken 要求请求带 Authorization: Bearer <AdminToken>，未配置 AdminToken 时一律拒绝
+func requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		want := currentConfig().AdminToken
+		if want == "" {
+			http.Error(w, "admin token is not configured, set adminToken to enable this request", http.StatusForbidden)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="database-monitor"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireTokenForWrites GET 和 HEAD 请求不校验，其余修改状态的请求需要 AdminToken
+func requireTokenForWrites(next http.HandlerFunc) http.HandlerFunc {
+	authorized := requireToken(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next(w, r)
+			return
+		}
+		authorized(w, r)
+	}
+}
+
+// 按指定通知后端渲染报告并返回将要发送的 payload，不会真正发送
+func (s *adminServer) handlePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req previewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	n := findNotifier(req.Notifier)
+	if n == nil {
+		http.Error(w, fmt.Sprintf("unknown notifier %q", req.Notifier), http.StatusNotFound)
+		return
+	}
+
+	var report monitor.Report
+	switch {
+	case req.Live:
+		report = s.m.Load().LastReport()
+	case req.Report != nil:
+		report = *req.Report
+	default:
+		http.Error(w, "either report or live must be set", http.StatusBadRequest)
+		return
+	}
+
+	payload, err := n.Render(report)
+	var tmplErr *monitor.TemplateError
+	if errors.As(err, &tmplErr) {
+		http.Error(w, redactor.String(tmplErr.Error()), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, redactor.String(err.Error()), http.StatusInternalServerError)
+		return
+	}
+	contentType := "application/json"
+	if ct, ok := n.(interface{ ContentType() string }); ok {
+		contentType = ct.ContentType()
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(redactor.Bytes(payload))
+}
+
+type configResponse struct {
+	Hash     string          `json:"hash"`
+	LoadedAt time.Time       `json:"loadedAt"`
+	Config   Config          `json:"config"`
+	Previous []configVersion `json:"previous"`
+}
+
+// 返回当前生效配置（已脱敏）及其哈希，以及之前加载过的版本
+func (s *adminServer) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	versions := configVersionHistory()
+	resp := configResponse{Config: currentConfig().redacted(), Previous: []configVersion{}}
+	if n := len(versions); n > 0 {
+		resp.Hash = versions[n-1].Hash
+		resp.LoadedAt = versions[n-1].LoadedAt
+		for i := n - 2; i >= 0; i-- {
+			resp.Previous = append(resp.Previous, versions[i])
+		}
+	}
+	writeJSON(w, resp)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Error encoding response", "err", err)
+	}
+}
+
+// 巡检主循环卡住时不健康，由 kubelet 重启；CRD 未安装等情况只影响就绪
+func (s *adminServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if live, reason := s.live(); !live {
+		markStalled(reason)
+		http.Error(w, "not live: "+reason, http.StatusServiceUnavailable)
+		return
+	}
+	clearStalled()
+	fmt.Fprintln(w, "ok")
+}
+
+// 备用副本和注册表模式下不要求主循环推进；各区域出错时自行重启，不影响进程存活
+func (s *adminServer) live() (bool, string) {
+	if !leading.Load() || regions != nil {
+		return true, ""
+	}
+	return s.m.Load().Live()
+}
+
+func (s *adminServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if ready, reason := s.ready(); !ready {
+		http.Error(w, "not ready: "+reason, http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// 备用副本不巡检，但能随时接管，视为就绪
+func (s *adminServer) ready() (bool, string) {
+	if clientset == nil || dynamicClient == nil {
+		return false, "kubernetes clients not initialized"
+	}
+	if !leading.Load() {
+		return true, "standby"
+	}
+	if regions != nil {
+		return regions.ready()
+	}
+	return s.m.Load().Ready()
+}
+
+type statusResponse struct {
+	Ready  bool   `json:"ready"`
+	Reason string `json:"reason,omitempty"`
+	// 选主模式下本副本是否为 leader
+	Leader bool `json:"leader"`
+	// 巡检主循环是否在推进，以及最近一次完成巡检的时间
+	Live      bool       `json:"live"`
+	LastCheck *time.Time `json:"lastCheck,omitempty"`
+	// 注册表模式下各区域的健康情况
+	Regions []regionStatus `json:"regions,omitempty"`
+}
+
+// 返回监控整体以及各区域的运行状态
+func (s *adminServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var resp statusResponse
+	resp.Ready, resp.Reason = s.ready()
+	resp.Leader = leading.Load()
+	resp.Live, _ = s.live()
+	if at := s.m.Load().LastCheck(); !at.IsZero() {
+		resp.LastCheck = &at
+	}
+	if regions != nil {
+		resp.Regions = regions.status()
+	}
+	writeJSON(w, resp)
+}
+
+// 注册表模式下为各区域的 Monitor，否则为主集群的 Monitor
+func (s *adminServer) monitors() []*monitor.Monitor {
+	if regions != nil {
+		return regions.monitors()
+	}
+	return []*monitor.Monitor{s.m.Load()}
+}
+
+// 各集群当前的 phase 和告警决策，可按 region、namespace、phase 过滤
+func (s *adminServer) handleClusters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	params := r.URL.Query()
+	clusters := []monitor.ClusterState{}
+	for _, m := range s.monitors() {
+		for _, c := range m.Clusters() {
+			if matchParam(params.Get("region"), c.Region) && matchParam(params.Get("namespace"), c.Namespace) &&
+				matchParam(params.Get("phase"), c.Phase) {
+				clusters = append(clusters, c)
+			}
+		}
+	}
+	writeJSON(w, clusters)
+}
+
+// 未关闭的事件，可按 region、namespace 过滤
+func (s *adminServer) handleActiveAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	params := r.URL.Query()
+	alerts := []monitor.RegionIncident{}
+	for _, m := range s.monitors() {
+		for _, a := range m.ActiveAlerts() {
+			if matchParam(params.Get("region"), a.Region) && matchParam(params.Get("namespace"), a.Namespace) {
+				alerts = append(alerts, a)
+			}
+		}
+	}
+	writeJSON(w, alerts)
+}
+
+// 最近关闭的事件，最新的在前；limit 默认 100，可按 region、namespace 过滤
+func (s *adminServer) handleResolvedAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	params := r.URL.Query()
+	limit := 100
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	incidents := []monitor.RegionIncident{}
+	for _, m := range s.monitors() {
+		for _, inc := range m.RecentIncidents(0) {
+			if matchParam(params.Get("region"), inc.Region) && matchParam(params.Get("namespace"), inc.Namespace) {
+				incidents = append(incidents, inc)
+			}
+		}
+	}
+	// 多区域时合并后按关闭时间重新排序
+	sort.Slice(incidents, func(i, j int) bool { return incidents[i].ClosedAt.After(*incidents[j].ClosedAt) })
+	if len(incidents) > limit {
+		incidents = incidents[:limit]
+	}
+	writeJSON(w, incidents)
+}
+
+// 当前欠费的 ns
+func (s *adminServer) handleDebtNamespaces(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	region := r.URL.Query().Get("region")
+	namespaces := []monitor.DebtNamespace{}
+	for _, m := range s.monitors() {
+		for _, ns := range m.DebtNamespaces() {
+			if matchParam(region, ns.Region) {
+				namespaces = append(namespaces, ns)
+			}
+		}
+	}
+	writeJSON(w, namespaces)
+}
+
+type silenceRequest struct {
+	// 为空时在所有区域创建
+	Region    string `json:"region"`
+	Namespace string `json:"namespace"`
+	Cluster   string `json:"cluster"`
+	Phase     string `json:"phase"`
+	// Alertmanager 格式的匹配条件，和上面的字段二选一
+	Matchers []silenceMatcher `json:"matchers"`
+	// 时长（例如 2h）和结束时间二选一
+	Duration  string    `json:"duration"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	Reason    string    `json:"reason"`
+	Comment   string    `json:"comment"`
+	CreatedBy string    `json:"createdBy"`
+}
+
+// silenceMatcher 只支持对 namespace、cluster、phase 的相等匹配，值可以是 glob
+type silenceMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual *bool  `json:"isEqual"`
+}
+
+// 把 Alertmanager 格式的匹配条件合并到请求的字段上
+func (req *silenceRequest) applyMatchers() error {
+	for _, mt := range req.Matchers {
+		if mt.IsRegex || mt.IsEqual != nil && !*mt.IsEqual {
+			return fmt.Errorf("matcher %q: only equality matchers are supported, use a glob value instead", mt.Name)
+		}
+		var field *string
+		switch mt.Name {
+		case "namespace":
+			field = &req.Namespace
+		case "cluster":
+			field = &req.Cluster
+		case "phase":
+			field = &req.Phase
+		default:
+			return fmt.Errorf("unknown matcher %q, expected namespace, cluster or phase", mt.Name)
+		}
+		if *field != "" && *field != mt.Value {
+			return fmt.Errorf("matcher %q conflicts with the %s field", mt.Name, mt.Name)
+		}
+		*field = mt.Value
+	}
+	if req.Reason == "" {
+		req.Reason = req.Comment
+	}
+	return nil
+}
+
+// GET 列出未过期的静默（参数 region），POST 创建静默；/api/silences 为兼容 Alertmanager 习惯的别名
+func (s *adminServer) handleSilences(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		region := r.URL.Query().Get("region")
+		silences := []monitor.Silence{}
+		for _, m := range s.monitors() {
+			for _, silence := range m.Silences() {
+				if matchParam(region, silence.Region) {
+					silences = append(silences, silence)
+				}
+			}
+		}
+		writeJSON(w, silences)
+	case http.MethodPost:
+		var req silenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := req.applyMatchers(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		silence := monitor.Silence{Namespace: req.Namespace, Cluster: req.Cluster, Phase: req.Phase, StartsAt: req.StartsAt,
+			EndsAt: req.EndsAt, Reason: req.Reason, CreatedBy: req.CreatedBy}
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil {
+				http.Error(w, "invalid duration: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			start := req.StartsAt
+			if start.IsZero() {
+				start = time.Now()
+			}
+			silence.EndsAt = start.Add(d)
+		}
+		created := []monitor.Silence{}
+		for _, m := range s.monitors() {
+			if req.Region != "" && m.Region() != req.Region {
+				continue
+			}
+			c, err := m.AddSilence(r.Context(), silence)
+			if err != nil {
+				http.Error(w, redactor.String(err.Error()), http.StatusBadRequest)
+				return
+			}
+			c.Region = m.Region()
+			created = append(created, c)
+		}
+		if len(created) == 0 {
+			http.Error(w, fmt.Sprintf("unknown region %q", req.Region), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, created)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// DELETE /api/v1/silences/{id} 或 /api/silences/{id} 提前结束静默
+func (s *adminServer) handleSilence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(strings.Replace(r.URL.Path, "/api/v1/", "/api/", 1), "/api/silences/")
+	found := false
+	for _, m := range s.monitors() {
+		ok, err := m.DeleteSilence(r.Context(), id)
+		if err != nil {
+			http.Error(w, redactor.String(err.Error()), http.StatusInternalServerError)
+			return
+		}
+		found = found || ok
+	}
+	if !found {
+		http.Error(w, "silence not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// 查询参数为空时不过滤
+func matchParam(want, got string) bool {
+	return want == "" || want == got
+}
+
+// 查询历史库中的状态变化和通知记录，参数 namespace、cluster、kind、since、until（RFC3339）、limit
+func (s *adminServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if historyStore == nil {
+		http.Error(w, "history is not enabled", http.StatusNotFound)
+		return
+	}
+	params := r.URL.Query()
+	q := history.Query{
+		Namespace: params.Get("namespace"),
+		Cluster:   params.Get("cluster"),
+		Kind:      params.Get("kind"),
+	}
+	var err error
+	if q.Since, err = parseTimeParam(params.Get("since")); err != nil {
+		http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.Until, err = parseTimeParam(params.Get("until")); err != nil {
+		http.Error(w, "invalid until: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if v := params.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	writeJSON(w, historyStore.Query(q))
+}
+
+// 集群的 phase 变化时间线及每个 phase 持续的时长，用于故障复盘
+func (s *adminServer) handleTransitions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if historyStore == nil {
+		http.Error(w, "history is not enabled", http.StatusNotFound)
+		return
+	}
+	params := r.URL.Query()
+	q := history.Query{Namespace: params.Get("namespace"), Cluster: params.Get("cluster")}
+	var err error
+	if q.Since, err = parseTimeParam(params.Get("since")); err != nil {
+		http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.Until, err = parseTimeParam(params.Get("until")); err != nil {
+		http.Error(w, "invalid until: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if v := params.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	writeJSON(w, historyStore.Transitions(q))
+}
+
+type firstFailureResponse struct {
+	Namespace    string     `json:"namespace"`
+	Cluster      string     `json:"cluster"`
+	FirstFailure *time.Time `json:"firstFailure"`
+	// 保留期内进入 Failed 的次数
+	Failures int `json:"failures"`
+}
+
+// 集群在保留期内（或 since 之后）第一次故障的时间
+func (s *adminServer) handleFirstFailure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if historyStore == nil {
+		http.Error(w, "history is not enabled", http.StatusNotFound)
+		return
+	}
+	params := r.URL.Query()
+	namespace, cluster := params.Get("namespace"), params.Get("cluster")
+	if namespace == "" || cluster == "" {
+		http.Error(w, "namespace and cluster are required", http.StatusBadRequest)
+		return
+	}
+	since, err := parseTimeParam(params.Get("since"))
+	if err != nil {
+		http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := firstFailureResponse{Namespace: namespace, Cluster: cluster, Failures: historyStore.Failures(namespace, cluster, since)}
+	if at, ok := historyStore.FirstFailure(namespace, cluster, since); ok {
+		resp.FirstFailure = &at
+	}
+	writeJSON(w, resp)
+}
+
+// 各集群在 since 到 until 之间的可用性，默认为最近 slaReportPeriod；by=namespace 时按 ns 汇总，format=csv 时返回 CSV
+func (s *adminServer) handleSLA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if historyStore == nil {
+		http.Error(w, "history is not enabled", http.StatusNotFound)
+		return
+	}
+	params := r.URL.Query()
+	until, err := parseTimeParam(params.Get("until"))
+	if err != nil {
+		http.Error(w, "invalid until: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+	since, err := parseTimeParam(params.Get("since"))
+	if err != nil {
+		http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if since.IsZero() {
+		since = until.Add(-currentConfig().SLAReportPeriod)
+	}
+	list := historyStore.Availability(since, until, slaDown)
+	switch params.Get("by") {
+	case "", "cluster":
+	case "namespace":
+		list = history.ByNamespace(list)
+	default:
+		http.Error(w, "by must be cluster or namespace", http.StatusBadRequest)
+		return
+	}
+	switch params.Get("format") {
+	case "", "json":
+		writeJSON(w, list)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=availability.csv")
+		if err := writeAvailabilityCSV(w, list); err != nil {
+			slog.Error("Error writing availability CSV", "err", err)
+		}
+	default:
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+	}
+}
+
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"database-monitor/pkg/monitor"
+)
+
+// newAdminTest 返回管理接口的 handler，AdminToken 为 token
+func newAdminTest(t *testing.T, token string) (http.Handler, *monitor.Monitor) {
+	t.Helper()
+	prevCfg := cfg
+	t.Cleanup(func() { cfg = prevCfg })
+	cfg = defaultConfig()
+	cfg.AdminToken = token
+	m := monitor.New(monitor.Deps{
+		Config:     cfg.Config,
+		Store:      &memStore{},
+		Registerer: prometheus.NewRegistry(),
+		Redactor:   redactor,
+		Logger:     slog.Default(),
+	})
+	s := &adminServer{}
+	s.m.Store(m)
+	return s.routes(), m
+}
+
+func serveAdmin(h http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// 创建和删除静默需要 AdminToken，查询不需要
+func TestSilencesRequireToken(t *testing.T) {
+	const body = `{"namespace":"ns1","duration":"2h","reason":"maintenance"}`
+	h, m := newAdminTest(t, "secret")
+	for _, path := range []string{"/api/v1/silences", "/api/silences"} {
+		for _, token := range []string{"", "wrong"} {
+			if rec := serveAdmin(h, http.MethodPost, path, token, body); rec.Code != http.StatusUnauthorized {
+				t.Errorf("POST %s with token %q: status %d, want 401", path, token, rec.Code)
+			}
+		}
+	}
+	if got := m.Silences(); len(got) != 0 {
+		t.Fatalf("silences created without a valid token: %+v", got)
+	}
+
+	if rec := serveAdmin(h, http.MethodPost, "/api/v1/silences", "secret", body); rec.Code != http.StatusCreated {
+		t.Fatalf("POST with the admin token: status %d: %s", rec.Code, rec.Body)
+	}
+	silences := m.Silences()
+	if len(silences) != 1 {
+		t.Fatalf("silences = %+v, want one", silences)
+	}
+	if rec := serveAdmin(h, http.MethodGet, "/api/v1/silences", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("GET without a token: status %d, want 200", rec.Code)
+	}
+
+	path := "/api/v1/silences/" + silences[0].ID
+	if rec := serveAdmin(h, http.MethodDelete, path, "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("DELETE without a token: status %d, want 401", rec.Code)
+	}
+	if rec := serveAdmin(h, http.MethodDelete, path, "secret", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE with the admin token: status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// 未配置 AdminToken 时拒绝修改状态的请求
+func TestSilencesWithoutAdminToken(t *testing.T) {
+	h, m := newAdminTest(t, "")
+	rec := serveAdmin(h, http.MethodPost, "/api/v1/silences", "", `{"namespace":"ns1","duration":"2h"}`)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST without adminToken configured: status %d, want 403", rec.Code)
+	}
+	if got := m.Silences(); len(got) != 0 {
+		t.Errorf("silences = %+v, want none", got)
+	}
+}
+
+// 预览和配置接口暴露配置和路由，任何方法都需要 AdminToken
+func TestConfigEndpointsRequireToken(t *testing.T) {
+	h, _ := newAdminTest(t, "secret")
+	tests := []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/api/v1/config", ""},
+		{http.MethodPost, "/admin/preview", `{"notifier":"feishu","report":{}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if rec := serveAdmin(h, tt.method, tt.path, "", tt.body); rec.Code != http.StatusUnauthorized {
+				t.Errorf("%s without a token: status %d, want 401", tt.method, rec.Code)
+			}
+			if rec := serveAdmin(h, tt.method, tt.path, "wrong", tt.body); rec.Code != http.StatusUnauthorized {
+				t.Errorf("%s with a wrong token: status %d, want 401", tt.method, rec.Code)
+			}
+			if rec := serveAdmin(h, tt.method, tt.path, "secret", tt.body); rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden {
+				t.Errorf("%s with the admin token: status %d: %s", tt.method, rec.Code, rec.Body)
+			}
+		})
+	}
+	// 返回的配置中 AdminToken 已脱敏
+	rec := serveAdmin(h, http.MethodGet, "/api/v1/config", "secret", "")
+	if strings.Contains(rec.Body.String(), `"secret"`) || !strings.Contains(rec.Body.String(), `"adminToken":"***"`) {
+		t.Errorf("config response does not redact the admin token: %s", rec.Body)
+	}
+}
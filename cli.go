@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"database-monitor/pkg/monitor"
+)
+
+// 子命令的退出码：检查发现故障集群或测试消息发送失败为 1，配置或运行错误为 2
+const (
+	exitFailing = 1
+	exitError   = 2
+)
+
+// command 一个子命令，run 返回进程退出码
+type command struct {
+	name    string
+	summary string
+	run     func() int
+}
+
+var commands = []command{
+	{"run", "monitor clusters until a termination signal is received (default)", runMonitor},
+	{"check-once", "check every cluster once, send notifications and exit 1 if any cluster is failing", checkOnce},
+	{"send-test", "send a test message to every configured notifier and exit 1 if any of them fails", sendTest},
+	{"list", "print the current phase of every monitored cluster", listClusters},
+}
+
+// 第一个参数不是参数名时视为子命令，省略时为 run，兼容只传参数的旧用法
+func parseCommand(args []string) (command, []string) {
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	for _, c := range commands {
+		if c.name == name {
+			return c, args
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	usage()
+	os.Exit(exitError)
+	return command{}, nil
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [command] [flags]\n\nCommands:\n", os.Args[0])
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", c.name, c.summary)
+	}
+	w.Flush()
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.CommandLine.PrintDefaults()
+}
+
+// 一次性子命令的 context，收到退出信号时取消；不记录退出原因，避免覆盖常驻进程的记录
+func commandContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+}
+
+// 一次性子命令使用的 Monitor：只读取共享的状态（静默等），不写入，常驻进程的巡检状态和去重记录不受影响
+func commandMonitor(notifiers []monitor.Notifier) (*monitor.Monitor, bool) {
+	if cfg.RegistryResource != "" || len(cfg.KubeContexts) > 0 {
+		fmt.Fprintln(os.Stderr, "This command only supports monitoring the local cluster, not --registry-resource or --kube-contexts")
+		return nil, false
+	}
+	config := cfg.Config
+	// 单次巡检没有连续的观察，第一次不健康即告警
+	config.AlertAfterChecks = 1
+	return monitor.New(monitor.Deps{
+		Config:        config,
+		Dynamic:       dynamicClient,
+		Kube:          clientset,
+		Notifiers:     notifiers,
+		ConfigVersion: currentConfigHash(),
+		Redactor:      redactor,
+		Store:         readOnlyStore{monitor.NewConfigMapStore(clientset, cfg.StateNamespace, cfg.StateConfigMap)},
+		Logger:        slog.Default(),
+
+		NamespaceNotifier: namespaceNotifier,
+	}), true
+}
+
+// check-once 子命令：巡检一次并发送通知，有需要告警的集群时退出码为 1，适合 CI 和 CronJob
+func checkOnce() int {
+	m, ok := commandMonitor(notifiers)
+	if !ok {
+		return exitError
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+	if err := m.DetectAPI(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error detecting the KubeBlocks API: %v\n", redactor.String(err.Error()))
+		return exitError
+	}
+	if err := m.RefreshDebt(ctx); err != nil {
+		slog.Warn("Error refreshing namespaces in debt, clusters in debt may be reported", "err", err)
+	}
+	report, err := m.RunOnce(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking clusters: %v\n", redactor.String(err.Error()))
+		return exitError
+	}
+	if len(report.Entries) == 0 {
+		fmt.Println("All clusters are healthy")
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tPHASE\tREASON\tNOTE")
+	for _, e := range report.Entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Namespace, e.Name, e.Phase, e.Reason, e.Note)
+	}
+	w.Flush()
+	return exitFailing
+}
+
+// send-test 子命令：不经路由和去重，直接向每个通知后端发送一条测试消息，用于验证 webhook 配置
+func sendTest() int {
+	all := append(append([]monitor.Notifier(nil), notifiers...), escalationNotifiers...)
+	if len(all) == 0 {
+		fmt.Fprintln(os.Stderr, "No notifiers configured")
+		return exitError
+	}
+	m := monitor.New(monitor.Deps{Config: cfg.Config, Redactor: redactor, Logger: slog.Default()})
+	ctx, cancel := commandContext()
+	defer cancel()
+	report := m.NewNotice("database-monitor test message: this notifier is configured correctly")
+	code := 0
+	for _, n := range all {
+		payload, err := n.Render(report)
+		if err == nil && cfg.DryRun {
+			fmt.Printf("dry run %s: %s\n", n.Name(), redactor.String(string(payload)))
+			continue
+		}
+		if err == nil {
+			err = n.Send(ctx, payload)
+		}
+		if err != nil {
+			fmt.Printf("FAILED  %s: %s\n", n.Name(), redactor.String(err.Error()))
+			code = exitFailing
+			continue
+		}
+		fmt.Printf("ok      %s\n", n.Name())
+	}
+	return code
+}
+
+// list 子命令：打印受监控集群当前的 phase，不评估也不发送通知
+func listClusters() int {
+	m, ok := commandMonitor(nil)
+	if !ok {
+		return exitError
+	}
+	ctx, cancel := commandContext()
+	defer cancel()
+	if err := m.DetectAPI(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error detecting the KubeBlocks API: %v\n", redactor.String(err.Error()))
+		return exitError
+	}
+	if err := m.RefreshDebt(ctx); err != nil {
+		slog.Warn("Error refreshing namespaces in debt", "err", err)
+	}
+	clusters, err := m.ListClusters(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing clusters: %v\n", redactor.String(err.Error()))
+		return exitError
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tPHASE\tIN DEBT")
+	for _, c := range clusters {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\n", c.Namespace, c.Name, c.Phase, c.InDebt)
+	}
+	w.Flush()
+	return 0
+}
+
+// readOnlyStore 丢弃写入的 StateStore
+type readOnlyStore struct {
+	store monitor.StateStore
+}
+
+func (s readOnlyStore) Get(ctx context.Context, key string) (string, error) {
+	return s.store.Get(ctx, key)
+}
+
+func (readOnlyStore) Set(context.Context, string, string) error {
+	return nil
+}
@@ -0,0 +1,591 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"database-monitor/pkg/monitor"
+	"database-monitor/pkg/notify"
+)
+
+// Config 监控进程的配置：巡检逻辑参数以及通知、管理接口等进程级设置
+type Config struct {
+	monitor.Config
+	// 连接被巡检集群使用的 kubeconfig 文件，为空时依次尝试 in-cluster 配置、KUBECONFIG 环境变量和 ~/.kube/config
+	Kubeconfig string `json:"kubeconfig"`
+	// 启用的通知后端，可同时启用多个
+	Notifiers []string `json:"notifiers"`
+	// 各通知后端接收的严重程度（critical、warning、info），未配置的后端接收全部
+	NotifierSeverities map[string][]string `json:"notifierSeverities"`
+	// 各通知后端的 Go 模板文件，用于自定义消息格式和语言；支持 feishu、slack、dingtalk、wecom 和 telegram
+	NotifierTemplates map[string]string `json:"notifierTemplates"`
+	// 各通知后端的语言（en、zh 或中英双语 zh-en），未配置的后端使用 locale
+	NotifierLocales map[string]string `json:"notifierLocales"`
+	// 从 Secret 读取的敏感字段，键为字段名（例如 feishuWebhookURL），读取到的值覆盖该字段。
+	// 启动和重新加载配置时读取，Secret 变化后在 configReloadInterval 内生效；只能在配置文件中设置
+	SecretRefs map[string]SecretRef `json:"secretRefs"`
+	// 飞书机器人 webhook 地址，包含 token，属于敏感信息
+	FeishuWebhookURL string `json:"feishuWebhookURL"`
+	// 飞书机器人的签名校验密钥，属于敏感信息；也可以用 namespace/name/key 指定从 Secret 中读取，等同于 secretRefs 中的 feishuSecret
+	FeishuSecret     string `json:"feishuSecret"`
+	FeishuSecretFrom string `json:"feishuSecretFrom"`
+	// 飞书消息的 Go 模板文件，为空时使用默认文本表格；notifierTemplates 和目的地的 template 优先
+	FeishuTemplate string `json:"feishuTemplate"`
+	// 飞书消息格式：card 为消息卡片，text 为等宽文本表格
+	FeishuFormat string `json:"feishuFormat"`
+	// 卡片中是否带确认和静默按钮，点击后飞书回调管理接口的 /api/v1/feishu/callback；
+	// 回调用应用的 Verification Token 校验，属于敏感信息
+	FeishuCardActions       bool   `json:"feishuCardActions"`
+	FeishuVerificationToken string `json:"feishuVerificationToken"`
+	// Slack incoming webhook 地址，属于敏感信息
+	SlackWebhookURL string `json:"slackWebhookURL"`
+	// 钉钉机器人 webhook 地址和加签密钥，属于敏感信息
+	DingTalkWebhookURL string `json:"dingtalkWebhookURL"`
+	DingTalkSecret     string `json:"dingtalkSecret"`
+	// 企业微信群机器人 webhook 地址，属于敏感信息。不同环境的群可以用 destinations 分别配置
+	WeComWebhookURL string `json:"wecomWebhookURL"`
+	// Telegram 机器人 token 和目标会话 ID，token 属于敏感信息
+	TelegramBotToken string `json:"telegramBotToken"`
+	TelegramChatID   string `json:"telegramChatID"`
+	// PagerDuty 服务的 Events API v2 Integration Key，属于敏感信息
+	PagerDutyRoutingKey string `json:"pagerdutyRoutingKey"`
+	// 通用 webhook 的地址和附加请求头，请求头的值可能包含凭据，属于敏感信息
+	WebhookURL     string            `json:"webhookURL"`
+	WebhookHeaders map[string]string `json:"webhookHeaders"`
+	// Alertmanager 地址，例如 http://alertmanager:9093
+	AlertmanagerURL string `json:"alertmanagerURL"`
+	// 邮件通知使用的 SMTP 服务器，TLS 为 starttls、tls 或 none，密码属于敏感信息
+	SMTPHost     string   `json:"smtpHost"`
+	SMTPPort     int      `json:"smtpPort"`
+	SMTPTLS      string   `json:"smtpTLS"`
+	SMTPUsername string   `json:"smtpUsername"`
+	SMTPPassword string   `json:"smtpPassword"`
+	EmailFrom    string   `json:"emailFrom"`
+	EmailTo      []string `json:"emailTo"`
+	// 管理接口监听地址，为空时不启动
+	AdminAddr string `json:"adminAddr"`
+	// 管理接口修改状态的请求（创建、删除静默）以及预览和配置接口需要带 Authorization: Bearer <adminToken>，
+	// 为空时拒绝这些请求；属于敏感信息
+	AdminToken string `json:"adminToken"`
+	// 检查配置文件（包括挂载的 ConfigMap）是否变化的间隔，变化后不重启进程重新加载；0 表示只在收到 SIGHUP 时重新加载
+	ConfigReloadInterval time.Duration `json:"configReloadInterval"`
+	// 保存跨重启状态的 ConfigMap 所在命名空间和名称
+	StateNamespace string `json:"stateNamespace"`
+	StateConfigMap string `json:"stateConfigMap"`
+	// 发布各集群 phase、开始不健康的时间和欠费标记的 ConfigMap，位于 StateNamespace；为空时不发布
+	StatusConfigMap string `json:"statusConfigMap"`
+	// panic 时 goroutine dump 的写入目录
+	DumpDir string `json:"dumpDir"`
+	// 是否在集群对象上创建事件打开和关闭的 Event
+	EmitEvents bool `json:"emitEvents"`
+	// 区域注册表资源（group/version/resource），设置后按注册表巡检各区域而不是本集群
+	RegistryResource string `json:"registryResource"`
+	// 注册表条目所在的 ns，为空表示所有 ns
+	RegistryNamespace string `json:"registryNamespace"`
+	// kubeconfig 中的 context 名称，设置后把每个 context 作为一个区域巡检，区域名即 context 名；与注册表不能同时使用
+	KubeContexts []string `json:"kubeContexts"`
+	// 消息默认的语言（en、zh 或中英双语 zh-en）和显示时区，目的地未单独配置时使用
+	Locale   string `json:"locale"`
+	Timezone string `json:"timezone"`
+	// 额外的通知目的地，例如给不同租户的飞书群
+	Destinations []Destination `json:"destinations"`
+	// 多副本部署时通过 Lease 选主，只有 leader 巡检和发送通知
+	LeaderElect         bool   `json:"leaderElect"`
+	LeaderElectionLease string `json:"leaderElectionLease"`
+	// 审计日志文件，"-" 表示标准输出，为空时不记录
+	AuditLog string `json:"auditLog"`
+	// 审计日志文件轮转的大小和保留的旧文件数
+	AuditMaxBytes int64 `json:"auditMaxBytes"`
+	AuditBackups  int   `json:"auditBackups"`
+	// 审计日志只写入这些类型：transition、notification、alert、recovery、incident，为空时写入全部；历史库不受影响
+	AuditKinds []string `json:"auditKinds"`
+	// 本地历史库文件（BoltDB），记录所有状态变化和通知，供 /api/v1/history 查询；为空时不启用
+	HistoryPath string `json:"historyPath"`
+	// 历史记录的保留时长，0 表示永久保留
+	HistoryRetention time.Duration `json:"historyRetention"`
+	// OTLP/HTTP trace 接收地址，例如 http://otel-collector:4318，为空时不导出 trace；采样比例为 0~1
+	OTLPEndpoint     string  `json:"otlpEndpoint"`
+	TraceSampleRatio float64 `json:"traceSampleRatio"`
+	// 按 cron 表达式发送可用性报告，统计最近 SLAReportPeriod 内各集群和 ns 的可用性；需要启用历史库
+	SLAReportSchedule  string        `json:"slaReportSchedule"`
+	SLAReportPeriod    time.Duration `json:"slaReportPeriod"`
+	SLAReportNotifiers []string      `json:"slaReportNotifiers"`
+	// 日志级别：debug、info、warn 或 error
+	LogLevel string `json:"logLevel"`
+	// 日志格式：text 或 json，json 便于 Loki、ELK 等采集
+	LogFormat string `json:"logFormat"`
+}
+
+// Destination 一个通知目的地，可以单独指定语言和时区
+type Destination struct {
+	Name string `json:"name"`
+	// 通知后端类型：feishu、slack、dingtalk、wecom、webhook 或 stdout
+	Type string `json:"type"`
+	// webhook 地址，属于敏感信息
+	URL string `json:"url,omitempty"`
+	// 飞书或钉钉机器人的签名密钥，属于敏感信息
+	Secret string `json:"secret,omitempty"`
+	// 接收的严重程度，为空时接收全部
+	Severities []string `json:"severities,omitempty"`
+	// 只接收这些 ns（支持 glob）的告警，为空时接收全部；用于把租户的告警发送到各自团队的群
+	Namespaces []string `json:"namespaces,omitempty"`
+	// 兜底渠道：只接收没有被其他目的地或 ns 注解认领的 ns 的告警
+	Fallback bool `json:"fallback,omitempty"`
+	// 只接收 escalations 中引用它的升级通知，不接收普通报告
+	EscalationOnly bool `json:"escalationOnly,omitempty"`
+	// 通用 webhook 附加的请求头，只能在配置文件中设置
+	Headers  map[string]string `json:"headers,omitempty"`
+	Locale   string            `json:"locale,omitempty"`
+	Timezone string            `json:"timezone,omitempty"`
+	// 自定义消息的 Go 模板文件，只用于 feishu、slack、dingtalk 和 wecom
+	Template string `json:"template,omitempty"`
+	// 从 Secret 读取 url 或 secret，只能在配置文件中设置
+	SecretRefs map[string]SecretRef `json:"secretRefs,omitempty"`
+}
+
+// SecretRef 引用 Secret 中的一个键，namespace 为空时使用 stateNamespace
+type SecretRef struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+}
+
+// 全局配置中可以从 Secret 读取的字段
+func (c *Config) secretFields() map[string]*string {
+	return map[string]*string{
+		"feishuWebhookURL":         &c.FeishuWebhookURL,
+		"feishuSecret":             &c.FeishuSecret,
+		"feishuVerificationToken":  &c.FeishuVerificationToken,
+		"adminToken":               &c.AdminToken,
+		"slackWebhookURL":          &c.SlackWebhookURL,
+		"dingtalkWebhookURL":       &c.DingTalkWebhookURL,
+		"dingtalkSecret":           &c.DingTalkSecret,
+		"wecomWebhookURL":          &c.WeComWebhookURL,
+		"telegramBotToken":         &c.TelegramBotToken,
+		"pagerdutyRoutingKey":      &c.PagerDutyRoutingKey,
+		"webhookURL":               &c.WebhookURL,
+		"smtpPassword":             &c.SMTPPassword,
+		"resolutionCallbackURL":    &c.ResolutionCallbackURL,
+		"resolutionCallbackSecret": &c.ResolutionCallbackSecret,
+		"grafanaAPIToken":          &c.GrafanaAPIToken,
+	}
+}
+
+// 目的地中可以从 Secret 读取的字段
+func (d *Destination) secretFields() map[string]*string {
+	return map[string]*string{"url": &d.URL, "secret": &d.Secret}
+}
+
+// 解析 name=ops,type=feishu,url=...,locale=zh,timezone=Asia/Shanghai,severities=critical|warning,namespaces=ns-team-a*|ns-b 形式的目的地
+func parseDestination(v string) (Destination, error) {
+	var d Destination
+	for _, item := range splitList(v) {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return d, fmt.Errorf("invalid destination field %q, want key=value", item)
+		}
+		switch key {
+		case "name":
+			d.Name = value
+		case "type":
+			d.Type = value
+		case "url":
+			d.URL = value
+		case "secret":
+			d.Secret = value
+		case "locale":
+			d.Locale = value
+		case "timezone":
+			d.Timezone = value
+		case "template":
+			d.Template = value
+		case "severities":
+			d.Severities = strings.Split(value, "|")
+		case "namespaces":
+			d.Namespaces = strings.Split(value, "|")
+		case "fallback":
+			fallback, err := strconv.ParseBool(value)
+			if err != nil {
+				return d, fmt.Errorf("invalid destination fallback %q: %w", value, err)
+			}
+			d.Fallback = fallback
+		case "escalationOnly":
+			escalationOnly, err := strconv.ParseBool(value)
+			if err != nil {
+				return d, fmt.Errorf("invalid destination escalationOnly %q: %w", value, err)
+			}
+			d.EscalationOnly = escalationOnly
+		default:
+			return d, fmt.Errorf("unknown destination field %q", key)
+		}
+	}
+	if d.Name == "" || d.Type == "" {
+		return d, fmt.Errorf("destination %q needs a name and a type", v)
+	}
+	return d, nil
+}
+
+var cfg = defaultConfig()
+
+func defaultConfig() Config {
+	return Config{
+		Config:        monitor.DefaultConfig(),
+		Notifiers:     []string{"feishu"},
+		AdminAddr:     ":8080",
+		LogLevel:      "info",
+		LogFormat:     "text",
+		Locale:        "en",
+		AuditMaxBytes: 100 << 20,
+		FeishuFormat:  "card",
+		SMTPPort:      587,
+		SMTPTLS:       notify.SMTPStartTLS,
+
+		LeaderElectionLease: "database-monitor-leader",
+		AuditBackups:        5,
+		HistoryRetention:    30 * 24 * time.Hour,
+		SLAReportPeriod:     7 * 24 * time.Hour,
+		TraceSampleRatio:    1,
+		StateNamespace:      defaultStateNamespace(),
+		StateConfigMap:      "database-monitor-state",
+		StatusConfigMap:     "database-monitor-status",
+		DumpDir:             os.TempDir(),
+
+		ConfigReloadInterval: 30 * time.Second,
+	}
+}
+
+func (c *Config) bindFlags(fs *flag.FlagSet) {
+	// 配置文件在解析参数之前由 configFileFromArgs 读取，这里只为了 -help 和参数校验
+	fs.String("config", "", "path to a YAML configuration file; command line flags override its values")
+	fs.StringVar(&c.Kubeconfig, "kubeconfig", c.Kubeconfig,
+		"path to a kubeconfig file; empty to use the in-cluster config, then $KUBECONFIG, then ~/.kube/config")
+	fs.DurationVar(&c.CheckInterval, "check-interval", c.CheckInterval,
+		"how often clusters are checked")
+	fs.StringVar(&c.CheckSchedule, "check-schedule", c.CheckSchedule,
+		"cron expression (minute hour day month weekday, optional CRON_TZ= prefix) for checks; overrides check-interval")
+	fs.BoolVar(&c.AlertOnDeletingFailures, "alert-on-deleting-failures", c.AlertOnDeletingFailures,
+		"alert on Failed clusters even when they are being deleted")
+	fs.Func("include-namespaces", "comma separated namespaces or glob patterns to monitor, empty for all", func(v string) error {
+		c.IncludeNamespaces = splitList(v)
+		return nil
+	})
+	fs.Func("exclude-namespaces", "comma separated namespaces or glob patterns to skip, e.g. kb-system,test-*", func(v string) error {
+		c.ExcludeNamespaces = splitList(v)
+		return nil
+	})
+	fs.StringVar(&c.ClusterSelector, "cluster-selector", c.ClusterSelector,
+		"label selector of clusters to monitor, e.g. monitoring=enabled; empty for all")
+	fs.IntVar(&c.AlertAfterChecks, "alert-after-checks", c.AlertAfterChecks,
+		"number of consecutive unhealthy checks before a cluster is alerted")
+	fs.DurationVar(&c.StuckDeletingAfter, "stuck-deleting-after", c.StuckDeletingAfter,
+		"how long a cluster may stay in deletion before it is reported as stuck")
+	fs.Func("notifiers", "comma separated notifier backends to enable: feishu, slack, dingtalk, wecom, email, pagerduty, telegram, webhook, alertmanager, stdout (default feishu)", func(v string) error {
+		c.Notifiers = splitList(v)
+		return nil
+	})
+	fs.Func("notifier-severities", "severities a notifier receives, as name=critical|warning (repeatable); notifiers not listed receive all", func(v string) error {
+		name, severities, ok := strings.Cut(v, "=")
+		if !ok || name == "" || severities == "" {
+			return fmt.Errorf("invalid notifier severities %q, want name=critical|warning", v)
+		}
+		if c.NotifierSeverities == nil {
+			c.NotifierSeverities = make(map[string][]string)
+		}
+		c.NotifierSeverities[name] = strings.Split(severities, "|")
+		return nil
+	})
+	fs.Func("notifier-template", "Go text/template file a notifier renders messages with, as name=path (repeatable); supported by feishu, slack, dingtalk, wecom and telegram", func(v string) error {
+		name, path, ok := strings.Cut(v, "=")
+		if !ok || name == "" || path == "" {
+			return fmt.Errorf("invalid notifier template %q, want name=path", v)
+		}
+		if c.NotifierTemplates == nil {
+			c.NotifierTemplates = make(map[string]string)
+		}
+		c.NotifierTemplates[name] = path
+		return nil
+	})
+	fs.Func("notifier-locale", "message language of a notifier, as name=en|zh|zh-en (repeatable); notifiers not listed use --locale", func(v string) error {
+		name, locale, ok := strings.Cut(v, "=")
+		if !ok || name == "" || locale == "" {
+			return fmt.Errorf("invalid notifier locale %q, want name=locale", v)
+		}
+		if c.NotifierLocales == nil {
+			c.NotifierLocales = make(map[string]string)
+		}
+		c.NotifierLocales[name] = locale
+		return nil
+	})
+	fs.StringVar(&c.FeishuWebhookURL, "feishu-webhook", c.FeishuWebhookURL,
+		"Feishu bot webhook URL; required by the feishu notifier unless secretRefs in the configuration file reads it from a Secret")
+	fs.StringVar(&c.SlackWebhookURL, "slack-webhook", c.SlackWebhookURL,
+		"Slack incoming webhook URL")
+	fs.StringVar(&c.DingTalkWebhookURL, "dingtalk-webhook", c.DingTalkWebhookURL,
+		"DingTalk robot webhook URL")
+	fs.StringVar(&c.DingTalkSecret, "dingtalk-secret", c.DingTalkSecret,
+		"DingTalk robot signing secret, required when the robot uses signed security settings")
+	fs.StringVar(&c.WeComWebhookURL, "wecom-webhook", c.WeComWebhookURL,
+		"WeCom group robot webhook URL")
+	fs.StringVar(&c.TelegramBotToken, "telegram-bot-token", c.TelegramBotToken, "Telegram bot token")
+	fs.StringVar(&c.TelegramChatID, "telegram-chat-id", c.TelegramChatID, "Telegram chat ID that receives notifications")
+	fs.StringVar(&c.PagerDutyRoutingKey, "pagerduty-routing-key", c.PagerDutyRoutingKey,
+		"PagerDuty Events API v2 integration key; critical clusters trigger incidents that resolve on recovery")
+	fs.StringVar(&c.WebhookURL, "webhook-url", c.WebhookURL,
+		"URL that receives structured JSON alerts from the webhook notifier")
+	fs.Func("webhook-header", "extra header sent by the webhook notifier, as Name: value (repeatable)", func(v string) error {
+		name, value, ok := strings.Cut(v, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid header %q, want Name: value", v)
+		}
+		if c.WebhookHeaders == nil {
+			c.WebhookHeaders = make(map[string]string)
+		}
+		c.WebhookHeaders[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		return nil
+	})
+	fs.StringVar(&c.AlertmanagerURL, "alertmanager-url", c.AlertmanagerURL,
+		"Alertmanager base URL; alerts are posted to its /api/v2/alerts endpoint")
+	fs.StringVar(&c.SMTPHost, "smtp-host", c.SMTPHost, "SMTP server host for email notifications")
+	fs.IntVar(&c.SMTPPort, "smtp-port", c.SMTPPort, "SMTP server port")
+	fs.StringVar(&c.SMTPTLS, "smtp-tls", c.SMTPTLS, "SMTP connection security: starttls, tls or none")
+	fs.StringVar(&c.SMTPUsername, "smtp-username", c.SMTPUsername, "SMTP username, empty to send without authentication")
+	fs.StringVar(&c.SMTPPassword, "smtp-password", c.SMTPPassword, "SMTP password")
+	fs.StringVar(&c.EmailFrom, "email-from", c.EmailFrom, "sender address of email notifications")
+	fs.Func("email-to", "comma separated recipient addresses of email notifications", func(v string) error {
+		c.EmailTo = splitList(v)
+		return nil
+	})
+	fs.StringVar(&c.FeishuSecret, "feishu-secret", c.FeishuSecret,
+		"Feishu bot signing secret, required when the bot has signature verification enabled")
+	fs.StringVar(&c.FeishuSecretFrom, "feishu-secret-from", c.FeishuSecretFrom,
+		"read the Feishu signing secret from a Kubernetes Secret, as namespace/name/key")
+	fs.StringVar(&c.FeishuTemplate, "feishu-template", c.FeishuTemplate,
+		"path to a Go text/template file used to render Feishu messages")
+	fs.StringVar(&c.FeishuFormat, "feishu-format", c.FeishuFormat,
+		"Feishu message format: card for interactive cards, text for the plain text table")
+	fs.BoolVar(&c.FeishuCardActions, "feishu-card-actions", c.FeishuCardActions,
+		"add Acknowledge and Silence 2h buttons to Feishu cards; clicks are sent to /api/v1/feishu/callback on the admin server")
+	fs.StringVar(&c.FeishuVerificationToken, "feishu-verification-token", c.FeishuVerificationToken,
+		"verification token of the Feishu app, used to authenticate card callbacks")
+	fs.StringVar(&c.AdminAddr, "admin-addr", c.AdminAddr,
+		"listen address of the admin HTTP server, empty to disable")
+	fs.StringVar(&c.AdminToken, "admin-token", c.AdminToken,
+		"bearer token required by admin requests that change state, such as creating or deleting silences, and by /admin/preview and /api/v1/config; empty rejects them")
+	fs.DurationVar(&c.ConfigReloadInterval, "config-reload-interval", c.ConfigReloadInterval,
+		"how often the configuration file is checked for changes and reloaded without a restart, 0 to reload only on SIGHUP")
+	fs.StringVar(&c.StateNamespace, "state-namespace", c.StateNamespace,
+		"namespace of the ConfigMap that persists monitor state")
+	fs.StringVar(&c.StateConfigMap, "state-configmap", c.StateConfigMap,
+		"name of the ConfigMap that persists monitor state")
+	fs.BoolVar(&c.DryRun, "dry-run", c.DryRun,
+		"run the full check pipeline but log what would be sent to each notifier instead of sending it; resolution callbacks and tenant notifications are skipped too")
+	fs.StringVar(&c.StatusConfigMap, "status-configmap", c.StatusConfigMap,
+		"name of the ConfigMap in --state-namespace where per-cluster phase, unhealthy-since and debt flags are published every check, empty to disable")
+	fs.StringVar(&c.DumpDir, "dump-dir", c.DumpDir,
+		"directory for goroutine dumps written when the monitor panics")
+	fs.BoolVar(&c.Watch, "watch", c.Watch,
+		"watch clusters with an informer instead of listing them periodically; --watch=false to poll")
+	fs.DurationVar(&c.WatchDebounce, "watch-debounce", c.WatchDebounce,
+		"in watch mode, how long to wait after a change before sending the report")
+	fs.IntVar(&c.NotifyAttempts, "notify-attempts", c.NotifyAttempts,
+		"how many times to try sending a notification before giving up, including the first attempt")
+	fs.DurationVar(&c.NotifyBackoff, "notify-backoff", c.NotifyBackoff,
+		"delay before the first notification retry, doubled on each further retry")
+	fs.DurationVar(&c.NotifyMaxBackoff, "notify-max-backoff", c.NotifyMaxBackoff,
+		"maximum delay between notification retries")
+	fs.Float64Var(&c.NotifyJitter, "notify-jitter", c.NotifyJitter,
+		"random jitter applied to notification retry delays, as a fraction between 0 and 1")
+	fs.Float64Var(&c.NotifyRatePerMinute, "notify-rate-per-minute", c.NotifyRatePerMinute,
+		"maximum notifications per minute across all notifiers, 0 for no limit")
+	fs.IntVar(&c.NotifyBurst, "notify-burst", c.NotifyBurst,
+		"number of notifications that can be sent at once before the rate limit applies")
+	fs.StringVar(&c.NotifyOverflow, "notify-overflow", c.NotifyOverflow,
+		"what to do with notifications over the rate limit: summarize combines them per notifier, queue sends them one by one later")
+	fs.DurationVar(&c.NotifyTimeout, "notify-timeout", c.NotifyTimeout,
+		"timeout of a single notification attempt, resolution callback or Grafana annotation request")
+	fs.DurationVar(&c.CheckTimeout, "check-timeout", c.CheckTimeout,
+		"abandon a check or background refresh that takes longer than this and retry it in the next round, 0 for no limit")
+	fs.DurationVar(&c.APIOutageAlertAfter, "api-outage-alert-after", c.APIOutageAlertAfter,
+		"send a monitor alert when checks keep failing, for example because the API server is unreachable, for this long; 0 to disable")
+	fs.DurationVar(&c.StallTimeout, "stall-timeout", c.StallTimeout,
+		"fail the liveness probe when a check is overdue by this long, 0 to disable")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout,
+		"on SIGTERM or SIGINT, how long to wait for in-flight notifications and state saves before exiting")
+	fs.Int64Var(&c.ListPageSize, "list-page-size", c.ListPageSize,
+		"number of objects per page when listing clusters and audited resources")
+	fs.IntVar(&c.Workers, "workers", c.Workers,
+		"number of workers evaluating clusters concurrently")
+	fs.IntVar(&c.QueueHighWatermark, "queue-high-watermark", c.QueueHighWatermark,
+		"work queue depth above which a warning is logged")
+	fs.Float64Var(&c.APIQPS, "api-qps", c.APIQPS,
+		"queries per second allowed against the API server")
+	fs.IntVar(&c.APIBurst, "api-burst", c.APIBurst,
+		"burst allowed against the API server")
+	fs.DurationVar(&c.RealertInterval, "realert-interval", c.RealertInterval,
+		"minimum time before an unchanged cluster is notified again; phase or severity changes are sent immediately")
+	fs.IntVar(&c.RepeatedIncidentThreshold, "repeated-incident-threshold", c.RepeatedIncidentThreshold,
+		"warn when a cluster has more than this many recovered incidents within 24h, 0 to disable")
+	fs.StringVar(&c.NamespaceWebhookAnnotation, "namespace-webhook-annotation", c.NamespaceWebhookAnnotation,
+		"namespace annotation holding a tenant's Feishu webhook URL; alerts of annotated namespaces go there instead of fallback destinations, empty to disable")
+	fs.StringVar(&c.IgnoreAnnotation, "ignore-annotation", c.IgnoreAnnotation,
+		"cluster or namespace annotation that, when \"true\", stops the cluster from alerting, e.g. for test databases; empty to disable")
+	fs.Func("owner-annotations", "comma separated namespace annotations or labels added to alerts as owner info, e.g. owner,team; empty to disable", func(v string) error {
+		c.OwnerAnnotations = splitList(v)
+		return nil
+	})
+	fs.StringVar(&c.OwnerMentionAnnotation, "owner-mention-annotation", c.OwnerMentionAnnotation,
+		"namespace annotation holding comma separated Feishu user IDs mentioned in Feishu alerts of the namespace, empty to disable")
+	fs.DurationVar(&c.DebtInterval, "debt-interval", c.DebtInterval,
+		"how often the set of namespaces in debt is refreshed")
+	fs.DurationVar(&c.DebtRecordTTL, "debt-record-ttl", c.DebtRecordTTL,
+		"resume alerting for namespaces in debt when the debt set could not be refreshed for this long, 0 to keep the last result")
+	fs.BoolVar(&c.StopInDebt, "stop-in-debt", c.StopInDebt,
+		"stop KubeBlocks clusters in namespaces in debt; they are not started again automatically")
+	fs.StringVar(&c.DebtSource, "debt-source", c.DebtSource,
+		"how debt is detected: auto (sealos Debt CRD, falling back to the debt-limit0 quota), crd or quota")
+	fs.DurationVar(&c.CRDPollInterval, "crd-poll-interval", c.CRDPollInterval,
+		"how often to check for the clusters CRD while it is not installed")
+	fs.DurationVar(&c.DigestInterval, "digest-interval", c.DigestInterval,
+		"how often the digest is sent, 0 to disable")
+	fs.StringVar(&c.DigestSchedule, "digest-schedule", c.DigestSchedule,
+		"cron expression for sending the digest, e.g. \"CRON_TZ=Asia/Shanghai 0 9 * * *\"; overrides --digest-interval")
+	fs.Func("digest-notifiers", "comma separated notifier or destination names that receive the digest, empty for all", func(v string) error {
+		c.DigestNotifiers = splitList(v)
+		return nil
+	})
+	fs.DurationVar(&c.BackupCheckInterval, "backup-check-interval", c.BackupCheckInterval,
+		"how often backup freshness is refreshed, 0 to disable")
+	fs.DurationVar(&c.OpsCheckInterval, "ops-check-interval", c.OpsCheckInterval,
+		"how often KubeBlocks OpsRequests are checked for failures, 0 to disable")
+	fs.DurationVar(&c.OpsRunningTimeout, "ops-running-timeout", c.OpsRunningTimeout,
+		"alert when an OpsRequest has been Running for longer than this")
+	fs.DurationVar(&c.RemediationAfter, "remediation-after", c.RemediationAfter,
+		"create a restart OpsRequest for clusters that have been Failed for this long and are not in debt, 0 to disable")
+	fs.IntVar(&c.RemediationMaxAttempts, "remediation-max-attempts", c.RemediationMaxAttempts,
+		"maximum number of restarts attempted per incident")
+	fs.DurationVar(&c.VolumeCheckInterval, "volume-check-interval", c.VolumeCheckInterval,
+		"how often database PVC usage is read from kubelet stats, 0 to disable")
+	fs.Float64Var(&c.VolumeWarningPercent, "volume-warning-percent", c.VolumeWarningPercent,
+		"warn when a database PVC is at least this full, 0 to disable")
+	fs.Float64Var(&c.VolumeCriticalPercent, "volume-critical-percent", c.VolumeCriticalPercent,
+		"alert as critical when a database PVC is at least this full, 0 to disable")
+	fs.DurationVar(&c.ProbeInterval, "probe-interval", c.ProbeInterval,
+		"how often MySQL and PostgreSQL clusters are probed with SELECT 1 (a TCP connect when no SQL driver is linked), 0 to disable")
+	fs.DurationVar(&c.ProbeTimeout, "probe-timeout", c.ProbeTimeout,
+		"a probe that does not complete within this time counts as unreachable")
+	fs.DurationVar(&c.ProbeLatencyThreshold, "probe-latency-threshold", c.ProbeLatencyThreshold,
+		"alert when a probe takes longer than this, 0 to disable")
+	fs.DurationVar(&c.ReplicationLagThreshold, "replication-lag-threshold", c.ReplicationLagThreshold,
+		"during probes, alert when a replica of a multi-replica cluster is this far behind or not replicating, 0 to disable; needs a linked SQL driver")
+	fs.DurationVar(&c.BackupSLA, "backup-sla", c.BackupSLA,
+		"alert when a cluster with a backup schedule has had no completed backup for this long, 0 to disable")
+	fs.BoolVar(&c.BackupMetrics, "backup-metrics", c.BackupMetrics,
+		"export per-cluster backup age metrics (one series per scheduled cluster)")
+	fs.StringVar(&c.ResolutionCallbackURL, "resolution-callback-url", c.ResolutionCallbackURL,
+		"URL called once with the incident ID and resolution time when an incident closes")
+	fs.StringVar(&c.ResolutionCallbackSecret, "resolution-callback-secret", c.ResolutionCallbackSecret,
+		"secret used to sign resolution callback bodies with HMAC-SHA256")
+	fs.StringVar(&c.ConsoleURL, "console-url", c.ConsoleURL,
+		"URL template of a database's console page linked from every alert, {namespace}, {name} and {region} are replaced")
+	fs.StringVar(&c.GrafanaURL, "grafana-url", c.GrafanaURL,
+		"Grafana base URL; when set, incidents are added as annotations tagged with namespace and cluster")
+	fs.StringVar(&c.GrafanaAPIToken, "grafana-api-token", c.GrafanaAPIToken,
+		"Grafana service account token with permission to write annotations")
+	fs.StringVar(&c.GrafanaDashboardUID, "grafana-dashboard-uid", c.GrafanaDashboardUID,
+		"attach annotations to this dashboard instead of the whole organization")
+	fs.BoolVar(&c.EmitEvents, "emit-events", c.EmitEvents,
+		"create Kubernetes Events on clusters when incidents open and close")
+	fs.DurationVar(&c.EventClusterInterval, "event-cluster-interval", c.EventClusterInterval,
+		"minimum interval between two Events created for the same cluster")
+	fs.IntVar(&c.EventGlobalPerHour, "event-global-per-hour", c.EventGlobalPerHour,
+		"maximum number of Events created per hour across all clusters, 0 for no limit")
+	fs.StringVar(&c.RegistryResource, "registry-resource", c.RegistryResource,
+		"group/version/resource of the region registry; when set, every registered region is monitored instead of this cluster")
+	fs.StringVar(&c.RegistryNamespace, "registry-namespace", c.RegistryNamespace,
+		"namespace of the region registry entries, empty for all namespaces")
+	fs.Func("kube-contexts", "comma separated kubeconfig contexts to monitor, each as a region named after the context", func(v string) error {
+		c.KubeContexts = splitList(v)
+		return nil
+	})
+	fs.StringVar(&c.Locale, "locale", c.Locale,
+		"default message language: en, zh, or zh-en for both Chinese and English")
+	fs.StringVar(&c.Timezone, "timezone", c.Timezone,
+		"default IANA timezone for timestamps in messages, empty for the local timezone")
+	fs.BoolVar(&c.LeaderElect, "leader-elect", c.LeaderElect,
+		"use Lease-based leader election so that only one replica checks clusters and sends notifications")
+	fs.StringVar(&c.LeaderElectionLease, "leader-election-lease", c.LeaderElectionLease,
+		"name of the Lease used for leader election, in the state namespace")
+	fs.StringVar(&c.AuditLog, "audit-log", c.AuditLog,
+		"append-only NDJSON audit log of transitions, notification decisions, alerts and recoveries, - for stdout, empty to disable")
+	fs.Int64Var(&c.AuditMaxBytes, "audit-max-bytes", c.AuditMaxBytes,
+		"size at which the audit log file is rotated")
+	fs.IntVar(&c.AuditBackups, "audit-backups", c.AuditBackups,
+		"number of rotated audit log files to keep")
+	fs.Func("audit-kinds", "comma separated record kinds written to the audit log: transition, notification, alert, recovery, incident (default all)", func(v string) error {
+		c.AuditKinds = splitList(v)
+		return nil
+	})
+	fs.StringVar(&c.HistoryPath, "history-path", c.HistoryPath,
+		"local history database file of transitions and notifications, queried via /api/v1/history; empty to disable")
+	fs.StringVar(&c.OTLPEndpoint, "otlp-endpoint", c.OTLPEndpoint,
+		"OTLP/HTTP endpoint that check cycles, Kubernetes API calls and notification spans are exported to, e.g. http://otel-collector:4318; empty to disable tracing")
+	fs.Float64Var(&c.TraceSampleRatio, "trace-sample-ratio", c.TraceSampleRatio,
+		"fraction of traces sampled, between 0 and 1")
+	fs.DurationVar(&c.HistoryRetention, "history-retention", c.HistoryRetention,
+		"how long history records are kept, 0 to keep forever")
+	fs.StringVar(&c.SLAReportSchedule, "sla-report-schedule", c.SLAReportSchedule,
+		"cron expression for sending the availability report, e.g. \"CRON_TZ=Asia/Shanghai 0 9 * * 1\"; requires --history-path, empty to disable")
+	fs.DurationVar(&c.SLAReportPeriod, "sla-report-period", c.SLAReportPeriod,
+		"period covered by the availability report, e.g. 168h for a week or 720h for a month")
+	fs.Func("sla-report-notifiers", "comma separated notifier or destination names that receive the availability report, empty for all", func(v string) error {
+		c.SLAReportNotifiers = splitList(v)
+		return nil
+	})
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "log level: debug, info, warn or error")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "log output format: text or json")
+	fs.Func("destination", "additional notification destination, may be repeated: name=NAME,type=feishu|stdout,url=URL,locale=en|zh|zh-en,timezone=TZ,template=PATH", func(v string) error {
+		d, err := parseDestination(v)
+		if err != nil {
+			return err
+		}
+		c.Destinations = append(c.Destinations, d)
+		return nil
+	})
+}
+
+// 解析 namespace/name/key 形式的 Secret 引用
+func parseSecretKeyRef(v string) (namespace, name, key string, err error) {
+	parts := strings.Split(v, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("invalid secret reference %q, want namespace/name/key", v)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// 解析逗号分隔的列表，忽略空项
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// 优先使用 Pod 所在的命名空间
+func defaultStateNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	if data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		if ns := strings.TrimSpace(string(data)); ns != "" {
+			return ns
+		}
+	}
+	return "default"
+}
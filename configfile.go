@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+
+	"database-monitor/pkg/monitor"
+	"database-monitor/pkg/notify"
+	"database-monitor/pkg/schedule"
+)
+
+// 在解析其他命令行参数之前找出 --config，配置文件中的值作为各参数的默认值
+func configFileFromArgs(args []string) string {
+	for i, arg := range args {
+		switch {
+		case arg == "--config" || arg == "-config":
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+		case strings.HasPrefix(arg, "--config="):
+			return strings.TrimPrefix(arg, "--config=")
+		case strings.HasPrefix(arg, "-config="):
+			return strings.TrimPrefix(arg, "-config=")
+		}
+	}
+	return ""
+}
+
+// loadConfigFile 把 YAML 配置文件合并到 c 中。字段名与 JSON 标签一致，时长可以写成 "5m" 这样的字符串，
+// 未知字段视为错误，避免拼写错误被静默忽略
+func (c *Config) loadConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(jsonData, &raw); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	if raw == nil {
+		return nil
+	}
+	if err := normalizeDurations(raw, reflect.TypeOf(*c)); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	jsonData, err = json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// 把 time.Duration 字段的字符串值转换为纳秒数，嵌入的结构体按 JSON 的规则展开，结构体切片逐个元素转换
+func normalizeDurations(raw map[string]interface{}, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := normalizeDurations(raw, f.Type); err != nil {
+				return err
+			}
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" {
+			continue
+		}
+		if f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct {
+			items, _ := raw[name].([]interface{})
+			for j, item := range items {
+				if obj, ok := item.(map[string]interface{}); ok {
+					if err := normalizeDurations(obj, f.Type.Elem()); err != nil {
+						return fmt.Errorf("%s[%d]: %w", name, j, err)
+					}
+				}
+			}
+			continue
+		}
+		if f.Type != durationType {
+			continue
+		}
+		s, ok := raw[name].(string)
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		raw[name] = int64(d)
+	}
+	return nil
+}
+
+// validate 检查配置是否可用，返回第一个问题
+func (c Config) validate() error {
+	positive := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"checkInterval", c.CheckInterval},
+		{"stuckDeletingAfter", c.StuckDeletingAfter},
+		{"realertInterval", c.RealertInterval},
+		{"debtInterval", c.DebtInterval},
+		{"crdPollInterval", c.CRDPollInterval},
+		{"watchDebounce", c.WatchDebounce},
+		{"shutdownTimeout", c.ShutdownTimeout},
+		{"opsRunningTimeout", c.OpsRunningTimeout},
+		{"notifyBackoff", c.NotifyBackoff},
+		{"probeTimeout", c.ProbeTimeout},
+		{"notifyMaxBackoff", c.NotifyMaxBackoff},
+		{"notifyTimeout", c.NotifyTimeout},
+		{"slaReportPeriod", c.SLAReportPeriod},
+	}
+	for _, p := range positive {
+		if p.d <= 0 {
+			return fmt.Errorf("%s must be positive, got %s", p.name, p.d)
+		}
+	}
+	switch {
+	case c.AlertAfterChecks < 1:
+		return fmt.Errorf("alertAfterChecks must be at least 1, got %d", c.AlertAfterChecks)
+	case c.RemediationAfter > 0 && c.RemediationMaxAttempts < 1:
+		return fmt.Errorf("remediationMaxAttempts must be at least 1, got %d", c.RemediationMaxAttempts)
+	case c.NotifyAttempts < 1:
+		return fmt.Errorf("notifyAttempts must be at least 1, got %d", c.NotifyAttempts)
+	case c.NotifyJitter < 0 || c.NotifyJitter > 1:
+		return fmt.Errorf("notifyJitter must be between 0 and 1, got %v", c.NotifyJitter)
+	case c.ConsoleURL != "" && !strings.HasPrefix(c.ConsoleURL, "http://") && !strings.HasPrefix(c.ConsoleURL, "https://"):
+		return fmt.Errorf("consoleURL must be an http or https URL, got %q", c.ConsoleURL)
+	case c.APIOutageAlertAfter < 0:
+		return fmt.Errorf("apiOutageAlertAfter must not be negative, got %s", c.APIOutageAlertAfter)
+	case c.CheckTimeout < 0:
+		return fmt.Errorf("checkTimeout must not be negative, got %s", c.CheckTimeout)
+	case c.ConfigReloadInterval < 0:
+		return fmt.Errorf("configReloadInterval must not be negative, got %s", c.ConfigReloadInterval)
+	case c.NotifyRatePerMinute < 0:
+		return fmt.Errorf("notifyRatePerMinute must not be negative, got %v", c.NotifyRatePerMinute)
+	case c.NotifyRatePerMinute > 0 && c.NotifyBurst < 1:
+		return fmt.Errorf("notifyBurst must be at least 1, got %d", c.NotifyBurst)
+	case c.NotifyOverflow != monitor.NotifyOverflowSummarize && c.NotifyOverflow != monitor.NotifyOverflowQueue:
+		return fmt.Errorf("notifyOverflow must be %q or %q, got %q", monitor.NotifyOverflowSummarize, monitor.NotifyOverflowQueue, c.NotifyOverflow)
+	case c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1:
+		return fmt.Errorf("traceSampleRatio must be between 0 and 1, got %v", c.TraceSampleRatio)
+	case c.VolumeWarningPercent < 0 || c.VolumeWarningPercent > 100:
+		return fmt.Errorf("volumeWarningPercent must be between 0 and 100, got %v", c.VolumeWarningPercent)
+	case c.VolumeCriticalPercent < 0 || c.VolumeCriticalPercent > 100:
+		return fmt.Errorf("volumeCriticalPercent must be between 0 and 100, got %v", c.VolumeCriticalPercent)
+	case c.VolumeWarningPercent > 0 && c.VolumeCriticalPercent > 0 && c.VolumeWarningPercent > c.VolumeCriticalPercent:
+		return fmt.Errorf("volumeWarningPercent must not exceed volumeCriticalPercent")
+	case c.ListPageSize <= 0:
+		return fmt.Errorf("listPageSize must be positive, got %d", c.ListPageSize)
+	case c.Workers <= 0:
+		return fmt.Errorf("workers must be positive, got %d", c.Workers)
+	case c.APIQPS <= 0:
+		return fmt.Errorf("apiQPS must be positive, got %v", c.APIQPS)
+	case c.APIBurst <= 0:
+		return fmt.Errorf("apiBurst must be positive, got %d", c.APIBurst)
+	}
+	for _, p := range append(append([]string(nil), c.IncludeNamespaces...), c.ExcludeNamespaces...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid namespace pattern %q: %w", p, err)
+		}
+	}
+	names := make(map[string]bool)
+	for _, name := range c.Notifiers {
+		names[name] = true
+	}
+	for _, d := range c.Destinations {
+		names[d.Name] = true
+	}
+	for _, name := range c.DigestNotifiers {
+		if !names[name] {
+			return fmt.Errorf("digestNotifiers: unknown notifier %q", name)
+		}
+	}
+	for _, name := range c.SLAReportNotifiers {
+		if !names[name] {
+			return fmt.Errorf("slaReportNotifiers: unknown notifier %q", name)
+		}
+	}
+	if c.SLAReportSchedule != "" {
+		if _, err := schedule.Parse(c.SLAReportSchedule, nil); err != nil {
+			return fmt.Errorf("invalid slaReportSchedule: %w", err)
+		}
+		if c.HistoryPath == "" {
+			return fmt.Errorf("historyPath must be set when slaReportSchedule is set")
+		}
+	}
+	for _, t := range c.Escalations {
+		if err := monitor.ValidateEscalationTier(t); err != nil {
+			return fmt.Errorf("escalation %s: %w", t.Name, err)
+		}
+		if err := validSeverities(t.Severities); err != nil {
+			return fmt.Errorf("escalation %s: %w", t.Name, err)
+		}
+		for _, name := range t.Notifiers {
+			if !names[name] {
+				return fmt.Errorf("escalation %s: unknown notifier %q", t.Name, name)
+			}
+			// 这两个后端会解决报告中不存在的告警，收到升级的部分条目后其余告警会反复解决和重新触发
+			if name == "pagerduty" || name == "alertmanager" {
+				return fmt.Errorf("escalation %s: %s notifier cannot be an escalation target, it resolves alerts missing from the escalated report", t.Name, name)
+			}
+		}
+	}
+	for _, r := range c.Resources {
+		if err := monitor.ValidateMonitoredResource(r); err != nil {
+			return fmt.Errorf("resource %s: %w", r.Resource, err)
+		}
+	}
+	for i, r := range c.ConditionRules {
+		if err := monitor.ValidateConditionRule(r); err != nil {
+			return fmt.Errorf("condition rule %d: %w", i+1, err)
+		}
+	}
+	for _, w := range c.MaintenanceWindows {
+		if err := monitor.ValidateMaintenanceWindow(w); err != nil {
+			return fmt.Errorf("maintenance window %s: %w", w.Name, err)
+		}
+	}
+	if c.CheckSchedule != "" {
+		if _, err := schedule.Parse(c.CheckSchedule, nil); err != nil {
+			return fmt.Errorf("invalid checkSchedule: %w", err)
+		}
+	}
+	if c.DigestSchedule != "" {
+		if _, err := schedule.Parse(c.DigestSchedule, nil); err != nil {
+			return fmt.Errorf("invalid digestSchedule: %w", err)
+		}
+	}
+	if _, err := labels.Parse(c.ClusterSelector); err != nil {
+		return fmt.Errorf("invalid clusterSelector: %w", err)
+	}
+	for _, name := range c.Notifiers {
+		switch name {
+		case "feishu":
+			if !c.hasSecret(c.FeishuWebhookURL, "feishuWebhookURL") {
+				return fmt.Errorf("feishuWebhookURL or secretRefs.feishuWebhookURL must be set when the feishu notifier is enabled")
+			}
+		case "slack":
+			if !c.hasSecret(c.SlackWebhookURL, "slackWebhookURL") {
+				return fmt.Errorf("slackWebhookURL or secretRefs.slackWebhookURL must be set when the slack notifier is enabled")
+			}
+		case "dingtalk":
+			if !c.hasSecret(c.DingTalkWebhookURL, "dingtalkWebhookURL") {
+				return fmt.Errorf("dingtalkWebhookURL or secretRefs.dingtalkWebhookURL must be set when the dingtalk notifier is enabled")
+			}
+		case "wecom":
+			if !c.hasSecret(c.WeComWebhookURL, "wecomWebhookURL") {
+				return fmt.Errorf("wecomWebhookURL or secretRefs.wecomWebhookURL must be set when the wecom notifier is enabled")
+			}
+		case "alertmanager":
+			if c.AlertmanagerURL == "" {
+				return fmt.Errorf("alertmanagerURL must be set when the alertmanager notifier is enabled")
+			}
+		case "webhook":
+			if !c.hasSecret(c.WebhookURL, "webhookURL") {
+				return fmt.Errorf("webhookURL or secretRefs.webhookURL must be set when the webhook notifier is enabled")
+			}
+		case "telegram":
+			if !c.hasSecret(c.TelegramBotToken, "telegramBotToken") || c.TelegramChatID == "" {
+				return fmt.Errorf("telegramBotToken (or secretRefs.telegramBotToken) and telegramChatID must be set when the telegram notifier is enabled")
+			}
+		case "pagerduty":
+			if !c.hasSecret(c.PagerDutyRoutingKey, "pagerdutyRoutingKey") {
+				return fmt.Errorf("pagerdutyRoutingKey or secretRefs.pagerdutyRoutingKey must be set when the pagerduty notifier is enabled")
+			}
+		case "email":
+			switch {
+			case c.SMTPHost == "" || c.EmailFrom == "" || len(c.EmailTo) == 0:
+				return fmt.Errorf("smtpHost, emailFrom and emailTo must be set when the email notifier is enabled")
+			case c.SMTPPort <= 0:
+				return fmt.Errorf("smtpPort must be positive, got %d", c.SMTPPort)
+			case c.SMTPTLS != notify.SMTPStartTLS && c.SMTPTLS != notify.SMTPTLS && c.SMTPTLS != notify.SMTPPlain:
+				return fmt.Errorf("smtpTLS must be %s, %s or %s, got %q", notify.SMTPStartTLS, notify.SMTPTLS, notify.SMTPPlain, c.SMTPTLS)
+			}
+		case "stdout":
+		default:
+			return fmt.Errorf("unknown notifier %q", name)
+		}
+	}
+	for name, severities := range c.NotifierSeverities {
+		if err := validSeverities(severities); err != nil {
+			return fmt.Errorf("notifierSeverities %s: %w", name, err)
+		}
+	}
+	for _, kind := range c.AuditKinds {
+		if !slices.Contains(monitor.AuditKinds, kind) {
+			return fmt.Errorf("auditKinds: unknown kind %q, expected one of %s", kind, strings.Join(monitor.AuditKinds, ", "))
+		}
+	}
+	for name, locale := range c.NotifierLocales {
+		if !notify.ValidLocale(locale) {
+			return fmt.Errorf("notifierLocales %s: unknown locale %q", name, locale)
+		}
+	}
+	for name := range c.NotifierTemplates {
+		switch name {
+		case "feishu", "slack", "dingtalk", "wecom", "telegram":
+		default:
+			return fmt.Errorf("notifierTemplates %s: notifier does not support templates", name)
+		}
+	}
+	for _, d := range c.Destinations {
+		if err := validSeverities(d.Severities); err != nil {
+			return fmt.Errorf("destination %s: %w", d.Name, err)
+		}
+		switch d.Type {
+		case "feishu", "slack", "dingtalk", "wecom", "webhook", "stdout":
+		default:
+			return fmt.Errorf("destination %s: unknown type %q", d.Name, d.Type)
+		}
+		if d.Template != "" && (d.Type == "webhook" || d.Type == "stdout") {
+			return fmt.Errorf("destination %s: %s does not support templates", d.Name, d.Type)
+		}
+		if d.Type != "stdout" && d.URL == "" && d.SecretRefs["url"].Name == "" {
+			return fmt.Errorf("destination %s: url must be set", d.Name)
+		}
+		for name, ref := range d.SecretRefs {
+			if _, ok := d.secretFields()[name]; !ok {
+				return fmt.Errorf("destination %s: secretRefs %s: only url and secret can be read from a Secret", d.Name, name)
+			}
+			if ref.Name == "" || ref.Key == "" {
+				return fmt.Errorf("destination %s: secretRefs %s: name and key must be set", d.Name, name)
+			}
+		}
+		if d.Locale != "" && !notify.ValidLocale(d.Locale) {
+			return fmt.Errorf("destination %s: unknown locale %q", d.Name, d.Locale)
+		}
+		if _, err := notify.ParseFormat(d.Locale, d.Timezone); err != nil {
+			return fmt.Errorf("destination %s: %w", d.Name, err)
+		}
+		for _, p := range d.Namespaces {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("destination %s: invalid namespace pattern %q: %w", d.Name, p, err)
+			}
+		}
+	}
+	if c.FeishuSecretFrom != "" {
+		if _, _, _, err := parseSecretKeyRef(c.FeishuSecretFrom); err != nil {
+			return err
+		}
+	}
+	fields := c.secretFields()
+	for name, ref := range c.SecretRefs {
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("secretRefs %s: field cannot be read from a Secret", name)
+		}
+		if ref.Name == "" || ref.Key == "" {
+			return fmt.Errorf("secretRefs %s: name and key must be set", name)
+		}
+	}
+	if c.FeishuFormat != "card" && c.FeishuFormat != "text" {
+		return fmt.Errorf("feishuFormat must be card or text, got %q", c.FeishuFormat)
+	}
+	if c.FeishuCardActions && (!c.hasSecret(c.FeishuVerificationToken, "feishuVerificationToken") || c.AdminAddr == "") {
+		return fmt.Errorf("feishuCardActions needs feishuVerificationToken and adminAddr to receive button clicks")
+	}
+	if !notify.ValidLocale(c.Locale) {
+		return fmt.Errorf("locale must be %s, %s or %s, got %q", notify.LocaleEnglish, notify.LocaleChinese, notify.LocaleBilingual, c.Locale)
+	}
+	if _, err := notify.ParseFormat(c.Locale, c.Timezone); err != nil {
+		return err
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return fmt.Errorf("invalid logLevel: %w", err)
+	}
+	switch c.DebtSource {
+	case monitor.DebtSourceAuto, monitor.DebtSourceCRD, monitor.DebtSourceQuota:
+	default:
+		return fmt.Errorf("debtSource must be %s, %s or %s, got %q", monitor.DebtSourceAuto, monitor.DebtSourceCRD, monitor.DebtSourceQuota, c.DebtSource)
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("logFormat must be text or json, got %q", c.LogFormat)
+	}
+	if c.LeaderElect && c.LeaderElectionLease == "" {
+		return fmt.Errorf("leaderElectionLease must be set when leader election is enabled")
+	}
+	if c.RegistryResource != "" {
+		if _, err := parseGVR(c.RegistryResource); err != nil {
+			return err
+		}
+		if len(c.KubeContexts) > 0 {
+			return fmt.Errorf("registryResource and kubeContexts cannot be used together")
+		}
+	}
+	return nil
+}
+
+// 字段已设置，或者从 Secret 读取
+func (c Config) hasSecret(value, name string) bool {
+	return value != "" || c.SecretRefs[name].Name != ""
+}
+
+func validSeverities(severities []string) error {
+	for _, s := range severities {
+		switch s {
+		case monitor.SeverityCritical, monitor.SeverityWarning, monitor.SeverityInfo:
+		default:
+			return fmt.Errorf("unknown severity %q", s)
+		}
+	}
+	return nil
+}
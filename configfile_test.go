@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 飞书卡片按钮的校验 token 可以直接配置，也可以只通过 secretRefs 从 Secret 读取
+func TestValidateFeishuVerificationToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		valid bool
+	}{
+		{"inline token", "feishuVerificationToken: inline\n", true},
+		{"secretRef only", "secretRefs:\n  feishuVerificationToken: {name: feishu-bot, key: verification-token}\n", true},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			data := "feishuWebhookURL: https://open.feishu.cn/hook/x\nfeishuCardActions: true\nadminAddr: \":8080\"\n" + tt.yaml
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			c := defaultConfig()
+			if err := c.loadConfigFile(path); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.validate(); (err == nil) != tt.valid {
+				t.Errorf("validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+// 升级只发送部分条目，会解决报告外告警的 pagerduty 和 alertmanager 不能作为升级目标
+func TestValidateEscalationNotifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		notifier string
+		valid    bool
+	}{
+		{"chat notifier", "stdout", true},
+		{"pagerduty", "pagerduty", false},
+		{"alertmanager", "alertmanager", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			data := "notifiers: [stdout, pagerduty, alertmanager]\npagerdutyRoutingKey: key\nalertmanagerURL: http://alertmanager:9093\n" +
+				"escalations:\n- {name: oncall, after: 30m, notifiers: [" + tt.notifier + "]}\n"
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			c := defaultConfig()
+			if err := c.loadConfigFile(path); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.validate(); (err == nil) != tt.valid {
+				t.Errorf("validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// 最多保留的历史配置版本数
+const maxConfigVersions = 10
+
+// configVersion 一次加载生效的配置
+type configVersion struct {
+	Hash     string    `json:"hash"`
+	LoadedAt time.Time `json:"loadedAt"`
+}
+
+var (
+	configVersionsMu sync.Mutex
+	// 按加载顺序排列，最后一个是当前生效的版本
+	configVersions []configVersion
+)
+
+// 对生效配置计算短哈希，用于把告警行为归因到具体的配置版本
+func configHash(c Config) string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "unknown"
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func recordConfigVersion(hash string) {
+	configVersionsMu.Lock()
+	defer configVersionsMu.Unlock()
+	configVersions = append(configVersions, configVersion{Hash: hash, LoadedAt: time.Now()})
+	if len(configVersions) > maxConfigVersions {
+		configVersions = configVersions[len(configVersions)-maxConfigVersions:]
+	}
+}
+
+func currentConfigHash() string {
+	configVersionsMu.Lock()
+	defer configVersionsMu.Unlock()
+	if len(configVersions) == 0 {
+		return ""
+	}
+	return configVersions[len(configVersions)-1].Hash
+}
+
+func configVersionHistory() []configVersion {
+	configVersionsMu.Lock()
+	defer configVersionsMu.Unlock()
+	return append([]configVersion(nil), configVersions...)
+}
+
+// 去掉敏感字段后的配置，用于对外展示
+func (c Config) redacted() Config {
+	if c.FeishuWebhookURL != "" {
+		c.FeishuWebhookURL = "***"
+	}
+	if c.FeishuSecret != "" {
+		c.FeishuSecret = "***"
+	}
+	if c.SlackWebhookURL != "" {
+		c.SlackWebhookURL = "***"
+	}
+	if c.DingTalkWebhookURL != "" {
+		c.DingTalkWebhookURL = "***"
+	}
+	if c.DingTalkSecret != "" {
+		c.DingTalkSecret = "***"
+	}
+	if c.WeComWebhookURL != "" {
+		c.WeComWebhookURL = "***"
+	}
+	if c.TelegramBotToken != "" {
+		c.TelegramBotToken = "***"
+	}
+	if c.PagerDutyRoutingKey != "" {
+		c.PagerDutyRoutingKey = "***"
+	}
+	c.WebhookHeaders = redactHeaders(c.WebhookHeaders)
+	if c.SMTPPassword != "" {
+		c.SMTPPassword = "***"
+	}
+	destinations := make([]Destination, len(c.Destinations))
+	for i, d := range c.Destinations {
+		if d.URL != "" {
+			d.URL = "***"
+		}
+		if d.Secret != "" {
+			d.Secret = "***"
+		}
+		d.Headers = redactHeaders(d.Headers)
+		destinations[i] = d
+	}
+	c.Destinations = destinations
+	if c.ResolutionCallbackSecret != "" {
+		c.ResolutionCallbackSecret = "***"
+	}
+	if c.FeishuVerificationToken != "" {
+		c.FeishuVerificationToken = "***"
+	}
+	if c.GrafanaAPIToken != "" {
+		c.GrafanaAPIToken = "***"
+	}
+	if c.AdminToken != "" {
+		c.AdminToken = "***"
+	}
+	return c
+}
+
+// 请求头的值可能是凭据，只保留名称
+func redactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(headers))
+	for k := range headers {
+		redacted[k] = "***"
+	}
+	return redacted
+}
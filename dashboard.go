@@ -0,0 +1,20 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// 值班用的只读页面，数据来自 /api/v1 下的状态接口，定时刷新
+//
+//go:embed dashboard/index.html
+var dashboardHTML []byte
+
+func (s *adminServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/dashboard/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+// 中文消息中与语言相关的文本对应的英文
+var zhToEnglish = strings.NewReplacer(
+	"数据库巡检", "Database monitor",
+	"1 个集群需要关注", "1 clusters need attention",
+	"数据库名称", "DatabaseName",
+	"命名空间", "Namespace",
+	"状态", "Status",
+	"区域", "Region",
+	"生成时间", "Generated at",
+	"负责人", "Owner",
+)
+
+var (
+	renderedTime = regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} \S+`)
+	// 表头按各语言文本的宽度补齐空格
+	padding = regexp.MustCompile(` {2,}`)
+)
+
+// 同一份报告渲染到两个配置了不同语言和时区的目的地，只有语言相关的文本和时间不同
+func TestRenderToTwoDestinations(t *testing.T) {
+	report := monitor.Report{
+		Region:      "ap-southeast-1",
+		GeneratedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Entries:     []monitor.ReportEntry{{Namespace: "ns1", Name: "db", Phase: "Failed", Severity: monitor.SeverityCritical, Owner: "alice"}},
+	}
+	ops, err := parseDestination("name=ops,type=feishu,url=https://open.feishu.cn/hook/ops,locale=zh,timezone=Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenant, err := parseDestination("name=tenant-sg,type=feishu,url=https://open.feishu.cn/hook/sg,locale=en,timezone=Asia/Singapore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"text", "card"} {
+		t.Run(format, func(t *testing.T) {
+			c := defaultConfig()
+			c.FeishuFormat = format
+			render := func(d Destination) string {
+				n, err := newNotifier(c, d, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				payload, err := n.Render(report)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return string(payload)
+			}
+			zh, en := render(ops), render(tenant)
+			if !strings.Contains(zh, "生成时间: 2024-01-01 08:00:00 CST") {
+				t.Errorf("ops message is not in Chinese with CST timestamps:\n%s", zh)
+			}
+			if !strings.Contains(en, "Generated at: 2024-01-01 08:00:00 +08") {
+				t.Errorf("tenant message is not in English with Singapore timestamps:\n%s", en)
+			}
+			normalize := func(s string) string {
+				return padding.ReplaceAllString(renderedTime.ReplaceAllString(s, "<time>"), " ")
+			}
+			if got, want := normalize(zhToEnglish.Replace(zh)), normalize(en); got != want {
+				t.Errorf("messages differ beyond locale and timezone:\nops:    %s\ntenant: %s", got, want)
+			}
+		})
+	}
+}
+
+// 目的地未指定语言和时区时使用全局设置
+func TestDestinationFormatDefaults(t *testing.T) {
+	c := defaultConfig()
+	c.FeishuFormat = "text"
+	c.Locale, c.Timezone = "zh", "Asia/Shanghai"
+	n, err := newNotifier(c, Destination{Name: "ops", Type: "feishu", URL: "https://open.feishu.cn/hook/ops"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := n.Render(monitor.Report{GeneratedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(payload), "生成时间: 2024-01-01 08:00:00 CST") {
+		t.Errorf("payload = %s, want the global locale and timezone", payload)
+	}
+}
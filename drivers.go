@@ -0,0 +1,8 @@
+package main
+
+// 主动探测通过 database/sql 执行 SELECT 1 和复制状态查询，驱动在这里注册；
+// 没有注册驱动的引擎只检查 TCP 连接
+import (
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
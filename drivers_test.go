@@ -0,0 +1,16 @@
+package main
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+)
+
+// 发布的二进制必须注册探测使用的驱动，否则探测只会检查 TCP 连接
+func TestProbeDriversRegistered(t *testing.T) {
+	for _, name := range []string{"mysql", "pgx"} {
+		if !slices.Contains(sql.Drivers(), name) {
+			t.Errorf("database/sql driver %q is not registered, registered: %v", name, sql.Drivers())
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"database-monitor/pkg/monitor"
+	"database-monitor/pkg/notify"
+)
+
+// feishuCallback 飞书的请求网址校验和卡片回传交互，兼容旧版回调和 2.0 版本的 card.action.trigger 事件
+type feishuCallback struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Token     string `json:"token"`
+	Encrypt   string `json:"encrypt"`
+
+	Schema string `json:"schema"`
+	Header struct {
+		Token     string `json:"token"`
+		EventType string `json:"event_type"`
+	} `json:"header"`
+	Event struct {
+		Operator feishuOperator `json:"operator"`
+		Action   feishuAction   `json:"action"`
+	} `json:"event"`
+
+	// 旧版回调直接在顶层给出操作人和按钮
+	feishuOperator
+	Action feishuAction `json:"action"`
+}
+
+type feishuOperator struct {
+	OpenID string `json:"open_id"`
+	UserID string `json:"user_id"`
+}
+
+type feishuAction struct {
+	Value notify.FeishuActionValue `json:"value"`
+}
+
+// 操作人，优先使用 user_id
+func (o feishuOperator) String() string {
+	if o.UserID != "" {
+		return "feishu:" + o.UserID
+	}
+	return "feishu:" + o.OpenID
+}
+
+// POST /api/v1/feishu/callback 处理卡片上的确认和静默按钮，用应用的 Verification Token 校验来源
+func (s *adminServer) handleFeishuCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	cfg := currentConfig()
+	if !cfg.FeishuCardActions {
+		http.Error(w, "feishu card actions are not enabled", http.StatusNotFound)
+		return
+	}
+	var cb feishuCallback
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&cb); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if cb.Encrypt != "" {
+		http.Error(w, "encrypted callbacks are not supported, disable the Encrypt Key of the Feishu app", http.StatusBadRequest)
+		return
+	}
+	token, operator, value := cb.Token, cb.feishuOperator, cb.Action.Value
+	if cb.Schema != "" {
+		token, operator, value = cb.Header.Token, cb.Event.Operator, cb.Event.Action.Value
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.FeishuVerificationToken)) != 1 {
+		http.Error(w, "invalid verification token", http.StatusUnauthorized)
+		return
+	}
+	if cb.Type == "url_verification" {
+		writeJSON(w, map[string]string{"challenge": cb.Challenge})
+		return
+	}
+
+	message, err := s.applyFeishuAction(r, value, operator.String())
+	if err != nil {
+		slog.Warn("Feishu card action failed", "action", value.Action, "cluster", value.Cluster, "namespace", value.Namespace, "err", err)
+		writeFeishuToast(w, "error", redactor.String(err.Error()))
+		return
+	}
+	slog.Info("Feishu card action applied", "action", value.Action, "cluster", value.Cluster, "namespace", value.Namespace, "by", operator.String())
+	writeFeishuToast(w, "success", message)
+}
+
+// 在按钮所属区域的 Monitor 上确认事件或创建静默，返回给点击者的提示
+func (s *adminServer) applyFeishuAction(r *http.Request, v notify.FeishuActionValue, by string) (string, error) {
+	if v.Namespace == "" || v.Cluster == "" {
+		return "", fmt.Errorf("button has no cluster")
+	}
+	var m *monitor.Monitor
+	for _, candidate := range s.monitors() {
+		if candidate.Region() == v.Region {
+			m = candidate
+		}
+	}
+	if m == nil {
+		return "", fmt.Errorf("unknown region %q", v.Region)
+	}
+	switch v.Action {
+	case "ack":
+		if _, err := m.Acknowledge(r.Context(), v.Namespace, v.Cluster, by); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Acknowledged %s/%s", v.Namespace, v.Cluster), nil
+	case "silence":
+		d, err := time.ParseDuration(v.Duration)
+		if err != nil || d <= 0 {
+			return "", fmt.Errorf("invalid duration %q", v.Duration)
+		}
+		silence := monitor.Silence{Namespace: v.Namespace, Cluster: v.Cluster, EndsAt: time.Now().Add(d),
+			Reason: "silenced from Feishu", CreatedBy: by}
+		if _, err := m.AddSilence(r.Context(), silence); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Silenced %s/%s for %s", v.Namespace, v.Cluster, d), nil
+	}
+	return "", fmt.Errorf("unknown action %q", v.Action)
+}
+
+// 飞书在点击者的客户端上显示的提示，旧版回调忽略该字段
+func writeFeishuToast(w http.ResponseWriter, kind, content string) {
+	writeJSON(w, map[string]interface{}{"toast": map[string]string{"type": kind, "content": content}})
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sync/atomic"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+const (
+	lastExitKey = "last-exit"
+	// 一小时内异常退出达到该次数时升级通知
+	escalateAbnormalExits = 3
+
+	exitReasonSignal = "signal"
+	exitReasonPanic  = "panic"
+	// 存活检查失败后收到 SIGTERM，通常是 kubelet 因主循环卡住而重启进程
+	exitReasonStalled = "stalled"
+	// 选主模式下失去 leader 身份，属于正常的主备切换
+	exitReasonLeaderLost = "leader lost"
+)
+
+// exitRecord 记录监控进程上一次退出的原因
+type exitRecord struct {
+	Reason   string    `json:"reason"`
+	Message  string    `json:"message,omitempty"`
+	Time     time.Time `json:"time"`
+	DumpFile string    `json:"dumpFile,omitempty"`
+	// 是否已在启动时通知过
+	Reported bool `json:"reported"`
+	// 最近一小时内的异常退出时间
+	AbnormalExits []time.Time `json:"abnormalExits,omitempty"`
+}
+
+func (r exitRecord) abnormal() bool {
+	return r.Reason != exitReasonSignal && r.Reason != exitReasonLeaderLost
+}
+
+func loadExitRecord(ctx context.Context) (*exitRecord, error) {
+	value, err := store.Get(ctx, lastExitKey)
+	if err != nil || value == "" {
+		return nil, err
+	}
+	var record exitRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func saveExitRecord(ctx context.Context, record exitRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return store.Set(ctx, lastExitKey, string(data))
+}
+
+// 在退出路径上写入退出记录
+func recordExit(reason, message, dumpFile string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	record := exitRecord{Reason: reason, Message: message, Time: time.Now(), DumpFile: dumpFile}
+	if prev, err := loadExitRecord(ctx); err == nil && prev != nil {
+		for _, t := range prev.AbnormalExits {
+			if record.Time.Sub(t) < time.Hour {
+				record.AbnormalExits = append(record.AbnormalExits, t)
+			}
+		}
+	}
+	if record.abnormal() {
+		record.AbnormalExits = append(record.AbnormalExits, record.Time)
+	}
+	if err := saveExitRecord(ctx, record); err != nil {
+		slog.Error("Error saving exit record", "err", err)
+	}
+}
+
+// 启动时报告上一次退出的原因，异常退出时发送一次自监控通知
+func reportLastExit(ctx context.Context, m *monitor.Monitor) {
+	record, err := loadExitRecord(ctx)
+	if err != nil {
+		slog.Error("Error loading last exit record", "err", err)
+		return
+	}
+	if record == nil {
+		slog.Info("No previous exit record found")
+		return
+	}
+	slog.Info("Previous exit", "reason", record.Reason, "time", record.Time.Format(time.RFC3339),
+		"message", record.Message, "dump", record.DumpFile)
+	if record.Reported || !record.abnormal() {
+		return
+	}
+
+	recent := 0
+	for _, t := range record.AbnormalExits {
+		if time.Since(t) < time.Hour {
+			recent++
+		}
+	}
+	notice := fmt.Sprintf("database-monitor restarted after an abnormal exit (%s at %s): %s",
+		record.Reason, record.Time.Format(time.RFC3339), record.Message)
+	if record.DumpFile != "" {
+		notice += "\ngoroutine dump: " + record.DumpFile
+	}
+	if recent >= escalateAbnormalExits {
+		notice = fmt.Sprintf("[ESCALATED] database-monitor exited abnormally %d times within the last hour\n", recent) + notice
+	}
+	m.Notify(ctx, m.NewNotice(notice))
+
+	record.Reported = true
+	if err := saveExitRecord(ctx, *record); err != nil {
+		slog.Error("Error saving exit record", "err", err)
+	}
+}
+
+// 捕获 fn 中的 panic，写入 goroutine dump 和退出记录后继续向上抛出。
+// Monitor 自己启动的 goroutine 不在这里，由 Deps.OnPanic 调用 recordPanic
+func runGuarded(fn func()) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		recordPanic(r)
+		panic(r)
+	}()
+	fn()
+}
+
+// 在 panic 的 goroutine 中写入 goroutine dump 和退出记录，调用方随后继续抛出
+func recordPanic(r interface{}) {
+	dumpFile := writeGoroutineDump("panic")
+	recordExit(exitReasonPanic, redactor.String(fmt.Sprint(r)), dumpFile)
+}
+
+// stall 存活检查失败时记录的原因和当时的 goroutine dump
+type stall struct {
+	reason   string
+	dumpFile string
+}
+
+// 存活检查失败后保存原因，检查恢复后清空；随后收到的 SIGTERM 记为 stalled 而不是正常退出
+var lastStall atomic.Pointer[stall]
+
+// 存活检查失败时调用，只在第一次失败时写入 goroutine dump，便于查看主循环卡在哪里
+func markStalled(reason string) {
+	if lastStall.Load() != nil {
+		return
+	}
+	s := &stall{reason: reason, dumpFile: writeGoroutineDump("stall")}
+	if lastStall.CompareAndSwap(nil, s) {
+		slog.Warn("Liveness check failed, checks are stalled", "reason", reason, "dump", s.dumpFile)
+	}
+}
+
+func clearStalled() {
+	if lastStall.Swap(nil) != nil {
+		slog.Info("Liveness check passing again")
+	}
+}
+
+// 收到退出信号时的退出原因：存活检查失败之后的 SIGTERM 多半来自 kubelet 重启，记为 stalled
+func signalExit(sig os.Signal) (reason, message, dumpFile string) {
+	if s := lastStall.Load(); s != nil {
+		return exitReasonStalled, fmt.Sprintf("%s after the liveness check failed: %s", sig, s.reason), s.dumpFile
+	}
+	return exitReasonSignal, sig.String(), ""
+}
+
+func writeGoroutineDump(kind string) string {
+	path := filepath.Join(cfg.DumpDir, fmt.Sprintf("database-monitor-%s-%d.txt", kind, time.Now().Unix()))
+	f, err := os.Create(path)
+	if err != nil {
+		slog.Error("Error creating goroutine dump", "err", err)
+		return ""
+	}
+	defer f.Close()
+	if err := pprof.Lookup("goroutine").WriteTo(f, 2); err != nil {
+		slog.Error("Error writing goroutine dump", "err", err)
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// 存活检查失败后收到的 SIGTERM 记为 stalled，作为异常退出在下次启动时通知；检查恢复后再收到的信号是正常退出
+func TestSignalAfterFailedLiveness(t *testing.T) {
+	prevCfg, prevStore := cfg, store
+	t.Cleanup(func() {
+		cfg, store = prevCfg, prevStore
+		lastStall.Store(nil)
+	})
+	cfg.DumpDir = t.TempDir()
+	store = &memStore{}
+
+	markStalled("no check completed for 12m0s")
+	markStalled("no check completed for 13m0s")
+	recordExit(signalExit(syscall.SIGTERM))
+	record, err := loadExitRecord(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Reason != exitReasonStalled || !record.abnormal() || !strings.Contains(record.Message, "12m0s") {
+		t.Errorf("exit record = %+v, want an abnormal stalled exit with the first failure", record)
+	}
+	if _, err := os.Stat(record.DumpFile); err != nil {
+		t.Errorf("goroutine dump: %v", err)
+	}
+
+	clearStalled()
+	if reason, _, _ := signalExit(syscall.SIGTERM); reason != exitReasonSignal {
+		t.Errorf("reason after the liveness check recovered = %q, want %q", reason, exitReasonSignal)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// 当前进程是否为 leader；未启用选主时始终为 true
+var leading atomic.Bool
+
+// runElected 未启用选主时直接运行 run；启用时只有选为 leader 后才运行，
+// 失去 leader 身份时退出进程，由 Deployment 重启后重新参与选举。
+// ctx 结束时等待 run 返回，然后释放 Lease，让新副本立即接手
+func runElected(ctx context.Context, run func(ctx context.Context)) {
+	if !cfg.LeaderElect {
+		leading.Store(true)
+		run(ctx)
+		return
+	}
+
+	identity := os.Getenv("POD_NAME")
+	if identity == "" {
+		identity, _ = os.Hostname()
+	}
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: cfg.LeaderElectionLease, Namespace: cfg.StateNamespace},
+		Client:     clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+	// 作为 leader 时等 run 保存完状态才释放 Lease，避免两个副本同时发送通知
+	leaseCtx, releaseLease := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		if leading.Load() {
+			<-done
+		}
+		releaseLease()
+	}()
+	leaderelection.RunOrDie(leaseCtx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				slog.Info("Became leader", "identity", identity)
+				leading.Store(true)
+				run(ctx)
+				close(done)
+			},
+			OnStoppedLeading: func() {
+				if ctx.Err() != nil {
+					slog.Info("Released leadership", "identity", identity)
+					return
+				}
+				slog.Warn("Lost leadership, exiting")
+				recordExit(exitReasonLeaderLost, identity, "")
+				os.Exit(1)
+			},
+			OnNewLeader: func(current string) {
+				if current != identity {
+					slog.Info("Standing by", "leader", current)
+				}
+			},
+		},
+	})
+}
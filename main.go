@@ -1,216 +1,615 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"k8s.io/client-go/tools/clientcmd"
+	"log/slog"
+	"maps"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	//v1 "github.com/labring/sealos/controllers/pkg/notification/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-)
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 
-const (
-	feishuWebhookURL = "https://open.feishu.cn/open-apis/bot/v2/hook/39260980-fbea-4c1a-9f72-f75c372c1b73"
+	"database-monitor/pkg/audit"
+	"database-monitor/pkg/history"
+	"database-monitor/pkg/httpclient"
+	"database-monitor/pkg/monitor"
+	"database-monitor/pkg/notify"
+	"database-monitor/pkg/redact"
 )
 
 var (
-	clientset     *kubernetes.Clientset
-	dynamicClient *dynamic.DynamicClient
-	// 记录上一次的数据库状态
-	lastStatus = make(map[string]string)
-	// 记录欠费的ns
-	debtRecord = make(map[string]bool)
+	clientset     kubernetes.Interface
+	dynamicClient dynamic.Interface
+	// 已启用的通知后端
+	notifiers []monitor.Notifier
+	// 只接收升级通知的后端
+	escalationNotifiers []monitor.Notifier
+	// 进程级状态（上次退出记录等）
+	store monitor.StateStore
+	// 发布各集群状态的 ConfigMap，未启用时为 nil
+	statusStore monitor.StateStore
+	// 日志和通知中需要隐藏的敏感值
+	redactor = redact.New()
+	// 注册表模式下管理各区域的巡检，单集群模式下为 nil
+	regions *regionManager
+	// 审计日志，未启用时为 nil
+	auditWriter *audit.Writer
+	// 本地历史库，未启用时为 nil
+	historyStore *history.Store
+	// 在集群对象上创建 Event，未启用时为 nil；重新加载配置时沿用
+	eventRecorder record.EventRecorder
+	// 通知后端、恢复回调和 Grafana 标注使用的客户端，超时为 notifyTimeout；重新加载配置时替换
+	httpClient *http.Client
 )
 
-type FeishuMessage struct {
-	MsgType string `json:"msg_type"`
-	Content struct {
-		Text string `json:"text"`
-	} `json:"content"`
+func main() {
+	cmd, args := parseCommand(os.Args[1:])
+	if path := configFileFromArgs(args); path != "" {
+		if err := cfg.loadConfigFile(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	cfg.bindFlags(flag.CommandLine)
+	flag.CommandLine.Usage = usage
+	flag.CommandLine.Parse(args)
+	if err := cfg.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	recordConfigVersion(configHash(cfg))
+	redactConfig(cfg)
+	httpClient = httpclient.New(cfg.NotifyTimeout)
+	commandArgs = args
+
+	if err := initLogger(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(exitError)
+	}
+	if err := initClient(); err != nil {
+		startupFailed("Error creating Kubernetes clients", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	err := resolveSecretRefs(ctx, &cfg)
+	cancel()
+	if err != nil {
+		startupFailed("Error reading secrets referenced by the configuration", err)
+	}
+	redactConfig(cfg)
+	if cmd.name == "run" {
+		// 常驻进程的状态保存在 ConfigMap 中，通知后端（例如 PagerDuty 已触发的事件）也用它跨重启保存状态
+		store = monitor.NewConfigMapStore(clientset, cfg.StateNamespace, cfg.StateConfigMap)
+	}
+	if err := initNotifiers(); err != nil {
+		startupFailed("Error creating notifiers", err)
+	}
+	shutdownTracing, err := initTracing()
+	if err != nil {
+		startupFailed("Error initializing tracing", err)
+	}
+	code := cmd.run()
+	shutdownTracing()
+	os.Exit(code)
 }
 
-func main() {
-	initClient()
-	database_monitor()
-	//CreateNotification("ns-hkfnwdfz", "test", "updating")
+// 启动阶段无法继续时记录错误并以 exitError 退出，日志经过脱敏
+func startupFailed(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(exitError)
 }
 
-func initClient() {
-	// 使用 kubeconfig 连接 Kubernetes 集群
-	config, err := clientcmd.BuildConfigFromFlags("", "/Users/james/go/src/github.com/wally/database-monitor/config/kubeconfig")
-	if err != nil {
-		panic(err.Error())
+// 配置中的敏感值加入脱敏列表，重新加载配置时也会调用
+func redactConfig(c Config) {
+	redactor.AddURL(c.FeishuWebhookURL)
+	redactor.Add(c.FeishuSecret)
+	redactor.Add(c.FeishuVerificationToken)
+	redactor.Add(c.AdminToken)
+	redactor.AddURL(c.SlackWebhookURL)
+	redactor.AddURL(c.DingTalkWebhookURL)
+	redactor.Add(c.DingTalkSecret)
+	redactor.AddURL(c.WeComWebhookURL)
+	redactor.Add(c.SMTPPassword)
+	redactor.Add(c.PagerDutyRoutingKey)
+	redactor.Add(c.TelegramBotToken)
+	redactor.AddURL(c.WebhookURL)
+	for _, v := range c.WebhookHeaders {
+		redactor.Add(v)
 	}
+	redactor.AddURL(c.ResolutionCallbackURL)
+	redactor.Add(c.ResolutionCallbackSecret)
+	redactor.Add(c.GrafanaAPIToken)
+	for _, d := range c.Destinations {
+		redactor.AddURL(d.URL)
+		redactor.Add(d.Secret)
+		for _, v := range d.Headers {
+			redactor.Add(v)
+		}
+	}
+}
 
-	dynamicClient, err = dynamic.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
+// run 子命令：持续巡检直到收到退出信号
+func runMonitor() int {
+	if cfg.DryRun {
+		slog.Warn("Dry run enabled, notifications are logged instead of sent")
+	}
+	if err := initAudit(); err != nil {
+		slog.Error("Error opening audit log or history", "err", err)
+		return exitError
+	}
+	if cfg.StatusConfigMap != "" {
+		statusStore = monitor.NewConfigMapStore(clientset, cfg.StateNamespace, cfg.StatusConfigMap)
+	}
+	if cfg.RegistryResource != "" {
+		gvr, err := parseGVR(cfg.RegistryResource)
+		if err != nil {
+			slog.Error("Invalid region registry resource", "err", err)
+			return exitError
+		}
+		regions = newRegionManager(gvr, cfg.RegistryNamespace)
+	} else if len(cfg.KubeContexts) > 0 {
+		regions = newContextRegions(cfg.KubeContexts)
+	}
+	eventRecorder = newEventRecorder()
+	m := newMonitor()
+	admin := startAdminServer(m)
+	ctx := handleSignals()
+	reloads := watchConfig(ctx)
+	// 只有 leader 巡检和发送通知，包括上次退出的通知
+	runElected(ctx, func(ctx context.Context) {
+		reportLastExit(ctx, m)
+		for {
+			next, ok := runChecks(ctx, m, reloads)
+			if !ok {
+				return
+			}
+			applyConfig(next)
+			prev := m
+			m = newMonitor()
+			m.TakeOver(prev)
+			admin.m.Store(m)
+		}
+	})
+	// 巡检已保存状态并返回，写完审计记录后退出
+	if auditWriter != nil {
+		auditWriter.Close()
+	}
+	if historyStore != nil {
+		historyStore.Close()
 	}
+	slog.Info("Shutdown complete")
+	return 0
+}
 
-	clientset, err = kubernetes.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
+// newMonitor 按当前配置创建主集群的 Monitor，指标注册到新的注册表，重新加载后替换旧的指标。
+// 注册表或多 context 模式下它不巡检主集群，只用于发送进程自身的通知
+func newMonitor() *monitor.Monitor {
+	reg := prometheus.NewRegistry()
+	monitorMetrics.reg.Store(reg)
+	return monitor.New(monitor.Deps{
+		Config:        cfg.Config,
+		Dynamic:       dynamicClient,
+		Kube:          clientset,
+		Notifiers:     notifiers,
+		Registerer:    reg,
+		ConfigVersion: currentConfigHash(),
+		Redactor:      redactor,
+		Store:         store,
+		StatusStore:   statusStore,
+		Events:        eventRecorder,
+		Audit:         auditSink(),
+		History:       auditHistory(),
+		Logger:        slog.Default(),
+
+		NamespaceNotifier:   namespaceNotifier,
+		EscalationNotifiers: escalationNotifiers,
+		HTTPClient:          currentHTTPClient(),
+		TracerProvider:      otel.GetTracerProvider(),
+		OnPanic:             recordPanic,
+	})
+}
+
+// 巡检出错停止后重新开始前的等待时间
+const checksRestartDelay = 30 * time.Second
+
+// 运行巡检直到 ctx 结束或配置重新加载。重新加载时停止巡检并等待保存状态，返回新配置，
+// 新的 Monitor 从保存的状态接着运行，已打开的事件和告警去重状态不变，不会重新发送告警
+func runChecks(ctx context.Context, m *monitor.Monitor, reloads <-chan reloadedConfig) (reloadedConfig, bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go runSLAReports(ctx, m)
+	go func() {
+		defer close(done)
+		runGuarded(func() {
+			run := m.Run
+			if regions != nil {
+				run = regions.run
+			}
+			// 出错时不退出进程，等待后重新开始巡检，API server 恢复后自动继续。
+			// 每次使用单独的 context，上一次启动的后台检查随之停止，不会重复运行
+			for {
+				attemptCtx, stop := context.WithCancel(ctx)
+				err := run(attemptCtx)
+				stop()
+				if err == nil || ctx.Err() != nil {
+					return
+				}
+				slog.Error("Checks stopped with an error, restarting", "retryIn", checksRestartDelay, "err", err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(checksRestartDelay):
+				}
+			}
+		})
+	}()
+	select {
+	case <-done:
+		return reloadedConfig{}, false
+	case next := <-reloads:
+		slog.Info("Configuration changed, restarting checks", "hash", next.hash)
+		cancel()
+		<-done
+		return next, true
 	}
 }
 
-func database_monitor() {
+// 收到退出信号时记录退出原因并取消返回的 context，巡检完成进行中的通知、保存状态后 main 返回。
+// 超过 2 倍 ShutdownTimeout 仍未退出时强制退出，再次收到信号时立即退出
+func handleSignals() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-sigCh
+		timeout := currentConfig().ShutdownTimeout
+		slog.Info("Received signal, shutting down", "signal", sig.String(), "timeout", timeout)
+		recordExit(signalExit(sig))
+		cancel()
+		select {
+		case sig = <-sigCh:
+			slog.Warn("Received second signal, exiting immediately", "signal", sig.String())
+		case <-time.After(2 * timeout):
+			slog.Warn("Shutdown timed out, exiting")
+		}
+		os.Exit(1)
+	}()
+	return ctx
+}
 
-	// CRD GVR
-	gvr := schema.GroupVersionResource{
-		Group:    "apps.kubeblocks.io",
-		Version:  "v1alpha1",
-		Resource: "clusters",
+func initClient() error {
+	config, err := loadRESTConfig()
+	if err != nil {
+		return err
 	}
 
-	for {
-		// 每隔 5 分钟执行一次
-		checkDatabases(gvr)
-		time.Sleep(5 * time.Minute)
+	config.QPS = float32(cfg.APIQPS)
+	config.Burst = cfg.APIBurst
+	traceKubeRequests(config)
+
+	if dynamicClient, err = dynamic.NewForConfig(config); err != nil {
+		return err
 	}
+	clientset, err = kubernetes.NewForConfig(config)
+	return err
 }
 
-func checkDatabases(gvr schema.GroupVersionResource) {
-	// clusters, err := dynamicClient.Resource(gvr).Namespace(namespace).List(context.Background(), metav1.ListOptions{})
-	clusters, err := dynamicClient.Resource(gvr).List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		panic(err.Error())
+// resolveSecretRefs 读取配置引用的 Secret，把值写入对应的字段；同一个 Secret 只读取一次。
+// 配置中直接给出飞书签名密钥时不读取 feishuSecretFrom
+func resolveSecretRefs(ctx context.Context, c *Config) error {
+	secrets := make(map[string]*corev1.Secret)
+	read := func(ref SecretRef) (string, error) {
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = c.StateNamespace
+		}
+		id := namespace + "/" + ref.Name
+		secret, ok := secrets[id]
+		if !ok {
+			var err error
+			if secret, err = clientset.CoreV1().Secrets(namespace).Get(ctx, ref.Name, metav1.GetOptions{}); err != nil {
+				return "", fmt.Errorf("read secret %s: %w", id, err)
+			}
+			secrets[id] = secret
+		}
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf("secret %s has no key %q", id, ref.Key)
+		}
+		return strings.TrimSpace(string(value)), nil
 	}
 
-	database_message := fmt.Sprintf("%-50s %-50s %-50s\n", "DatabaseName", "Status", "Namespace")
-	for _, cluster := range clusters.Items {
-		status, found, err := unstructured.NestedString(cluster.Object, "status", "phase")
-		name, namespace := cluster.GetName(), cluster.GetNamespace()
-		if err != nil || !found {
-			fmt.Printf("Unable to get %s status in ns %s: %v\n", name, namespace, err)
-			continue
+	refs := c.SecretRefs
+	if _, ok := refs["feishuSecret"]; !ok && c.FeishuSecret == "" && c.FeishuSecretFrom != "" {
+		namespace, name, key, err := parseSecretKeyRef(c.FeishuSecretFrom)
+		if err != nil {
+			return err
 		}
-		if status == "Running" || status == "Stopped" {
-			delete(lastStatus, name)
-			continue
+		refs = maps.Clone(refs)
+		if refs == nil {
+			refs = make(map[string]SecretRef)
 		}
-		if _, ok := lastStatus[name]; !ok {
-			// 如果 lastStatus 中不存在 name，直接更新状态
-			lastStatus[name] = status
-			continue
+		refs["feishuSecret"] = SecretRef{Namespace: namespace, Name: name, Key: key}
+	}
+	fields := c.secretFields()
+	for name, ref := range refs {
+		value, err := read(ref)
+		if err != nil {
+			return fmt.Errorf("secretRefs %s: %w", name, err)
 		}
-		if status == "Failed" && !debtRecord[namespace] {
-			_, debt := checkQuota(namespace)
-			if !debt {
-				database_message += fmt.Sprintf("%-50s %-50s %-50s\n", name, status, namespace)
-				CreateNotification(namespace, name, status)
-				continue
+		*fields[name] = value
+	}
+	for i := range c.Destinations {
+		d := &c.Destinations[i]
+		fields := d.secretFields()
+		for name, ref := range d.SecretRefs {
+			value, err := read(ref)
+			if err != nil {
+				return fmt.Errorf("destination %s: secretRefs %s: %w", d.Name, name, err)
 			}
+			*fields[name] = value
+		}
+	}
+	return nil
+}
 
-			debtRecord[namespace] = true
-			delete(lastStatus, name)
-			continue
+// 配置是否引用了 Secret，引用时定期重新读取以发现 Secret 的变化
+func hasSecretRefs(c Config) bool {
+	if len(c.SecretRefs) > 0 || c.FeishuSecretFrom != "" {
+		return true
+	}
+	for _, d := range c.Destinations {
+		if len(d.SecretRefs) > 0 {
+			return true
 		}
-		database_message += fmt.Sprintf("%-50s %-50s %-50s\n", name, status, namespace)
-		// 更新状态
-		lastStatus[name] = status
 	}
-	// 如果数据库依然处于异常状态，则发送通知
-	err = sendFeishuNotification(database_message)
-	if err != nil {
-		fmt.Printf("Error sending notification: %v\n", err)
-	} else {
-		fmt.Println("Notification sent successfully")
+	return false
+}
+
+func initAudit() error {
+	switch cfg.AuditLog {
+	case "":
+	case "-":
+		auditWriter = audit.NewStdout(cfg.AuditKinds, prometheus.DefaultRegisterer)
+	default:
+		w, err := audit.NewFile(cfg.AuditLog, cfg.AuditMaxBytes, cfg.AuditBackups, cfg.AuditKinds, prometheus.DefaultRegisterer)
+		if err != nil {
+			return err
+		}
+		auditWriter = w
+	}
+	if cfg.HistoryPath != "" {
+		s, err := history.Open(cfg.HistoryPath, cfg.HistoryRetention, prometheus.DefaultRegisterer)
+		if err != nil {
+			return err
+		}
+		historyStore = s
+	}
+	return nil
+}
+
+// 审计日志和历史库都接收审计记录；避免把 nil 指针包装成非 nil 的接口
+func auditSink() monitor.AuditSink {
+	var sinks teeSink
+	if auditWriter != nil {
+		sinks = append(sinks, auditWriter)
+	}
+	if historyStore != nil {
+		sinks = append(sinks, historyStore)
+	}
+	switch len(sinks) {
+	case 0:
+		return nil
+	case 1:
+		return sinks[0]
 	}
+	return sinks
 }
 
-func checkQuota(ns string) (error, bool) {
-	resourceQuotasClient := clientset.CoreV1().ResourceQuotas(ns)
+// 历史库同时用于查询反复故障等跨重启的统计
+func auditHistory() monitor.AuditHistory {
+	if historyStore == nil {
+		return nil
+	}
+	return historyStore
+}
 
-	// 查找名为 "debt-limit0" 的 ResourceQuota
-	resourceQuota, err := resourceQuotasClient.Get(context.TODO(), "debt-limit0", metav1.GetOptions{})
-	if err != nil {
-		// 处理错误：资源不存在或其他错误。
-		fmt.Printf("Error getting ResourceQuota: %s\n", err.Error())
-		return err, false
+// teeSink 把审计记录交给多个 AuditSink
+type teeSink []monitor.AuditSink
+
+func (t teeSink) Record(r monitor.AuditRecord) {
+	for _, s := range t {
+		s.Record(r)
 	}
-	return nil, resourceQuota != nil
 }
 
-func sendFeishuNotification(database_message string) error {
+// 未启用 Event 时返回 nil
+func newEventRecorder() record.EventRecorder {
+	if !cfg.EmitEvents {
+		return nil
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "database-monitor"})
+}
 
-	message := FeishuMessage{
-		MsgType: "text",
-		Content: struct {
-			Text string `json:"text"`
-		}{
-			Text: database_message,
-		},
+// 显式指定的 kubeconfig 优先；否则在集群内运行时使用 ServiceAccount，
+// 不在集群内时按 KUBECONFIG 环境变量和 ~/.kube/config 查找
+func loadRESTConfig() (*rest.Config, error) {
+	if cfg.Kubeconfig != "" {
+		slog.Info("Using kubeconfig", "path", cfg.Kubeconfig)
+		return clientcmd.BuildConfigFromFlags("", cfg.Kubeconfig)
+	}
+	config, err := rest.InClusterConfig()
+	if err == nil {
+		slog.Info("Using in-cluster config")
+		return config, nil
 	}
-	// 序列化消息为 JSON
-	messageBytes, err := json.Marshal(message)
+	if !errors.Is(err, rest.ErrNotInCluster) {
+		return nil, err
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	slog.Info("Not running in a cluster, loading kubeconfig", "path", strings.Join(rules.Precedence, ":"))
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
+
+func initNotifiers() error {
+	var err error
+	notifiers, escalationNotifiers, err = buildNotifiers(cfg, httpClient)
+	return err
+}
+
+// 按配置创建通知后端，返回普通后端和只接收升级通知的后端
+func buildNotifiers(c Config, client *http.Client) (all, escalation []monitor.Notifier, err error) {
+	for _, name := range c.Notifiers {
+		d := Destination{Name: name, Type: name, Template: c.NotifierTemplates[name], Locale: c.NotifierLocales[name]}
+		switch name {
+		case "feishu":
+			d.URL, d.Secret = c.FeishuWebhookURL, c.FeishuSecret
+		case "slack":
+			d.URL = c.SlackWebhookURL
+		case "dingtalk":
+			d.URL, d.Secret = c.DingTalkWebhookURL, c.DingTalkSecret
+		case "wecom":
+			d.URL = c.WeComWebhookURL
+		case "webhook":
+			d.URL, d.Headers = c.WebhookURL, c.WebhookHeaders
+		}
+		n, err := newNotifier(c, d, client)
+		if err != nil {
+			return nil, nil, err
+		}
+		all = append(all, notify.Route(n, c.NotifierSeverities[name]))
+	}
+	for _, d := range c.Destinations {
+		n, err := newNotifier(c, d, client)
+		if err != nil {
+			return nil, nil, err
+		}
+		n = notify.RouteNamespaces(notify.Route(n, d.Severities), d.Namespaces, d.Fallback)
+		if d.EscalationOnly {
+			escalation = append(escalation, n)
+			continue
+		}
+		all = append(all, n)
+	}
+	return all, escalation, nil
+}
+
+// 由 ns 注解中的飞书 webhook 地址创建租户的通知后端
+func namespaceNotifier(namespace, url string) (monitor.Notifier, error) {
+	cfg, client := currentConfig(), currentHTTPClient()
+	format, err := notify.ParseFormat(cfg.Locale, cfg.Timezone)
 	if err != nil {
-		fmt.Printf("Error marshalling message: %v\n", err)
-		return err
+		return nil, err
+	}
+	var tmpl *notify.Template
+	if cfg.FeishuTemplate != "" {
+		if tmpl, err = notify.ParseTemplateFile(cfg.FeishuTemplate, format); err != nil {
+			return nil, err
+		}
 	}
+	return notify.NewFeishu("feishu-"+namespace, url, "", tmpl, cfg.FeishuFormat == "card", false, format, client), nil
+}
 
-	// 发送 POST 请求到 Feishu Webhook
-	resp, err := http.Post(feishuWebhookURL, "application/json", bytes.NewBuffer(messageBytes))
+// 目的地未指定语言和时区时使用全局设置
+func newNotifier(c Config, d Destination, client *http.Client) (monitor.Notifier, error) {
+	locale, timezone := d.Locale, d.Timezone
+	if locale == "" {
+		locale = c.Locale
+	}
+	if timezone == "" {
+		timezone = c.Timezone
+	}
+	format, err := notify.ParseFormat(locale, timezone)
 	if err != nil {
-		fmt.Printf("Error sending alert to Feishu: %v\n", err)
-		return err
+		return nil, err
+	}
+	templateFile := d.Template
+	if templateFile == "" && d.Type == "feishu" {
+		templateFile = c.FeishuTemplate
+	}
+	var tmpl *notify.Template
+	if templateFile != "" {
+		if tmpl, err = notify.ParseTemplateFile(templateFile, format); err != nil {
+			return nil, fmt.Errorf("notifier %s: %w", d.Name, err)
+		}
 	}
-	defer resp.Body.Close()
+	switch d.Type {
+	case "feishu":
+		return notify.NewFeishu(d.Name, d.URL, d.Secret, tmpl, c.FeishuFormat == "card", c.FeishuCardActions, format, client), nil
+	case "slack":
+		return notify.NewSlack(d.Name, d.URL, tmpl, format, client), nil
+	case "dingtalk":
+		return notify.NewDingTalk(d.Name, d.URL, d.Secret, tmpl, format, client), nil
+	case "wecom":
+		return notify.NewWeCom(d.Name, d.URL, tmpl, format, client), nil
+	case "alertmanager":
+		// 告警在两次重复提醒之间保持有效
+		return notify.NewAlertmanager(d.Name, c.AlertmanagerURL, 2*c.RealertInterval, store, client), nil
+	case "webhook":
+		return notify.NewWebhook(d.Name, d.URL, d.Headers, client), nil
+	case "telegram":
+		return notify.NewTelegram(d.Name, c.TelegramBotToken, c.TelegramChatID, tmpl, format, client), nil
+	case "pagerduty":
+		return notify.NewPagerDuty(d.Name, c.PagerDutyRoutingKey, store, client), nil
+	case "email":
+		return notify.NewEmail(d.Name, notify.SMTPConfig{
+			Host:     c.SMTPHost,
+			Port:     c.SMTPPort,
+			TLS:      c.SMTPTLS,
+			Username: c.SMTPUsername,
+			Password: c.SMTPPassword,
+			From:     c.EmailFrom,
+			To:       c.EmailTo,
+		}, format), nil
+	case "stdout":
+		return notify.NewStdout(d.Name), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", d.Type)
+	}
+}
 
-	// 检查响应状态
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Failed to send alert. Status code: %d\n", resp.StatusCode)
-	} else {
-		fmt.Println("Alert sent successfully")
+func findNotifier(name string) monitor.Notifier {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	for _, n := range notifiers {
+		if n.Name() == name {
+			return n
+		}
 	}
 	return nil
 }
 
-func CreateNotification(namespace string, name, status string) {
-
-	gvr := schema.GroupVersionResource{
-		Group:    "notification.sealos.io",
-		Version:  "v1",
-		Resource: "notifications",
-	}
-
-	now := time.Now().UTC().Unix()
-	message := "database : " + name + " is " + status + ". Please check in time."
-	notification := &unstructured.Unstructured{
-		Object: map[string]interface{}{
-			"apiVersion": "notification.sealos.io/v1",
-			"kind":       "Notification",
-			"metadata": map[string]interface{}{
-				"name": "database-monitor-notification",
-			},
-			"spec": map[string]interface{}{
-				"title":        "Database Exception",
-				"message":      message,
-				"timestamp":    now,
-				"from":         "database-monitor-cronjob",
-				"importance":   "High",
-				"desktopPopup": true,
-				"i18ns": map[string]interface{}{
-					"en": map[string]interface{}{
-						"title":   "English Title",
-						"message": "English Message",
-						"from":    "User A",
-					},
-				},
-			},
-		},
-	}
-
-	// 使用客户端和 GVR 创建 CRD
-	_, err := dynamicClient.Resource(gvr).Namespace(namespace).Create(context.TODO(), notification, metav1.CreateOptions{})
-	if err != nil {
-		fmt.Println(err.Error())
+// initLogger 按 --log-level 和 --log-format 设置默认的 slog 日志，输出前经过脱敏
+func initLogger() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", cfg.LogLevel, err)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler = slog.NewTextHandler(os.Stdout, opts)
+	if cfg.LogFormat == "json" {
+		h = slog.NewJSONHandler(os.Stdout, opts)
 	}
+	slog.SetDefault(slog.New(redactor.Handler(h)))
+	return nil
 }
@@ -0,0 +1,125 @@
+// Package audit 把巡检的状态变化、通知决定以及告警和恢复事件以 NDJSON 追加写入审计日志，
+// 与聊天记录和内存中有上限的结构无关，满足合规留档的要求，也可以由 Fluent Bit 等采集后长期保存。
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"database-monitor/pkg/monitor"
+)
+
+// 写入 stdout 时每行带上该类型字段，和其他日志区分
+const stdoutType = "database_monitor_audit"
+
+// 缓冲的记录数，写入跟不上时丢弃新记录
+const bufferSize = 4096
+
+// line 审计日志中的一行
+type line struct {
+	Type string `json:"type,omitempty"`
+	monitor.AuditRecord
+}
+
+// Writer 异步写入审计记录的 AuditSink。Record 从不阻塞，缓冲区满时丢弃并计数
+type Writer struct {
+	out     io.WriteCloser
+	typed   bool
+	kinds   map[string]bool
+	records chan monitor.AuditRecord
+	dropped prometheus.Counter
+	written prometheus.Counter
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewFile 写入 path，超过 maxBytes 时轮转，最多保留 backups 个旧文件；kinds 为空时写入所有类型的记录
+func NewFile(path string, maxBytes int64, backups int, kinds []string, reg prometheus.Registerer) (*Writer, error) {
+	f, err := openRotating(path, maxBytes, backups)
+	if err != nil {
+		return nil, err
+	}
+	return newWriter(f, false, kinds, reg), nil
+}
+
+// NewStdout 写入标准输出，每行带 type 字段，适合在容器中运行
+func NewStdout(kinds []string, reg prometheus.Registerer) *Writer {
+	return newWriter(nopCloser{os.Stdout}, true, kinds, reg)
+}
+
+func newWriter(out io.WriteCloser, typed bool, kinds []string, reg prometheus.Registerer) *Writer {
+	w := &Writer{
+		out:     out,
+		typed:   typed,
+		records: make(chan monitor.AuditRecord, bufferSize),
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "database_monitor_audit_dropped_total",
+			Help: "Number of audit records dropped because the writer could not keep up.",
+		}),
+		written: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "database_monitor_audit_written_total",
+			Help: "Number of audit records written.",
+		}),
+		done: make(chan struct{}),
+	}
+	if len(kinds) > 0 {
+		w.kinds = make(map[string]bool, len(kinds))
+		for _, k := range kinds {
+			w.kinds[k] = true
+		}
+	}
+	if reg != nil {
+		reg.MustRegister(w.dropped, w.written)
+	}
+	go w.loop()
+	return w
+}
+
+func (w *Writer) Record(r monitor.AuditRecord) {
+	if w.kinds != nil && !w.kinds[r.Kind] {
+		return
+	}
+	select {
+	case w.records <- r:
+	default:
+		w.dropped.Inc()
+	}
+}
+
+// Close 写完已缓冲的记录后关闭输出
+func (w *Writer) Close() error {
+	w.once.Do(func() { close(w.records) })
+	<-w.done
+	return w.out.Close()
+}
+
+func (w *Writer) loop() {
+	defer close(w.done)
+	enc := json.NewEncoder(w.out)
+	for r := range w.records {
+		l := line{AuditRecord: r}
+		if w.typed {
+			l.Type = stdoutType
+		}
+		if err := enc.Encode(l); err != nil {
+			// 写入失败不影响巡检，只计入丢弃
+			w.dropped.Inc()
+			slog.Error("Error writing audit record", "err", err)
+			continue
+		}
+		w.written.Inc()
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,160 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"database-monitor/pkg/monitor"
+)
+
+func record(cluster string) monitor.AuditRecord {
+	return monitor.AuditRecord{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Kind: monitor.AuditTransition, Namespace: "ns1", Cluster: cluster}
+}
+
+// 文件中各行记录的集群名
+func clustersIn(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r monitor.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		names = append(names, r.Cluster)
+	}
+	return names
+}
+
+// 写满 maxBytes 后轮转为 path.1、path.2，超过 backups 的旧文件被删除，每行完整地写在一个文件中
+func TestFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	data, _ := json.Marshal(line{AuditRecord: record("a")})
+	lineSize := int64(len(data) + 1)
+	// 每个文件正好容纳两行
+	w, err := NewFile(path, 2*lineSize, 2, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		w.Record(record(name))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		path:        "g",
+		path + ".1": "e,f",
+		path + ".2": "c,d",
+	}
+	for file, clusters := range want {
+		if got := strings.Join(clustersIn(t, file), ","); got != clusters {
+			t.Errorf("%s contains %q, want %q", filepath.Base(file), got, clusters)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 exists beyond the configured backups: %v", filepath.Base(path), err)
+	}
+	if got := testutil.ToFloat64(w.written); got != 7 {
+		t.Errorf("written = %v, want 7", got)
+	}
+}
+
+// 重新打开已有的文件时从当前大小继续计算，不会超过阈值
+func TestFileRotationAfterReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	data, _ := json.Marshal(line{AuditRecord: record("a")})
+	lineSize := int64(len(data) + 1)
+	for _, name := range []string{"a", "b"} {
+		w, err := NewFile(path, 2*lineSize-1, 1, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Record(record(name))
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Join(clustersIn(t, path+".1"), ","); got != "a" {
+		t.Errorf("rotated file contains %q, want a", got)
+	}
+	if got := strings.Join(clustersIn(t, path), ","); got != "b" {
+		t.Errorf("current file contains %q, want b", got)
+	}
+}
+
+// blockingOutput 在 release 关闭前阻塞写入，started 在第一次写入时关闭
+type blockingOutput struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (o *blockingOutput) Write(p []byte) (int, error) {
+	select {
+	case <-o.started:
+	default:
+		close(o.started)
+	}
+	<-o.release
+	return len(p), nil
+}
+
+func (o *blockingOutput) Close() error { return nil }
+
+// 写入跟不上时缓冲区满后的记录被丢弃并计数，Record 不阻塞
+func TestRecordDropsWhenBufferFull(t *testing.T) {
+	out := &blockingOutput{started: make(chan struct{}), release: make(chan struct{})}
+	w := newWriter(out, false, nil, nil)
+	w.Record(record("first"))
+	<-out.started
+
+	const extra = 5
+	for i := 0; i < bufferSize+extra; i++ {
+		w.Record(record("queued"))
+	}
+	if got := testutil.ToFloat64(w.dropped); got != extra {
+		t.Errorf("dropped = %v, want %d", got, extra)
+	}
+	close(out.release)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(w.written); got != bufferSize+1 {
+		t.Errorf("written = %v, want %d", got, bufferSize+1)
+	}
+}
+
+// 只写入配置的类型，其余类型不计入丢弃
+func TestRecordKinds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	w, err := NewFile(path, 0, 0, []string{monitor.AuditAlert}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Record(record("transition"))
+	alert := record("alert")
+	alert.Kind = monitor.AuditAlert
+	w.Record(alert)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(clustersIn(t, path), ","); got != "alert" {
+		t.Errorf("audit log contains %q, want only the alert", got)
+	}
+	if got := testutil.ToFloat64(w.dropped); got != 0 {
+		t.Errorf("dropped = %v, want 0", got)
+	}
+}
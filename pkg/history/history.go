@@ -0,0 +1,305 @@
+// Package history 是本地嵌入的状态变化和通知历史库，基于 BoltDB：记录以时间为 key 写入单个文件，
+// 按时间范围查询，例如“集群 X 什么时候第一次故障”。超过保留期的记录在启动时和之后每小时删除。
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	bolt "go.etcd.io/bbolt"
+
+	"database-monitor/pkg/monitor"
+)
+
+// 等待写入的记录数，写入跟不上时丢弃新记录并计数
+const bufferSize = 4096
+
+// 一个写事务最多写入的记录数
+const batchSize = 512
+
+// 删除过期记录的间隔
+const pruneInterval = time.Hour
+
+var recordsBucket = []byte("records")
+
+// Store 历史库，实现 monitor.AuditSink 和 monitor.AuditHistory。Record 从不阻塞，
+// 记录由后台 goroutine 批量写入，写入前的记录同样可以查询
+type Store struct {
+	db        *bolt.DB
+	retention time.Duration
+	dropped   prometheus.Counter
+
+	mu      sync.Mutex
+	pending []monitor.AuditRecord
+
+	wake    chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Open 打开 path 处的历史库，不存在时创建；早于 retention 的记录被删除，retention 为 0 表示永久保留。
+// reg 不为空时注册丢弃记录数的指标
+func Open(path string, retention time.Duration, reg prometheus.Registerer) (*Store, error) {
+	// 另一个进程持有文件锁时报错，而不是一直等待
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open history %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(recordsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open history %s: %w", path, err)
+	}
+	s := &Store{
+		db:        db,
+		retention: retention,
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "database_monitor_history_dropped_total",
+			Help: "Number of history records dropped because the writer could not keep up.",
+		}),
+		wake:    make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := s.prune(time.Now()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("prune history %s: %w", path, err)
+	}
+	if reg != nil {
+		reg.MustRegister(s.dropped)
+	}
+	go s.loop()
+	return s, nil
+}
+
+// key 为 8 字节的纳秒时间戳加 8 字节的序号，按字节序即按时间排序
+func recordKey(t time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, timePrefix(t))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+func timePrefix(t time.Time) uint64 {
+	if t.Unix() < 0 {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, timePrefix(t))
+	return key
+}
+
+// prune 删除早于保留期的记录
+func (s *Store) prune(now time.Time) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	cutoff := timeKey(now.Add(-s.retention))
+	expired := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(recordsBucket).Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			expired++
+		}
+		return nil
+	})
+	if expired > 0 {
+		slog.Info("Deleted expired history records", "records", expired)
+	}
+	return err
+}
+
+func (s *Store) Record(r monitor.AuditRecord) {
+	s.mu.Lock()
+	full := len(s.pending) >= bufferSize
+	if !full {
+		s.pending = append(s.pending, r)
+	}
+	s.mu.Unlock()
+	if full {
+		s.dropped.Inc()
+		slog.Warn("History writer is behind, record dropped", "namespace", r.Namespace, "cluster", r.Cluster)
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Store) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.wake:
+			s.flush()
+		case <-ticker.C:
+			if err := s.prune(time.Now()); err != nil {
+				slog.Error("Error deleting expired history records", "err", err)
+			}
+		case <-s.closing:
+			s.flush()
+			return
+		}
+	}
+}
+
+// flush 分批写入等待中的记录，记录在提交后才从 pending 中移除，写入期间的查询不会漏掉它们
+func (s *Store) flush() {
+	for {
+		s.mu.Lock()
+		batch := s.pending
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		s.mu.Unlock()
+		if len(batch) == 0 {
+			return
+		}
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket(recordsBucket)
+			for _, r := range batch {
+				data, err := json.Marshal(r)
+				if err != nil {
+					return err
+				}
+				seq, err := b.NextSequence()
+				if err != nil {
+					return err
+				}
+				if err := b.Put(recordKey(r.Time, seq), data); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// 写入失败的记录计入丢弃，不反复重试
+			s.dropped.Add(float64(len(batch)))
+			slog.Error("Error writing history records", "records", len(batch), "err", err)
+		}
+		s.mu.Lock()
+		s.pending = s.pending[len(batch):]
+		s.mu.Unlock()
+	}
+}
+
+// Close 写完等待中的记录后关闭数据库
+func (s *Store) Close() error {
+	s.once.Do(func() { close(s.closing) })
+	<-s.done
+	return s.db.Close()
+}
+
+// Query 查询条件，零值字段不过滤
+type Query struct {
+	Namespace string
+	Cluster   string
+	Kind      string
+	Since     time.Time
+	Until     time.Time
+	// 最多返回的条数，从最新的开始截取；0 表示不限
+	Limit int
+}
+
+func (q Query) matches(r monitor.AuditRecord) bool {
+	switch {
+	case q.Namespace != "" && r.Namespace != q.Namespace:
+		return false
+	case q.Cluster != "" && r.Cluster != q.Cluster:
+		return false
+	case q.Kind != "" && r.Kind != q.Kind:
+		return false
+	case !q.Since.IsZero() && r.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !r.Time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// Query 返回按时间排序的匹配记录
+func (s *Store) Query(q Query) []monitor.AuditRecord {
+	result := []monitor.AuditRecord{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(recordsBucket).Cursor()
+		var until []byte
+		if !q.Until.IsZero() {
+			until = timeKey(q.Until)
+		}
+		for k, v := c.Seek(timeKey(q.Since)); k != nil; k, v = c.Next() {
+			if until != nil && bytes.Compare(k[:8], until) >= 0 {
+				break
+			}
+			var r monitor.AuditRecord
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			if q.matches(r) {
+				result = append(result, r)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Error reading history", "err", err)
+	}
+	s.mu.Lock()
+	for _, r := range s.pending {
+		if q.matches(r) {
+			result = append(result, r)
+		}
+	}
+	s.mu.Unlock()
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	return result
+}
+
+// FirstFailure 集群在 since 之后第一次进入 Failed 的时间
+func (s *Store) FirstFailure(namespace, cluster string, since time.Time) (time.Time, bool) {
+	for _, r := range s.Query(Query{Namespace: namespace, Cluster: cluster, Kind: monitor.AuditTransition, Since: since}) {
+		if r.NewPhase == "Failed" && r.OldPhase != "Failed" {
+			return r.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// AuditRecords 实现 monitor.AuditHistory
+func (s *Store) AuditRecords(kind string, since time.Time) []monitor.AuditRecord {
+	return s.Query(Query{Kind: kind, Since: since})
+}
+
+// Failures 集群在 since 之后进入 Failed 的次数
+func (s *Store) Failures(namespace, cluster string, since time.Time) int {
+	n := 0
+	for _, r := range s.Query(Query{Namespace: namespace, Cluster: cluster, Kind: monitor.AuditTransition, Since: since}) {
+		if r.NewPhase == "Failed" && r.OldPhase != "Failed" {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,137 @@
+package history
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	bolt "go.etcd.io/bbolt"
+
+	"database-monitor/pkg/monitor"
+)
+
+func transition(at time.Time, cluster, from, to string) monitor.AuditRecord {
+	return monitor.AuditRecord{Time: at, Kind: monitor.AuditTransition, Namespace: "ns1", Cluster: cluster, OldPhase: from, NewPhase: to}
+}
+
+func openStore(t *testing.T, path string, retention time.Duration) *Store {
+	t.Helper()
+	s, err := Open(path, retention, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func clusters(records []monitor.AuditRecord) []string {
+	var names []string
+	for _, r := range records {
+		names = append(names, r.Cluster)
+	}
+	return names
+}
+
+// 刚追加还未写入的记录同样可以查询，结果按时间排序并按条件过滤
+func TestRecordAndQuery(t *testing.T) {
+	now := time.Now()
+	s := openStore(t, filepath.Join(t.TempDir(), "history.db"), 0)
+	defer s.Close()
+	s.Record(transition(now.Add(-time.Minute), "b", "Running", "Failed"))
+	s.Record(transition(now.Add(-time.Hour), "a", "Running", "Failed"))
+	s.Record(monitor.AuditRecord{Time: now, Kind: monitor.AuditNotification, Decision: "send"})
+
+	tests := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{"all", Query{}, []string{"a", "b", ""}},
+		{"kind", Query{Kind: monitor.AuditTransition}, []string{"a", "b"}},
+		{"cluster", Query{Cluster: "b"}, []string{"b"}},
+		{"since", Query{Since: now.Add(-30 * time.Minute)}, []string{"b", ""}},
+		{"until", Query{Until: now.Add(-30 * time.Minute)}, []string{"a"}},
+		{"limit keeps the newest", Query{Limit: 2}, []string{"b", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clusters(s.Query(tt.q)); !slices.Equal(got, tt.want) {
+				t.Errorf("Query(%+v) = %q, want %q", tt.q, got, tt.want)
+			}
+		})
+	}
+	if at, ok := s.FirstFailure("ns1", "a", time.Time{}); !ok || !at.Equal(now.Add(-time.Hour)) {
+		t.Errorf("FirstFailure = %v, %v", at, ok)
+	}
+}
+
+// Close 写完等待中的记录，重新打开后仍能查询
+func TestReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	now := time.Now()
+	s := openStore(t, path, 0)
+	for _, name := range []string{"a", "b", "c"} {
+		s.Record(transition(now, name, "Running", "Failed"))
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openStore(t, path, 0)
+	defer s.Close()
+	if got := clusters(s.Query(Query{})); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("after reopening got %q", got)
+	}
+	if n := s.Failures("ns1", "b", now.Add(-time.Minute)); n != 1 {
+		t.Errorf("Failures = %d, want 1", n)
+	}
+}
+
+// 重新打开时删除早于保留期的记录
+func TestReopenDeletesExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	now := time.Now()
+	s := openStore(t, path, 0)
+	s.Record(transition(now.Add(-48*time.Hour), "old", "Running", "Failed"))
+	s.Record(transition(now.Add(-time.Hour), "recent", "Running", "Failed"))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openStore(t, path, 24*time.Hour)
+	defer s.Close()
+	if got := clusters(s.Query(Query{})); !slices.Equal(got, []string{"recent"}) {
+		t.Errorf("after reopening with a 24h retention got %q, want only the recent record", got)
+	}
+}
+
+// 写入跟不上时超出缓冲的记录被丢弃并计数，已缓冲的记录之后照常写入
+func TestRecordDropsWhenBehind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	s := openStore(t, path, 0)
+	now := time.Now()
+	const extra = 3
+	// 持有写事务，后台写入无法提交
+	err := s.db.Update(func(*bolt.Tx) error {
+		for i := 0; i < bufferSize+extra; i++ {
+			s.Record(transition(now, "db", "Running", "Failed"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(s.dropped); got != extra {
+		t.Errorf("dropped = %v, want %d", got, extra)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openStore(t, path, 0)
+	defer s.Close()
+	if got := len(s.Query(Query{})); got != bufferSize {
+		t.Errorf("%d records persisted, want %d", got, bufferSize)
+	}
+}
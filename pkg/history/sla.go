@@ -0,0 +1,133 @@
+package history
+
+import (
+	"sort"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+// Availability 一个集群或 ns 在统计区间内的可用性
+type Availability struct {
+	Region    string `json:"region,omitempty"`
+	Namespace string `json:"namespace"`
+	// 按 ns 汇总时为空
+	Cluster string `json:"cluster,omitempty"`
+	// 区间内观察到集群存在的时长，集群在区间中途创建或删除时小于区间长度
+	Observed time.Duration `json:"observed"`
+	Downtime time.Duration `json:"downtime"`
+	// 区间内进入不可用状态的次数
+	Outages int `json:"outages"`
+}
+
+// Percent 可用性百分比，没有观察时长时为 100
+func (a Availability) Percent() float64 {
+	if a.Observed <= 0 {
+		return 100
+	}
+	return 100 * (1 - float64(a.Downtime)/float64(a.Observed))
+}
+
+type clusterPhase struct {
+	phase string
+	since time.Time
+}
+
+// Availability 根据状态变化记录计算 [since, until) 内各集群的可用性，down 判断处于该 phase 时是否不可用。
+// 区间开始前最后一次变化决定集群的初始状态；集群消失的记录（NewPhase 为空）结束观察。结果按不可用时长降序
+func (s *Store) Availability(since, until time.Time, down func(phase string) bool) []Availability {
+	records := s.Query(Query{Kind: monitor.AuditTransition, Until: until})
+	current := make(map[string]clusterPhase)
+	result := make(map[string]*Availability)
+	// 把 key 在 [from, to) 内处于 phase 的时间计入结果
+	account := func(key string, r monitor.AuditRecord, phase string, from, to time.Time) {
+		if from.Before(since) {
+			from = since
+		}
+		if !to.After(from) || phase == "" {
+			return
+		}
+		a, ok := result[key]
+		if !ok {
+			a = &Availability{Region: r.Region, Namespace: r.Namespace, Cluster: r.Cluster}
+			result[key] = a
+		}
+		a.Observed += to.Sub(from)
+		if down(phase) {
+			a.Downtime += to.Sub(from)
+		}
+	}
+	last := make(map[string]monitor.AuditRecord)
+	for _, r := range records {
+		key := r.Region + "/" + r.Namespace + "/" + r.Cluster
+		prev := current[key]
+		account(key, r, prev.phase, prev.since, r.Time)
+		if prev.phase == r.NewPhase {
+			// 只是决策或原因变化
+			continue
+		}
+		if !r.Time.Before(since) && r.NewPhase != "" && down(r.NewPhase) && (prev.phase == "" || !down(prev.phase)) {
+			a, ok := result[key]
+			if !ok {
+				a = &Availability{Region: r.Region, Namespace: r.Namespace, Cluster: r.Cluster}
+				result[key] = a
+			}
+			a.Outages++
+		}
+		current[key] = clusterPhase{phase: r.NewPhase, since: r.Time}
+		last[key] = r
+	}
+	end := until
+	if now := time.Now(); end.IsZero() || end.After(now) {
+		end = now
+	}
+	for key, c := range current {
+		account(key, last[key], c.phase, c.since, end)
+	}
+
+	list := make([]Availability, 0, len(result))
+	for _, a := range result {
+		list = append(list, *a)
+	}
+	sortAvailability(list)
+	return list
+}
+
+// ByNamespace 把各集群的可用性按区域和 ns 汇总
+func ByNamespace(clusters []Availability) []Availability {
+	byNS := make(map[string]*Availability)
+	for _, c := range clusters {
+		key := c.Region + "/" + c.Namespace
+		a, ok := byNS[key]
+		if !ok {
+			a = &Availability{Region: c.Region, Namespace: c.Namespace}
+			byNS[key] = a
+		}
+		a.Observed += c.Observed
+		a.Downtime += c.Downtime
+		a.Outages += c.Outages
+	}
+	list := make([]Availability, 0, len(byNS))
+	for _, a := range byNS {
+		list = append(list, *a)
+	}
+	sortAvailability(list)
+	return list
+}
+
+// 不可用时长降序，相同时按区域、ns 和集群名
+func sortAvailability(list []Availability) {
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Downtime != b.Downtime {
+			return a.Downtime > b.Downtime
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Cluster < b.Cluster
+	})
+}
@@ -0,0 +1,60 @@
+package history
+
+import (
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+// Transition 集群的一次 phase 变化
+type Transition struct {
+	Time      time.Time `json:"time"`
+	Region    string    `json:"region,omitempty"`
+	Namespace string    `json:"namespace"`
+	Cluster   string    `json:"cluster"`
+	// 变化前后的 phase，集群首次出现时 From 为空，消失时 To 为空
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// 处于 From 的时长，From 的开始时间已不在历史中时为 0
+	Duration   time.Duration `json:"duration,omitempty"`
+	Decision   string        `json:"decision"`
+	Reason     string        `json:"reason,omitempty"`
+	IncidentID string        `json:"incidentId,omitempty"`
+}
+
+// Transitions 返回按时间排序的 phase 变化，忽略只是决策或原因变化的记录；q.Kind 不生效。
+// since 之前的记录只用于计算第一条变化的 Duration
+func (s *Store) Transitions(q Query) []Transition {
+	records := s.Query(Query{Namespace: q.Namespace, Cluster: q.Cluster, Kind: monitor.AuditTransition, Until: q.Until})
+	since := make(map[string]time.Time)
+	result := []Transition{}
+	for _, r := range records {
+		if r.OldPhase == r.NewPhase {
+			continue
+		}
+		key := r.Region + "/" + r.Namespace + "/" + r.Cluster
+		t := Transition{
+			Time:       r.Time,
+			Region:     r.Region,
+			Namespace:  r.Namespace,
+			Cluster:    r.Cluster,
+			From:       r.OldPhase,
+			To:         r.NewPhase,
+			Decision:   r.Decision,
+			Reason:     r.Reason,
+			IncidentID: r.IncidentID,
+		}
+		if start, ok := since[key]; ok && r.OldPhase != "" {
+			t.Duration = r.Time.Sub(start)
+		}
+		since[key] = r.Time
+		if !q.Since.IsZero() && r.Time.Before(q.Since) {
+			continue
+		}
+		result = append(result, t)
+	}
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[len(result)-q.Limit:]
+	}
+	return result
+}
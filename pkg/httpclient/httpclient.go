@@ -0,0 +1,21 @@
+// Package httpclient 创建通知后端、恢复回调和 Grafana 标注使用的 HTTP 客户端。
+// 所有请求都有超时，挂起的 webhook 不会一直占住巡检。客户端由调用方创建后传入，包内没有共享的状态。
+package httpclient
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout 调用方没有指定超时时使用的超时
+const DefaultTimeout = 10 * time.Second
+
+// New 创建一个请求总时长不超过 timeout 的客户端，连接、TLS 握手和等待响应头也分别受 timeout 限制
+func New(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = timeout
+	transport.ResponseHeaderTimeout = timeout
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
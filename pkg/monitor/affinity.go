@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	componentLabel = "apps.kubeblocks.io/component-name"
+	hostnameKey    = "kubernetes.io/hostname"
+)
+
+// affinityIntent 集群 spec 中声明的调度意图（KubeBlocks spec.affinity / componentSpecs[].affinity）
+type affinityIntent struct {
+	PodAntiAffinity string
+	TopologyKeys    []string
+	Tenancy         string
+}
+
+// 是否要求同一组件的副本分散在不同节点上
+func (a affinityIntent) spreadAcrossNodes() bool {
+	if a.Tenancy == "DedicatedNode" {
+		return true
+	}
+	if a.PodAntiAffinity == "" {
+		return false
+	}
+	for _, key := range a.TopologyKeys {
+		if key == hostnameKey {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAffinity(obj map[string]interface{}, fields ...string) (affinityIntent, bool) {
+	affinity, found, err := unstructured.NestedMap(obj, fields...)
+	if err != nil || !found {
+		return affinityIntent{}, false
+	}
+	var intent affinityIntent
+	intent.PodAntiAffinity, _, _ = unstructured.NestedString(affinity, "podAntiAffinity")
+	intent.TopologyKeys, _, _ = unstructured.NestedStringSlice(affinity, "topologyKeys")
+	intent.Tenancy, _, _ = unstructured.NestedString(affinity, "tenancy")
+	return intent, true
+}
+
+// 每个组件生效的调度意图，组件级配置优先于集群级配置
+func componentAffinities(cluster *unstructured.Unstructured) map[string]affinityIntent {
+	clusterIntent, _ := parseAffinity(cluster.Object, "spec", "affinity")
+	result := make(map[string]affinityIntent)
+	components, _, _ := unstructured.NestedSlice(cluster.Object, "spec", "componentSpecs")
+	for _, c := range components {
+		comp, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(comp, "name")
+		if name == "" {
+			continue
+		}
+		if intent, ok := parseAffinity(comp, "affinity"); ok {
+			result[name] = intent
+		} else {
+			result[name] = clusterIntent
+		}
+	}
+	return result
+}
+
+// 找出要求反亲和却有多个副本调度到同一节点的组件
+func antiAffinityViolations(cluster *unstructured.Unstructured, pods []corev1.Pod) []string {
+	intents := componentAffinities(cluster)
+	// 组件 -> 节点 -> 副本数
+	placement := make(map[string]map[string]int)
+	total := make(map[string]int)
+	for _, pod := range pods {
+		comp := pod.Labels[componentLabel]
+		if pod.Spec.NodeName == "" || !intents[comp].spreadAcrossNodes() {
+			continue
+		}
+		if placement[comp] == nil {
+			placement[comp] = make(map[string]int)
+		}
+		placement[comp][pod.Spec.NodeName]++
+		total[comp]++
+	}
+
+	var notes []string
+	for comp, nodes := range placement {
+		for node, count := range nodes {
+			if count > 1 {
+				notes = append(notes, fmt.Sprintf("%s: %d/%d replicas on %s (anti-affinity not satisfied)", comp, count, total[comp], node))
+			}
+		}
+	}
+	sort.Strings(notes)
+	return notes
+}
@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// kubeblocksAPI 一个 KubeBlocks API 版本中用到的资源和字段路径
+type kubeblocksAPI struct {
+	version              string
+	clusters             schema.GroupVersionResource
+	opsRequests          schema.GroupVersionResource
+	clusterDefinitions   schema.GroupVersionResource
+	clusterVersions      schema.GroupVersionResource
+	componentDefinitions schema.GroupVersionResource
+	// 集群引用 ClusterDefinition 的字段
+	clusterDefPath []string
+}
+
+var kubeblocksV1alpha1 = kubeblocksAPI{
+	version:              "v1alpha1",
+	clusters:             clustersGVR,
+	opsRequests:          opsRequestsGVR,
+	clusterDefinitions:   clusterDefinitionsGVR,
+	clusterVersions:      clusterVersionsGVR,
+	componentDefinitions: componentDefinitionsGVR,
+	clusterDefPath:       []string{"spec", "clusterDefinitionRef"},
+}
+
+// v1 中 OpsRequest 移到了 operations.kubeblocks.io，ClusterVersion 被移除，集群的 clusterDefinitionRef 改为 clusterDef
+var kubeblocksV1 = kubeblocksAPI{
+	version:              "v1",
+	clusters:             schema.GroupVersionResource{Group: "apps.kubeblocks.io", Version: "v1", Resource: "clusters"},
+	opsRequests:          schema.GroupVersionResource{Group: "operations.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"},
+	clusterDefinitions:   schema.GroupVersionResource{Group: "apps.kubeblocks.io", Version: "v1", Resource: "clusterdefinitions"},
+	clusterVersions:      clusterVersionsGVR,
+	componentDefinitions: schema.GroupVersionResource{Group: "apps.kubeblocks.io", Version: "v1", Resource: "componentdefinitions"},
+	clusterDefPath:       []string{"spec", "clusterDef"},
+}
+
+// 当前使用的 KubeBlocks API，检测之前为 v1alpha1
+func (m *Monitor) api() *kubeblocksAPI {
+	if api := m.kbAPI.Load(); api != nil {
+		return api
+	}
+	return &kubeblocksV1alpha1
+}
+
+// DetectAPI 通过 discovery 选择 KubeBlocks 提供的 API 版本，优先使用 v1。
+// Run 在 CRD 出现时会自动检测；只调用 RunOnce 的嵌入方需要自行调用。未巡检 KubeBlocks 集群时不做任何事
+func (m *Monitor) DetectAPI(ctx context.Context) error {
+	if m.kubeblocks() == nil {
+		return nil
+	}
+	installed, err := m.detectAPI(ctx)
+	if err == nil && !installed {
+		err = fmt.Errorf("KubeBlocks clusters CRD not installed")
+	}
+	return err
+}
+
+// 两个版本都没有提供 clusters 时返回 false，保留之前的选择
+func (m *Monitor) detectAPI(ctx context.Context) (bool, error) {
+	for _, api := range []kubeblocksAPI{kubeblocksV1, kubeblocksV1alpha1} {
+		installed, err := m.resourceInstalled(ctx, api.clusters)
+		if err != nil {
+			return false, err
+		}
+		if !installed {
+			continue
+		}
+		if api.version == kubeblocksV1.version {
+			// 升级过程中可能出现集群已提供 v1、OpsRequest 还在 apps.kubeblocks.io 的情况
+			moved, err := m.resourceInstalled(ctx, api.opsRequests)
+			if err != nil {
+				return false, err
+			}
+			if !moved {
+				api.opsRequests = opsRequestsGVR
+			}
+		}
+		if prev := m.kbAPI.Swap(&api); prev == nil || prev.version != api.version || prev.opsRequests != api.opsRequests {
+			m.log.Info("Using KubeBlocks API", "version", api.version, "opsRequests", api.opsRequests.GroupVersion().String())
+		}
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,56 @@
+package monitor
+
+import "time"
+
+// 审计记录的类型；alert 和 recovery 为事件第一次告警和告警过的事件恢复，便于下游按事件采集；
+// incident 为进入过故障类 phase 或告警过的事件关闭，Decision 为关闭原因
+const (
+	AuditTransition   = "transition"
+	AuditNotification = "notification"
+	AuditAlert        = "alert"
+	AuditRecovery     = "recovery"
+	AuditIncident     = "incident"
+)
+
+// AuditKinds 所有审计记录类型
+var AuditKinds = []string{AuditTransition, AuditNotification, AuditAlert, AuditRecovery, AuditIncident}
+
+// AuditRecord 审计日志中的一条记录：状态变化及对应的决策，或一次是否发送通知的决定
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"`
+	Cluster   string    `json:"cluster,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	OldPhase  string    `json:"oldPhase,omitempty"`
+	NewPhase  string    `json:"newPhase,omitempty"`
+	Decision  string    `json:"decision"`
+	Reason    string    `json:"reason,omitempty"`
+	// 通知发往的目的地，未发送时为空
+	Destinations []string `json:"destinations,omitempty"`
+	IncidentID   string   `json:"incidentId,omitempty"`
+	Region       string   `json:"region,omitempty"`
+	// 告警的严重程度，只用于 alert
+	Severity string `json:"severity,omitempty"`
+	// 事件从打开到恢复的秒数，只用于 recovery
+	DowntimeSeconds float64 `json:"downtimeSeconds,omitempty"`
+}
+
+// AuditSink 接收审计记录。Record 会在持有内部锁时调用，实现必须立即返回，不能阻塞巡检
+type AuditSink interface {
+	Record(AuditRecord)
+}
+
+// AuditHistory 可查询的审计历史，反复故障等需要跨重启的统计从这里读取
+type AuditHistory interface {
+	// 返回 since 之后指定类型的记录，按时间排序
+	AuditRecords(kind string, since time.Time) []AuditRecord
+}
+
+func (m *Monitor) audit(r AuditRecord) {
+	if m.auditSink == nil {
+		return
+	}
+	r.Time = m.now()
+	r.Region = m.cfg.Region
+	m.auditSink.Record(r)
+}
@@ -0,0 +1,356 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// KubeBlocks 备份和备份计划的 GVR
+var (
+	backupsGVR = schema.GroupVersionResource{
+		Group:    "dataprotection.kubeblocks.io",
+		Version:  "v1alpha1",
+		Resource: "backups",
+	}
+	backupSchedulesGVR = schema.GroupVersionResource{
+		Group:    "dataprotection.kubeblocks.io",
+		Version:  "v1alpha1",
+		Resource: "backupschedules",
+	}
+)
+
+// backupFreshness 一个配置了备份计划的集群最近一次成功备份的情况
+type backupFreshness struct {
+	Namespace string
+	Name      string
+	// 最近一次 Completed 备份的完成时间，从未成功备份时为零值
+	LastCompleted time.Time
+	// 备份计划的创建时间，从未成功备份时据此判断是否超过 SLA
+	ScheduledSince time.Time
+}
+
+// failedBackup 一次失败的备份，包括手动发起的
+type failedBackup struct {
+	Namespace string
+	Name      string
+	UID       string
+	Cluster   string
+	Reason    string
+	FailedAt  time.Time
+}
+
+// backupTracker 保存最近一次备份审计的结果
+type backupTracker struct {
+	mu      sync.Mutex
+	results []backupFreshness
+	at      time.Time
+	// 已提醒过的失败备份（failed/ns/name/uid）和超过 SLA 的集群（stale/ns/name），
+	// 同一问题只提醒一次，集群恢复后可以再次提醒
+	notified map[string]bool
+}
+
+// 失败时间超过该时长的备份不再提醒，避免首次启动时把历史上的失败全部发一遍
+const backupFailureMaxAge = 24 * time.Hour
+
+// 列出备份计划和备份各一次，在内存中按集群关联
+func (m *Monitor) auditBackups(ctx context.Context) ([]backupFreshness, []failedBackup, error) {
+	scheduled := make(map[string]*backupFreshness)
+	var failed []failedBackup
+	err := m.listAll(ctx, backupSchedulesGVR, func(obj *unstructured.Unstructured) {
+		if !scheduleEnabled(obj) {
+			return
+		}
+		name := obj.GetLabels()[instanceLabel]
+		if name == "" {
+			return
+		}
+		key := clusterKey(obj.GetNamespace(), name)
+		scheduled[key] = &backupFreshness{Namespace: obj.GetNamespace(), Name: name, ScheduledSince: obj.GetCreationTimestamp().Time}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	err = m.listAll(ctx, backupsGVR, func(obj *unstructured.Unstructured) {
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		if phase == "Failed" && m.namespaceAllowed(obj.GetNamespace()) {
+			failed = append(failed, newFailedBackup(obj))
+			return
+		}
+		f, ok := scheduled[clusterKey(obj.GetNamespace(), obj.GetLabels()[instanceLabel])]
+		if !ok {
+			return
+		}
+		if phase != "Completed" {
+			return
+		}
+		completed, _, _ := unstructured.NestedString(obj.Object, "status", "completionTimestamp")
+		t, err := time.Parse(time.RFC3339, completed)
+		if err != nil {
+			return
+		}
+		if t.After(f.LastCompleted) {
+			f.LastCompleted = t
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]backupFreshness, 0, len(scheduled))
+	for _, f := range scheduled {
+		results = append(results, *f)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return clusterKey(results[i].Namespace, results[i].Name) < clusterKey(results[j].Namespace, results[j].Name)
+	})
+	return results, failed, nil
+}
+
+func newFailedBackup(obj *unstructured.Unstructured) failedBackup {
+	b := failedBackup{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		UID:       string(obj.GetUID()),
+		Cluster:   obj.GetLabels()[instanceLabel],
+		FailedAt:  obj.GetCreationTimestamp().Time,
+	}
+	b.Reason, _, _ = unstructured.NestedString(obj.Object, "status", "failureReason")
+	if completed, _, _ := unstructured.NestedString(obj.Object, "status", "completionTimestamp"); completed != "" {
+		if t, err := time.Parse(time.RFC3339, completed); err == nil {
+			b.FailedAt = t
+		}
+	}
+	return b
+}
+
+// 备份计划中至少有一项启用
+func scheduleEnabled(obj *unstructured.Unstructured) bool {
+	schedules, _, _ := unstructured.NestedSlice(obj.Object, "spec", "schedules")
+	for _, s := range schedules {
+		if s, ok := s.(map[string]interface{}); ok {
+			if enabled, _ := s["enabled"].(bool); enabled {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 分页列出所有 ns 下的某种资源
+func (m *Monitor) listAll(ctx context.Context, gvr schema.GroupVersionResource, fn func(*unstructured.Unstructured)) error {
+	return m.listSelected(ctx, gvr, "", fn)
+}
+
+// 分页列出所有 ns 下匹配 label selector 的资源，selector 为空时列出全部。
+// 每页处理完即释放，内存占用只和 ListPageSize 有关，与集群总数无关
+func (m *Monitor) listSelected(ctx context.Context, gvr schema.GroupVersionResource, selector string, fn func(*unstructured.Unstructured)) error {
+	opts := metav1.ListOptions{Limit: m.listPageSize(), LabelSelector: selector}
+	for {
+		if err := m.budget.Wait(ctx); err != nil {
+			return err
+		}
+		list, err := m.dynamic.Resource(gvr).List(ctx, opts)
+		if token, ok := expiredContinue(err); ok {
+			m.log.Warn("List continue token expired, continuing with an inconsistent list", "resource", gvr.Resource)
+			opts.Continue = token
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for i := range list.Items {
+			fn(&list.Items[i])
+		}
+		if list.GetContinue() == "" {
+			return nil
+		}
+		opts.Continue = list.GetContinue()
+	}
+}
+
+func (m *Monitor) listPageSize() int64 {
+	if m.cfg.ListPageSize <= 0 {
+		return 500
+	}
+	return m.cfg.ListPageSize
+}
+
+// 大量集群分页较慢时 continue token 可能过期（410），apiserver 在错误中给出从当前位置继续的 token。
+// 继续列出可能漏掉或重复翻页期间变化的少量对象，比从头重新 List 重复处理已评估的集群更好
+func expiredContinue(err error) (string, bool) {
+	if err == nil || !apierrors.IsResourceExpired(err) {
+		return "", false
+	}
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return "", false
+	}
+	token := status.Status().ListMeta.Continue
+	return token, token != ""
+}
+
+// RefreshBackups 立即刷新一次备份新鲜度并提醒新出现的问题。Run 会定期刷新；只调用 RunOnce 的嵌入方需要自行调用
+func (m *Monitor) RefreshBackups(ctx context.Context) error {
+	return m.refreshBackups(ctx)
+}
+
+func (m *Monitor) refreshBackups(ctx context.Context) error {
+	results, failed, err := m.auditBackups(ctx)
+	if err != nil {
+		return err
+	}
+	now := m.now()
+	m.backups.mu.Lock()
+	m.backups.results = results
+	m.backups.at = now
+	lines, keys := m.backupProblemsLocked(results, failed, now)
+	m.backups.mu.Unlock()
+	if len(lines) > 0 {
+		m.log.Warn("Backups need attention", "count", len(lines))
+		// 送达后才记录为已提醒，发送失败或被丢弃时下一次刷新重新提醒
+		m.notifyDelivered(ctx, m.NewNotice("Backups need attention:\n"+strings.Join(lines, "\n")), func(_ context.Context, delivered bool) {
+			if !delivered {
+				return
+			}
+			m.backups.mu.Lock()
+			defer m.backups.mu.Unlock()
+			if m.backups.notified == nil {
+				m.backups.notified = make(map[string]bool)
+			}
+			for _, key := range keys {
+				m.backups.notified[key] = true
+			}
+		})
+	}
+
+	if m.cfg.BackupMetrics {
+		// 整体重建，已删除的集群不会残留
+		m.metrics.backupAge.Reset()
+		for _, f := range results {
+			m.metrics.backupAge.WithLabelValues(f.Namespace, f.Name).Set(backupAgeHours(f, now))
+		}
+	}
+	return nil
+}
+
+// 新出现的失败备份和超过 BackupSLA 没有成功备份的集群，以及它们的 key，送达后由调用方记录为已提醒；
+// 调用方需持有 m.backups.mu
+func (m *Monitor) backupProblemsLocked(results []backupFreshness, failed []failedBackup, now time.Time) (lines, keys []string) {
+	t := &m.backups
+	current := make(map[string]bool)
+	for _, b := range failed {
+		if now.Sub(b.FailedAt) > backupFailureMaxAge {
+			continue
+		}
+		key := "failed/" + b.Namespace + "/" + b.Name + "/" + b.UID
+		if t.notified[key] {
+			current[key] = true
+			continue
+		}
+		keys = append(keys, key)
+		line := fmt.Sprintf("backup %s/%s failed", b.Namespace, b.Name)
+		if b.Cluster != "" {
+			line = fmt.Sprintf("backup %s/%s of cluster %s failed", b.Namespace, b.Name, b.Cluster)
+		}
+		if b.Reason != "" {
+			line += ": " + b.Reason
+		}
+		lines = append(lines, line)
+	}
+	if m.cfg.BackupSLA > 0 {
+		for _, f := range results {
+			var line string
+			switch {
+			case f.LastCompleted.IsZero() && now.Sub(f.ScheduledSince) > m.cfg.BackupSLA:
+				line = fmt.Sprintf("cluster %s has never completed a backup since its schedule was created %s ago, SLA %s",
+					clusterKey(f.Namespace, f.Name), now.Sub(f.ScheduledSince).Round(time.Hour), m.cfg.BackupSLA)
+			case !f.LastCompleted.IsZero() && now.Sub(f.LastCompleted) > m.cfg.BackupSLA:
+				line = fmt.Sprintf("cluster %s last completed a backup %s ago, SLA %s",
+					clusterKey(f.Namespace, f.Name), now.Sub(f.LastCompleted).Round(time.Hour), m.cfg.BackupSLA)
+			default:
+				continue
+			}
+			key := "stale/" + clusterKey(f.Namespace, f.Name)
+			if t.notified[key] {
+				current[key] = true
+				continue
+			}
+			keys = append(keys, key)
+			lines = append(lines, line)
+		}
+	}
+	// 已恢复或已删除的问题不再记录，再次出现时重新提醒
+	t.notified = current
+	sort.Strings(lines)
+	return lines, keys
+}
+
+// 距最近一次成功备份的小时数，从未成功备份时为 +Inf
+func backupAgeHours(f backupFreshness, now time.Time) float64 {
+	if f.LastCompleted.IsZero() {
+		return math.Inf(1)
+	}
+	return now.Sub(f.LastCompleted).Hours()
+}
+
+// 定期刷新备份新鲜度；未安装 dataprotection CRD 时只打印一次日志
+func (m *Monitor) startBackupLoop(ctx context.Context) {
+	if m.cfg.BackupCheckInterval <= 0 {
+		return
+	}
+	missingLogged := false
+	m.until(ctx, m.withCycleTimeout("backups", func(ctx context.Context) {
+		err := m.refreshBackups(ctx)
+		switch {
+		case err != nil && isMissingCRD(err):
+			if !missingLogged {
+				missingLogged = true
+				m.log.Info("KubeBlocks dataprotection CRDs not installed, skipping backup freshness")
+			}
+		case err != nil:
+			m.log.Error("Error refreshing backup freshness", "err", err)
+		default:
+			missingLogged = false
+		}
+	}), m.cfg.BackupCheckInterval)
+}
+
+// 摘要中的备份一节：最旧的备份，以及有计划但从未成功备份的集群
+func (m *Monitor) backupDigestSection() digestSection {
+	m.backups.mu.Lock()
+	results, at := m.backups.results, m.backups.at
+	m.backups.mu.Unlock()
+
+	section := digestSection{Title: "Backups"}
+	var oldest *backupFreshness
+	var never []string
+	for i := range results {
+		f := &results[i]
+		if f.LastCompleted.IsZero() {
+			never = append(never, clusterKey(f.Namespace, f.Name))
+			continue
+		}
+		if oldest == nil || f.LastCompleted.Before(oldest.LastCompleted) {
+			oldest = f
+		}
+	}
+	if oldest != nil {
+		section.Lines = append(section.Lines, fmt.Sprintf("oldest backup: %s, %dh",
+			clusterKey(oldest.Namespace, oldest.Name), int(at.Sub(oldest.LastCompleted).Hours())))
+	}
+	for _, key := range never {
+		section.Lines = append(section.Lines, "no completed backup: "+key)
+	}
+	return section
+}
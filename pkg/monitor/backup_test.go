@@ -0,0 +1,41 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// 发送失败的备份提醒不记录为已提醒，下一次刷新重新提醒，送达后不再重复
+func TestBackupNoticeResentAfterFailedDelivery(t *testing.T) {
+	ctx := context.Background()
+	backup := &unstructured.Unstructured{}
+	backup.SetAPIVersion("dataprotection.kubeblocks.io/v1alpha1")
+	backup.SetKind("Backup")
+	backup.SetNamespace("ns1")
+	backup.SetName("db-1")
+	backup.SetUID(types.UID("ns1-db-1"))
+	backup.SetLabels(map[string]string{"app.kubernetes.io/instance": "db"})
+	backup.SetCreationTimestamp(metav1.NewTime(testEpoch))
+	unstructured.SetNestedField(backup.Object, "Failed", "status", "phase")
+	unstructured.SetNestedField(backup.Object, testEpoch.Format(time.RFC3339), "status", "completionTimestamp")
+	env := newTestEnv(t, DefaultConfig(), Deps{Dynamic: newTestDynamic(backup)})
+	env.m.cfg.NotifyAttempts = 1
+	env.notifier.err = errors.New("webhook unavailable")
+
+	for i, want := range []int{1, 1, 0} {
+		if err := env.m.RefreshBackups(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got := env.notifier.take(); len(got) != want {
+			t.Errorf("refresh %d sent %d notices, want %d", i+1, len(got), want)
+		}
+		env.notifier.err = nil
+		env.now = env.now.Add(time.Minute)
+	}
+}
@@ -0,0 +1,231 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 待发送的回调保存在状态存储的这个 key 下
+const pendingCallbacksKey = "pending-callbacks"
+
+// 回调重试的退避上限，以及最多保留的待发送回调数
+const (
+	callbackMaxBackoff = 10 * time.Minute
+	maxPendingCallback = 1000
+)
+
+// ResolutionCallback 事件关闭时发给外部系统（例如工单机器人）的回调内容。
+// 接收方应以 IncidentID 作为幂等键，重复收到同一事件的回调时忽略即可
+type ResolutionCallback struct {
+	IncidentID string    `json:"incidentId"`
+	Namespace  string    `json:"namespace"`
+	Name       string    `json:"name"`
+	Resolution string    `json:"resolution"`
+	ClosedAt   time.Time `json:"closedAt"`
+
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"nextAttempt"`
+}
+
+// callbackQueue 待发送的回调。事件关闭后先持久化再发送，进程在两者之间退出也不会丢失；
+// 发送成功但未来得及移除时会重发，由接收方按幂等键去重
+type callbackQueue struct {
+	mu      sync.Mutex
+	pending []ResolutionCallback
+	wake    chan struct{}
+}
+
+// 事件关闭时调用，调用方可能持有 m.mu，这里只入队不发起请求。
+// 只有告警过的事件才回调，未告警就恢复的过渡状态接收方从未见过；同一事件已在队列中时替换为最新的内容
+func (m *Monitor) enqueueResolutionCallback(inc *Incident) {
+	if m.cfg.ResolutionCallbackURL == "" || inc.ClosedAt == nil || !inc.Alerted {
+		return
+	}
+	cb := ResolutionCallback{
+		IncidentID: inc.ID,
+		Namespace:  inc.Namespace,
+		Name:       inc.Name,
+		Resolution: inc.Resolution,
+		ClosedAt:   *inc.ClosedAt,
+	}
+	q := &m.callbacks
+	q.mu.Lock()
+	queued := false
+	for i := range q.pending {
+		if q.pending[i].IncidentID == cb.IncidentID {
+			q.pending[i], queued = cb, true
+		}
+	}
+	if !queued {
+		q.pending = append(q.pending, cb)
+	}
+	if len(q.pending) > maxPendingCallback {
+		q.pending = q.pending[len(q.pending)-maxPendingCallback:]
+	}
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 加载上次未发送完的回调，之后在后台持续发送
+func (m *Monitor) startCallbackLoop(ctx context.Context) {
+	if m.cfg.ResolutionCallbackURL == "" {
+		return
+	}
+	if err := m.loadPendingCallbacks(ctx); err != nil {
+		m.log.Error("Error loading pending resolution callbacks", "err", err)
+	}
+	go func() {
+		defer m.crashed()
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		for {
+			m.deliverCallbacks(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.callbacks.wake:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (m *Monitor) loadPendingCallbacks(ctx context.Context) error {
+	if m.store == nil {
+		return nil
+	}
+	data, err := m.store.Get(ctx, pendingCallbacksKey)
+	if err != nil || data == "" {
+		return err
+	}
+	var pending []ResolutionCallback
+	if err := json.Unmarshal([]byte(data), &pending); err != nil {
+		return err
+	}
+	m.callbacks.mu.Lock()
+	m.callbacks.pending = append(pending, m.callbacks.pending...)
+	m.callbacks.mu.Unlock()
+	return nil
+}
+
+func (m *Monitor) savePendingCallbacks(ctx context.Context, pending []ResolutionCallback) {
+	if m.store == nil {
+		return
+	}
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return
+	}
+	if err := m.store.Set(ctx, pendingCallbacksKey, string(data)); err != nil {
+		m.log.Error("Error saving pending resolution callbacks", "err", err)
+	}
+}
+
+// 先持久化当前队列，再发送到期的回调，最后持久化剩余的部分
+func (m *Monitor) deliverCallbacks(ctx context.Context) {
+	q := &m.callbacks
+	q.mu.Lock()
+	pending := append([]ResolutionCallback(nil), q.pending...)
+	q.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	m.savePendingCallbacks(ctx, pending)
+
+	now := m.now()
+	delivered := make(map[string]bool)
+	failed := make(map[string]ResolutionCallback)
+	for _, cb := range pending {
+		if now.Before(cb.NextAttempt) {
+			continue
+		}
+		if err := m.sendResolutionCallback(ctx, cb); err != nil {
+			cb.Attempts++
+			cb.NextAttempt = now.Add(callbackBackoff(cb.Attempts))
+			failed[cb.IncidentID] = cb
+			m.log.Error("Error sending resolution callback", "incident", cb.IncidentID, "attempt", cb.Attempts, "err", err)
+			continue
+		}
+		delivered[cb.IncidentID] = true
+		m.log.Info("Resolution callback sent", "incident", cb.IncidentID)
+	}
+	if len(delivered) == 0 && len(failed) == 0 {
+		return
+	}
+
+	q.mu.Lock()
+	remaining := q.pending[:0]
+	for _, cb := range q.pending {
+		if delivered[cb.IncidentID] {
+			continue
+		}
+		if f, ok := failed[cb.IncidentID]; ok {
+			cb = f
+		}
+		remaining = append(remaining, cb)
+	}
+	q.pending = remaining
+	pending = append([]ResolutionCallback(nil), remaining...)
+	q.mu.Unlock()
+	m.savePendingCallbacks(ctx, pending)
+}
+
+func callbackBackoff(attempts int) time.Duration {
+	backoff := 30 * time.Second
+	for i := 1; i < attempts && backoff < callbackMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > callbackMaxBackoff {
+		backoff = callbackMaxBackoff
+	}
+	return backoff
+}
+
+// 请求头带上幂等键；配置了密钥时附带 body 的 HMAC-SHA256 签名
+func (m *Monitor) sendResolutionCallback(ctx context.Context, cb ResolutionCallback) error {
+	body, err := json.Marshal(struct {
+		IncidentID string    `json:"incidentId"`
+		Namespace  string    `json:"namespace"`
+		Name       string    `json:"name"`
+		Resolution string    `json:"resolution"`
+		ClosedAt   time.Time `json:"closedAt"`
+	}{cb.IncidentID, cb.Namespace, cb.Name, cb.Resolution, cb.ClosedAt})
+	if err != nil {
+		return err
+	}
+	if m.cfg.DryRun {
+		m.log.Info("Dry run, resolution callback not sent", "url", m.cfg.ResolutionCallbackURL, "body", string(body))
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.cfg.ResolutionCallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", cb.IncidentID)
+	if m.cfg.ResolutionCallbackSecret != "" {
+		mac := hmac.New(sha256.New, []byte(m.cfg.ResolutionCallbackSecret))
+		mac.Write(body)
+		req.Header.Set("X-Database-Monitor-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// callbackReceiver 记录收到的恢复回调
+type callbackReceiver struct {
+	mu       sync.Mutex
+	keys     []string
+	bodies   []string
+	failNext int
+}
+
+func (c *callbackReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failNext > 0 {
+		c.failNext--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	c.keys = append(c.keys, r.Header.Get("Idempotency-Key"))
+	c.bodies = append(c.bodies, string(body))
+}
+
+func (c *callbackReceiver) received() ([]string, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.keys...), append([]string(nil), c.bodies...)
+}
+
+// historyStore 记录每次写入的内容，用于模拟进程在两次写入之间退出
+type historyStore struct {
+	memStore
+	history []string
+}
+
+func (s *historyStore) Set(ctx context.Context, key, value string) error {
+	s.history = append(s.history, value)
+	return s.memStore.Set(ctx, key, value)
+}
+
+func callbackEnv(t *testing.T, receiver *callbackReceiver, store StateStore) *testEnv {
+	t.Helper()
+	srv := httptest.NewServer(receiver)
+	t.Cleanup(srv.Close)
+	cfg := DefaultConfig()
+	cfg.ResolutionCallbackURL = srv.URL
+	return newTestEnv(t, cfg, Deps{Store: store, HTTPClient: srv.Client()})
+}
+
+// 打开并关闭一个事件，alerted 表示关闭前是否已告警
+func closeTestIncident(m *Monitor, name string, alerted bool) *Incident {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	inc := m.openIncident("ns1", name, "Failed", testEpoch)
+	inc.Alerted = alerted
+	m.closeIncident("ns1", name, resolutionRecovered, testEpoch.Add(time.Minute))
+	return inc
+}
+
+func TestResolutionCallbackOnlyForAlertedIncidents(t *testing.T) {
+	receiver := &callbackReceiver{}
+	env := callbackEnv(t, receiver, nil)
+	alerted := closeTestIncident(env.m, "alerted", true)
+	closeTestIncident(env.m, "transient", false)
+	env.m.deliverCallbacks(context.Background())
+
+	keys, _ := receiver.received()
+	if len(keys) != 1 || keys[0] != alerted.ID {
+		t.Errorf("callbacks sent for %v, want only %s", keys, alerted.ID)
+	}
+}
+
+// 发送成功但未来得及从队列移除时进程退出，重启后会重发；重发的请求与第一次完全相同，接收方按幂等键去重
+func TestResolutionCallbackDuplicateAfterRestart(t *testing.T) {
+	receiver := &callbackReceiver{}
+	store := &historyStore{}
+	first := callbackEnv(t, receiver, store)
+	inc := closeTestIncident(first.m, "db", true)
+	first.m.deliverCallbacks(context.Background())
+	if len(store.history) != 2 {
+		t.Fatalf("store written %d times, want 2 (before and after sending)", len(store.history))
+	}
+
+	// 重启后的进程只看到发送前保存的队列
+	restarted := &memStore{}
+	restarted.Set(context.Background(), pendingCallbacksKey, store.history[0])
+	second := callbackEnv(t, receiver, restarted)
+	if err := second.m.loadPendingCallbacks(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	second.m.deliverCallbacks(context.Background())
+
+	keys, bodies := receiver.received()
+	if len(keys) != 2 || keys[0] != inc.ID || keys[1] != inc.ID {
+		t.Fatalf("idempotency keys = %v, want %s twice", keys, inc.ID)
+	}
+	if bodies[0] != bodies[1] {
+		t.Errorf("duplicate callback body differs:\n%s\n%s", bodies[0], bodies[1])
+	}
+	var left []ResolutionCallback
+	saved, _ := restarted.Get(context.Background(), pendingCallbacksKey)
+	if err := json.Unmarshal([]byte(saved), &left); err != nil || len(left) != 0 {
+		t.Errorf("pending callbacks after redelivery = %s, want none", saved)
+	}
+}
+
+// 同一事件重复入队（例如关闭后从检查点恢复又关闭一次）只发送一次，失败后按退避重试
+func TestResolutionCallbackQueuedOnce(t *testing.T) {
+	receiver := &callbackReceiver{failNext: 1}
+	env := callbackEnv(t, receiver, nil)
+	inc := closeTestIncident(env.m, "db", true)
+	env.m.enqueueResolutionCallback(inc)
+	ctx := context.Background()
+
+	env.m.deliverCallbacks(ctx)
+	if keys, _ := receiver.received(); len(keys) != 0 {
+		t.Fatalf("callbacks delivered despite failure: %v", keys)
+	}
+	env.m.callbacks.mu.Lock()
+	pending := append([]ResolutionCallback(nil), env.m.callbacks.pending...)
+	env.m.callbacks.mu.Unlock()
+	if len(pending) != 1 || pending[0].Attempts != 1 {
+		t.Fatalf("pending = %+v, want one callback with one attempt", pending)
+	}
+
+	env.now = pending[0].NextAttempt
+	env.m.deliverCallbacks(ctx)
+	if keys, _ := receiver.received(); len(keys) != 1 || keys[0] != inc.ID {
+		t.Errorf("callbacks sent for %v, want %s once", keys, inc.ID)
+	}
+}
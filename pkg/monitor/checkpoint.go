@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// 巡检状态在 StateStore 中的 key
+const checkpointKey = "checkpoint"
+
+// checkpoint 跨重启保留的巡检状态：不健康集群的连续次数、未关闭的事件和欠费 ns，
+// 重启后不会把已告警的集群当成新故障，也不会在欠费集合刷新前误报欠费 ns 的集群
+type checkpoint struct {
+	LastStatus    map[string]checkpointStatus `json:"lastStatus"`
+	OpenIncidents map[string]*Incident        `json:"openIncidents,omitempty"`
+	DebtRecord    map[string]bool             `json:"debtRecord,omitempty"`
+	DebtSeen      map[string]time.Time        `json:"debtSeen,omitempty"`
+	// 已提醒过的运维操作，重启后不重复提醒
+	NotifiedOps map[string]string `json:"notifiedOps,omitempty"`
+	// 已提醒过的失败备份和超过 SLA 的集群
+	NotifiedBackups map[string]bool `json:"notifiedBackups,omitempty"`
+	// 已提醒过的 PVC 及其使用率级别
+	NotifiedVolumes map[string]string `json:"notifiedVolumes,omitempty"`
+	// 已提醒过的探测失败的集群
+	NotifiedProbes map[string]string `json:"notifiedProbes,omitempty"`
+}
+
+type checkpointStatus struct {
+	Phase   string    `json:"phase"`
+	Checks  int       `json:"checks"`
+	Counted time.Time `json:"counted"`
+}
+
+// 启动时加载上次保存的巡检状态
+func (m *Monitor) loadCheckpoint(ctx context.Context) error {
+	if m.store == nil {
+		return nil
+	}
+	data, err := m.store.Get(ctx, checkpointKey)
+	if err != nil || data == "" {
+		return err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal([]byte(data), &cp); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	for key, st := range cp.LastStatus {
+		m.lastStatus[key] = clusterState{phase: st.Phase, checks: st.Checks, counted: st.Counted}
+	}
+	for key, inc := range cp.OpenIncidents {
+		inc.oomSeen = make(map[string]bool)
+		m.openIncidents[key] = inc
+	}
+	m.mu.Unlock()
+
+	m.debt.mu.Lock()
+	if cp.DebtRecord != nil {
+		m.debt.record = cp.DebtRecord
+	}
+	for ns, at := range cp.DebtSeen {
+		m.debt.seen[ns] = at
+	}
+	m.debt.mu.Unlock()
+
+	m.ops.mu.Lock()
+	for key, problem := range cp.NotifiedOps {
+		m.ops.notified[key] = problem
+	}
+	m.ops.mu.Unlock()
+
+	m.backups.mu.Lock()
+	if cp.NotifiedBackups != nil {
+		m.backups.notified = cp.NotifiedBackups
+	}
+	m.backups.mu.Unlock()
+
+	m.volumes.mu.Lock()
+	for key, level := range cp.NotifiedVolumes {
+		m.volumes.notified[key] = level
+	}
+	m.volumes.mu.Unlock()
+
+	m.probes.mu.Lock()
+	for key, problem := range cp.NotifiedProbes {
+		m.probes.notified[key] = problem
+	}
+	m.probes.mu.Unlock()
+	m.lastCheckpoint = data
+	m.log.Info("Restored state checkpoint", "clusters", len(cp.LastStatus), "openIncidents", len(cp.OpenIncidents))
+	return nil
+}
+
+// 每轮巡检后保存状态，内容没有变化时不写入
+func (m *Monitor) saveCheckpoint(ctx context.Context) {
+	if m.store == nil {
+		return
+	}
+	m.checkpointMu.Lock()
+	defer m.checkpointMu.Unlock()
+	cp := checkpoint{LastStatus: make(map[string]checkpointStatus)}
+	// 与评估时的加锁顺序一致：先 m.mu 再 debt.mu
+	m.mu.Lock()
+	m.debt.mu.RLock()
+	m.ops.mu.Lock()
+	cp.NotifiedOps = m.ops.notified
+	m.backups.mu.Lock()
+	cp.NotifiedBackups = m.backups.notified
+	m.volumes.mu.Lock()
+	cp.NotifiedVolumes = m.volumes.notified
+	m.probes.mu.Lock()
+	cp.NotifiedProbes = m.probes.notified
+	cp.DebtRecord, cp.DebtSeen = m.debt.record, m.debt.seen
+	for key, st := range m.lastStatus {
+		cp.LastStatus[key] = checkpointStatus{Phase: st.phase, Checks: st.checks, Counted: st.counted}
+	}
+	cp.OpenIncidents = m.openIncidents
+	data, err := json.Marshal(cp)
+	m.probes.mu.Unlock()
+	m.volumes.mu.Unlock()
+	m.backups.mu.Unlock()
+	m.ops.mu.Unlock()
+	m.debt.mu.RUnlock()
+	m.mu.Unlock()
+	if err != nil {
+		m.log.Error("Error encoding state checkpoint", "err", err)
+		return
+	}
+	if string(data) == m.lastCheckpoint {
+		return
+	}
+	if err := m.store.Set(ctx, checkpointKey, string(data)); err != nil {
+		m.log.Error("Error saving state checkpoint", "err", err)
+		return
+	}
+	m.lastCheckpoint = string(data)
+}
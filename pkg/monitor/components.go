@@ -0,0 +1,81 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ComponentStatus 多组件集群中一个组件的概况，例如 mysql + proxy、redis + sentinel
+type ComponentStatus struct {
+	Name string `json:"name"`
+	// status.components 中的 phase，KubeBlocks 尚未汇报时为空
+	Phase string `json:"phase,omitempty"`
+	// 就绪的 Pod 数和 spec 中的副本数
+	Ready    int   `json:"ready"`
+	Replicas int64 `json:"replicas"`
+}
+
+// String 单行描述，例如 proxy Running, 2/2 ready
+func (c ComponentStatus) String() string {
+	phase := c.Phase
+	if phase == "" {
+		phase = "Unknown"
+	}
+	return fmt.Sprintf("%s %s, %d/%d ready", c.Name, phase, c.Ready, c.Replicas)
+}
+
+// ComponentLines 用于文本类消息的组件概况，每个组件一行
+func (e ReportEntry) ComponentLines() []string {
+	lines := make([]string, 0, len(e.Components))
+	for _, c := range e.Components {
+		lines = append(lines, c.String())
+	}
+	return lines
+}
+
+// 按 spec.componentSpecs 和 status.components 汇总各组件，就绪数按组件标签统计 Pod；
+// 只有一个组件时顶层 phase 已经足够，返回 nil
+func componentStatuses(cluster *unstructured.Unstructured, pods []corev1.Pod) []ComponentStatus {
+	specs, _, _ := unstructured.NestedSlice(cluster.Object, "spec", "componentSpecs")
+	if len(specs) < 2 {
+		return nil
+	}
+	ready := make(map[string]int)
+	for _, pod := range pods {
+		if podReady(pod) {
+			ready[pod.Labels[componentLabel]]++
+		}
+	}
+	statuses := make([]ComponentStatus, 0, len(specs))
+	for _, spec := range specs {
+		spec, ok := spec.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := spec["name"].(string)
+		if name == "" {
+			continue
+		}
+		replicas, _, _ := unstructured.NestedInt64(spec, "replicas")
+		phase, _, _ := unstructured.NestedString(cluster.Object, "status", "components", name, "phase")
+		statuses = append(statuses, ComponentStatus{Name: name, Phase: phase, Ready: ready[name], Replicas: replicas})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// 未在删除中且 Ready 条件为 True
+func podReady(pod corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package monitor
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// 任意条件类型或组件
+const matchAll = "*"
+
+// ConditionRule 对 phase 健康的集群按 status.conditions 或 KubeBlocks 的组件状态告警，
+// 例如 phase 仍为 Running，但某个组件已经是 Abnormal。Type 和 Component 二选一
+type ConditionRule struct {
+	// 条件类型，例如 Ready、ReplicasReady，* 匹配所有条件
+	Type string `json:"type,omitempty"`
+	// 匹配的条件取值，默认 False
+	Status string `json:"status,omitempty"`
+	// 只匹配这些 reason，为空时不限
+	Reasons []string `json:"reasons,omitempty"`
+	// KubeBlocks 集群 status.components 中的组件名，* 匹配所有组件
+	Component string `json:"component,omitempty"`
+	// 匹配的组件 phase，默认 Failed 和 Abnormal
+	ComponentPhases []string `json:"componentPhases,omitempty"`
+	// 匹配时集群按该 phase 告警，Failed 或 Abnormal，默认 Abnormal
+	Phase string `json:"phase,omitempty"`
+}
+
+// ValidateConditionRule 检查规则只匹配条件或组件之一，且 phase 为 Failed 或 Abnormal
+func ValidateConditionRule(r ConditionRule) error {
+	if (r.Type == "") == (r.Component == "") {
+		return fmt.Errorf("exactly one of type and component is required")
+	}
+	if r.Component != "" && (r.Status != "" || len(r.Reasons) > 0) {
+		return fmt.Errorf("status and reasons only apply to condition rules")
+	}
+	if r.Type != "" && len(r.ComponentPhases) > 0 {
+		return fmt.Errorf("componentPhases only applies to component rules")
+	}
+	if r.Phase != "" && r.Phase != "Failed" && r.Phase != "Abnormal" {
+		return fmt.Errorf("phase must be Failed or Abnormal, got %q", r.Phase)
+	}
+	return nil
+}
+
+func (r ConditionRule) phase() string {
+	if r.Phase == "" {
+		return "Abnormal"
+	}
+	return r.Phase
+}
+
+// 按规则检查集群的条件和组件状态，返回集群应视为的 phase 和说明，没有匹配时 phase 为空。
+// 多条规则匹配时 Failed 优先，其次按规则的顺序
+func matchConditionRules(rules []ConditionRule, res *monitoredResource, obj *unstructured.Unstructured) (phase, note string) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	var components map[string]interface{}
+	if res.kubeblocks {
+		components, _, _ = unstructured.NestedMap(obj.Object, "status", "components")
+	}
+	for _, r := range rules {
+		if ValidateConditionRule(r) != nil {
+			continue
+		}
+		var matched string
+		if r.Type != "" {
+			matched = matchCondition(r, conditions)
+		} else {
+			matched = matchComponent(r, components)
+		}
+		if matched == "" || phase == "Failed" {
+			continue
+		}
+		if phase == "" || r.phase() == "Failed" {
+			phase, note = r.phase(), matched
+		}
+	}
+	return phase, note
+}
+
+// 第一个匹配的条件，例如 Ready=False: ComponentsNotReady
+func matchCondition(r ConditionRule, conditions []interface{}) string {
+	status := r.Status
+	if status == "" {
+		status = "False"
+	}
+	for _, c := range conditions {
+		c, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		condType, _ := c["type"].(string)
+		condStatus, _ := c["status"].(string)
+		reason, _ := c["reason"].(string)
+		if r.Type != matchAll && condType != r.Type || condStatus != status {
+			continue
+		}
+		if len(r.Reasons) > 0 && !slices.Contains(r.Reasons, reason) {
+			continue
+		}
+		if reason == "" {
+			return condType + "=" + condStatus
+		}
+		return condType + "=" + condStatus + ": " + reason
+	}
+	return ""
+}
+
+// 第一个匹配的组件，按组件名排序，例如 component mysql Abnormal
+func matchComponent(r ConditionRule, components map[string]interface{}) string {
+	phases := r.ComponentPhases
+	if len(phases) == 0 {
+		phases = []string{"Failed", "Abnormal"}
+	}
+	names := make([]string, 0, len(components))
+	for name := range components {
+		if r.Component == matchAll || name == r.Component {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c, ok := components[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if phase, _ := c["phase"].(string); slices.Contains(phases, phase) {
+			return "component " + name + " " + phase
+		}
+	}
+	return ""
+}
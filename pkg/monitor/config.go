@@ -0,0 +1,196 @@
+package monitor
+
+import "time"
+
+// Config 巡检逻辑的可调参数
+type Config struct {
+	// 被巡检的 Kubernetes 集群（区域）名称，多区域部署时用于区分报告来源，单集群时为空
+	Region string `json:"region,omitempty"`
+	// 巡检周期；watch 模式下也是 informer 的 resync 周期和报告发送周期
+	CheckInterval time.Duration `json:"checkInterval"`
+	// 按 cron 表达式巡检，例如 "*/1 9-18 * * 1-5"；设置后代替 CheckInterval 决定巡检时间
+	CheckSchedule string `json:"checkSchedule"`
+	// 只巡检匹配的 ns，为空时巡检所有 ns；支持 glob，例如 prod-*
+	IncludeNamespaces []string `json:"includeNamespaces"`
+	// 不巡检匹配的 ns，例如 kb-system、test-*，优先于 IncludeNamespaces
+	ExcludeNamespaces []string `json:"excludeNamespaces"`
+	// 巡检的资源，为空时只巡检 KubeBlocks 集群；同时巡检 KubeBlocks 集群时需要列出 apps.kubeblocks.io/v1alpha1/clusters，版本按 discovery 自动选择。
+	// 第一种资源的 CRD 安装后才开始巡检
+	Resources []MonitoredResource `json:"resources,omitempty"`
+	// phase 健康时仍按 status.conditions 或组件状态告警的规则，为空时只看 phase
+	ConditionRules []ConditionRule `json:"conditionRules,omitempty"`
+	// 只巡检匹配该 label selector 的集群，例如 monitoring=enabled 或 tier=prod,monitoring!=disabled
+	ClusterSelector string `json:"clusterSelector"`
+	// 删除中（deletionTimestamp 非空）的集群处于 Failed 时是否仍然告警
+	AlertOnDeletingFailures bool `json:"alertOnDeletingFailures"`
+	// 删除中的集群超过该时长仍未消失，则视为卡在 Deleting 并告警
+	StuckDeletingAfter time.Duration `json:"stuckDeletingAfter"`
+	// 集群连续多少次评估都不健康才告警，用于忽略升级等过程中的短暂 phase
+	AlertAfterChecks int `json:"alertAfterChecks"`
+	// 轮询模式下分页 List 集群及审计资源时每页的对象数
+	ListPageSize int64 `json:"listPageSize"`
+	// 使用 informer 监听集群变化，而不是定时 List
+	Watch bool `json:"watch"`
+	// watch 模式下报告内容变化后等待该时长再发送，合并短时间内的多个变化
+	WatchDebounce time.Duration `json:"watchDebounce"`
+	// 并发评估集群的 worker 数，轮询和 watch 模式都适用
+	Workers int `json:"workers"`
+	// 队列积压超过该值时打印告警
+	QueueHighWatermark int `json:"queueHighWatermark"`
+	// 巡检额外发起的 API 调用（配额、Pod 查询等）的速率预算
+	APIQPS   float64 `json:"apiQPS"`
+	APIBurst int     `json:"apiBurst"`
+	// 每个集群的冷却时间：phase 类别和严重程度没有变化时，至少间隔这么久才重复提醒，重启后仍然生效
+	RealertInterval time.Duration `json:"realertInterval"`
+	// 24 小时内已恢复的事件超过该次数时提醒，0 表示关闭
+	RepeatedIncidentThreshold int `json:"repeatedIncidentThreshold"`
+	// 刷新欠费 ns 集合的周期
+	DebtInterval time.Duration `json:"debtInterval"`
+	// 欠费判断方式：auto 优先读取 sealos Debt CRD，不可用时退回 debt-limit0 ResourceQuota；crd、quota 只用其中一种
+	DebtSource string `json:"debtSource"`
+	// 欠费集合超过该时长没有刷新成功时清空，恢复对这些 ns 的告警，0 表示一直沿用上次的结果
+	DebtRecordTTL time.Duration `json:"debtRecordTTL"`
+	// 是否停止欠费 ns 中的集群，避免其反复重启、占用资源；欠费结束后不会自动启动
+	StopInDebt bool `json:"stopInDebt"`
+	// 未安装 clusters CRD 时检查其是否出现的周期
+	CRDPollInterval time.Duration `json:"crdPollInterval"`
+	// 每日摘要的发送周期，0 表示关闭
+	DigestInterval time.Duration `json:"digestInterval"`
+	// 按 cron 表达式发送摘要，例如 "CRON_TZ=Asia/Shanghai 0 9 * * *"；设置后代替 DigestInterval
+	DigestSchedule string `json:"digestSchedule"`
+	// 摘要发送到的通知后端名称，为空时发送到所有接收监控自身通知的后端
+	DigestNotifiers []string `json:"digestNotifiers"`
+	// 刷新备份新鲜度的周期，0 表示关闭
+	BackupCheckInterval time.Duration `json:"backupCheckInterval"`
+	// 检查 OpsRequest 的周期，0 表示关闭；Running 超过 OpsRunningTimeout 的操作视为卡住
+	OpsCheckInterval  time.Duration `json:"opsCheckInterval"`
+	OpsRunningTimeout time.Duration `json:"opsRunningTimeout"`
+	// 集群 Failed 超过该时长且不欠费时自动创建重启 OpsRequest，0 表示关闭；每个事件最多尝试 RemediationMaxAttempts 次
+	RemediationAfter       time.Duration `json:"remediationAfter"`
+	RemediationMaxAttempts int           `json:"remediationMaxAttempts"`
+	// 检查数据库 PVC 使用率的周期，0 表示关闭；用量来自各节点 kubelet 的 /stats/summary
+	VolumeCheckInterval time.Duration `json:"volumeCheckInterval"`
+	// PVC 使用率达到该百分比时分别按 warning、critical 提醒，0 表示不检查该级别
+	VolumeWarningPercent  float64 `json:"volumeWarningPercent"`
+	VolumeCriticalPercent float64 `json:"volumeCriticalPercent"`
+	// 主动探测 MySQL/PostgreSQL 集群连通性的周期，0 表示关闭；单次探测超过 ProbeTimeout 视为不可达，
+	// 超过 ProbeLatencyThreshold 视为过慢（0 表示不检查延迟）
+	ProbeInterval         time.Duration `json:"probeInterval"`
+	ProbeTimeout          time.Duration `json:"probeTimeout"`
+	ProbeLatencyThreshold time.Duration `json:"probeLatencyThreshold"`
+	// 探测时顺带检查多副本集群的复制延迟，超过该时长或复制中断时提醒，0 表示不检查；需要注册 SQL 驱动
+	ReplicationLagThreshold time.Duration `json:"replicationLagThreshold"`
+	// 维护窗口内匹配的集群不告警，事件照常打开，窗口结束后仍未恢复的集群立即告警
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows"`
+	// 持续告警的集群按事件打开的时长逐级升级
+	Escalations []EscalationTier `json:"escalations"`
+	// ns 上记录租户 webhook 地址的注解，有该注解的 ns 的告警发送到该地址，不再发送到兜底渠道；为空时不读取
+	NamespaceWebhookAnnotation string `json:"namespaceWebhookAnnotation"`
+	// 集群或 ns 上该注解为 true 时不告警，例如测试和临时数据库；为空时不读取
+	IgnoreAnnotation string `json:"ignoreAnnotation"`
+	// 作为负责人信息附在告警中的 ns 注解，没有注解时读取同名标签；为空时不读取
+	OwnerAnnotations []string `json:"ownerAnnotations"`
+	// ns 上记录负责人飞书 user ID 的注解，多个以逗号分隔，飞书通知中 @ 这些用户；为空时不 @
+	OwnerMentionAnnotation string `json:"ownerMentionAnnotation"`
+	// 配置了备份计划的集群超过该时长没有成功备份时提醒，0 表示不检查
+	BackupSLA time.Duration `json:"backupSLA"`
+	// 是否导出按集群区分的备份时长指标；标签为 namespace+name，集群多时注意基数
+	BackupMetrics bool `json:"backupMetrics"`
+	// 事件关闭时回调的地址，为空时不回调
+	ResolutionCallbackURL string `json:"resolutionCallbackURL"`
+	// 回调 body 的 HMAC-SHA256 签名密钥，属于敏感信息
+	ResolutionCallbackSecret string `json:"resolutionCallbackSecret"`
+	// 事件打开和关闭时在该 Grafana 上创建标注，为空时不创建；token 属于敏感信息，dashboard 为空时为全局标注
+	GrafanaURL          string `json:"grafanaURL"`
+	GrafanaAPIToken     string `json:"grafanaAPIToken"`
+	GrafanaDashboardUID string `json:"grafanaDashboardUID"`
+	// 管理控制台中数据库页面的地址模板，{namespace}、{name}、{region} 会被替换，例如 https://cloud.example.com/db/{namespace}/{name}；
+	// 设置后每条告警都带上跳转链接
+	ConsoleURL string `json:"consoleURL"`
+	// 每个集群同一种 Event 之间的最小间隔，以及每小时全局最多创建的 Event 数（0 表示不限，与已发出的开启 Event 成对的关闭 Event 不计入限制）
+	EventClusterInterval time.Duration `json:"eventClusterInterval"`
+	EventGlobalPerHour   int           `json:"eventGlobalPerHour"`
+	// 发送通知失败时最多尝试的次数（含第一次），以及重试的初始间隔、最大间隔和随机抖动比例（0~1）
+	NotifyAttempts   int           `json:"notifyAttempts"`
+	NotifyBackoff    time.Duration `json:"notifyBackoff"`
+	NotifyMaxBackoff time.Duration `json:"notifyMaxBackoff"`
+	NotifyJitter     float64       `json:"notifyJitter"`
+	// 所有通知后端共享的令牌桶：每分钟补充的令牌数（0 表示不限）和桶容量；
+	// 超出时按 NotifyOverflow 处理，summarize 合并暂缓的通知，queue 排队逐条发送
+	NotifyRatePerMinute float64 `json:"notifyRatePerMinute"`
+	NotifyBurst         int     `json:"notifyBurst"`
+	NotifyOverflow      string  `json:"notifyOverflow"`
+	// 单次发送通知的超时，也用于恢复回调和 Grafana 标注请求
+	NotifyTimeout time.Duration `json:"notifyTimeout"`
+	// 每轮巡检以及欠费、备份等后台检查的最长时间，API server 或通知后端挂起时放弃本轮，下一轮重试；0 表示不限制
+	CheckTimeout time.Duration `json:"checkTimeout"`
+	// 巡检连续失败（例如无法访问 API server）超过该时长时发送一次监控自身的告警，恢复后再通知；0 表示不告警
+	APIOutageAlertAfter time.Duration `json:"apiOutageAlertAfter"`
+	// 巡检超过预期时间这么久仍未完成时，存活探针失败，由 kubelet 重启进程；0 表示不检查
+	StallTimeout time.Duration `json:"stallTimeout"`
+	// 收到退出信号后等待进行中的通知和状态保存完成的最长时间
+	ShutdownTimeout time.Duration `json:"shutdownTimeout"`
+	// 照常巡检和去重，但只把将要发送给各通知后端的内容写入日志，也不回调、不创建租户通知
+	DryRun bool `json:"dryRun"`
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() Config {
+	return Config{
+		CheckInterval:      5 * time.Minute,
+		Watch:              true,
+		WatchDebounce:      5 * time.Second,
+		StuckDeletingAfter: 30 * time.Minute,
+		AlertAfterChecks:   2,
+		Workers:            4,
+		ListPageSize:       500,
+		QueueHighWatermark: 500,
+		APIQPS:             20,
+		APIBurst:           40,
+		RealertInterval:    2 * time.Hour,
+		DebtInterval:       10 * time.Minute,
+		DebtSource:         DebtSourceAuto,
+		DebtRecordTTL:      time.Hour,
+		CRDPollInterval:    3 * time.Minute,
+		DigestInterval:     24 * time.Hour,
+
+		BackupCheckInterval: 30 * time.Minute,
+		OpsCheckInterval:    2 * time.Minute,
+		OpsRunningTimeout:   time.Hour,
+
+		RemediationMaxAttempts: 2,
+
+		VolumeCheckInterval:   5 * time.Minute,
+		VolumeWarningPercent:  80,
+		VolumeCriticalPercent: 90,
+
+		ProbeTimeout:          5 * time.Second,
+		ProbeLatencyThreshold: time.Second,
+
+		ReplicationLagThreshold: 30 * time.Second,
+
+		NamespaceWebhookAnnotation: "monitor.db/feishu-webhook",
+		IgnoreAnnotation:           "monitor.db/ignore",
+		OwnerAnnotations:           []string{"owner", "team", "user.sealos.io/owner"},
+		OwnerMentionAnnotation:     "monitor.db/feishu-user-id",
+
+		EventClusterInterval: time.Hour,
+		EventGlobalPerHour:   100,
+
+		RepeatedIncidentThreshold: 3,
+
+		ShutdownTimeout: 10 * time.Second,
+		StallTimeout:    10 * time.Minute,
+		CheckTimeout:    5 * time.Minute,
+
+		APIOutageAlertAfter: 2 * time.Minute,
+
+		NotifyAttempts:   3,
+		NotifyBackoff:    time.Second,
+		NotifyMaxBackoff: 30 * time.Second,
+		NotifyJitter:     0.2,
+		NotifyTimeout:    10 * time.Second,
+		NotifyBurst:      10,
+		NotifyOverflow:   NotifyOverflowSummarize,
+	}
+}
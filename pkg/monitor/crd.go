@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// 巡检的第一种资源的名称，未安装其 CRD 时不开始巡检
+func (m *Monitor) crdName() string {
+	if r := m.resources[0]; !r.kubeblocks {
+		return r.gvr.GroupResource().String()
+	}
+	return "KubeBlocks clusters"
+}
+
+// 区分 CRD 未安装和其他 List 错误
+func isMissingCRD(err error) bool {
+	return meta.IsNoMatchError(err) || apierrors.IsNotFound(err)
+}
+
+// 巡检的第一种资源是否已注册，KubeBlocks 集群同时选择 API 版本
+func (m *Monitor) crdInstalled(ctx context.Context) (bool, error) {
+	if m.resources[0].kubeblocks {
+		return m.detectAPI(ctx)
+	}
+	return m.resourceInstalled(ctx, m.resources[0].gvr)
+}
+
+// 通过 discovery 判断 gvr 是否已注册
+func (m *Monitor) resourceInstalled(ctx context.Context, gvr schema.GroupVersionResource) (bool, error) {
+	if err := m.budget.Wait(ctx); err != nil {
+		return false, err
+	}
+	resources, err := m.kube.Discovery().ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == gvr.Resource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// waitForCRD 在 CRD 出现前按 CRDPollInterval 轮询 discovery，只在进入等待时打印一次日志。
+// 等待过之后检测到 CRD 时发送一条自身通知
+func (m *Monitor) waitForCRD(ctx context.Context) error {
+	waited := false
+	for {
+		installed, err := m.crdInstalled(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			m.log.Error("Error checking for the CRD", "resource", m.crdName(), "err", err)
+		}
+		if installed {
+			m.setReady("")
+			if waited {
+				m.log.Info(m.crdName() + " CRD detected, monitoring started")
+				m.Notify(ctx, m.NewNotice(m.crdName()+" CRD detected, monitoring started"))
+			}
+			return nil
+		}
+		if err == nil && !waited {
+			waited = true
+			m.setReady(m.crdName() + " CRD not installed")
+			m.expectCheck(time.Time{})
+			m.log.Warn(m.crdName()+" CRD not installed, waiting for it to appear", "pollInterval", m.cfg.CRDPollInterval)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.cfg.CRDPollInterval):
+		}
+	}
+}
+
+// Ready 返回是否在正常巡检；未就绪时同时返回原因
+func (m *Monitor) Ready() (bool, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.notReady == "", m.notReady
+}
+
+func (m *Monitor) setReady(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notReady = reason
+}
@@ -0,0 +1,266 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// 欠费的 ns 会被 sealos 创建名为 debt-limit0 的 ResourceQuota；没有 Debt CRD 时据此判断
+const debtQuotaName = "debt-limit0"
+
+// debtSeen 中的记录保留时长
+const debtSeenRetention = 30 * 24 * time.Hour
+
+// DebtDetector 返回当前欠费的 ns 集合
+type DebtDetector interface {
+	DebtNamespaces(ctx context.Context) (map[string]bool, error)
+}
+
+// NewQuotaDebtDetector 通过 debt-limit0 ResourceQuota 判断欠费，一次分页 List 覆盖所有 ns
+func NewQuotaDebtDetector(kube kubernetes.Interface) DebtDetector {
+	return &quotaDebtDetector{kube: kube}
+}
+
+type quotaDebtDetector struct {
+	kube kubernetes.Interface
+}
+
+func (d *quotaDebtDetector) DebtNamespaces(ctx context.Context) (map[string]bool, error) {
+	record := make(map[string]bool)
+	opts := metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", debtQuotaName).String(),
+		Limit:         500,
+	}
+	for {
+		quotas, err := d.kube.CoreV1().ResourceQuotas("").List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, q := range quotas.Items {
+			record[q.Namespace] = true
+		}
+		if quotas.Continue == "" {
+			return record, nil
+		}
+		opts.Continue = quotas.Continue
+	}
+}
+
+// sealos 账户服务的欠费对象
+var debtsGVR = schema.GroupVersionResource{
+	Group:    "account.sealos.io",
+	Version:  "v1",
+	Resource: "debts",
+}
+
+// sealos 用户的 ns 名称为 ns-<用户名>
+const sealosUserNamespacePrefix = "ns-"
+
+// Debt 状态为这些值时未欠费；新版本为 NormalPeriod，旧版本为 Normal
+var debtNormalStatuses = map[string]bool{"": true, "Normal": true, "NormalPeriod": true}
+
+// 欠费判断方式
+const (
+	// 优先读取 Debt CRD，未安装或无权限时退回 ResourceQuota
+	DebtSourceAuto  = "auto"
+	DebtSourceCRD   = "crd"
+	DebtSourceQuota = "quota"
+)
+
+// NewSealosDebtDetector 读取 sealos 的 debts.account.sealos.io，status.status 不是正常状态的用户的 ns 视为欠费。
+// fallback 非空时，CRD 未安装或没有读取权限则改用 fallback 判断
+func NewSealosDebtDetector(dyn dynamic.Interface, fallback DebtDetector) DebtDetector {
+	return &sealosDebtDetector{dynamic: dyn, fallback: fallback}
+}
+
+type sealosDebtDetector struct {
+	dynamic  dynamic.Interface
+	fallback DebtDetector
+}
+
+func (d *sealosDebtDetector) DebtNamespaces(ctx context.Context) (map[string]bool, error) {
+	record := make(map[string]bool)
+	opts := metav1.ListOptions{Limit: 500}
+	for {
+		debts, err := d.dynamic.Resource(debtsGVR).List(ctx, opts)
+		if err != nil && d.fallback != nil && (isMissingCRD(err) || apierrors.IsForbidden(err)) {
+			return d.fallback.DebtNamespaces(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, debt := range debts.Items {
+			status, _, _ := unstructured.NestedString(debt.Object, "status", "status")
+			user, _, _ := unstructured.NestedString(debt.Object, "spec", "userName")
+			if user != "" && !debtNormalStatuses[status] {
+				record[sealosUserNamespacePrefix+user] = true
+			}
+		}
+		if debts.GetContinue() == "" {
+			return record, nil
+		}
+		opts.Continue = debts.GetContinue()
+	}
+}
+
+// debtTracker 维护欠费 ns 集合。欠费状态按计费周期变化，和故障评估解耦，
+// 由独立的 goroutine 定期整体替换，评估时只读
+type debtTracker struct {
+	mu sync.RWMutex
+	// 记录欠费的ns
+	record map[string]bool
+	// 每个 ns 最近一次被观察到欠费的时间，用于判断集群消失是否由欠费清理导致
+	seen map[string]time.Time
+	// 最近一次成功刷新的时间，为零表示还没有刷新过
+	refreshed time.Time
+}
+
+func newDebtTracker() *debtTracker {
+	return &debtTracker{record: make(map[string]bool), seen: make(map[string]time.Time)}
+}
+
+// 替换欠费集合，返回不再欠费的 ns
+func (t *debtTracker) replace(record map[string]bool, now time.Time) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var recovered []string
+	for ns := range t.record {
+		if !record[ns] {
+			recovered = append(recovered, ns)
+		}
+	}
+	sort.Strings(recovered)
+	t.record, t.refreshed = record, now
+	for ns := range record {
+		t.seen[ns] = now
+	}
+	for ns, at := range t.seen {
+		if now.Sub(at) > debtSeenRetention {
+			delete(t.seen, ns)
+		}
+	}
+	return recovered
+}
+
+// 超过 ttl 没有成功刷新时清空欠费集合，恢复对这些 ns 的告警，而不是一直按过期的结果抑制。
+// 从检查点恢复、还没有刷新过的集合不清空，返回被清空的 ns
+func (t *debtTracker) expire(now time.Time, ttl time.Duration) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ttl <= 0 || t.refreshed.IsZero() || now.Sub(t.refreshed) <= ttl || len(t.record) == 0 {
+		return nil
+	}
+	expired := make([]string, 0, len(t.record))
+	for ns := range t.record {
+		expired = append(expired, ns)
+	}
+	sort.Strings(expired)
+	t.record = make(map[string]bool)
+	return expired
+}
+
+func (t *debtTracker) inDebt(namespace string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.record[namespace]
+}
+
+// ns 当前或近期是否欠费
+func (t *debtTracker) wasInDebt(namespace string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, ok := t.seen[namespace]
+	return ok
+}
+
+// 当前欠费 ns 的有序列表
+func (t *debtTracker) snapshot() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	namespaces := make([]string, 0, len(t.record))
+	for ns := range t.record {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// RefreshDebt 立即刷新欠费 ns 集合。Run 会定期刷新；只调用 RunOnce 的嵌入方需要自行调用
+func (m *Monitor) RefreshDebt(ctx context.Context) error {
+	return m.refreshDebt(ctx)
+}
+
+func (m *Monitor) refreshDebt(ctx context.Context) error {
+	if err := m.budget.Wait(ctx); err != nil {
+		return err
+	}
+	ctx, span := m.tracer.Start(ctx, "refresh debt", trace.WithAttributes(attribute.String("source", m.cfg.DebtSource)))
+	record, err := m.debtDetector.DebtNamespaces(ctx)
+	span.SetAttributes(attribute.Int("namespaces", len(record)))
+	m.spanError(span, err)
+	span.End()
+	if err != nil {
+		if expired := m.debt.expire(m.now(), m.cfg.DebtRecordTTL); len(expired) > 0 {
+			m.log.Warn("Debt namespaces not refreshed in time, resuming alerts for them", "ttl", m.cfg.DebtRecordTTL, "namespaces", len(expired))
+			m.metrics.debtNamespaces.Set(0)
+		}
+		return err
+	}
+	recovered := m.debt.replace(record, m.now())
+	m.metrics.debtNamespaces.Set(float64(len(record)))
+	m.notifyDebtRecovered(ctx, recovered)
+	return nil
+}
+
+// 不再欠费且有集群的 ns 发一条提示，这些 ns 的集群恢复正常评估
+func (m *Monitor) notifyDebtRecovered(ctx context.Context, namespaces []string) {
+	if len(namespaces) == 0 {
+		return
+	}
+	counts := make(map[string]int)
+	m.mu.Lock()
+	for key := range m.phases {
+		counts[strings.SplitN(key, "/", 2)[0]]++
+	}
+	m.mu.Unlock()
+	var lines []string
+	for _, ns := range namespaces {
+		if n := counts[ns]; n > 0 {
+			lines = append(lines, fmt.Sprintf("%s: alerting resumed for %d cluster(s)", ns, n))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	m.log.Info("Namespaces recovered from debt", "count", len(lines))
+	m.Notify(ctx, m.NewNotice("Namespaces recovered from debt:\n"+strings.Join(lines, "\n")))
+}
+
+// 先同步刷新一次，避免刚启动时把欠费 ns 的集群当成故障，之后在后台定期刷新；每次刷新成功后停止欠费 ns 中的集群
+func (m *Monitor) startDebtLoop(ctx context.Context) {
+	refresh := m.withCycleTimeout("debt", func(ctx context.Context) {
+		if err := m.refreshDebt(ctx); err != nil {
+			m.log.Error("Error refreshing debt namespaces", "err", err)
+			return
+		}
+		if err := m.stopDebtClusters(ctx); err != nil && ctx.Err() == nil {
+			m.log.Error("Error stopping clusters in debt", "err", err)
+		}
+	})
+	refresh(ctx)
+	m.until(ctx, refresh, m.cfg.DebtInterval)
+}
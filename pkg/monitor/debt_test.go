@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// alternatingDebt 每次调用在两组欠费 ns 之间切换
+type alternatingDebt struct {
+	calls atomic.Int64
+}
+
+func (d *alternatingDebt) DebtNamespaces(context.Context) (map[string]bool, error) {
+	if d.calls.Add(1)%2 == 0 {
+		return map[string]bool{"ns0": true, "ns2": true}, nil
+	}
+	return map[string]bool{"ns1": true}, nil
+}
+
+func TestDebtTrackerReplace(t *testing.T) {
+	tracker := newDebtTracker()
+	tracker.replace(map[string]bool{"ns1": true, "ns2": true}, testEpoch)
+	recovered := tracker.replace(map[string]bool{"ns2": true}, testEpoch.Add(time.Hour))
+	if !reflect.DeepEqual(recovered, []string{"ns1"}) {
+		t.Errorf("recovered = %v, want [ns1]", recovered)
+	}
+	if tracker.inDebt("ns1") || !tracker.inDebt("ns2") {
+		t.Errorf("inDebt(ns1, ns2) = %v, %v; want false, true", tracker.inDebt("ns1"), tracker.inDebt("ns2"))
+	}
+	if !tracker.wasInDebt("ns1") {
+		t.Error("ns1 should still count as recently in debt")
+	}
+	tracker.replace(map[string]bool{}, testEpoch.Add(debtSeenRetention+2*time.Hour))
+	if tracker.wasInDebt("ns1") {
+		t.Error("ns1 should be forgotten after the retention period")
+	}
+	if expired := tracker.expire(testEpoch.Add(debtSeenRetention+2*time.Hour), time.Hour); expired != nil {
+		t.Errorf("empty record expired %v", expired)
+	}
+}
+
+// 欠费刷新、评估和管理接口的读取并发进行，需配合 -race 运行
+func TestDebtConcurrentAccess(t *testing.T) {
+	var clusters []runtime.Object
+	for i := 0; i < 3; i++ {
+		clusters = append(clusters, testCluster(fmt.Sprintf("ns%d", i), "db", "Failed"))
+	}
+	env := newTestEnv(t, DefaultConfig(), Deps{Dynamic: newTestDynamic(clusters...), Debt: &alternatingDebt{}, Store: &memStore{}})
+	m := env.m
+	ctx := context.Background()
+
+	const rounds = 50
+	var wg sync.WaitGroup
+	run := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				fn()
+			}
+		}()
+	}
+	run(func() {
+		if err := m.refreshDebt(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+	run(func() {
+		if _, err := m.RunOnce(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+	run(func() {
+		for _, ns := range []string{"ns0", "ns1", "ns2"} {
+			m.debt.inDebt(ns)
+			m.debt.wasInDebt(ns)
+		}
+		m.debt.snapshot()
+		m.saveCheckpoint(ctx)
+	})
+	run(func() {
+		m.DebtNamespaces()
+		m.LastReport()
+		m.Decisions()
+		m.Silences()
+		m.ActiveAlerts()
+		m.RecentIncidents(10)
+	})
+	wg.Wait()
+
+	// 最后一次刷新的结果与 DebtNamespaces 一致
+	var names []string
+	for _, ns := range m.DebtNamespaces() {
+		names = append(names, ns.Namespace)
+	}
+	if want := m.debt.snapshot(); !reflect.DeepEqual(names, want) {
+		t.Errorf("DebtNamespaces = %v, snapshot = %v", names, want)
+	}
+}
@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// 因欠费被停止的集群带有该注解，值为停止时间，欠费结束后需要用户自行启动
+const stoppedForDebtAnnotation = "monitor.db/stopped-for-debt"
+
+// debtStopTracker 记录已停止过的集群，同一次欠费期间只停止和通知一次；ns 不再欠费后清除
+type debtStopTracker struct {
+	mu      sync.Mutex
+	stopped map[string]bool
+}
+
+// StopDebtClusters 立即停止欠费 ns 中仍在运行的集群。Run 在每次刷新欠费后执行；只调用 RunOnce 的嵌入方需要自行调用
+func (m *Monitor) StopDebtClusters(ctx context.Context) error {
+	return m.stopDebtClusters(ctx)
+}
+
+// 给欠费 ns 中未停止的集群的每个组件设置 stop，按 KubeBlocks 的语义停止 Pod、保留数据，停止的集群合并为一条通知
+func (m *Monitor) stopDebtClusters(ctx context.Context) error {
+	if !m.cfg.StopInDebt || m.kubeblocks() == nil {
+		return nil
+	}
+	api := m.api()
+	var candidates []*unstructured.Unstructured
+	inDebt := make(map[string]bool)
+	err := m.listSelected(ctx, api.clusters, m.cfg.ClusterSelector, func(cluster *unstructured.Unstructured) {
+		ns := cluster.GetNamespace()
+		if !m.namespaceAllowed(ns) || !m.debt.inDebt(ns) || cluster.GetDeletionTimestamp() != nil {
+			return
+		}
+		inDebt[clusterKey(ns, cluster.GetName())] = true
+		switch phase, _, _ := unstructured.NestedString(cluster.Object, "status", "phase"); phase {
+		case "Stopped", "Stopping", "Deleting":
+			return
+		}
+		candidates = append(candidates, cluster)
+	})
+	if err != nil {
+		return err
+	}
+
+	t := &m.debtStops
+	t.mu.Lock()
+	for key := range t.stopped {
+		if !inDebt[key] {
+			delete(t.stopped, key)
+		}
+	}
+	var todo []*unstructured.Unstructured
+	for _, c := range candidates {
+		if !t.stopped[clusterKey(c.GetNamespace(), c.GetName())] {
+			todo = append(todo, c)
+		}
+	}
+	t.mu.Unlock()
+
+	var lines []string
+	for _, cluster := range todo {
+		key := clusterKey(cluster.GetNamespace(), cluster.GetName())
+		phase, _, _ := unstructured.NestedString(cluster.Object, "status", "phase")
+		if err := m.stopCluster(ctx, api, cluster); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			m.log.Error("Error stopping cluster in debt", "cluster", cluster.GetName(), "namespace", cluster.GetNamespace(), "err", err)
+			lines = append(lines, fmt.Sprintf("%s: failed to stop: %v", key, err))
+			continue
+		}
+		m.log.Info("Stopped cluster in debt", "cluster", cluster.GetName(), "namespace", cluster.GetNamespace(), "phase", phase)
+		lines = append(lines, fmt.Sprintf("%s: stopped (was %s)", key, phase))
+	}
+	t.mu.Lock()
+	for _, c := range todo {
+		// 停止失败的集群也只通知一次，避免每次刷新都重复
+		t.stopped[clusterKey(c.GetNamespace(), c.GetName())] = true
+	}
+	t.mu.Unlock()
+
+	if len(lines) == 0 {
+		return nil
+	}
+	sort.Strings(lines)
+	m.Notify(ctx, m.NewNotice("Stopped databases in namespaces in debt, start them again after the debt is paid:\n"+strings.Join(lines, "\n")))
+	return nil
+}
+
+// 给每个组件设置 spec.componentSpecs[].stop，并记录停止时间
+func (m *Monitor) stopCluster(ctx context.Context, api *kubeblocksAPI, cluster *unstructured.Unstructured) error {
+	comps, _, _ := unstructured.NestedSlice(cluster.Object, "spec", "componentSpecs")
+	if len(comps) == 0 {
+		return fmt.Errorf("cluster has no components")
+	}
+	for _, c := range comps {
+		if c, ok := c.(map[string]interface{}); ok {
+			c["stop"] = true
+		}
+	}
+	obj := cluster.DeepCopy()
+	if err := unstructured.SetNestedSlice(obj.Object, comps, "spec", "componentSpecs"); err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[stoppedForDebtAnnotation] = m.now().UTC().Format(time.RFC3339)
+	obj.SetAnnotations(annotations)
+	if m.cfg.DryRun {
+		m.log.Info("Dry run, cluster not stopped", "cluster", obj.GetName(), "namespace", obj.GetNamespace())
+		return nil
+	}
+	if err := m.budget.Wait(ctx); err != nil {
+		return err
+	}
+	_, err := m.dynamic.Resource(api.clusters).Namespace(obj.GetNamespace()).Update(ctx, obj, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,104 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	actionAlert    = "alert"
+	actionSuppress = "suppress"
+	actionPending  = "pending"
+	actionHealthy  = "healthy"
+)
+
+// Decision 记录针对某个集群做出的告警决策及原因，便于解释监控行为
+type Decision struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Phase     string    `json:"phase"`
+	Action    string    `json:"action"`
+	Reason    string    `json:"reason"`
+	Time      time.Time `json:"time"`
+	// 做出决策时生效的配置版本
+	ConfigHash string `json:"configHash"`
+}
+
+// Decisions 返回每个集群最近一次的决策，按 namespace/name 排序
+func (m *Monitor) Decisions() []Decision {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	decisions := make([]Decision, 0, len(m.decisions))
+	for _, d := range m.decisions {
+		decisions = append(decisions, d)
+	}
+	sort.Slice(decisions, func(i, j int) bool {
+		return clusterKey(decisions[i].Namespace, decisions[i].Name) < clusterKey(decisions[j].Namespace, decisions[j].Name)
+	})
+	return decisions
+}
+
+// 调用方需持有 m.mu
+func (m *Monitor) recordDecision(namespace, name, phase, action, reason string) {
+	d := Decision{
+		Namespace:  namespace,
+		Name:       name,
+		Phase:      phase,
+		Action:     action,
+		Reason:     reason,
+		Time:       m.now(),
+		ConfigHash: m.configVersion,
+	}
+	key := clusterKey(namespace, name)
+	prev, ok := m.decisions[key]
+	if !ok || prev.Action != d.Action || prev.Reason != d.Reason {
+		m.log.Debug("Decision changed", "namespace", namespace, "cluster", name, "action", d.Action, "reason", d.Reason, "phase", d.Phase, "config", d.ConfigHash)
+	}
+	if !ok || prev.Phase != d.Phase || prev.Action != d.Action || prev.Reason != d.Reason {
+		record := AuditRecord{
+			Kind:      AuditTransition,
+			Cluster:   name,
+			Namespace: namespace,
+			OldPhase:  prev.Phase,
+			NewPhase:  phase,
+			Decision:  action,
+			Reason:    reason,
+		}
+		if inc, open := m.openIncidents[key]; open {
+			record.IncidentID = inc.ID
+		}
+		m.audit(record)
+	}
+	m.decisions[key] = d
+}
+
+// 集群消失时删除决策记录，并记录一次 phase 为空的状态变化，让历史中的观察在此结束；调用方需持有 m.mu
+func (m *Monitor) forgetDecision(key string) {
+	prev, ok := m.decisions[key]
+	if !ok {
+		return
+	}
+	delete(m.decisions, key)
+	m.audit(AuditRecord{
+		Kind:      AuditTransition,
+		Cluster:   prev.Name,
+		Namespace: prev.Namespace,
+		OldPhase:  prev.Phase,
+		Decision:  "removed",
+		Reason:    "cluster no longer exists",
+	})
+}
+
+// 清理本轮未出现的集群的决策记录和 phase 指标，调用方需持有 m.mu
+func (m *Monitor) pruneDecisions(seen map[string]bool) {
+	for key := range m.decisions {
+		if !seen[key] {
+			m.forgetDecision(key)
+		}
+	}
+	for key := range m.phases {
+		if !seen[key] {
+			m.forgetClusterPhase(key)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 告警状态在 StateStore 中的 key，重启后沿用，不会因重启而重发
+const alertStateKey = "alert-state"
+
+// reportDedup 按事件身份对报告去重
+type reportDedup struct {
+	mu sync.Mutex
+	// 上一次发送的报告中的事件 key 及其严重程度
+	lastSent map[string]string
+	// 每个事件最近一次被通知的时间，用于按集群计算重复提醒间隔
+	lastSentAt map[string]time.Time
+	// 上一次发送时各事件的原因类别，类别变化时不等重复提醒间隔直接重发
+	lastReasons map[string]string
+}
+
+// alertState 持久化的去重状态
+type alertState struct {
+	LastSent    map[string]string    `json:"lastSent"`
+	LastSentAt  map[string]time.Time `json:"lastSentAt"`
+	LastReasons map[string]string    `json:"lastReasons,omitempty"`
+}
+
+// 只有事件集合或严重程度变化、或超过重复提醒间隔时才需要发送，单纯的时长等内容变化不触发
+func (d *reportDedup) shouldSend(r Report, now time.Time, realert time.Duration) (bool, string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	keys := r.incidentKeys()
+	if d.lastSent == nil {
+		if len(keys) == 0 {
+			return false, "no open incidents"
+		}
+		return true, "first report"
+	}
+	if len(keys) != len(d.lastSent) {
+		return true, "incident set changed"
+	}
+	for key, severity := range keys {
+		prev, ok := d.lastSent[key]
+		if !ok {
+			return true, "incident set changed"
+		}
+		if prev != severity {
+			return true, "severity changed for " + key
+		}
+	}
+	for key, reason := range r.incidentReasons() {
+		if prev, ok := d.lastReasons[key]; ok && prev != reason {
+			return true, "reason changed for " + key
+		}
+	}
+	// 每个集群各自的冷却时间，任一集群超过重复提醒间隔时重发
+	for key := range keys {
+		if now.Sub(d.lastSentAt[key]) >= realert {
+			return true, "realert interval elapsed for " + key
+		}
+	}
+	return false, "no incident changes"
+}
+
+// 整份报告一起发送，但只有触发发送的事件（新出现、严重程度或原因变化、超过重复提醒间隔）重新开始冷却，
+// 其余事件沿用各自的冷却时间，避免一个集群的重发推迟其他集群的重复提醒
+func (d *reportDedup) markSent(r Report, now time.Time, realert time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	keys := r.incidentKeys()
+	reasons := r.incidentReasons()
+	sentAt := make(map[string]time.Time, len(keys))
+	for key, severity := range keys {
+		at, ok := d.lastSentAt[key]
+		prevReason, hadReason := d.lastReasons[key]
+		reason, hasReason := reasons[key]
+		switch {
+		case !ok || d.lastSent[key] != severity || now.Sub(at) >= realert:
+			at = now
+		case hadReason && hasReason && prevReason != reason:
+			at = now
+		}
+		sentAt[key] = at
+	}
+	d.lastSent, d.lastSentAt = keys, sentAt
+	// 原因未知的事件沿用之前记录的类别
+	for key, reason := range d.lastReasons {
+		if _, ok := reasons[key]; ok {
+			continue
+		}
+		if _, ok := d.lastSent[key]; ok {
+			reasons[key] = reason
+		}
+	}
+	d.lastReasons = reasons
+}
+
+// 集群消失且无需通知时，从已发送记录中移除，避免它的消失触发一次重发
+func (m *Monitor) forgetSentIncidents(namespace, name string) {
+	m.dedup.mu.Lock()
+	defer m.dedup.mu.Unlock()
+	prefix := clusterKey(namespace, name) + "/"
+	for key := range m.dedup.lastSent {
+		if strings.HasPrefix(key, prefix) {
+			delete(m.dedup.lastSent, key)
+			delete(m.dedup.lastReasons, key)
+		}
+	}
+}
+
+// 按事件身份去重后发送巡检报告
+func (m *Monitor) notifyReport(ctx context.Context, r Report) {
+	ctx, cancel := m.detached(ctx)
+	defer cancel()
+	m.sendRecoveries(ctx)
+	now := m.now()
+	// 升级不受整份报告去重的影响，按各级自己的内容判断
+	m.escalate(ctx, r, now)
+	send, reason := m.dedup.shouldSend(r, now, m.cfg.RealertInterval)
+	if !send {
+		m.log.Info("Skipping report", "reason", reason)
+		m.audit(AuditRecord{Kind: AuditNotification, Decision: "skip", Reason: reason})
+		return
+	}
+	m.log.Info("Sending report", "reason", reason)
+	destinations := make([]string, 0, len(m.notifiers))
+	for _, n := range m.notifiers {
+		destinations = append(destinations, n.Name())
+	}
+	m.audit(AuditRecord{Kind: AuditNotification, Decision: "send", Reason: reason, Destinations: destinations})
+	// 所有通知都送达后才记录为已发送；有通知发送失败或被丢弃时不记录，下一轮巡检重新发送
+	g := newDeliveryGroup(func(ctx context.Context, delivered bool) {
+		if !delivered {
+			m.log.Warn("Report was not delivered, it will be sent again after the next check")
+			return
+		}
+		m.dedup.markSent(r, now, m.cfg.RealertInterval)
+		m.markAlerted(r, destinations)
+		m.saveAlertState(ctx)
+	})
+	m.notifyRouted(ctx, r, now, g)
+	g.seal(ctx)
+}
+
+// 加载上次运行时的去重状态，重启后在冷却时间内不重发未变化的事件
+func (m *Monitor) loadAlertState(ctx context.Context) error {
+	if m.store == nil {
+		return nil
+	}
+	data, err := m.store.Get(ctx, alertStateKey)
+	if err != nil || data == "" {
+		return err
+	}
+	var state alertState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return err
+	}
+	d := &m.dedup
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if state.LastSent == nil {
+		state.LastSent = make(map[string]string)
+	}
+	d.lastSent, d.lastSentAt, d.lastReasons = state.LastSent, state.LastSentAt, state.LastReasons
+	return nil
+}
+
+func (m *Monitor) saveAlertState(ctx context.Context) {
+	if m.store == nil {
+		return
+	}
+	d := &m.dedup
+	d.mu.Lock()
+	data, err := json.Marshal(alertState{LastSent: d.lastSent, LastSentAt: d.lastSentAt, LastReasons: d.lastReasons})
+	d.mu.Unlock()
+	if err != nil {
+		return
+	}
+	if err := m.store.Set(ctx, alertStateKey, string(data)); err != nil {
+		m.log.Error("Error saving alert state", "err", err)
+	}
+}
@@ -0,0 +1,135 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testEntry(namespace, name, phase, severity string) ReportEntry {
+	return ReportEntry{Namespace: namespace, Name: name, Phase: phase, Severity: severity}
+}
+
+func reportOf(entries ...ReportEntry) Report {
+	return Report{GeneratedAt: testEpoch, Entries: entries}
+}
+
+// 在上一份已发送的报告之后，下一份报告是否需要发送
+func TestReportDedupShouldSend(t *testing.T) {
+	const realert = time.Hour
+	failed := testEntry("ns1", "db", "Failed", "critical")
+	other := testEntry("ns2", "cache", "Abnormal", "warning")
+	withReason := func(e ReportEntry, reason string) ReportEntry {
+		e.Reason = reason
+		return e
+	}
+	tests := []struct {
+		name    string
+		prev    *Report
+		next    Report
+		elapsed time.Duration
+		send    bool
+		reason  string
+	}{
+		{name: "first empty report", next: reportOf(), send: false, reason: "no open incidents"},
+		{name: "first report", next: reportOf(failed), send: true, reason: "first report"},
+		{name: "unchanged", prev: &Report{Entries: []ReportEntry{failed}}, next: reportOf(failed), elapsed: time.Minute, send: false, reason: "no incident changes"},
+		{name: "only duration and findings changed", prev: &Report{Entries: []ReportEntry{failed}},
+			next:    reportOf(ReportEntry{Namespace: "ns1", Name: "db", Phase: "Failed", Severity: "critical", Since: testEpoch.Add(-time.Hour), Findings: []string{"restarted 3 times"}}),
+			elapsed: time.Minute, send: false, reason: "no incident changes"},
+		{name: "new incident", prev: &Report{Entries: []ReportEntry{failed}}, next: reportOf(failed, other), elapsed: time.Minute, send: true, reason: "incident set changed"},
+		{name: "incident resolved", prev: &Report{Entries: []ReportEntry{failed, other}}, next: reportOf(failed), elapsed: time.Minute, send: true, reason: "incident set changed"},
+		{name: "incident replaced", prev: &Report{Entries: []ReportEntry{failed}}, next: reportOf(other), elapsed: time.Minute, send: true, reason: "incident set changed"},
+		{name: "phase class changed", prev: &Report{Entries: []ReportEntry{failed}}, next: reportOf(testEntry("ns1", "db", "Abnormal", "critical")), elapsed: time.Minute, send: true, reason: "incident set changed"},
+		{name: "phase within class unchanged", prev: &Report{Entries: []ReportEntry{testEntry("ns1", "db", "Creating", "info")}},
+			next: reportOf(testEntry("ns1", "db", "Updating", "info")), elapsed: time.Minute, send: false, reason: "no incident changes"},
+		{name: "severity changed", prev: &Report{Entries: []ReportEntry{failed}}, next: reportOf(testEntry("ns1", "db", "Failed", "warning")), elapsed: time.Minute, send: true, reason: "severity changed for ns1/db/failed"},
+		{name: "reason changed", prev: &Report{Entries: []ReportEntry{withReason(failed, reasonStorage)}}, next: reportOf(withReason(failed, reasonScheduling)), elapsed: time.Minute, send: true, reason: "reason changed for ns1/db/failed"},
+		{name: "reason became known", prev: &Report{Entries: []ReportEntry{failed}}, next: reportOf(withReason(failed, reasonStorage)), elapsed: time.Minute, send: false, reason: "no incident changes"},
+		{name: "reason became unknown", prev: &Report{Entries: []ReportEntry{withReason(failed, reasonStorage)}}, next: reportOf(withReason(failed, reasonUnknown)), elapsed: time.Minute, send: false, reason: "no incident changes"},
+		{name: "realert interval elapsed", prev: &Report{Entries: []ReportEntry{failed}}, next: reportOf(failed), elapsed: realert, send: true, reason: "realert interval elapsed for ns1/db/failed"},
+		{name: "all resolved", prev: &Report{Entries: []ReportEntry{failed}}, next: reportOf(), elapsed: time.Minute, send: true, reason: "incident set changed"},
+		{name: "still empty", prev: &Report{}, next: reportOf(), elapsed: realert, send: false, reason: "no incident changes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d reportDedup
+			if tt.prev != nil {
+				d.markSent(*tt.prev, testEpoch, realert)
+			}
+			send, reason := d.shouldSend(tt.next, testEpoch.Add(tt.elapsed), realert)
+			if send != tt.send || reason != tt.reason {
+				t.Errorf("shouldSend = %v, %q; want %v, %q", send, reason, tt.send, tt.reason)
+			}
+		})
+	}
+}
+
+// 原因未知的事件沿用之前记录的类别，之后变为其他类别时仍然重发
+func TestReportDedupKeepsKnownReason(t *testing.T) {
+	e := testEntry("ns1", "db", "Failed", "critical")
+	var d reportDedup
+	e.Reason = reasonStorage
+	d.markSent(reportOf(e), testEpoch, time.Hour)
+	e.Reason = reasonUnknown
+	d.markSent(reportOf(e), testEpoch.Add(time.Minute), time.Hour)
+	e.Reason = reasonScheduling
+	if send, reason := d.shouldSend(reportOf(e), testEpoch.Add(2*time.Minute), time.Hour); !send || reason != "reason changed for ns1/db/failed" {
+		t.Errorf("shouldSend = %v, %q; want reason change", send, reason)
+	}
+}
+
+// 每个集群按自己上次被提醒的时间冷却：后出现的集群随报告发送时不推迟先出现的集群的重复提醒
+func TestReportDedupStaggeredCooldowns(t *testing.T) {
+	const realert = time.Hour
+	a := testEntry("ns1", "a", "Failed", "critical")
+	b := testEntry("ns2", "b", "Failed", "critical")
+	var d reportDedup
+	d.markSent(reportOf(a), testEpoch, realert)
+	d.markSent(reportOf(a, b), testEpoch.Add(30*time.Minute), realert)
+	steps := []struct {
+		elapsed time.Duration
+		send    bool
+		reason  string
+	}{
+		{45 * time.Minute, false, "no incident changes"},
+		{time.Hour, true, "realert interval elapsed for ns1/a/failed"},
+		{75 * time.Minute, false, "no incident changes"},
+		{90 * time.Minute, true, "realert interval elapsed for ns2/b/failed"},
+		{2 * time.Hour, true, "realert interval elapsed for ns1/a/failed"},
+	}
+	for _, s := range steps {
+		now := testEpoch.Add(s.elapsed)
+		send, reason := d.shouldSend(reportOf(a, b), now, realert)
+		if send != s.send || reason != s.reason {
+			t.Fatalf("after %s: shouldSend = %v, %q; want %v, %q", s.elapsed, send, reason, s.send, s.reason)
+		}
+		if send {
+			d.markSent(reportOf(a, b), now, realert)
+		}
+	}
+}
+
+// 重试用尽仍发送失败的报告不记录为已发送，事件也不算已告警，下一轮巡检重新发送
+func TestFailedReportIsResent(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AlertAfterChecks = 1
+	cfg.NotifyAttempts = 2
+	cfg.NotifyBackoff, cfg.NotifyMaxBackoff = time.Millisecond, time.Millisecond
+	env := newTestEnv(t, cfg, Deps{Dynamic: newTestDynamic(testCluster("ns1", "db", "Failed")), Store: &memStore{}})
+	env.notifier.err = errors.New("webhook unavailable")
+	if err := env.m.RefreshDebt(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		env.runOnce(t)
+		if got := env.notifier.take(); len(got) != 1 || len(got[0].Entries) != 1 {
+			t.Fatalf("check %d rendered %+v, want the failed report attempted again", i+1, got)
+		}
+		env.now = env.now.Add(time.Minute)
+	}
+	if inc := env.m.openIncidents[clusterKey("ns1", "db")]; inc == nil || inc.Alerted {
+		t.Errorf("incident = %+v, want open and not alerted", inc)
+	}
+}
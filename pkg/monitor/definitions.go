@@ -0,0 +1,136 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// 集群引用的定义类资源，均为集群级别
+var (
+	clusterDefinitionsGVR = schema.GroupVersionResource{
+		Group:    "apps.kubeblocks.io",
+		Version:  "v1alpha1",
+		Resource: "clusterdefinitions",
+	}
+	clusterVersionsGVR = schema.GroupVersionResource{
+		Group:    "apps.kubeblocks.io",
+		Version:  "v1alpha1",
+		Resource: "clusterversions",
+	}
+	componentDefinitionsGVR = schema.GroupVersionResource{
+		Group:    "apps.kubeblocks.io",
+		Version:  "v1alpha1",
+		Resource: "componentdefinitions",
+	}
+)
+
+// 定义对象的 status.phase 为该值时视为已弃用
+const definitionUnavailable = "Unavailable"
+
+// definitionIndex 一种定义资源的全部对象；denied 为 true 时无法判断引用是否存在
+type definitionIndex struct {
+	gvr    schema.GroupVersionResource
+	phases map[string]string
+	denied bool
+}
+
+// 每种定义资源 List 一次，和集群在内存中关联，返回需要关注的集群
+func (m *Monitor) auditDefinitions(ctx context.Context) ([]string, error) {
+	api := m.api()
+	var indexes []*definitionIndex
+	for _, gvr := range []schema.GroupVersionResource{api.clusterDefinitions, api.clusterVersions, api.componentDefinitions} {
+		idx, err := m.indexDefinitions(ctx, gvr)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, idx)
+	}
+	clusterDefs, clusterVersions, componentDefs := indexes[0], indexes[1], indexes[2]
+
+	var lines []string
+	for _, idx := range indexes {
+		if idx != nil && idx.denied {
+			lines = append(lines, fmt.Sprintf("cannot verify %s references: RBAC denied", idx.gvr.Resource))
+		}
+	}
+	var findings []string
+	err := m.listAll(ctx, api.clusters, func(cluster *unstructured.Unstructured) {
+		key := clusterKey(cluster.GetNamespace(), cluster.GetName())
+		check := func(idx *definitionIndex, kind, name string) {
+			if name == "" {
+				return
+			}
+			if finding := idx.check(kind, name); finding != "" {
+				findings = append(findings, key+": "+finding)
+			}
+		}
+		ref, _, _ := unstructured.NestedString(cluster.Object, api.clusterDefPath...)
+		check(clusterDefs, "clusterDefinition", ref)
+		ref, _, _ = unstructured.NestedString(cluster.Object, "spec", "clusterVersionRef")
+		check(clusterVersions, "clusterVersion", ref)
+		comps, _, _ := unstructured.NestedSlice(cluster.Object, "spec", "componentSpecs")
+		for _, c := range comps {
+			if c, ok := c.(map[string]interface{}); ok {
+				name, _ := c["componentDef"].(string)
+				check(componentDefs, "componentDef", name)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(findings)
+	return append(lines, findings...), nil
+}
+
+// 没有权限时返回 denied 的索引，资源未注册（旧版本 KubeBlocks）时返回 nil
+func (m *Monitor) indexDefinitions(ctx context.Context, gvr schema.GroupVersionResource) (*definitionIndex, error) {
+	idx := &definitionIndex{gvr: gvr, phases: make(map[string]string)}
+	err := m.listAll(ctx, gvr, func(obj *unstructured.Unstructured) {
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		idx.phases[obj.GetName()] = phase
+	})
+	switch {
+	case apierrors.IsForbidden(err):
+		idx.denied = true
+		return idx, nil
+	case err != nil && isMissingCRD(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return idx, nil
+}
+
+// 引用的对象不存在或已弃用时返回说明；无法判断时不报告
+func (idx *definitionIndex) check(kind, name string) string {
+	if idx == nil || idx.denied {
+		return ""
+	}
+	phase, ok := idx.phases[name]
+	switch {
+	case !ok:
+		return fmt.Sprintf("%s %s deleted", kind, name)
+	case phase == definitionUnavailable:
+		return fmt.Sprintf("%s %s is %s", kind, name, phase)
+	}
+	return ""
+}
+
+// 摘要中的定义漂移一节，面向运维
+func (m *Monitor) definitionDigestSection(ctx context.Context) digestSection {
+	section := digestSection{Title: "Definition drift (ops)"}
+	lines, err := m.auditDefinitions(ctx)
+	if err != nil {
+		m.log.Error("Error auditing cluster definitions", "err", err)
+		section.Lines = []string{"audit failed: " + err.Error()}
+		return section
+	}
+	section.Lines = lines
+	return section
+}
@@ -0,0 +1,215 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"database-monitor/pkg/schedule"
+)
+
+// 摘要统计的时间范围
+const digestWindow = 24 * time.Hour
+
+// 摘要中最多列出的 OOM 集群和欠费 ns
+const digestTopN = 5
+
+// digestSection 每日摘要中的一节，Lines 为空时不输出
+type digestSection struct {
+	Title string
+	Lines []string
+}
+
+// 每日摘要由各项审计和统计拼成，和实时告警分开发送
+func (m *Monitor) buildDigest(ctx context.Context) string {
+	sections := []digestSection{
+		m.fleetDigestSection(),
+		m.backupDigestSection(),
+		m.definitionDigestSection(ctx),
+		m.integrityDigestSection(ctx),
+	}
+
+	var b strings.Builder
+	for _, s := range sections {
+		if len(s.Lines) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(s.Title + ":\n")
+		for _, line := range s.Lines {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "Daily digest\n\n" + b.String()
+}
+
+// 集群总数、各 phase 的数量，以及最近 24 小时新增的故障、恢复、OOM 和欠费 ns
+func (m *Monitor) fleetDigestSection() digestSection {
+	now := m.now()
+	section := digestSection{Title: "Fleet health"}
+	m.mu.Lock()
+	byPhase := make(map[string]int)
+	for _, phase := range m.phases {
+		byPhase[phase]++
+	}
+	total := len(m.phases)
+	var failures, recoveries int
+	oom := make(map[string]int)
+	count := func(inc *Incident) {
+		if now.Sub(inc.OpenedAt) <= digestWindow {
+			failures++
+		}
+		if inc.OOMKills > 0 && (inc.ClosedAt == nil || now.Sub(*inc.ClosedAt) <= digestWindow) {
+			oom[clusterKey(inc.Namespace, inc.Name)] += inc.OOMKills
+		}
+	}
+	for _, inc := range m.openIncidents {
+		count(inc)
+	}
+	for _, inc := range m.incidentHistory {
+		count(inc)
+		if inc.Resolution == resolutionRecovered && inc.ClosedAt != nil && now.Sub(*inc.ClosedAt) <= digestWindow {
+			recoveries++
+		}
+	}
+	m.mu.Unlock()
+	if total == 0 {
+		return section
+	}
+
+	phases := make([]string, 0, len(byPhase))
+	for phase := range byPhase {
+		phases = append(phases, phase)
+	}
+	// 数量多的在前，数量相同时按名称
+	sort.Slice(phases, func(i, j int) bool {
+		if byPhase[phases[i]] != byPhase[phases[j]] {
+			return byPhase[phases[i]] > byPhase[phases[j]]
+		}
+		return phases[i] < phases[j]
+	})
+	counts := make([]string, 0, len(phases))
+	for _, phase := range phases {
+		counts = append(counts, fmt.Sprintf("%s %d", phase, byPhase[phase]))
+	}
+	section.Lines = append(section.Lines,
+		fmt.Sprintf("clusters: %d (%s)", total, strings.Join(counts, ", ")),
+		fmt.Sprintf("last 24h: %d new incidents, %d recoveries", failures, recoveries))
+
+	offenders := make([]string, 0, len(oom))
+	for key := range oom {
+		offenders = append(offenders, key)
+	}
+	sort.Slice(offenders, func(i, j int) bool {
+		if oom[offenders[i]] != oom[offenders[j]] {
+			return oom[offenders[i]] > oom[offenders[j]]
+		}
+		return offenders[i] < offenders[j]
+	})
+	if len(offenders) > digestTopN {
+		offenders = offenders[:digestTopN]
+	}
+	for _, key := range offenders {
+		section.Lines = append(section.Lines, fmt.Sprintf("OOMKilled %d times: %s", oom[key], key))
+	}
+
+	if debt := m.debt.snapshot(); len(debt) > 0 {
+		line := fmt.Sprintf("namespaces in debt: %d", len(debt))
+		if len(debt) > digestTopN {
+			line += " (" + strings.Join(debt[:digestTopN], ", ") + ", ...)"
+		} else {
+			line += " (" + strings.Join(debt, ", ") + ")"
+		}
+		section.Lines = append(section.Lines, line)
+	}
+	return section
+}
+
+// 上次发送摘要的时间保存在状态存储的这个 key 下，重启或重新加载配置后按原来的间隔继续
+const digestSentKey = "digest-sent"
+
+// 每隔 DigestInterval 或按 DigestSchedule 发送一次摘要。启动时不发送，
+// 除非上次发送距今已超过 DigestInterval（例如频繁重新加载配置时计时一直被重置）
+func (m *Monitor) startDigestLoop(ctx context.Context) {
+	var sched *schedule.Cron
+	if m.cfg.DigestSchedule != "" {
+		var err error
+		if sched, err = schedule.Parse(m.cfg.DigestSchedule, nil); err != nil {
+			m.log.Warn("Ignoring digest schedule", "err", err)
+		}
+	}
+	if sched == nil && m.cfg.DigestInterval <= 0 {
+		return
+	}
+	last := m.loadDigestSent(ctx)
+	next := func() time.Duration {
+		now := m.now()
+		if sched == nil {
+			return digestDelay(last, now, m.cfg.DigestInterval)
+		}
+		if at := sched.Next(now); !at.IsZero() {
+			return at.Sub(now)
+		}
+		return digestWindow
+	}
+	go func() {
+		defer m.crashed()
+		timer := time.NewTimer(next())
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			if digest := m.buildDigest(ctx); digest != "" {
+				m.NotifyNamed(ctx, m.cfg.DigestNotifiers, m.NewNotice(digest))
+			} else {
+				m.log.Info("Skipping digest: nothing to report")
+			}
+			last = m.now()
+			m.saveDigestSent(ctx, last)
+			timer.Reset(next())
+		}
+	}()
+}
+
+// 按间隔发送时距下一次发送的时间，从未发送过时等待一个完整的间隔
+func digestDelay(last, now time.Time, interval time.Duration) time.Duration {
+	if last.IsZero() {
+		return interval
+	}
+	if d := last.Add(interval).Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+func (m *Monitor) loadDigestSent(ctx context.Context) time.Time {
+	if m.store == nil {
+		return time.Time{}
+	}
+	data, err := m.store.Get(ctx, digestSentKey)
+	if err != nil {
+		m.log.Error("Error loading digest state", "err", err)
+		return time.Time{}
+	}
+	last, _ := time.Parse(time.RFC3339, data)
+	return last
+}
+
+func (m *Monitor) saveDigestSent(ctx context.Context, at time.Time) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.Set(ctx, digestSentKey, at.UTC().Format(time.RFC3339)); err != nil {
+		m.log.Error("Error saving digest state", "err", err)
+	}
+}
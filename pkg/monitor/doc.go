@@ -0,0 +1,13 @@
+// Package monitor 实现数据库集群（KubeBlocks Cluster）的巡检逻辑：phase 策略、欠费检测、
+// 事件跟踪以及报告生成，供 database-monitor 以及其他需要嵌入巡检能力的内部工具使用。
+//
+// 稳定性：本包导出的类型和函数按语义化版本维护，不兼容的修改只会在主版本升级时出现；
+// 未导出的部分以及各结构体中未导出的字段随时可能变化。新增的导出项请保持最小化。
+//
+// 嵌入方式见 ExampleNew（使用 fake client）。
+//
+// 客户端、HTTP client、TracerProvider 和指标注册表都由 Deps 注入，包级变量只有 GVR、原因类别等只读的表。
+// 同一进程中可以创建多个实例；共用一个注册表时沿用已注册的指标，逐集群的健康指标只属于第一个注册的实例。
+//
+// 修改告警行为后运行 go test ./pkg/monitor -run TestScenarios，用 scenario_test.go 中的基准场景检查各功能之间的交互。
+package monitor
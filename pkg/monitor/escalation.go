@@ -0,0 +1,103 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// 升级在 routedDedup 中的 key 前缀
+const escalationRouteKey = "escalation:"
+
+// EscalationTier 一级升级：集群的事件打开超过 After 仍在告警时，发送到 Notifiers 并 @ Mentions。
+// 升级的集群变化时立即发送，未变化时按重复提醒间隔重发，全部恢复后发送一份空报告
+type EscalationTier struct {
+	Name  string        `json:"name"`
+	After time.Duration `json:"after"`
+	// 通知后端的名称，可以是普通的通知后端，也可以是只用于升级的目的地；
+	// 不能是 pagerduty 和 alertmanager，它们会解决升级报告中没有的告警
+	Notifiers []string `json:"notifiers"`
+	// 需要 @ 的用户 ID，格式取决于渠道，例如飞书的 open_id
+	Mentions []string `json:"mentions,omitempty"`
+	// 只升级这些严重程度的条目，为空时全部升级
+	Severities []string `json:"severities,omitempty"`
+}
+
+// ValidateEscalationTier 检查升级的名称、时长和通知后端
+func ValidateEscalationTier(t EscalationTier) error {
+	switch {
+	case t.Name == "":
+		return fmt.Errorf("name must be set")
+	case t.After <= 0:
+		return fmt.Errorf("after must be positive, got %s", t.After)
+	case len(t.Notifiers) == 0:
+		return fmt.Errorf("notifiers must be set")
+	}
+	return nil
+}
+
+// 按名称查找通知后端，先找只用于升级的后端
+func (m *Monitor) notifierByName(name string) Notifier {
+	for _, n := range m.escalators {
+		if n.Name() == name {
+			return n
+		}
+	}
+	for _, n := range m.notifiers {
+		if n.Name() == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// 按事件打开的时长把报告中的条目发送到各级升级渠道
+func (m *Monitor) escalate(ctx context.Context, r Report, now time.Time) {
+	if len(m.cfg.Escalations) == 0 {
+		return
+	}
+	m.mu.Lock()
+	openedAt := make(map[string]time.Time, len(r.Entries))
+	for _, e := range r.Entries {
+		if inc, ok := m.openIncidents[clusterKey(e.Namespace, e.Name)]; ok {
+			openedAt[clusterKey(e.Namespace, e.Name)] = inc.OpenedAt
+		}
+	}
+	m.mu.Unlock()
+
+	for _, tier := range m.cfg.Escalations {
+		view := filterReport(r, func(e ReportEntry) bool {
+			if len(tier.Severities) > 0 && !slices.Contains(tier.Severities, e.Severity) {
+				return false
+			}
+			at, ok := openedAt[clusterKey(e.Namespace, e.Name)]
+			return ok && now.Sub(at) >= tier.After
+		})
+		route := escalationRouteKey + tier.Name
+		if !m.routes.changed(route, view, now, m.cfg.RealertInterval) {
+			continue
+		}
+		if len(view.Entries) > 0 {
+			view.Notice = fmt.Sprintf("ESCALATION %s: %d cluster(s) failing for more than %s", tier.Name, len(view.Entries), tier.After)
+			view.Mentions = tier.Mentions
+		}
+		m.log.Warn("Escalating incidents", "tier", tier.Name, "count", len(view.Entries))
+		m.audit(AuditRecord{Kind: AuditNotification, Decision: "escalate", Reason: tier.Name, Destinations: tier.Notifiers})
+		// 各渠道都通过速率限制发送后才记录，被丢弃时下一轮重新升级
+		g := newDeliveryGroup(func(_ context.Context, delivered bool) {
+			if delivered {
+				m.routes.mark(route, view, now)
+			}
+		})
+		for _, name := range tier.Notifiers {
+			n := m.notifierByName(name)
+			if n == nil {
+				m.log.Warn("Unknown escalation notifier", "tier", tier.Name, "notifier", name)
+				continue
+			}
+			m.sendNotification(ctx, heldNotification{notifier: n, route: route, report: view, sent: g.add()})
+		}
+		g.seal(ctx)
+	}
+}
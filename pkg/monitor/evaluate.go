@@ -0,0 +1,194 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// 评估单个集群并更新状态，返回需要出现在报告中的条目，无需报告时返回 nil
+func (m *Monitor) evaluateCluster(ctx context.Context, res *monitoredResource, cluster *unstructured.Unstructured) *ReportEntry {
+	start := time.Now()
+	defer func() { m.metrics.evaluationDuration.Observe(time.Since(start).Seconds()) }()
+	ctx, span := m.tracer.Start(ctx, "evaluate", trace.WithAttributes(
+		attribute.String("namespace", cluster.GetNamespace()), attribute.String("cluster", cluster.GetName())))
+	defer span.End()
+
+	entry := m.evaluatePhase(ctx, res, cluster)
+	if entry != nil {
+		span.SetAttributes(attribute.String("phase", entry.Phase))
+	}
+	// Pod 等补充信息依赖 KubeBlocks 的标签
+	if entry == nil || !res.kubeblocks || (entry.Phase != "Failed" && entry.Phase != "Abnormal") {
+		return entry
+	}
+	if size := objectSize(cluster); size > maxClusterObjectBytes {
+		m.log.Warn("Cluster object too large after trimming, skipping enrichment", "cluster", entry.Name, "namespace", entry.Namespace, "bytes", size)
+		return entry
+	}
+	m.enrichEntry(ctx, cluster, entry)
+	return entry
+}
+
+// 对需要告警的集群检查 Pod，补充 Pod 概况、OOMKill、故障原因类别、反亲和未满足等信息
+func (m *Monitor) enrichEntry(ctx context.Context, cluster *unstructured.Unstructured, entry *ReportEntry) {
+	pods, err := m.listClusterPods(ctx, entry.Namespace, entry.Name)
+	if err != nil {
+		m.log.Error("Error listing cluster pods", "cluster", entry.Name, "namespace", entry.Namespace, "err", err)
+		return
+	}
+
+	m.mu.Lock()
+	since := m.now()
+	if inc, ok := m.openIncidents[clusterKey(entry.Namespace, entry.Name)]; ok {
+		// 事件在第一次观察到异常时才打开，向前多看一个周期
+		since = inc.OpenedAt.Add(-m.cfg.CheckInterval)
+	}
+	m.mu.Unlock()
+
+	findings := inspectPods(pods, since)
+	entry.OOMKilled = findings.oomSummary()
+	entry.Reason = categorizeReason(pods)
+	entry.Components = componentStatuses(cluster, pods)
+	entry.Pods = podStatuses(pods)
+	if entry.Phase == "Abnormal" {
+		entry.Findings = append(entry.Findings, antiAffinityViolations(cluster, pods)...)
+	}
+
+	m.mu.Lock()
+	m.recordOOMKills(entry.Namespace, entry.Name, findings.OOMKills)
+	change := m.updateIncidentReason(entry.Namespace, entry.Name, entry.Reason)
+	m.mu.Unlock()
+	if change != "" {
+		entry.Findings = append(entry.Findings, change)
+	}
+}
+
+func (m *Monitor) evaluatePhase(ctx context.Context, res *monitoredResource, cluster *unstructured.Unstructured) *ReportEntry {
+	status, raw, found, err := res.phase(cluster)
+	name, namespace := res.trackedName(cluster.GetName()), cluster.GetNamespace()
+	if err != nil || !found {
+		m.log.Warn("Unable to get cluster status", "cluster", name, "namespace", namespace, "err", err)
+		return nil
+	}
+	m.metrics.evaluations.Inc()
+	var condition string
+	if m.policy.Healthy(status) && len(m.cfg.ConditionRules) > 0 {
+		if phase, note := matchConditionRules(m.cfg.ConditionRules, res, cluster); phase != "" {
+			status, condition = phase, note
+		}
+	}
+	if !m.policy.Healthy(status) || cluster.GetDeletionTimestamp() != nil {
+		if reason, ok := m.ignoredByAnnotation(ctx, cluster); ok {
+			m.mu.Lock()
+			m.setClusterPhase(namespace, name, status)
+			m.recordDecision(namespace, name, status, actionSuppress, "suppressed: "+reason)
+			m.resolveCluster(namespace, name, resolutionIgnored)
+			m.mu.Unlock()
+			// 已告警的集群从报告中消失时不因此重发报告
+			m.forgetSentIncidents(namespace, name)
+			return nil
+		}
+	}
+
+	m.mu.Lock()
+	m.setClusterPhase(namespace, name, status)
+	m.uids[clusterKey(namespace, name)] = cluster.GetUID()
+	entry, notifyTenant := m.evaluateLocked(namespace, name, status, cluster.GetDeletionTimestamp())
+	if entry != nil {
+		entry.PreviousPhase = m.previousPhases[clusterKey(namespace, name)]
+		if condition != "" && entry.Note == "" {
+			entry.Note = condition
+		} else if raw != status && entry.Note == "" {
+			entry.Note = strings.Join(res.path, ".") + ": " + raw
+		}
+	}
+	m.mu.Unlock()
+	if notifyTenant {
+		m.createTenantNotification(ctx, namespace, name, status)
+	}
+	return entry
+}
+
+// 调用方需持有 m.mu；notifyTenant 为 true 时需要给租户发送 sealos 通知
+func (m *Monitor) evaluateLocked(namespace, name, status string, deletedAt *metav1.Time) (entry *ReportEntry, notifyTenant bool) {
+	key := clusterKey(namespace, name)
+	now := m.now()
+	if deletedAt != nil {
+		// 删除中的集群单独处理：默认不告警 Failed，但删除耗时过长时告警
+		if stuck := now.Sub(deletedAt.Time); stuck > m.cfg.StuckDeletingAfter {
+			// 先打开事件，决策记录中才能带上事件 ID
+			m.openIncident(namespace, name, "Deleting", now)
+			if reason, ok := m.silenced(namespace, name, "Deleting", now); ok {
+				m.recordDecision(namespace, name, status, actionSuppress, "suppressed: "+reason)
+				return nil, false
+			}
+			m.recordDecision(namespace, name, status, actionAlert, fmt.Sprintf("stuck deleting for %s", stuck.Round(time.Minute)))
+			delete(m.lastStatus, key)
+			entry := m.newEntry(namespace, name, "Deleting")
+			entry.Note = "stuck " + stuck.Round(time.Minute).String()
+			return entry, false
+		}
+		if status != "Failed" || !m.cfg.AlertOnDeletingFailures {
+			m.recordDecision(namespace, name, status, actionSuppress, "suppressed: being deleted")
+			m.resolveCluster(namespace, name, resolutionDeleting)
+			return nil, false
+		}
+	}
+	if m.policy.Healthy(status) {
+		m.recordDecision(namespace, name, status, actionHealthy, "phase is "+status)
+		m.queueRecovery(namespace, name, status, now)
+		m.resolveCluster(namespace, name, resolutionRecovered)
+		return nil, false
+	}
+	// 欠费集合由 debtLoop 单独维护，这里只读取
+	if status == "Failed" && m.debt.inDebt(namespace) {
+		m.recordDecision(namespace, name, status, actionSuppress, "suppressed: namespace in debt")
+		m.resolveCluster(namespace, name, resolutionDebt)
+		return nil, false
+	}
+	state := m.lastStatus[key]
+	state.phase = status
+	// watch 模式下事件和 resync 随时可能触发评估，每个 CheckInterval 最多计一次
+	if !m.cfg.Watch || state.counted.IsZero() || now.Sub(state.counted) >= m.cfg.CheckInterval {
+		state.checks++
+		state.counted = now
+	}
+	m.lastStatus[key] = state
+	if state.checks < m.cfg.AlertAfterChecks {
+		// 连续不健康的次数未达到阈值，只打开事件，暂不告警
+		m.openIncident(namespace, name, status, now)
+		reason := "first abnormal observation"
+		if state.checks > 1 {
+			reason = fmt.Sprintf("abnormal for %d of %d consecutive checks", state.checks, m.cfg.AlertAfterChecks)
+		}
+		m.recordDecision(namespace, name, status, actionPending, reason)
+		return nil, false
+	}
+	m.openIncident(namespace, name, status, now)
+	if reason, ok := m.silenced(namespace, name, status, now); ok {
+		m.recordDecision(namespace, name, status, actionSuppress, "suppressed: "+reason)
+		return nil, false
+	}
+	if status == "Failed" {
+		m.recordDecision(namespace, name, status, actionAlert, "cluster failed")
+		return m.newEntry(namespace, name, status), true
+	}
+	m.recordDecision(namespace, name, status, actionAlert, "abnormal for consecutive checks")
+	return m.newEntry(namespace, name, status), false
+}
+
+// clusterState 不健康集群的跟踪状态，恢复健康后删除
+type clusterState struct {
+	phase string
+	// 连续不健康的检查次数，轮询模式下为巡检次数，watch 模式下每个 CheckInterval 最多计一次
+	checks int
+	// 最近一次计入 checks 的时间
+	counted time.Time
+}
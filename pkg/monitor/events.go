@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// 全局预算的统计窗口
+const eventBudgetWindow = time.Hour
+
+// 被预算拦下的 Event 的原因
+const (
+	eventDroppedCluster = "cluster"
+	eventDroppedGlobal  = "global"
+)
+
+// 成对的 Event：开启的 Event 发出后，对应的关闭 Event 不受预算限制，避免集群上只有开启没有关闭
+var pairedEvents = map[string]string{"IncidentClosed": "IncidentOpened"}
+
+// eventBudget 限制监控自身创建的 Event 数量：每个集群的每种 Event 在 ClusterInterval 内最多一条，
+// 每个窗口内全局最多 GlobalPerHour 条。大面积故障时避免数千条 Event 压垮 etcd
+type eventBudget struct {
+	mu sync.Mutex
+	// key 为 集群/Event 原因
+	lastCluster map[string]time.Time
+	// 已发出、尚未发出对应关闭 Event 的开启 Event，key 同 lastCluster；事件关闭时总会清掉
+	unpaired    map[string]bool
+	windowStart time.Time
+	windowCount int
+	// 当前窗口内各原因被拦下的数量
+	dropped map[string]int
+}
+
+// 返回是否允许发送；不允许时返回原因以及本窗口内该原因已拦下的数量
+func (b *eventBudget) allow(key, event string, now time.Time, clusterInterval time.Duration, globalPerHour int) (bool, string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.lastCluster == nil {
+		b.lastCluster = make(map[string]time.Time)
+		b.unpaired = make(map[string]bool)
+	}
+	if now.Sub(b.windowStart) >= eventBudgetWindow {
+		b.windowStart = now
+		b.windowCount = 0
+		b.dropped = make(map[string]int)
+		for k, at := range b.lastCluster {
+			if now.Sub(at) >= clusterInterval {
+				delete(b.lastCluster, k)
+			}
+		}
+	}
+	if opener, ok := pairedEvents[event]; ok && b.unpaired[key+"/"+opener] {
+		delete(b.unpaired, key+"/"+opener)
+		b.lastCluster[key+"/"+event] = now
+		b.windowCount++
+		return true, "", 0
+	}
+	reason := ""
+	if at, ok := b.lastCluster[key+"/"+event]; ok && now.Sub(at) < clusterInterval {
+		reason = eventDroppedCluster
+	} else if globalPerHour > 0 && b.windowCount >= globalPerHour {
+		reason = eventDroppedGlobal
+	}
+	if reason != "" {
+		b.dropped[reason]++
+		return false, reason, b.dropped[reason]
+	}
+	b.lastCluster[key+"/"+event] = now
+	b.windowCount++
+	for _, opener := range pairedEvents {
+		if opener == event {
+			b.unpaired[key+"/"+event] = true
+		}
+	}
+	return true, "", 0
+}
+
+// 在集群对象上创建 Event，受 eventBudget 限制；未配置 recorder 时不做任何事。
+// recorder 本身是异步的，调用方需持有 m.mu
+func (m *Monitor) emitEvent(namespace, name, eventType, reason, message string) {
+	if m.events == nil {
+		return
+	}
+	key := clusterKey(namespace, name)
+	ok, dropReason, dropped := m.eventBudget.allow(key, reason, m.now(), m.cfg.EventClusterInterval, m.cfg.EventGlobalPerHour)
+	if !ok {
+		m.metrics.eventsDropped.WithLabelValues(dropReason).Inc()
+		m.log.Warn("Event budget exhausted, dropping event", "budget", dropReason, "event", reason, "key", key, "droppedThisHour", dropped)
+		return
+	}
+	res, objName := m.resourceOf(name)
+	if res == nil {
+		return
+	}
+	ref := &corev1.ObjectReference{
+		APIVersion: m.resourceGVR(res).GroupVersion().String(),
+		Kind:       res.kind,
+		Namespace:  namespace,
+		Name:       objName,
+		UID:        m.uids[key],
+	}
+	m.events.Event(ref, eventType, reason, message)
+	m.metrics.eventsEmitted.Inc()
+}
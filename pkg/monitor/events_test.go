@@ -0,0 +1,156 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/tools/record"
+)
+
+// 按 Event 原因统计 fake recorder 收到的 Event
+func recordedEvents(recorder *record.FakeRecorder) map[string]int {
+	counts := make(map[string]int)
+	for {
+		select {
+		case e := <-recorder.Events:
+			// FakeRecorder 的格式为 "类型 原因 消息"
+			counts[strings.Fields(e)[1]]++
+		default:
+			return counts
+		}
+	}
+}
+
+// 500 个集群同时故障并在一小时内恢复：开启 Event 受全局预算限制，已发出的开启 Event 都有对应的关闭 Event，每次丢弃都有日志和指标
+func TestEventBudgetOutage(t *testing.T) {
+	const clusters = 500
+	recorder := record.NewFakeRecorder(4 * clusters)
+	var logs bytes.Buffer
+	cfg := DefaultConfig()
+	env := newTestEnv(t, cfg, Deps{Events: recorder, Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+	m := env.m
+
+	m.mu.Lock()
+	for i := 0; i < clusters; i++ {
+		m.openIncident("ns1", fmt.Sprintf("db-%d", i), "Failed", env.now)
+	}
+	env.now = env.now.Add(30 * time.Minute)
+	for i := 0; i < clusters; i++ {
+		m.closeIncident("ns1", fmt.Sprintf("db-%d", i), resolutionRecovered, env.now)
+	}
+	m.mu.Unlock()
+
+	got := recordedEvents(recorder)
+	if got["IncidentOpened"] != cfg.EventGlobalPerHour || got["IncidentClosed"] != cfg.EventGlobalPerHour {
+		t.Errorf("events = %v, want %d opened and %d closed", got, cfg.EventGlobalPerHour, cfg.EventGlobalPerHour)
+	}
+	dropped := clusters - cfg.EventGlobalPerHour
+	if n := testutil.ToFloat64(m.metrics.eventsDropped.WithLabelValues(eventDroppedGlobal)); int(n) != 2*dropped {
+		t.Errorf("dropped metric = %v, want %d", n, 2*dropped)
+	}
+	if n := strings.Count(logs.String(), "Event budget exhausted"); n != 2*dropped {
+		t.Errorf("logged %d drops, want %d", n, 2*dropped)
+	}
+	if n := testutil.ToFloat64(m.metrics.eventsEmitted); int(n) != 2*cfg.EventGlobalPerHour {
+		t.Errorf("emitted metric = %v, want %d", n, 2*cfg.EventGlobalPerHour)
+	}
+}
+
+func TestEventBudgetAllow(t *testing.T) {
+	const interval = time.Hour
+	type step struct {
+		key, event string
+		after      time.Duration
+		ok         bool
+		reason     string
+	}
+	tests := []struct {
+		name   string
+		global int
+		steps  []step
+	}{
+		{"close pairs with emitted open", 10, []step{
+			{"ns1/db", "IncidentOpened", 0, true, ""},
+			{"ns1/db", "IncidentClosed", time.Minute, true, ""},
+		}},
+		{"flapping cluster", 10, []step{
+			{"ns1/db", "IncidentOpened", 0, true, ""},
+			{"ns1/db", "IncidentClosed", time.Minute, true, ""},
+			{"ns1/db", "IncidentOpened", 2 * time.Minute, false, eventDroppedCluster},
+			// 开启被拦下后，关闭也按每个集群的间隔限制
+			{"ns1/db", "IncidentClosed", 3 * time.Minute, false, eventDroppedCluster},
+			{"ns1/db", "IncidentOpened", interval + time.Minute, true, ""},
+			{"ns1/db", "IncidentClosed", interval + 2*time.Minute, true, ""},
+		}},
+		{"paired close ignores global budget", 1, []step{
+			{"ns1/a", "IncidentOpened", 0, true, ""},
+			{"ns1/b", "IncidentOpened", 0, false, eventDroppedGlobal},
+			{"ns1/a", "IncidentClosed", time.Minute, true, ""},
+			{"ns1/b", "IncidentClosed", time.Minute, false, eventDroppedGlobal},
+		}},
+		{"global window resets", 1, []step{
+			{"ns1/a", "IncidentOpened", 0, true, ""},
+			{"ns1/b", "IncidentOpened", time.Minute, false, eventDroppedGlobal},
+			{"ns1/b", "IncidentOpened", eventBudgetWindow, true, ""},
+		}},
+		{"unlimited global", 0, []step{
+			{"ns1/a", "IncidentOpened", 0, true, ""},
+			{"ns1/b", "IncidentOpened", 0, true, ""},
+			{"ns1/c", "IncidentOpened", 0, true, ""},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b eventBudget
+			for i, s := range tt.steps {
+				ok, reason, _ := b.allow(s.key, s.event, testEpoch.Add(s.after), interval, tt.global)
+				if ok != s.ok || reason != s.reason {
+					t.Errorf("step %d %s %s: allow = %v, %q; want %v, %q", i, s.key, s.event, ok, reason, s.ok, s.reason)
+				}
+			}
+		})
+	}
+}
+
+// 只有故障类 phase 或已告警的事件在集群上创建 Event，过渡状态不创建
+func TestIncidentEventsForFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		phases  []string
+		alerted bool
+		want    map[string]int
+	}{
+		{"transitional", []string{"Creating", "Updating"}, false, map[string]int{}},
+		{"deleting", []string{"Deleting"}, false, map[string]int{}},
+		{"failed", []string{"Failed"}, false, map[string]int{"IncidentOpened": 1, "IncidentClosed": 1}},
+		{"became abnormal", []string{"Creating", "Abnormal", "Failed"}, false, map[string]int{"IncidentOpened": 1, "IncidentClosed": 1}},
+		{"alerted while updating", []string{"Updating"}, true, map[string]int{"IncidentOpened": 1, "IncidentClosed": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			env := newTestEnv(t, DefaultConfig(), Deps{Events: recorder})
+			m := env.m
+			for _, phase := range tt.phases {
+				m.mu.Lock()
+				m.openIncident("ns1", "db", phase, env.now)
+				m.mu.Unlock()
+			}
+			if tt.alerted {
+				m.markAlerted(reportOf(testEntry("ns1", "db", tt.phases[len(tt.phases)-1], SeverityInfo)), []string{"test"})
+			}
+			m.mu.Lock()
+			m.closeIncident("ns1", "db", resolutionRecovered, env.now.Add(time.Minute))
+			m.mu.Unlock()
+			if got := recordedEvents(recorder); !maps.Equal(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package monitor_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	"database-monitor/pkg/monitor"
+)
+
+// printNotifier 把报告中的条目打印出来
+type printNotifier struct{}
+
+func (printNotifier) Name() string { return "print" }
+
+func (printNotifier) Render(r monitor.Report) ([]byte, error) {
+	var out []byte
+	for _, e := range r.Entries {
+		out = fmt.Appendf(out, "%s/%s %s %s\n", e.Namespace, e.Name, e.Phase, e.Severity)
+	}
+	return out, nil
+}
+
+func (printNotifier) Send(_ context.Context, payload []byte) error {
+	fmt.Print(string(payload))
+	return nil
+}
+
+func ExampleNew() {
+	cluster := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps.kubeblocks.io/v1alpha1",
+		"kind":       "Cluster",
+		"metadata":   map[string]interface{}{"namespace": "ns-alice", "name": "mysql"},
+		"status":     map[string]interface{}{"phase": "Failed"},
+	}}
+	dynamic := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Group: "apps.kubeblocks.io", Version: "v1alpha1", Resource: "clusters"}: "ClusterList",
+	}, cluster)
+
+	cfg := monitor.DefaultConfig()
+	// 单次巡检，第一次不健康即告警；欠费按 ResourceQuota 判断
+	cfg.AlertAfterChecks = 1
+	cfg.DebtSource = monitor.DebtSourceQuota
+	m := monitor.New(monitor.Deps{
+		Config:    cfg,
+		Dynamic:   dynamic,
+		Kube:      kubefake.NewSimpleClientset(),
+		Notifiers: []monitor.Notifier{printNotifier{}},
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	ctx := context.Background()
+	if err := m.RefreshDebt(ctx); err != nil {
+		fmt.Println(err)
+		return
+	}
+	report, err := m.RunOnce(ctx)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(len(report.Entries), "cluster(s) need attention")
+	// Output:
+	// ns-alice/mysql Failed critical
+	// 1 cluster(s) need attention
+}
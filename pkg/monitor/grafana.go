@@ -0,0 +1,231 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 已创建的标注 ID 保存在状态存储的这个 key 下，重启后仍能给事件的标注补上结束时间
+const grafanaAnnotationsKey = "grafana-annotations"
+
+// 创建或更新标注失败时最多尝试的次数
+const grafanaMaxAttempts = 5
+
+// grafanaAnnotation 待发送的标注：事件打开时创建，关闭时补上结束时间
+type grafanaAnnotation struct {
+	incidentID string
+	namespace  string
+	name       string
+	phase      string
+	resolution string
+	start      time.Time
+	// 为 nil 表示事件刚打开
+	end *time.Time
+
+	attempts    int
+	nextAttempt time.Time
+}
+
+// grafanaQueue 由单独的 goroutine 发送，事件打开和关闭时调用方持有 m.mu，不能直接发请求
+type grafanaQueue struct {
+	mu      sync.Mutex
+	pending []grafanaAnnotation
+	// 事件 ID 到标注 ID，事件关闭并更新标注后删除
+	ids  map[string]int64
+	wake chan struct{}
+}
+
+// 事件进入故障类 phase 时创建标注，关闭时补上结束时间；始终处于过渡状态的事件不打标注。调用方持有 m.mu
+func (m *Monitor) enqueueAnnotation(inc *Incident, at time.Time) {
+	if m.cfg.GrafanaURL == "" {
+		return
+	}
+	if inc.ClosedAt == nil {
+		if inc.AnnotatedAt != nil || !failurePhase(inc.Phase) {
+			return
+		}
+		inc.AnnotatedAt = &at
+	} else if inc.AnnotatedAt == nil {
+		return
+	}
+	q := &m.annotations
+	q.mu.Lock()
+	q.pending = append(q.pending, grafanaAnnotation{
+		incidentID: inc.ID,
+		namespace:  inc.Namespace,
+		name:       inc.Name,
+		phase:      inc.Phase,
+		resolution: inc.Resolution,
+		start:      *inc.AnnotatedAt,
+		end:        inc.ClosedAt,
+	})
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 加载已创建的标注 ID，之后在后台持续发送
+func (m *Monitor) startGrafanaLoop(ctx context.Context) {
+	if m.cfg.GrafanaURL == "" {
+		return
+	}
+	if err := m.loadAnnotationIDs(ctx); err != nil {
+		m.log.Error("Error loading Grafana annotation IDs", "err", err)
+	}
+	go func() {
+		defer m.crashed()
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		for {
+			m.deliverAnnotations(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.annotations.wake:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (m *Monitor) loadAnnotationIDs(ctx context.Context) error {
+	if m.store == nil {
+		return nil
+	}
+	data, err := m.store.Get(ctx, grafanaAnnotationsKey)
+	if err != nil || data == "" {
+		return err
+	}
+	ids := make(map[string]int64)
+	if err := json.Unmarshal([]byte(data), &ids); err != nil {
+		return err
+	}
+	m.annotations.mu.Lock()
+	for k, v := range ids {
+		m.annotations.ids[k] = v
+	}
+	m.annotations.mu.Unlock()
+	return nil
+}
+
+// 按顺序发送到期的标注；同一事件的打开还没成功时，关闭也要等待
+func (m *Monitor) deliverAnnotations(ctx context.Context) {
+	q := &m.annotations
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = nil
+	ids := make(map[string]int64, len(q.ids))
+	for k, v := range q.ids {
+		ids[k] = v
+	}
+	q.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	now := m.now()
+	var remaining []grafanaAnnotation
+	blocked := make(map[string]bool)
+	for _, a := range pending {
+		if blocked[a.incidentID] || now.Before(a.nextAttempt) {
+			blocked[a.incidentID] = true
+			remaining = append(remaining, a)
+			continue
+		}
+		id, err := m.sendAnnotation(ctx, a, ids[a.incidentID])
+		if err != nil {
+			a.attempts++
+			m.log.Error("Error sending Grafana annotation", "incident", a.incidentID, "attempt", a.attempts, "err", err)
+			if a.attempts < grafanaMaxAttempts {
+				a.nextAttempt = now.Add(callbackBackoff(a.attempts))
+				blocked[a.incidentID] = true
+				remaining = append(remaining, a)
+			}
+			continue
+		}
+		if a.end != nil {
+			delete(ids, a.incidentID)
+		} else {
+			ids[a.incidentID] = id
+		}
+	}
+
+	q.mu.Lock()
+	q.pending = append(remaining, q.pending...)
+	q.ids = ids
+	q.mu.Unlock()
+	if m.store == nil {
+		return
+	}
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return
+	}
+	if err := m.store.Set(ctx, grafanaAnnotationsKey, string(data)); err != nil {
+		m.log.Error("Error saving Grafana annotation IDs", "err", err)
+	}
+}
+
+// 事件打开时创建标注；关闭时给已有的标注补上结束时间，没有时直接创建一段区间标注。返回标注 ID
+func (m *Monitor) sendAnnotation(ctx context.Context, a grafanaAnnotation, id int64) (int64, error) {
+	tags := []string{"database-monitor", "namespace:" + a.namespace, "cluster:" + a.name}
+	if m.cfg.Region != "" {
+		tags = append(tags, "region:"+m.cfg.Region)
+	}
+	text := fmt.Sprintf("%s in %s is %s (incident %s)", a.name, a.namespace, a.phase, a.incidentID)
+	body := map[string]interface{}{"time": a.start.UnixMilli(), "tags": tags, "text": text}
+	if m.cfg.GrafanaDashboardUID != "" {
+		body["dashboardUID"] = m.cfg.GrafanaDashboardUID
+	}
+	method, path := http.MethodPost, "/api/annotations"
+	if a.end != nil {
+		body["timeEnd"] = a.end.UnixMilli()
+		body["text"] = fmt.Sprintf("%s in %s was %s, %s (incident %s)", a.name, a.namespace, a.phase, a.resolution, a.incidentID)
+		if id != 0 {
+			method, path = http.MethodPatch, "/api/annotations/"+strconv.FormatInt(id, 10)
+		}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	if m.cfg.DryRun {
+		m.log.Info("Dry run, Grafana annotation not sent", "method", method, "path", path, "body", string(data))
+		return id, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(m.cfg.GrafanaURL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.cfg.GrafanaAPIToken != "" {
+		req.Header.Set("Authorization", "Bearer "+m.cfg.GrafanaAPIToken)
+	}
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if method == http.MethodPatch {
+		return id, nil
+	}
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
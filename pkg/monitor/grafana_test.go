@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// grafanaRequest Grafana 收到的一次标注请求
+type grafanaRequest struct {
+	method, path string
+	body         map[string]interface{}
+}
+
+func grafanaEnv(t *testing.T) (*testEnv, func() []grafanaRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []grafanaRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := grafanaRequest{method: r.Method, path: r.URL.Path}
+		json.NewDecoder(r.Body).Decode(&req.body)
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		w.Write([]byte(`{"id": 7}`))
+	}))
+	t.Cleanup(srv.Close)
+	cfg := DefaultConfig()
+	cfg.GrafanaURL = srv.URL
+	env := newTestEnv(t, cfg, Deps{HTTPClient: srv.Client()})
+	return env, func() []grafanaRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]grafanaRequest(nil), requests...)
+	}
+}
+
+func TestGrafanaAnnotationsOnlyForFailures(t *testing.T) {
+	env, requests := grafanaEnv(t)
+	m := env.m
+	ctx := context.Background()
+	failedAt := testEpoch.Add(5 * time.Minute)
+
+	m.mu.Lock()
+	m.openIncident("ns1", "creating", "Creating", testEpoch)
+	m.openIncident("ns1", "creating", "Updating", failedAt)
+	m.openIncident("ns1", "db", "Creating", testEpoch)
+	m.openIncident("ns1", "db", "Failed", failedAt)
+	m.openIncident("ns1", "db", "Failed", failedAt.Add(time.Minute))
+	m.mu.Unlock()
+	m.deliverAnnotations(ctx)
+
+	m.mu.Lock()
+	m.closeIncident("ns1", "creating", resolutionRecovered, testEpoch.Add(time.Hour))
+	m.closeIncident("ns1", "db", resolutionRecovered, testEpoch.Add(time.Hour))
+	m.mu.Unlock()
+	m.deliverAnnotations(ctx)
+
+	got := requests()
+	if len(got) != 2 {
+		t.Fatalf("got %d annotation requests, want 2: %+v", len(got), got)
+	}
+	if got[0].method != http.MethodPost || got[0].body["time"] != float64(failedAt.UnixMilli()) {
+		t.Errorf("open annotation = %+v, want POST starting when the cluster failed", got[0])
+	}
+	if got[1].method != http.MethodPatch || got[1].path != "/api/annotations/7" || got[1].body["timeEnd"] != float64(testEpoch.Add(time.Hour).UnixMilli()) {
+		t.Errorf("close annotation = %+v, want PATCH of annotation 7 with the end time", got[1])
+	}
+}
@@ -0,0 +1,29 @@
+package monitor
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// crashed 在 Monitor 启动的后台 goroutine 中 panic 时先调用 Deps.OnPanic，再继续向上抛出。
+// 只能直接 defer m.crashed()，recover 在其他位置不生效
+func (m *Monitor) crashed() {
+	if m.onPanic == nil {
+		return
+	}
+	if r := recover(); r != nil {
+		m.onPanic(r)
+		panic(r)
+	}
+}
+
+// until 在后台 goroutine 中每隔 period 运行 f 直到 ctx 结束，与 wait.UntilWithContext 相同；
+// wait 的 HandleCrash 会继续抛出 f 中的 panic，由 crashed 交给 Deps.OnPanic
+func (m *Monitor) until(ctx context.Context, f func(context.Context), period time.Duration) {
+	go func() {
+		defer m.crashed()
+		wait.UntilWithContext(ctx, f, period)
+	}()
+}
@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// panicPolicy 评估集群时 panic
+type panicPolicy struct{}
+
+func (panicPolicy) Healthy(phase string) bool { panic("policy bug: " + phase) }
+func (panicPolicy) Severity(string) string    { return SeverityCritical }
+
+// 测试中记录 panic 后结束该 goroutine，不让 panic 继续抛出使测试进程退出
+func recordedPanics() (func(interface{}), <-chan interface{}) {
+	panics := make(chan interface{}, 8)
+	return func(r interface{}) {
+		panics <- r
+		runtime.Goexit()
+	}, panics
+}
+
+func TestEvaluatePoolPanicReachesOnPanic(t *testing.T) {
+	onPanic, panics := recordedPanics()
+	cfg := DefaultConfig()
+	cfg.Workers = 2
+	env := newTestEnv(t, cfg, Deps{Policy: panicPolicy{}, OnPanic: onPanic, Dynamic: newTestDynamic(testCluster("ns1", "db", "Failed"))})
+	env.m.RunOnce(context.Background())
+	select {
+	case r := <-panics:
+		if r != "policy bug: Failed" {
+			t.Errorf("OnPanic got %v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnPanic was not called for a panic in an evaluate worker")
+	}
+}
+
+// wait.UntilWithContext 中的 HandleCrash 会继续抛出 panic，仍然交给 OnPanic
+func TestUntilPanicReachesOnPanic(t *testing.T) {
+	onPanic, panics := recordedPanics()
+	env := newTestEnv(t, DefaultConfig(), Deps{OnPanic: onPanic})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	env.m.until(ctx, func(context.Context) { panic("loop bug") }, time.Hour)
+	select {
+	case r := <-panics:
+		if r != "loop bug" {
+			t.Errorf("OnPanic got %v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnPanic was not called for a panic in a periodic loop")
+	}
+}
@@ -0,0 +1,64 @@
+package monitor
+
+// TakeOver 在重新加载配置时接管上一个 Monitor 只保存在内存中的状态：事件历史、待发送的恢复通知、
+// 各路由和租户渠道的去重记录、待发送的 Grafana 标注以及 Event 和通知的速率预算，重新加载后不会重复告警。
+// 未关闭的事件、整份报告的去重状态和上次发送摘要的时间已保存在 StateStore 中，Run 启动时加载。
+// 需在 prev 的 Run 返回之后、m 的 Run 开始之前调用
+func (m *Monitor) TakeOver(prev *Monitor) {
+	prev.mu.Lock()
+	history := append([]*Incident(nil), prev.incidentHistory...)
+	recoveries := append([]recovery(nil), prev.recoveries...)
+	prev.recoveries = nil
+	prev.mu.Unlock()
+	m.mu.Lock()
+	m.incidentHistory = append(history, m.incidentHistory...)
+	if len(m.incidentHistory) > maxIncidentHistory {
+		m.incidentHistory = m.incidentHistory[len(m.incidentHistory)-maxIncidentHistory:]
+	}
+	m.recoveries = append(recoveries, m.recoveries...)
+	m.mu.Unlock()
+
+	prev.routes.mu.Lock()
+	m.routes.mu.Lock()
+	for route, view := range prev.routes.last {
+		if m.routes.last == nil {
+			m.routes.last = make(map[string]routedView)
+		}
+		m.routes.last[route] = view
+	}
+	m.routes.mu.Unlock()
+	prev.routes.mu.Unlock()
+
+	// 租户的通知后端按新配置重新创建，这里只保留发送过的 ns，让它们继续收到恢复后的报告
+	prev.tenants.mu.Lock()
+	m.tenants.mu.Lock()
+	for namespace := range prev.tenants.byNS {
+		m.tenants.byNS[namespace] = tenantRoute{}
+	}
+	m.tenants.mu.Unlock()
+	prev.tenants.mu.Unlock()
+
+	prev.annotations.mu.Lock()
+	m.annotations.mu.Lock()
+	m.annotations.pending = append(prev.annotations.pending, m.annotations.pending...)
+	prev.annotations.pending = nil
+	m.annotations.mu.Unlock()
+	prev.annotations.mu.Unlock()
+
+	prev.eventBudget.mu.Lock()
+	m.eventBudget.mu.Lock()
+	m.eventBudget.lastCluster = prev.eventBudget.lastCluster
+	m.eventBudget.unpaired = prev.eventBudget.unpaired
+	m.eventBudget.windowStart = prev.eventBudget.windowStart
+	m.eventBudget.windowCount = prev.eventBudget.windowCount
+	m.eventBudget.dropped = prev.eventBudget.dropped
+	m.eventBudget.mu.Unlock()
+	prev.eventBudget.mu.Unlock()
+
+	// 暂缓的通知已在 prev 退出时发出，只接管剩余的令牌
+	prev.notifyLimit.mu.Lock()
+	m.notifyLimit.mu.Lock()
+	m.notifyLimit.tokens, m.notifyLimit.last = prev.notifyLimit.tokens, prev.notifyLimit.last
+	m.notifyLimit.mu.Unlock()
+	prev.notifyLimit.mu.Unlock()
+}
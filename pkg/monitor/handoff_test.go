@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// criticalNotifier 只接收 critical 条目的通知后端
+type criticalNotifier struct{ testNotifier }
+
+func (n *criticalNotifier) AcceptsSeverity(severity string) bool { return severity == SeverityCritical }
+
+// 重新加载配置后的 Monitor 接管上一个的状态：同样的故障不再重复发送到任何渠道，恢复时租户仍收到报告
+func TestTakeOverDoesNotRealert(t *testing.T) {
+	const annotation = "monitor.example.com/webhook"
+	ctx := context.Background()
+	store := &memStore{}
+	dynamic := newTestDynamic(testCluster("ns1", "db", "Failed"))
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1", Annotations: map[string]string{annotation: "https://tenant.example.com/hook"}}}
+	main, critical, tenant := &testNotifier{name: "main"}, &criticalNotifier{testNotifier{name: "critical"}}, &testNotifier{name: "tenant"}
+	start := func(now time.Time) *testEnv {
+		cfg := DefaultConfig()
+		cfg.AlertAfterChecks = 1
+		cfg.NamespaceWebhookAnnotation = annotation
+		env := newTestEnv(t, cfg, Deps{
+			Dynamic:           dynamic,
+			Store:             store,
+			Notifiers:         []Notifier{main, critical},
+			NamespaceNotifier: func(string, string) (Notifier, error) { return tenant, nil },
+		}, namespace)
+		env.now = now
+		if err := env.m.RefreshDebt(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return env
+	}
+	sent := func() map[string]int {
+		return map[string]int{"main": len(main.take()), "critical": len(critical.take()), "tenant": len(tenant.take())}
+	}
+
+	before := start(testEpoch)
+	if _, err := before.m.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent(); got["main"] != 1 || got["critical"] != 1 || got["tenant"] != 1 {
+		t.Fatalf("first report sent %v, want one to every channel", got)
+	}
+	before.m.mu.Lock()
+	before.m.openIncident("ns1", "old", "Failed", testEpoch)
+	before.m.closeIncident("ns1", "old", resolutionRecovered, testEpoch)
+	before.m.mu.Unlock()
+	// Run 返回前保存状态
+	before.m.flush(ctx)
+
+	after := start(testEpoch.Add(time.Minute))
+	after.m.TakeOver(before.m)
+	if err := after.m.loadCheckpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := after.m.loadAlertState(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := after.m.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent(); got["main"]+got["critical"]+got["tenant"] != 0 {
+		t.Errorf("unchanged failure re-sent after reload: %v", got)
+	}
+	if history := after.m.RecentIncidents(0); len(history) != 1 || history[0].Name != "old" {
+		t.Errorf("incident history after reload = %+v", history)
+	}
+
+	recovered := testCluster("ns1", "db", "Running")
+	if _, err := dynamic.Resource(clustersGVR).Namespace("ns1").Update(ctx, recovered, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	after.now = after.now.Add(time.Minute)
+	if _, err := after.m.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent(); got["tenant"] == 0 || got["critical"] == 0 {
+		t.Errorf("recovery sent %v, want the tenant and the critical channel to get the resolved report", got)
+	}
+}
+
+func TestDigestDelay(t *testing.T) {
+	const interval = 24 * time.Hour
+	tests := []struct {
+		name string
+		last time.Time
+		want time.Duration
+	}{
+		{"never sent", time.Time{}, interval},
+		{"sent an hour ago", testEpoch.Add(-time.Hour), interval - time.Hour},
+		{"overdue", testEpoch.Add(-2 * interval), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := digestDelay(tt.last, testEpoch, interval); got != tt.want {
+				t.Errorf("digestDelay = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
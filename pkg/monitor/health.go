@@ -0,0 +1,57 @@
+package monitor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// loopHealth 巡检主循环的进度，存活探针据此判断主循环是否卡住。使用真实时间而不是 m.now
+type loopHealth struct {
+	mu        sync.Mutex
+	lastCheck time.Time
+	// 下一次巡检应当完成的时间，未在巡检（例如等待 CRD）时为零
+	nextCheck time.Time
+}
+
+// recordCheck 记录一轮巡检完成；next 为零时不改变预期的下一次巡检时间
+func (m *Monitor) recordCheck(next time.Time) {
+	h := &m.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastCheck = time.Now()
+	if !next.IsZero() {
+		h.nextCheck = next
+	}
+}
+
+// expectCheck 设置预期的下一次巡检时间，零值表示当前不要求巡检
+func (m *Monitor) expectCheck(next time.Time) {
+	h := &m.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextCheck = next
+}
+
+// Live 返回巡检主循环是否仍在推进：超过预期时间 StallTimeout 仍没有完成巡检时视为卡住
+func (m *Monitor) Live() (bool, string) {
+	h := &m.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.nextCheck.IsZero() || m.cfg.StallTimeout <= 0 {
+		return true, ""
+	}
+	if overdue := time.Since(h.nextCheck); overdue > m.cfg.StallTimeout {
+		return false, fmt.Sprintf("no check completed since %s, overdue by %s",
+			h.lastCheck.Format(time.RFC3339), overdue.Round(time.Second))
+	}
+	return true, ""
+}
+
+// LastCheck 最近一次完成巡检的时间，尚未完成过时为零
+func (m *Monitor) LastCheck() time.Time {
+	h := &m.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastCheck
+}
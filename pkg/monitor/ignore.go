@@ -0,0 +1,78 @@
+package monitor
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ignoredNamespaces 带有 IgnoreAnnotation 的 ns，每个 ns 最多每个 CheckInterval 读取一次
+type ignoredNamespaces struct {
+	mu   sync.Mutex
+	byNS map[string]ignoredNamespace
+}
+
+type ignoredNamespace struct {
+	ignored bool
+	at      time.Time
+}
+
+// 集群或所在 ns 上的 IgnoreAnnotation 为 true 时返回原因，例如测试和临时数据库；
+// ns 只在集群不健康时读取，读取失败时沿用上次的结果
+func (m *Monitor) ignoredByAnnotation(ctx context.Context, cluster *unstructured.Unstructured) (string, bool) {
+	if m.cfg.IgnoreAnnotation == "" {
+		return "", false
+	}
+	if annotationTrue(cluster.GetAnnotations()[m.cfg.IgnoreAnnotation]) {
+		return "cluster annotated " + m.cfg.IgnoreAnnotation, true
+	}
+	namespace := cluster.GetNamespace()
+	now := m.now()
+	t := &m.ignored
+	t.mu.Lock()
+	cached, ok := t.byNS[namespace]
+	t.mu.Unlock()
+	if !ok || now.Sub(cached.at) >= m.cfg.CheckInterval {
+		ignored, err := m.namespaceIgnored(ctx, namespace)
+		if err != nil {
+			m.log.Warn("Error reading namespace ignore annotation", "namespace", namespace, "err", err)
+		} else {
+			cached = ignoredNamespace{ignored: ignored, at: now}
+			t.mu.Lock()
+			if t.byNS == nil {
+				t.byNS = make(map[string]ignoredNamespace)
+			}
+			t.byNS[namespace] = cached
+			t.mu.Unlock()
+		}
+	}
+	if cached.ignored {
+		return "namespace annotated " + m.cfg.IgnoreAnnotation, true
+	}
+	return "", false
+}
+
+// ns 上的 IgnoreAnnotation 是否为 true，ns 不存在时为 false
+func (m *Monitor) namespaceIgnored(ctx context.Context, namespace string) (bool, error) {
+	if err := m.budget.Wait(ctx); err != nil {
+		return false, err
+	}
+	ns, err := m.kube.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return annotationTrue(ns.Annotations[m.cfg.IgnoreAnnotation]), nil
+}
+
+func annotationTrue(value string) bool {
+	ok, _ := strconv.ParseBool(value)
+	return ok
+}
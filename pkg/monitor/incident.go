@@ -0,0 +1,226 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// 最多保留的已关闭事件数
+const maxIncidentHistory = 1000
+
+const (
+	resolutionRecovered = "recovered"
+	resolutionDebt      = "suppressed: namespace in debt"
+	resolutionDeleting  = "suppressed: being deleted"
+	resolutionDeleted   = "cluster deleted"
+	resolutionIgnored   = "suppressed: ignored by annotation"
+	// 欠费 ns 被计费系统清理，集群随之消失
+	resolutionDebtCleanup = "removed due to debt cleanup"
+)
+
+// Incident 一个集群从开始异常到恢复（或消失）的完整过程
+type Incident struct {
+	ID         string     `json:"id"`
+	Namespace  string     `json:"namespace"`
+	Name       string     `json:"name"`
+	Phase      string     `json:"phase"`
+	OpenedAt   time.Time  `json:"openedAt"`
+	ClosedAt   *time.Time `json:"closedAt,omitempty"`
+	Resolution string     `json:"resolution,omitempty"`
+	// 事件期间观察到的 OOMKill 次数
+	OOMKills int `json:"oomKills"`
+	// 事件打开时生效的配置版本
+	ConfigHash string `json:"configHash"`
+	// 是否已因一天内反复故障发出过提醒，只在没有审计历史时使用
+	RepeatWarned bool `json:"repeatWarned,omitempty"`
+	// 当前的故障原因类别
+	Reason string `json:"reason,omitempty"`
+	// 事件期间的重要变化，例如原因类别改变
+	Timeline []IncidentEvent `json:"timeline,omitempty"`
+	// 是否已在报告中告警，告警过的事件恢复时发送恢复通知
+	Alerted bool `json:"alerted,omitempty"`
+	// 是否进入过故障类 phase，只有这类事件和告警过的事件计入反复故障
+	Failed bool `json:"failed,omitempty"`
+	// 是否已尝试在集群上创建 IncidentOpened Event（可能被预算拦下），关闭时只为这些事件创建 IncidentClosed
+	OpenEvent bool `json:"openEvent,omitempty"`
+	// 进入故障类 phase、创建 Grafana 标注的时间，为 nil 表示没有标注
+	AnnotatedAt *time.Time `json:"annotatedAt,omitempty"`
+	// 自动修复已尝试的次数和最近一次的时间
+	Remediations    int        `json:"remediations,omitempty"`
+	LastRemediation *time.Time `json:"lastRemediation,omitempty"`
+
+	// 已计数过的 OOMKill，避免重复统计
+	oomSeen map[string]bool
+}
+
+// IncidentEvent 事件时间线上的一条记录
+type IncidentEvent struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// 打开集群的事件，已存在时直接返回；调用方需持有 m.mu
+func (m *Monitor) openIncident(namespace, name, phase string, at time.Time) *Incident {
+	key := clusterKey(namespace, name)
+	if inc, ok := m.openIncidents[key]; ok {
+		inc.Phase = phase
+		inc.Failed = inc.Failed || failurePhase(phase)
+		m.enqueueAnnotation(inc, at)
+		m.emitOpenedEvent(inc)
+		return inc
+	}
+	inc := &Incident{
+		ID:         fmt.Sprintf("%s-%d", key, at.Unix()),
+		Namespace:  namespace,
+		Name:       name,
+		Phase:      phase,
+		OpenedAt:   at,
+		ConfigHash: m.configVersion,
+		Failed:     failurePhase(phase),
+		oomSeen:    make(map[string]bool),
+	}
+	m.openIncidents[key] = inc
+	m.enqueueAnnotation(inc, at)
+	m.emitOpenedEvent(inc)
+	return inc
+}
+
+// 事件进入故障类 phase 或已告警时在集群上创建 Warning Event，每个事件只创建一次；调用方需持有 m.mu
+func (m *Monitor) emitOpenedEvent(inc *Incident) {
+	if inc.OpenEvent || (!failurePhase(inc.Phase) && !inc.Alerted) {
+		return
+	}
+	inc.OpenEvent = true
+	m.emitEvent(inc.Namespace, inc.Name, corev1.EventTypeWarning, "IncidentOpened",
+		fmt.Sprintf("database-monitor opened incident %s: phase %s", inc.ID, inc.Phase))
+}
+
+func (m *Monitor) closeIncident(namespace, name, resolution string, at time.Time) {
+	key := clusterKey(namespace, name)
+	inc, ok := m.openIncidents[key]
+	if !ok {
+		return
+	}
+	delete(m.openIncidents, key)
+	inc.ClosedAt = &at
+	inc.Resolution = resolution
+	m.incidentHistory = append(m.incidentHistory, inc)
+	if len(m.incidentHistory) > maxIncidentHistory {
+		m.incidentHistory = m.incidentHistory[len(m.incidentHistory)-maxIncidentHistory:]
+	}
+	m.log.Info("Incident closed", "incident", inc.ID, "cluster", inc.Name, "namespace", inc.Namespace, "resolution", resolution)
+	if inc.Failed || inc.Alerted {
+		m.audit(AuditRecord{
+			Kind:            AuditIncident,
+			Cluster:         inc.Name,
+			Namespace:       inc.Namespace,
+			OldPhase:        inc.Phase,
+			Decision:        resolution,
+			IncidentID:      inc.ID,
+			DowntimeSeconds: at.Sub(inc.OpenedAt).Seconds(),
+		})
+	}
+	m.enqueueResolutionCallback(inc)
+	m.enqueueAnnotation(inc, at)
+	if inc.OpenEvent {
+		m.emitEvent(namespace, name, corev1.EventTypeNormal, "IncidentClosed",
+			fmt.Sprintf("database-monitor closed incident %s: %s", inc.ID, resolution))
+	}
+}
+
+// 集群不再需要跟踪：清理 lastStatus 并关闭事件，调用方需持有 m.mu
+func (m *Monitor) resolveCluster(namespace, name, resolution string) {
+	m.resolveClusterAt(namespace, name, resolution, m.now())
+}
+
+func (m *Monitor) resolveClusterAt(namespace, name, resolution string, at time.Time) {
+	delete(m.lastStatus, clusterKey(namespace, name))
+	m.closeIncident(namespace, name, resolution, at)
+}
+
+// 本轮未出现、但仍有未关闭事件的集群，调用方需持有 m.mu
+func (m *Monitor) missingIncidents(seen map[string]bool) []*Incident {
+	var missing []*Incident
+	for key, inc := range m.openIncidents {
+		if !seen[key] {
+			missing = append(missing, inc)
+		}
+	}
+	return missing
+}
+
+// 关闭已消失集群的事件；欠费 ns 被清理导致的消失只记录，不通知
+func (m *Monitor) closeDisappeared(ctx context.Context, missing []*Incident) {
+	for _, inc := range missing {
+		resolution, at := m.disappearanceResolution(ctx, inc.Namespace)
+		if resolution == resolutionDebtCleanup {
+			m.log.Info("Cluster removed due to debt cleanup", "cluster", inc.Name, "namespace", inc.Namespace)
+			m.forgetSentIncidents(inc.Namespace, inc.Name)
+		}
+		m.mu.Lock()
+		m.resolveClusterAt(inc.Namespace, inc.Name, resolution, at)
+		m.mu.Unlock()
+	}
+}
+
+// 判断集群消失的原因：所在 ns 曾欠费且已被删除或正在删除时，视为计费系统清理
+func (m *Monitor) disappearanceResolution(ctx context.Context, namespace string) (string, time.Time) {
+	now := m.now()
+	if !m.debt.wasInDebt(namespace) {
+		return resolutionDeleted, now
+	}
+	if err := m.budget.Wait(ctx); err != nil {
+		return resolutionDeleted, now
+	}
+	ns, err := m.kube.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return resolutionDebtCleanup, now
+	case err != nil:
+		m.log.Error("Error getting namespace", "namespace", namespace, "err", err)
+		return resolutionDeleted, now
+	case ns.DeletionTimestamp != nil:
+		return resolutionDebtCleanup, ns.DeletionTimestamp.Time
+	case ns.Status.Phase == corev1.NamespaceTerminating:
+		return resolutionDebtCleanup, now
+	}
+	return resolutionDeleted, now
+}
+
+// 把 OOMKill 记入集群当前的事件，调用方需持有 m.mu
+func (m *Monitor) recordOOMKills(namespace, name string, kills []oomKill) {
+	inc, ok := m.openIncidents[clusterKey(namespace, name)]
+	if !ok {
+		return
+	}
+	for _, k := range kills {
+		id := k.id()
+		if inc.oomSeen[id] {
+			continue
+		}
+		inc.oomSeen[id] = true
+		inc.OOMKills++
+	}
+}
+
+// 更新事件的原因类别，类别发生实质变化时记入时间线并返回变化说明；调用方需持有 m.mu
+func (m *Monitor) updateIncidentReason(namespace, name, reason string) string {
+	inc, ok := m.openIncidents[clusterKey(namespace, name)]
+	if !ok || reason == reasonUnknown || reason == inc.Reason {
+		return ""
+	}
+	prev := inc.Reason
+	inc.Reason = reason
+	if prev == "" {
+		return ""
+	}
+	change := fmt.Sprintf("reason changed: was %s, now %s", prev, reason)
+	inc.Timeline = append(inc.Timeline, IncidentEvent{Time: m.now(), Message: change})
+	m.log.Info("Incident reason changed", "incident", inc.ID, "was", prev, "reason", reason)
+	return change
+}
@@ -0,0 +1,159 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// 只检查 KubeBlocks 创建的资源；List 时用 label 过滤并分页，不在内存中保留完整对象
+const kubeblocksResourceSelector = "app.kubernetes.io/managed-by=kubeblocks," + instanceLabel
+
+// 连接凭据 Secret 的名称后缀
+const connCredentialSuffix = "-conn-credential"
+
+// 摘要中最多列出的条目数，其余只给出数量
+const maxIntegrityLines = 100
+
+// integrityTracker 保存上一次审计发现的问题，摘要只列出新增和已消失的部分
+type integrityTracker struct {
+	mu       sync.Mutex
+	previous map[string]bool
+	ran      bool
+}
+
+// 只读审计：资源的实例标签指向不存在的集群，或名称同时符合同一 ns 下多个集群的命名规则
+func (m *Monitor) auditIntegrity(ctx context.Context) (map[string]bool, error) {
+	clusters := make(map[string]map[string]bool)
+	err := m.listAll(ctx, m.api().clusters, func(obj *unstructured.Unstructured) {
+		ns := obj.GetNamespace()
+		if clusters[ns] == nil {
+			clusters[ns] = make(map[string]bool)
+		}
+		clusters[ns][obj.GetName()] = true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	findings := make(map[string]bool)
+	check := func(kind, namespace, name, instance string, belongs func(cluster string) bool) {
+		resource := fmt.Sprintf("%s %s/%s", kind, namespace, name)
+		if !clusters[namespace][instance] {
+			findings[fmt.Sprintf("%s: labelled for cluster %s which does not exist", resource, instance)] = true
+		}
+		var candidates []string
+		for cluster := range clusters[namespace] {
+			if belongs(cluster) {
+				candidates = append(candidates, cluster)
+			}
+		}
+		sort.Strings(candidates)
+		switch {
+		case len(candidates) > 1:
+			findings[fmt.Sprintf("%s: name is ambiguous between clusters %s", resource, strings.Join(candidates, ", "))] = true
+		case len(candidates) == 1 && candidates[0] != instance && clusters[namespace][instance]:
+			findings[fmt.Sprintf("%s: labelled for cluster %s but named like cluster %s", resource, instance, candidates[0])] = true
+		}
+	}
+
+	opts := metav1.ListOptions{LabelSelector: kubeblocksResourceSelector, Limit: m.listPageSize()}
+	for {
+		if err := m.budget.Wait(ctx); err != nil {
+			return nil, err
+		}
+		pvcs, err := m.kube.CoreV1().PersistentVolumeClaims("").List(ctx, opts)
+		if token, ok := expiredContinue(err); ok {
+			opts.Continue = token
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, pvc := range pvcs.Items {
+			name := pvc.Name
+			// PVC 名称形如 <volume>-<cluster>-<component>-<ordinal>
+			check("pvc", pvc.Namespace, name, pvc.Labels[instanceLabel], func(cluster string) bool {
+				return strings.Contains(name, "-"+cluster+"-")
+			})
+		}
+		if pvcs.Continue == "" {
+			break
+		}
+		opts.Continue = pvcs.Continue
+	}
+
+	opts = metav1.ListOptions{LabelSelector: kubeblocksResourceSelector, Limit: m.listPageSize()}
+	for {
+		if err := m.budget.Wait(ctx); err != nil {
+			return nil, err
+		}
+		secrets, err := m.kube.CoreV1().Secrets("").List(ctx, opts)
+		if token, ok := expiredContinue(err); ok {
+			opts.Continue = token
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, secret := range secrets.Items {
+			name := secret.Name
+			if !strings.HasSuffix(name, connCredentialSuffix) {
+				continue
+			}
+			check("secret", secret.Namespace, name, secret.Labels[instanceLabel], func(cluster string) bool {
+				return name == cluster+connCredentialSuffix
+			})
+		}
+		if secrets.Continue == "" {
+			break
+		}
+		opts.Continue = secrets.Continue
+	}
+	return findings, nil
+}
+
+// 摘要中的共享资源一节：与上一次审计相比新增和已解决的问题
+func (m *Monitor) integrityDigestSection(ctx context.Context) digestSection {
+	section := digestSection{Title: "Shared resources (ops)"}
+	findings, err := m.auditIntegrity(ctx)
+	if err != nil {
+		m.log.Error("Error auditing shared resources", "err", err)
+		section.Lines = []string{"audit failed: " + err.Error()}
+		return section
+	}
+
+	t := &m.integrity
+	t.mu.Lock()
+	previous, ran := t.previous, t.ran
+	t.previous, t.ran = findings, true
+	t.mu.Unlock()
+
+	var added, resolved []string
+	for f := range findings {
+		if !previous[f] {
+			added = append(added, "new: "+f)
+		}
+	}
+	if ran {
+		for f := range previous {
+			if !findings[f] {
+				resolved = append(resolved, "resolved: "+f)
+			}
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(resolved)
+	lines := append(added, resolved...)
+	if len(lines) > maxIntegrityLines {
+		more := len(lines) - maxIntegrityLines
+		lines = append(lines[:maxIntegrityLines], fmt.Sprintf("... and %d more", more))
+	}
+	section.Lines = lines
+	return section
+}
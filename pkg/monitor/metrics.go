@@ -0,0 +1,262 @@
+package monitor
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// metrics 每个 Monitor 独立的一组指标，注册到 Deps.Registerer
+type metrics struct {
+	evaluations    prometheus.Counter
+	debtNamespaces prometheus.Gauge
+	backupAge      *prometheus.GaugeVec
+	eventsEmitted  prometheus.Counter
+	eventsDropped  *prometheus.CounterVec
+
+	clusterStatus       *prometheus.GaugeVec
+	notificationsSent   *prometheus.CounterVec
+	notificationsFailed *prometheus.CounterVec
+	notificationRetries *prometheus.CounterVec
+	notificationsHeld   *prometheus.CounterVec
+	checkDuration       prometheus.Histogram
+	checkFailures       prometheus.Counter
+	evaluationDuration  prometheus.Histogram
+	probeLatency        *prometheus.HistogramVec
+
+	workqueueDepth          *prometheus.GaugeVec
+	workqueueAdds           *prometheus.CounterVec
+	workqueueLatency        *prometheus.HistogramVec
+	workqueueWorkDuration   *prometheus.HistogramVec
+	workqueueUnfinished     *prometheus.GaugeVec
+	workqueueLongestRunning *prometheus.GaugeVec
+	workqueueRetries        *prometheus.CounterVec
+}
+
+// 同一个注册表中已有同名指标时（例如多个 Monitor 共用注册表）沿用已注册的指标，其他注册错误收集到 errs
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		evaluations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "database_monitor_evaluations_total",
+			Help: "Number of cluster evaluations performed.",
+		}),
+		debtNamespaces: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "database_monitor_debt_namespaces",
+			Help: "Number of namespaces currently in debt.",
+		}),
+		backupAge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "database_monitor_backup_age_hours",
+			Help: "Hours since the last completed backup of clusters with a backup schedule, +Inf when there is none.",
+		}, []string{"namespace", "name"}),
+		eventsEmitted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "database_monitor_events_emitted_total",
+			Help: "Number of Kubernetes Events created by the monitor.",
+		}),
+		eventsDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "database_monitor_events_dropped_total",
+			Help: "Number of Kubernetes Events dropped because the per-cluster or global budget was exhausted.",
+		}, []string{"budget"}),
+		clusterStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "database_cluster_status",
+			Help: "Current phase of each database cluster, 1 for the phase the cluster is in.",
+		}, []string{"name", "namespace", "phase"}),
+		notificationsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "database_monitor_notifications_sent_total",
+			Help: "Number of notifications sent successfully, by notifier.",
+		}, []string{"notifier"}),
+		notificationsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "database_monitor_notifications_failed_total",
+			Help: "Number of notifications that failed to render or send, by notifier.",
+		}, []string{"notifier"}),
+		notificationRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "database_monitor_notification_retries_total",
+			Help: "Number of notification send attempts that failed and were retried, by notifier.",
+		}, []string{"notifier"}),
+		notificationsHeld: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "database_monitor_notifications_rate_limited_total",
+			Help: "Number of notifications held back by the notification rate limit, by notifier and outcome (deferred, summarized, replaced, dropped).",
+		}, []string{"notifier", "outcome"}),
+		checkDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "database_monitor_check_duration_seconds",
+			Help:    "Duration of a full check of all clusters.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+		}),
+		checkFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "database_monitor_check_failures_total",
+			Help: "Number of checks that failed, for example because the API server could not be reached, and were retried.",
+		}),
+		evaluationDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "database_monitor_evaluation_duration_seconds",
+			Help:    "Duration of evaluating a single cluster, including pod inspection.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+		}),
+		probeLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "database_monitor_probe_duration_seconds",
+			Help:    "Duration of connectivity probes against database clusters, by engine.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"engine"}),
+		workqueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "database_monitor_workqueue_depth",
+			Help: "Current depth of the work queue.",
+		}, []string{"name"}),
+		workqueueAdds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "database_monitor_workqueue_adds_total",
+			Help: "Number of adds handled by the work queue.",
+		}, []string{"name"}),
+		workqueueLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "database_monitor_workqueue_queue_duration_seconds",
+			Help:    "How long an item stays in the work queue before being processed.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"name"}),
+		workqueueWorkDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "database_monitor_workqueue_work_duration_seconds",
+			Help:    "How long processing an item from the work queue takes.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"name"}),
+		workqueueUnfinished: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "database_monitor_workqueue_unfinished_work_seconds",
+			Help: "Seconds of work in progress that has not been observed by work_duration.",
+		}, []string{"name"}),
+		workqueueLongestRunning: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "database_monitor_workqueue_longest_running_processor_seconds",
+			Help: "How many seconds the longest running processor has been running.",
+		}, []string{"name"}),
+		workqueueRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "database_monitor_workqueue_retries_total",
+			Help: "Number of retries handled by the work queue.",
+		}, []string{"name"}),
+	}
+	if reg == nil {
+		return m, nil
+	}
+	var errs []error
+	m.evaluations = register(reg, m.evaluations, &errs)
+	m.debtNamespaces = register(reg, m.debtNamespaces, &errs)
+	m.backupAge = register(reg, m.backupAge, &errs)
+	m.eventsEmitted = register(reg, m.eventsEmitted, &errs)
+	m.eventsDropped = register(reg, m.eventsDropped, &errs)
+	m.clusterStatus = register(reg, m.clusterStatus, &errs)
+	m.notificationsSent = register(reg, m.notificationsSent, &errs)
+	m.notificationsFailed = register(reg, m.notificationsFailed, &errs)
+	m.notificationRetries = register(reg, m.notificationRetries, &errs)
+	m.notificationsHeld = register(reg, m.notificationsHeld, &errs)
+	m.checkDuration = register(reg, m.checkDuration, &errs)
+	m.checkFailures = register(reg, m.checkFailures, &errs)
+	m.evaluationDuration = register(reg, m.evaluationDuration, &errs)
+	m.probeLatency = register(reg, m.probeLatency, &errs)
+	m.workqueueDepth = register(reg, m.workqueueDepth, &errs)
+	m.workqueueAdds = register(reg, m.workqueueAdds, &errs)
+	m.workqueueLatency = register(reg, m.workqueueLatency, &errs)
+	m.workqueueWorkDuration = register(reg, m.workqueueWorkDuration, &errs)
+	m.workqueueUnfinished = register(reg, m.workqueueUnfinished, &errs)
+	m.workqueueLongestRunning = register(reg, m.workqueueLongestRunning, &errs)
+	m.workqueueRetries = register(reg, m.workqueueRetries, &errs)
+	return m, errors.Join(errs...)
+}
+
+func register[T prometheus.Collector](reg prometheus.Registerer, c T, errs *[]error) T {
+	err := reg.Register(c)
+	if err == nil {
+		return c
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+	*errs = append(*errs, err)
+	return c
+}
+
+// 更新集群的 phase 指标，phase 变化时删除旧的序列；调用方需持有 m.mu
+func (m *Monitor) setClusterPhase(namespace, name, phase string) {
+	key := clusterKey(namespace, name)
+	if prev, ok := m.phases[key]; ok && prev != phase {
+		m.metrics.clusterStatus.DeleteLabelValues(name, namespace, prev)
+		m.previousPhases[key] = prev
+	}
+	m.phases[key] = phase
+	m.metrics.clusterStatus.WithLabelValues(name, namespace, phase).Set(1)
+}
+
+// 集群消失后删除其 phase 指标；调用方需持有 m.mu
+func (m *Monitor) forgetClusterPhase(key string) {
+	prev, ok := m.phases[key]
+	if !ok {
+		return
+	}
+	namespace, name, _ := strings.Cut(key, "/")
+	m.metrics.clusterStatus.DeleteLabelValues(name, namespace, prev)
+	delete(m.phases, key)
+	delete(m.previousPhases, key)
+	delete(m.uids, key)
+}
+
+var (
+	unhealthySecondsDesc = prometheus.NewDesc("database_cluster_unhealthy_seconds",
+		"Seconds since each unhealthy cluster's incident opened, by the phase it is currently in.",
+		[]string{"name", "namespace", "phase"}, nil)
+	namespaceInDebtDesc = prometheus.NewDesc("database_namespace_in_debt",
+		"1 for each namespace currently in debt.",
+		[]string{"namespace"}, nil)
+)
+
+// healthCollector 在抓取时按当前打开的事件和欠费 ns 生成逐集群的指标，让不健康时长随时间增长，
+// 不依赖巡检周期；供 recording rule 计算整体的健康 SLO
+type healthCollector struct {
+	m *Monitor
+}
+
+func (c healthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- unhealthySecondsDesc
+	ch <- namespaceInDebtDesc
+}
+
+func (c healthCollector) Collect(ch chan<- prometheus.Metric) {
+	now := c.m.now()
+	c.m.mu.Lock()
+	for _, inc := range c.m.openIncidents {
+		ch <- prometheus.MustNewConstMetric(unhealthySecondsDesc, prometheus.GaugeValue,
+			now.Sub(inc.OpenedAt).Seconds(), inc.Name, inc.Namespace, inc.Phase)
+	}
+	c.m.mu.Unlock()
+	for _, ns := range c.m.debt.snapshot() {
+		ch <- prometheus.MustNewConstMetric(namespaceInDebtDesc, prometheus.GaugeValue, 1, ns)
+	}
+}
+
+// 把 client-go workqueue 的指标接入 Prometheus
+type workqueueMetricsProvider struct {
+	m *metrics
+}
+
+func (p workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return p.m.workqueueDepth.WithLabelValues(name)
+}
+
+func (p workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return p.m.workqueueAdds.WithLabelValues(name)
+}
+
+func (p workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return p.m.workqueueLatency.WithLabelValues(name)
+}
+
+func (p workqueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return p.m.workqueueWorkDuration.WithLabelValues(name)
+}
+
+func (p workqueueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return p.m.workqueueUnfinished.WithLabelValues(name)
+}
+
+func (p workqueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return p.m.workqueueLongestRunning.WithLabelValues(name)
+}
+
+func (p workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return p.m.workqueueRetries.WithLabelValues(name)
+}
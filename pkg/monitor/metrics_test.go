@@ -0,0 +1,23 @@
+package monitor
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// 两个 Monitor 共用一个注册表时不会 panic，计数指标由两者共享
+func TestNewSharesRegistry(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	first := newTestEnv(t, DefaultConfig(), Deps{Registerer: reg})
+	second := newTestEnv(t, DefaultConfig(), Deps{Registerer: reg})
+	first.m.metrics.evaluations.Inc()
+	second.m.metrics.evaluations.Inc()
+	if got := testutil.ToFloat64(first.m.metrics.evaluations); got != 2 {
+		t.Errorf("shared evaluations counter = %v, want 2", got)
+	}
+	if _, err := reg.Gather(); err != nil {
+		t.Errorf("gather: %v", err)
+	}
+}
@@ -0,0 +1,528 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"database-monitor/pkg/httpclient"
+	"database-monitor/pkg/redact"
+	"database-monitor/pkg/schedule"
+)
+
+// KubeBlocks v1alpha1 Cluster 的 GVR，实际使用的版本由 detectAPI 选择
+var clustersGVR = schema.GroupVersionResource{
+	Group:    "apps.kubeblocks.io",
+	Version:  "v1alpha1",
+	Resource: "clusters",
+}
+
+// Deps 创建 Monitor 所需的依赖，除客户端外均可省略
+type Deps struct {
+	Config  Config
+	Dynamic dynamic.Interface
+	Kube    kubernetes.Interface
+	// 报告发送到的通知后端
+	Notifiers []Notifier
+	// 为空时使用 DefaultPhasePolicy
+	Policy PhasePolicy
+	// 为空时使用 NewQuotaDebtDetector
+	Debt DebtDetector
+	// 指标注册到这里，为空时不注册
+	Registerer prometheus.Registerer
+	// 生效配置的版本标识，会记录到决策和事件中
+	ConfigVersion string
+	// 为空时使用 time.Now，测试中可以替换
+	Now func() time.Time
+	// 日志和通知内容经过脱敏后才输出，为空时不脱敏
+	Redactor *redact.Redactor
+	// 保存待发送的回调等需要跨重启的状态，为空时只保存在内存中
+	Store StateStore
+	// 每轮巡检后把各集群的 phase、开始不健康的时间和欠费标记写入这里，供其他控制器读取；为空时不发布
+	StatusStore StateStore
+	// 事件打开和关闭时在集群对象上创建 Event，为空时不创建
+	Events record.EventRecorder
+	// 状态变化和通知决定写入审计日志，为空时不记录
+	Audit AuditSink
+	// 可查询的审计历史，通常与 Audit 为同一个历史库；为空时只统计内存中的事件历史
+	History AuditHistory
+	// 结构化日志，为空时使用 slog.Default() 并经 Redactor 脱敏
+	Logger *slog.Logger
+	// 只接收升级通知的后端，由 EscalationTier.Notifiers 按名称引用
+	EscalationNotifiers []Notifier
+	// 由 ns 注解中的 webhook 地址创建通知后端，为空时不按注解路由
+	NamespaceNotifier func(namespace, url string) (Notifier, error)
+	// 发送恢复回调和 Grafana 标注，为空时创建一个超时为 NotifyTimeout 的客户端
+	HTTPClient *http.Client
+	// 巡检、欠费刷新和通知发送的 span 由这里创建，为空时使用 otel 的全局 TracerProvider
+	TracerProvider trace.TracerProvider
+	// watch worker、各定时检查和评估池等后台 goroutine panic 时在该 goroutine 中调用，
+	// 返回后 panic 继续向上抛出；用于写入 goroutine dump 和退出记录，为空时不处理
+	OnPanic func(r interface{})
+}
+
+// Monitor 巡检数据库集群并发送报告
+type Monitor struct {
+	cfg           Config
+	dynamic       dynamic.Interface
+	kube          kubernetes.Interface
+	notifiers     []Notifier
+	nsNotifier    func(namespace, url string) (Notifier, error)
+	escalators    []Notifier
+	policy        PhasePolicy
+	resources     []*monitoredResource
+	kbAPI         atomic.Pointer[kubeblocksAPI]
+	debtDetector  DebtDetector
+	configVersion string
+	now           func() time.Time
+	redactor      *redact.Redactor
+	store         StateStore
+	statusStore   StateStore
+	events        record.EventRecorder
+	auditSink     AuditSink
+	auditHistory  AuditHistory
+	log           *slog.Logger
+	httpClient    *http.Client
+	tracer        trace.Tracer
+	onPanic       func(r interface{})
+	// 巡检额外发起的 API 调用共享同一个令牌桶
+	budget  flowcontrol.RateLimiter
+	metrics *metrics
+	debt    *debtTracker
+	dedup   reportDedup
+	backups backupTracker
+	ops     opsTracker
+	volumes volumeTracker
+	probes  probeTracker
+	// 维护窗口和临时静默
+	silences silenceTracker
+	// 事件关闭回调，由单独的 goroutine 发送
+	callbacks   callbackQueue
+	annotations grafanaQueue
+	debtStops   debtStopTracker
+	eventBudget eventBudget
+	notifyLimit notifyLimiter
+	integrity   integrityTracker
+	outage      apiOutage
+
+	// mu 保护以下巡检状态，watch 模式下会被多个 worker 并发访问
+	mu sync.Mutex
+	// 记录不健康集群上一次的状态和连续不健康的次数
+	lastStatus      map[string]clusterState
+	decisions       map[string]Decision
+	openIncidents   map[string]*Incident
+	incidentHistory []*Incident
+	// watch 模式下每个集群最近一次评估得到的报告条目
+	watchEntries map[string]ReportEntry
+	// 最近一轮巡检的报告
+	lastReport Report
+	// 未就绪的原因，为空表示正在正常巡检
+	notReady string
+	health   loopHealth
+	// 每个集群当前导出的 phase 指标
+	phases map[string]string
+	// 每个集群进入当前 phase 之前的 phase
+	previousPhases map[string]string
+	// 每个集群对象的 UID，Event 需要带上 UID 才能在 kubectl describe 中显示
+	uids map[string]types.UID
+	// 等待发送的恢复通知
+	recoveries []recovery
+	// 按严重程度过滤的通知后端各自的去重状态
+	routes routedDedup
+	// 由 ns 注解创建的租户通知后端
+	tenants tenantRoutes
+	// ns 注解中的负责人信息
+	owners namespaceOwners
+	// 带有忽略注解的 ns
+	ignored ignoredNamespaces
+	// CheckSchedule 解析后的时间表，未设置时为 nil
+	schedule *schedule.Cron
+	// 最近一次保存的巡检状态；巡检主循环和退出时都会保存，由 checkpointMu 串行化
+	checkpointMu   sync.Mutex
+	lastCheckpoint string
+	// 最近一次发布的集群状态及时间，只由巡检主循环访问
+	published struct {
+		clusters string
+		at       time.Time
+	}
+}
+
+// New 创建 Monitor，不会发起任何 API 调用
+func New(deps Deps) *Monitor {
+	metrics, metricsErr := newMetrics(deps.Registerer)
+	m := &Monitor{
+		cfg:           deps.Config,
+		dynamic:       deps.Dynamic,
+		kube:          deps.Kube,
+		notifiers:     deps.Notifiers,
+		nsNotifier:    deps.NamespaceNotifier,
+		escalators:    deps.EscalationNotifiers,
+		policy:        deps.Policy,
+		debtDetector:  deps.Debt,
+		configVersion: deps.ConfigVersion,
+		now:           deps.Now,
+		redactor:      deps.Redactor,
+		store:         deps.Store,
+		statusStore:   deps.StatusStore,
+		events:        deps.Events,
+		auditSink:     deps.Audit,
+		auditHistory:  deps.History,
+		log:           deps.Logger,
+		httpClient:    deps.HTTPClient,
+		onPanic:       deps.OnPanic,
+		budget:        flowcontrol.NewTokenBucketRateLimiter(float32(deps.Config.APIQPS), deps.Config.APIBurst),
+		metrics:       metrics,
+		debt:          newDebtTracker(),
+		lastStatus:    make(map[string]clusterState),
+		decisions:     make(map[string]Decision),
+		openIncidents: make(map[string]*Incident),
+		watchEntries:  make(map[string]ReportEntry),
+		notReady:      "starting",
+		phases:        make(map[string]string),
+
+		previousPhases: make(map[string]string),
+		uids:           make(map[string]types.UID),
+	}
+	m.callbacks.wake = make(chan struct{}, 1)
+	m.annotations.wake = make(chan struct{}, 1)
+	m.annotations.ids = make(map[string]int64)
+	m.debtStops.stopped = make(map[string]bool)
+	m.ops.notified = make(map[string]string)
+	m.volumes.notified = make(map[string]string)
+	m.probes.notified = make(map[string]string)
+	m.tenants.byNS = make(map[string]tenantRoute)
+	if m.log == nil {
+		m.log = slog.New(m.redactor.Handler(slog.Default().Handler()))
+	}
+	if deps.Registerer != nil {
+		// 逐集群的健康指标属于某一个 Monitor，注册表中已有时不能沿用，注册失败只打日志
+		if err := errors.Join(metricsErr, deps.Registerer.Register(healthCollector{m: m})); err != nil {
+			m.log.Warn("Unable to register metrics", "err", err)
+		}
+	}
+	if m.httpClient == nil {
+		m.httpClient = httpclient.New(m.cfg.NotifyTimeout)
+	}
+	tp := deps.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	m.tracer = tp.Tracer(tracerName)
+	if m.cfg.CheckSchedule != "" {
+		sched, err := schedule.Parse(m.cfg.CheckSchedule, nil)
+		if err != nil {
+			m.log.Warn("Ignoring check schedule", "err", err)
+		} else {
+			m.schedule = sched
+		}
+	}
+	for _, w := range m.cfg.MaintenanceWindows {
+		cron, err := schedule.Parse(w.Schedule, nil)
+		if err != nil {
+			m.log.Warn("Ignoring maintenance window", "window", w.Name, "err", err)
+			continue
+		}
+		m.silences.windows = append(m.silences.windows, maintenanceWindow{MaintenanceWindow: w, cron: cron})
+	}
+	for _, r := range m.cfg.Resources {
+		res, err := newMonitoredResource(r)
+		if err != nil {
+			m.log.Warn("Ignoring monitored resource", "resource", r.Resource, "err", err)
+			continue
+		}
+		m.resources = append(m.resources, res)
+	}
+	if len(m.resources) == 0 {
+		m.resources = []*monitoredResource{kubeblocksResource}
+	}
+	if m.policy == nil {
+		m.policy = DefaultPhasePolicy()
+	}
+	if m.debtDetector == nil {
+		switch m.cfg.DebtSource {
+		case DebtSourceQuota:
+			m.debtDetector = NewQuotaDebtDetector(deps.Kube)
+		case DebtSourceCRD:
+			m.debtDetector = NewSealosDebtDetector(deps.Dynamic, nil)
+		default:
+			m.debtDetector = NewSealosDebtDetector(deps.Dynamic, NewQuotaDebtDetector(deps.Kube))
+		}
+	}
+	if m.now == nil {
+		m.now = time.Now
+	}
+	return m
+}
+
+// Run 持续巡检直到 ctx 结束；List 等致命错误会中止并返回。
+// 未安装 clusters CRD 时不报错，而是等待 CRD 出现后再开始巡检
+func (m *Monitor) Run(ctx context.Context) error {
+	if err := m.waitForCRD(ctx); err != nil {
+		return nil
+	}
+	if err := m.loadCheckpoint(ctx); err != nil {
+		m.log.Error("Error loading state checkpoint", "err", err)
+	}
+	m.startDebtLoop(ctx)
+	if m.kubeblocks() != nil {
+		m.startBackupLoop(ctx)
+		m.startOpsLoop(ctx)
+		m.startVolumeLoop(ctx)
+		m.startProbeLoop(ctx)
+		m.startRemediationLoop(ctx)
+	}
+	m.startDigestLoop(ctx)
+	m.startCallbackLoop(ctx)
+	m.startGrafanaLoop(ctx)
+	m.startNotifyLimitLoop(ctx)
+	if err := m.loadAlertState(ctx); err != nil {
+		m.log.Error("Error loading alert state", "err", err)
+	}
+	defer m.flush(ctx)
+	if m.cfg.Watch {
+		return m.watch(ctx)
+	}
+
+	for {
+		checkCtx, cancel := m.cycleContext(ctx)
+		_, err := m.RunOnce(checkCtx)
+		timedOut := ctx.Err() == nil && errors.Is(checkCtx.Err(), context.DeadlineExceeded)
+		cancel()
+		if err != nil && isMissingCRD(err) {
+			// CRD 在运行中被删除
+			if err := m.waitForCRD(ctx); err != nil {
+				return nil
+			}
+			continue
+		}
+		if err != nil && ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			if timedOut {
+				err = fmt.Errorf("check timed out after %s: %w", m.cfg.CheckTimeout, err)
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(m.checkFailed(ctx, err)):
+			}
+			continue
+		}
+		m.checkSucceeded(ctx)
+		delay := m.nextCheckDelay()
+		m.recordCheck(time.Now().Add(delay))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// 一轮巡检的 context，最多 CheckTimeout
+func (m *Monitor) cycleContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.cfg.CheckTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, m.cfg.CheckTimeout)
+}
+
+// 给定期执行的后台检查加上 CheckTimeout，超时时记录日志，下一轮照常执行
+func (m *Monitor) withCycleTimeout(name string, fn func(ctx context.Context)) func(ctx context.Context) {
+	return func(parent context.Context) {
+		ctx, cancel := m.cycleContext(parent)
+		defer cancel()
+		fn(ctx)
+		if parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			m.log.Error("Background check timed out, retrying in the next round", "check", name, "timeout", m.cfg.CheckTimeout)
+		}
+	}
+}
+
+// 距下一次巡检的时间：设置了时间表时按时间表，否则为 CheckInterval
+func (m *Monitor) nextCheckDelay() time.Duration {
+	if m.schedule == nil {
+		return m.cfg.CheckInterval
+	}
+	now := time.Now()
+	next := m.schedule.Next(now)
+	if next.IsZero() {
+		return m.cfg.CheckInterval
+	}
+	return next.Sub(now)
+}
+
+// RunOnce 执行一轮巡检：评估所有集群、生成报告，并在事件变化时发送通知
+func (m *Monitor) RunOnce(ctx context.Context) (Report, error) {
+	start := time.Now()
+	defer func() { m.metrics.checkDuration.Observe(time.Since(start).Seconds()) }()
+	ctx, span := m.tracer.Start(ctx, "check", trace.WithAttributes(attribute.String("region", m.cfg.Region)))
+	defer span.End()
+
+	if err := m.loadSilences(ctx); err != nil {
+		m.log.Error("Error loading silences", "err", err)
+	}
+	// 分页 List，每页裁剪后立即交给 worker 并发评估，不同时持有全部集群对象
+	pool := m.newEvaluatePool(ctx)
+	seen := make(map[string]bool)
+	var err error
+	for _, res := range m.resources {
+		err = m.listSelected(ctx, m.resourceGVR(res), m.cfg.ClusterSelector, func(cluster *unstructured.Unstructured) {
+			if !m.namespaceAllowed(cluster.GetNamespace()) {
+				return
+			}
+			trimCluster(cluster)
+			seen[clusterKey(cluster.GetNamespace(), res.trackedName(cluster.GetName()))] = true
+			pool.submit(res, cluster)
+		})
+		if err != nil && res != m.resources[0] && isMissingCRD(err) {
+			// 其他资源的 CRD 未安装时跳过，不影响第一种资源的巡检
+			m.log.Warn("Monitored resource not installed, skipping", "resource", m.resourceGVR(res).String())
+			err = nil
+		}
+		if err != nil {
+			break
+		}
+	}
+	entries := pool.wait()
+	span.SetAttributes(attribute.Int("clusters", len(seen)), attribute.Int("entries", len(entries)))
+	if err != nil {
+		m.spanError(span, err)
+		return Report{}, err
+	}
+	// 评估被退出信号打断时结果不完整，不据此关闭事件或发送报告
+	if err := ctx.Err(); err != nil {
+		m.spanError(span, err)
+		return Report{}, err
+	}
+	m.mu.Lock()
+	m.pruneDecisions(seen)
+	missing := m.missingIncidents(seen)
+	m.mu.Unlock()
+	m.closeDisappeared(ctx, missing)
+	m.checkRepeatedIncidents(ctx)
+
+	report := m.newReport()
+	report.Entries = entries
+	m.annotateOwners(ctx, &report)
+	m.setLastReport(report)
+	// 如果数据库依然处于异常状态，则发送通知
+	m.notifyReport(ctx, report)
+	m.saveCheckpoint(ctx)
+	m.publishStatus(ctx)
+	return report, nil
+}
+
+// Notify 不经去重，把报告发送到所有通知后端，用于监控自身的通知
+func (m *Monitor) Notify(ctx context.Context, r Report) {
+	m.notifyDelivered(ctx, r, func(context.Context, bool) {})
+}
+
+// 同 Notify，各后端都有结果后调用一次 done；有后端发送失败或被丢弃时 delivered 为 false
+func (m *Monitor) notifyDelivered(ctx context.Context, r Report, done func(ctx context.Context, delivered bool)) {
+	g := newDeliveryGroup(done)
+	for _, n := range m.notifiers {
+		// 进程自身的提醒可能涉及多个租户，不发送到租户的渠道
+		if f, ok := n.(NamespaceFilter); ok && !f.Fallback() {
+			continue
+		}
+		m.sendNotification(ctx, heldNotification{notifier: n, report: r, sent: g.add()})
+	}
+	g.seal(ctx)
+}
+
+// NotifyNamed 不经去重，把报告发送到指定名称的通知后端（包括只用于升级的后端），names 为空时同 Notify
+func (m *Monitor) NotifyNamed(ctx context.Context, names []string, r Report) {
+	if len(names) == 0 {
+		m.Notify(ctx, r)
+		return
+	}
+	for _, name := range names {
+		n := m.notifierByName(name)
+		if n == nil {
+			m.log.Warn("Unknown notifier", "notifier", name)
+			continue
+		}
+		m.sendTo(ctx, n, r)
+	}
+}
+
+// 超过通知速率时暂缓，之后由 FlushNotifications 发送
+func (m *Monitor) sendTo(ctx context.Context, n Notifier, r Report) {
+	m.sendNotification(ctx, heldNotification{notifier: n, report: r})
+}
+
+// 速率限制允许时立即发送 h，否则暂缓到之后发送
+func (m *Monitor) sendNotification(ctx context.Context, h heldNotification) {
+	if m.holdNotification(ctx, h) {
+		m.log.Info("Notification held by the rate limit", "notifier", h.notifier.Name())
+		return
+	}
+	err := m.deliver(ctx, h.notifier, h.report)
+	h.done(ctx, err == nil)
+}
+
+// 渲染并发送，返回重试用尽后的错误
+func (m *Monitor) deliver(ctx context.Context, n Notifier, r Report) error {
+	payload, err := n.Render(r)
+	if err != nil {
+		m.log.Error("Error rendering notification", "notifier", n.Name(), "err", err)
+		m.metrics.notificationsFailed.WithLabelValues(n.Name()).Inc()
+		return err
+	}
+	if err := m.sendWithRetry(ctx, n, m.redactor.Bytes(payload)); err != nil {
+		m.log.Error("Error sending notification", "notifier", n.Name(), "err", err)
+		m.metrics.notificationsFailed.WithLabelValues(n.Name()).Inc()
+		return err
+	}
+	m.log.Info("Notification sent", "notifier", n.Name())
+	m.metrics.notificationsSent.WithLabelValues(n.Name()).Inc()
+	return nil
+}
+
+// NewNotice 生成一份只包含通知文本的报告
+func (m *Monitor) NewNotice(text string) Report {
+	r := m.newReport()
+	r.Notice = text
+	return r
+}
+
+// LastReport 最近一轮巡检的报告
+func (m *Monitor) LastReport() Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastReport
+}
+
+func (m *Monitor) setLastReport(r Report) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastReport = r
+}
+
+func (m *Monitor) newReport() Report {
+	return Report{
+		GeneratedAt:    m.now(),
+		DebtNamespaces: m.debt.snapshot(),
+		ConfigHash:     m.configVersion,
+		Region:         m.cfg.Region,
+	}
+}
@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+// 测试使用的固定时刻
+var testEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testNotifier 记录收到的报告，err 不为空时每次发送都返回它
+type testNotifier struct {
+	name    string
+	err     error
+	mu      sync.Mutex
+	reports []Report
+}
+
+func (n *testNotifier) Name() string {
+	if n.name == "" {
+		return "test"
+	}
+	return n.name
+}
+
+func (n *testNotifier) Render(r Report) ([]byte, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.reports = append(n.reports, r)
+	return []byte("{}"), nil
+}
+
+func (n *testNotifier) Send(context.Context, []byte) error { return n.err }
+
+func (n *testNotifier) take() []Report {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	reports := n.reports
+	n.reports = nil
+	return reports
+}
+
+// 收到的通知中 Notice 的第一行
+func (n *testNotifier) notices() []string {
+	var lines []string
+	for _, r := range n.take() {
+		if r.Notice != "" {
+			lines = append(lines, strings.SplitN(r.Notice, "\n", 2)[0])
+		}
+	}
+	return lines
+}
+
+// testEnv 基于 fake client 的 Monitor，时钟由 now 控制
+type testEnv struct {
+	m        *Monitor
+	dynamic  *dynamicfake.FakeDynamicClient
+	kube     *kubefake.Clientset
+	notifier *testNotifier
+	now      time.Time
+}
+
+func newTestDynamic(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		clustersGVR:        "ClusterList",
+		opsRequestsGVR:     "OpsRequestList",
+		backupsGVR:         "BackupList",
+		backupSchedulesGVR: "BackupScheduleList",
+		debtsGVR:           "DebtList",
+		notificationGVR:    "NotificationList",
+	}, objects...)
+}
+
+// 按 deps 创建测试用的 Monitor，未设置的客户端、时钟和通知后端使用 fake
+func newTestEnv(t *testing.T, cfg Config, deps Deps, kubeObjects ...runtime.Object) *testEnv {
+	t.Helper()
+	cfg.APIQPS, cfg.APIBurst = 1e6, 1e6
+	if cfg.DebtSource == DebtSourceAuto {
+		cfg.DebtSource = DebtSourceQuota
+	}
+	env := &testEnv{kube: kubefake.NewSimpleClientset(kubeObjects...), notifier: &testNotifier{}, now: testEpoch}
+	if d, ok := deps.Dynamic.(*dynamicfake.FakeDynamicClient); ok {
+		env.dynamic = d
+	} else {
+		env.dynamic = newTestDynamic()
+	}
+	deps.Config, deps.Dynamic, deps.Kube = cfg, env.dynamic, env.kube
+	if deps.Notifiers == nil {
+		deps.Notifiers = []Notifier{env.notifier}
+	}
+	if deps.Now == nil {
+		deps.Now = func() time.Time { return env.now }
+	}
+	env.m = New(deps)
+	return env
+}
+
+// 测试用的 KubeBlocks 集群
+func testCluster(namespace, name, phase string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps.kubeblocks.io/v1alpha1",
+		"kind":       "Cluster",
+		"metadata":   map[string]interface{}{"namespace": namespace, "name": name},
+		"status":     map[string]interface{}{"phase": phase},
+	}}
+	return obj
+}
+
+// memStore 内存中的 StateStore
+type memStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (s *memStore) Get(_ context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key], nil
+}
+
+func (s *memStore) Set(_ context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make(map[string]string)
+	}
+	s.values[key] = value
+	return nil
+}
@@ -0,0 +1,22 @@
+package monitor
+
+import "path"
+
+// namespaceAllowed 按 IncludeNamespaces 和 ExcludeNamespaces 判断是否巡检该 ns，
+// 两者都支持 glob（例如 prod-*）。Include 为空时包含所有 ns，Exclude 优先
+func (m *Monitor) namespaceAllowed(namespace string) bool {
+	if matchAny(m.cfg.ExcludeNamespaces, namespace) {
+		return false
+	}
+	return len(m.cfg.IncludeNamespaces) == 0 || matchAny(m.cfg.IncludeNamespaces, namespace)
+}
+
+func matchAny(patterns []string, namespace string) bool {
+	for _, p := range patterns {
+		// 模式在加载配置时已校验过
+		if ok, _ := path.Match(p, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+)
+
+// Notifier 通知后端：先把报告渲染成待发送的 payload，再负责发送
+type Notifier interface {
+	Name() string
+	Render(r Report) ([]byte, error)
+	Send(ctx context.Context, payload []byte) error
+}
+
+// SeverityFilter 只接收部分严重程度的通知后端，例如只接收 critical 的呼叫系统。
+// 巡检报告发送给它之前只保留它接收的条目，并按过滤后的内容单独去重
+type SeverityFilter interface {
+	AcceptsSeverity(severity string) bool
+}
+
+// NamespaceFilter 只接收部分 ns 的通知后端，例如租户团队自己的群。巡检报告发送给它之前只保留这些 ns 的条目，
+// 进程自身的提醒只发送给兜底渠道。Fallback 为 true 的后端是兜底渠道，只接收没有被其他后端认领的 ns
+type NamespaceFilter interface {
+	AcceptsNamespace(namespace string) bool
+	Fallback() bool
+}
+
+// TemplateError 表示用户自定义模板执行失败
+type TemplateError struct {
+	Err error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("template error: %v", e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// PartialSendError 表示拆成多条发送的通知中前面的消息已经送达，Remaining 为还未送达的部分，
+// 重试时只发送 Remaining，用户不会重复收到已送达的消息
+type PartialSendError struct {
+	Remaining []byte
+	Err       error
+}
+
+func (e *PartialSendError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PartialSendError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,270 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 超过通知速率时的处理方式
+const (
+	// 合并为每个后端一条通知，报告只保留最新的一份，其余通知列出第一行
+	NotifyOverflowSummarize = "summarize"
+	// 按顺序排队，令牌恢复后逐条发送
+	NotifyOverflowQueue = "queue"
+)
+
+// queue 模式下最多排队的通知数，超过时丢弃最早的
+const notifyQueueMax = 200
+
+// 合并通知中最多列出的被合并通知
+const notifySummaryLines = 20
+
+// heldNotification 因速率限制暂缓发送的通知
+type heldNotification struct {
+	notifier Notifier
+	// 去重路由的 key，queue 模式下同一后端同一路由的通知还在排队时用新的报告替换；为空时不替换
+	route  string
+	report Report
+	// 送达后以 true 调用，发送失败、被丢弃或替换时以 false 调用，用于记录去重状态；可以为空
+	sent func(ctx context.Context, delivered bool)
+}
+
+func (h heldNotification) done(ctx context.Context, delivered bool) {
+	if h.sent != nil {
+		h.sent(ctx, delivered)
+	}
+}
+
+// deliveryGroup 一份报告拆出的通知都有结果后调用一次 done；其中有通知发送失败、被丢弃或替换时 delivered 为 false
+type deliveryGroup struct {
+	mu      sync.Mutex
+	pending int
+	sealed  bool
+	dropped bool
+	done    func(ctx context.Context, delivered bool)
+}
+
+func newDeliveryGroup(done func(ctx context.Context, delivered bool)) *deliveryGroup {
+	return &deliveryGroup{done: done}
+}
+
+// add 登记一条通知，返回它的 sent 回调
+func (g *deliveryGroup) add() func(context.Context, bool) {
+	g.mu.Lock()
+	g.pending++
+	g.mu.Unlock()
+	return func(ctx context.Context, delivered bool) {
+		g.mu.Lock()
+		g.pending--
+		g.dropped = g.dropped || !delivered
+		finished, dropped := g.sealed && g.pending == 0, g.dropped
+		g.mu.Unlock()
+		if finished {
+			g.done(ctx, !dropped)
+		}
+	}
+}
+
+// seal 在所有通知都已登记后调用
+func (g *deliveryGroup) seal(ctx context.Context) {
+	g.mu.Lock()
+	g.sealed = true
+	finished, dropped := g.pending == 0, g.dropped
+	g.mu.Unlock()
+	if finished {
+		g.done(ctx, !dropped)
+	}
+}
+
+// notifyLimiter 所有通知后端共享的令牌桶。大面积故障时上百个集群同时变化，
+// 逐条发送会让飞书机器人被限流，超出的通知先暂缓，之后排队或合并发送
+type notifyLimiter struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	held   []heldNotification
+}
+
+// 按经过的时间补充令牌，有令牌时消耗一个；调用方持有 l.mu
+func (l *notifyLimiter) take(now time.Time, perMinute float64, burst int) bool {
+	if l.last.IsZero() {
+		l.tokens = float64(burst)
+	} else if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Minutes() * perMinute
+	}
+	if l.tokens > float64(burst) {
+		l.tokens = float64(burst)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// 没有令牌或已有暂缓的通知时暂缓 h，保持发送顺序；返回 false 表示调用方可以直接发送
+func (m *Monitor) holdNotification(ctx context.Context, h heldNotification) bool {
+	if m.cfg.NotifyRatePerMinute <= 0 {
+		return false
+	}
+	// 被丢弃或替换的通知在释放锁之后回调
+	var discarded []heldNotification
+	defer func() {
+		for _, d := range discarded {
+			d.done(ctx, false)
+		}
+	}()
+	l := &m.notifyLimit
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.held) == 0 && l.take(m.now(), m.cfg.NotifyRatePerMinute, m.cfg.NotifyBurst) {
+		return false
+	}
+	if len(l.held) == 0 {
+		m.log.Warn("Notification rate limit reached, holding notifications", "perMinute", m.cfg.NotifyRatePerMinute, "overflow", m.cfg.NotifyOverflow)
+	}
+	m.metrics.notificationsHeld.WithLabelValues(h.notifier.Name(), "deferred").Inc()
+	queue := m.cfg.NotifyOverflow == NotifyOverflowQueue
+	// 报告是完整的快照，排队中的旧报告没有必要再发送
+	if queue && h.route != "" {
+		for i, prev := range l.held {
+			if prev.notifier == h.notifier && prev.route == h.route {
+				m.metrics.notificationsHeld.WithLabelValues(h.notifier.Name(), "replaced").Inc()
+				discarded = append(discarded, prev)
+				l.held[i] = h
+				return true
+			}
+		}
+	}
+	l.held = append(l.held, h)
+	if queue && len(l.held) > notifyQueueMax {
+		m.metrics.notificationsHeld.WithLabelValues(l.held[0].notifier.Name(), "dropped").Inc()
+		m.log.Warn("Notification queue full, dropping the oldest notification", "notifier", l.held[0].notifier.Name())
+		discarded = append(discarded, l.held[0])
+		l.held = l.held[1:]
+	}
+	return true
+}
+
+// FlushNotifications 在令牌允许时发送因速率限制暂缓的通知。Run 会定期执行；只调用 RunOnce 的嵌入方需要自行调用
+func (m *Monitor) FlushNotifications(ctx context.Context) {
+	m.flushNotifications(ctx, false)
+}
+
+// force 为 true 时忽略速率限制，用于退出前发送全部暂缓的通知
+func (m *Monitor) flushNotifications(ctx context.Context, force bool) {
+	l := &m.notifyLimit
+	l.mu.Lock()
+	held := l.held
+	l.held = nil
+	if len(held) == 0 {
+		l.mu.Unlock()
+		return
+	}
+	if force || m.cfg.NotifyOverflow != NotifyOverflowQueue {
+		held = m.summarizeHeld(held)
+	}
+	var ready []heldNotification
+	for i, h := range held {
+		if !force && !l.take(m.now(), m.cfg.NotifyRatePerMinute, m.cfg.NotifyBurst) {
+			l.held = held[i:]
+			break
+		}
+		ready = append(ready, h)
+	}
+	l.mu.Unlock()
+
+	for _, h := range ready {
+		err := m.deliver(ctx, h.notifier, h.report)
+		h.done(ctx, err == nil)
+	}
+}
+
+// 每个后端暂缓的通知合并为一条：以最新的报告为准，其余通知的第一行附在 Notice 中
+func (m *Monitor) summarizeHeld(held []heldNotification) []heldNotification {
+	var order []Notifier
+	groups := make(map[Notifier][]heldNotification)
+	for _, h := range held {
+		if _, ok := groups[h.notifier]; !ok {
+			order = append(order, h.notifier)
+		}
+		groups[h.notifier] = append(groups[h.notifier], h)
+	}
+	out := make([]heldNotification, 0, len(order))
+	for _, n := range order {
+		if len(groups[n]) == 1 {
+			out = append(out, groups[n][0])
+			continue
+		}
+		reports := make([]Report, 0, len(groups[n]))
+		for _, h := range groups[n] {
+			reports = append(reports, h.report)
+		}
+		m.metrics.notificationsHeld.WithLabelValues(n.Name(), "summarized").Add(float64(len(reports) - 1))
+		// 巡检报告是完整的快照，最新的一份已包含之前的条目
+		base := -1
+		for i, r := range reports {
+			if r.Notice == "" || len(r.Entries) > 0 {
+				base = i
+			}
+		}
+		var lines []string
+		// 合并后的通知包含了这些通知的内容：作为基础的报告，以及第一行被列出的纯提醒
+		included := make([]bool, len(reports))
+		for i, r := range reports {
+			if i == base {
+				included[i] = true
+			} else if r.Notice != "" {
+				included[i] = len(r.Entries) == 0 && len(lines) < notifySummaryLines
+				lines = append(lines, strings.SplitN(r.Notice, "\n", 2)[0])
+			}
+		}
+		summary := m.NewNotice("")
+		if base >= 0 {
+			summary = reports[base]
+		}
+		text := fmt.Sprintf("Notification rate limit reached, %d notifications were combined into this one", len(reports))
+		if len(lines) > notifySummaryLines {
+			lines = append(lines[:notifySummaryLines], fmt.Sprintf("... and %d more", len(lines)-notifySummaryLines))
+		}
+		if len(lines) > 0 {
+			text += ":\n" + strings.Join(lines, "\n")
+		}
+		if summary.Notice != "" {
+			text = summary.Notice + "\n\n" + text
+		}
+		summary.Notice = text
+		// 被更新的报告替换、或未列出的通知不算已送达，由各自的回调安排重发
+		merged := groups[n]
+		out = append(out, heldNotification{notifier: n, report: summary, sent: func(ctx context.Context, delivered bool) {
+			for i, h := range merged {
+				h.done(ctx, delivered && included[i])
+			}
+		}})
+	}
+	return out
+}
+
+// 每 10 秒检查一次是否可以发送暂缓的通知
+func (m *Monitor) startNotifyLimitLoop(ctx context.Context) {
+	if m.cfg.NotifyRatePerMinute <= 0 {
+		return
+	}
+	go func() {
+		defer m.crashed()
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			m.FlushNotifications(ctx)
+		}
+	}()
+}
@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// queue 模式、每分钟一条的速率限制，启动通知用掉唯一的令牌
+func newLimitedEnv(t *testing.T) *testEnv {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.AlertAfterChecks = 1
+	cfg.NotifyRatePerMinute, cfg.NotifyBurst = 1, 1
+	cfg.NotifyOverflow = NotifyOverflowQueue
+	env := newTestEnv(t, cfg, Deps{Dynamic: newTestDynamic(testCluster("ns1", "db", "Failed")), Store: &memStore{}})
+	if err := env.m.RefreshDebt(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	env.m.Notify(context.Background(), env.m.NewNotice("started"))
+	if got := env.notifier.take(); len(got) != 1 {
+		t.Fatalf("start-up notice sent %d times, want once", len(got))
+	}
+	return env
+}
+
+func (e *testEnv) runOnce(t *testing.T) {
+	t.Helper()
+	if _, err := e.m.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 被速率限制暂缓的报告送达后才记录为已发送，暂缓期间的巡检不会重复排队
+func TestHeldReportMarkedSentAfterDelivery(t *testing.T) {
+	ctx := context.Background()
+	env := newLimitedEnv(t)
+	env.runOnce(t)
+	env.runOnce(t)
+	if got := env.notifier.take(); len(got) != 0 {
+		t.Fatalf("sent %d reports without a token", len(got))
+	}
+	if held := len(env.m.notifyLimit.held); held != 1 {
+		t.Errorf("%d notifications held, want the report queued once", held)
+	}
+
+	env.now = env.now.Add(time.Minute)
+	env.m.FlushNotifications(ctx)
+	if got := env.notifier.take(); len(got) != 1 || len(got[0].Entries) != 1 {
+		t.Fatalf("flush sent %+v, want the held report", got)
+	}
+	env.now = env.now.Add(time.Minute)
+	env.runOnce(t)
+	if got := env.notifier.take(); len(got) != 0 {
+		t.Errorf("delivered report sent again: %+v", got)
+	}
+}
+
+// 排队满时被丢弃的报告没有记录为已发送，下一轮巡检重新发送
+func TestDroppedReportIsResent(t *testing.T) {
+	ctx := context.Background()
+	env := newLimitedEnv(t)
+	env.runOnce(t)
+	for i := 0; i < notifyQueueMax; i++ {
+		env.m.Notify(ctx, env.m.NewNotice("notice"))
+	}
+	env.m.flushNotifications(ctx, true)
+	for _, r := range env.notifier.take() {
+		if len(r.Entries) > 0 {
+			t.Fatalf("dropped report was delivered: %+v", r)
+		}
+	}
+
+	env.now = env.now.Add(time.Minute)
+	env.runOnce(t)
+	if got := env.notifier.take(); len(got) != 1 || len(got[0].Entries) != 1 {
+		t.Errorf("after the report was dropped the next check sent %+v, want the report again", got)
+	}
+}
+
+// 暂缓的报告在释放时发送失败，不记录为已发送，下一轮巡检重新排队
+func TestHeldReportFailedDeliveryIsResent(t *testing.T) {
+	ctx := context.Background()
+	env := newLimitedEnv(t)
+	env.runOnce(t)
+	env.m.cfg.NotifyAttempts = 1
+	env.notifier.err = errors.New("webhook unavailable")
+	env.now = env.now.Add(time.Minute)
+	env.m.FlushNotifications(ctx)
+	if got := env.notifier.take(); len(got) != 1 || len(got[0].Entries) != 1 {
+		t.Fatalf("flush rendered %+v, want the held report", got)
+	}
+
+	env.notifier.err = nil
+	env.runOnce(t)
+	env.now = env.now.Add(time.Minute)
+	env.m.FlushNotifications(ctx)
+	if got := env.notifier.take(); len(got) != 1 || len(got[0].Entries) != 1 {
+		t.Errorf("after the failed delivery the next check sent %+v, want the report again", got)
+	}
+}
+
+// 合并发送后只有作为基础的最新报告和被列出的提醒算已送达，被替换的旧报告按未送达回调
+func TestSummarizedHeldDelivery(t *testing.T) {
+	env := newTestEnv(t, DefaultConfig(), Deps{})
+	got := make(map[string]bool)
+	held := func(name string, r Report) heldNotification {
+		return heldNotification{notifier: env.notifier, report: r, sent: func(_ context.Context, delivered bool) { got[name] = delivered }}
+	}
+	old := reportOf(testEntry("ns1", "a", "Failed", "critical"))
+	latest := reportOf(testEntry("ns1", "a", "Failed", "critical"), testEntry("ns1", "b", "Failed", "critical"))
+	merged := env.m.summarizeHeld([]heldNotification{
+		held("old report", old),
+		held("notice", env.m.NewNotice("started")),
+		held("latest report", latest),
+	})
+	if len(merged) != 1 || len(merged[0].report.Entries) != 2 {
+		t.Fatalf("summarized into %+v, want one notification with the latest report", merged)
+	}
+	merged[0].done(context.Background(), true)
+	want := map[string]bool{"old report": false, "notice": true, "latest report": true}
+	for name, delivered := range want {
+		if got[name] != delivered {
+			t.Errorf("%s delivered = %v, want %v", name, got[name], delivered)
+		}
+	}
+}
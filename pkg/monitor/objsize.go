@@ -0,0 +1,74 @@
+package monitor
+
+import "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+// 一些租户会在集群上挂很大的注解（CI 元数据等），超过该长度的注解在读取后立即丢弃
+const maxAnnotationBytes = 4 << 10
+
+// 裁剪后仍超过该大小的集群只评估 phase，不做 Pod 检查等补充
+const maxClusterObjectBytes = 256 << 10
+
+// 评估只需要 status 和少量 spec，这些注解总是去掉
+var droppedAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+}
+
+// trimCluster 去掉 managedFields 和过大的注解，在 List 或 informer 缓存之前调用
+func trimCluster(obj *unstructured.Unstructured) {
+	obj.SetManagedFields(nil)
+	annotations := obj.GetAnnotations()
+	if len(annotations) == 0 {
+		return
+	}
+	trimmed := false
+	for _, key := range droppedAnnotations {
+		if _, ok := annotations[key]; ok {
+			delete(annotations, key)
+			trimmed = true
+		}
+	}
+	for key, value := range annotations {
+		if len(value) > maxAnnotationBytes {
+			delete(annotations, key)
+			trimmed = true
+		}
+	}
+	if trimmed {
+		obj.SetAnnotations(annotations)
+	}
+}
+
+// informer 的 transform，缓存中只保存裁剪后的对象
+func trimClusterTransform(obj interface{}) (interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		trimCluster(u)
+	}
+	return obj, nil
+}
+
+// 对象大致的序列化大小，只累加键和值的长度，不做序列化，避免为超大对象再分配一份同样大的缓冲区
+func objectSize(obj *unstructured.Unstructured) int {
+	return valueSize(obj.Object)
+}
+
+func valueSize(v interface{}) int {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		n := 2
+		for key, value := range v {
+			n += len(key) + 4 + valueSize(value)
+		}
+		return n
+	case []interface{}:
+		n := 2
+		for _, value := range v {
+			n += valueSize(value) + 1
+		}
+		return n
+	case string:
+		return len(v) + 2
+	default:
+		// 数字、布尔值和 null
+		return 8
+	}
+}
@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const hugeBytes = 2 << 20
+
+// 带 2MB CI 元数据注解和 managedFields 的集群
+func annotatedCluster(namespace, name, phase string) *unstructured.Unstructured {
+	obj := testCluster(namespace, name, phase)
+	obj.SetAnnotations(map[string]string{
+		"ci.example.com/metadata": strings.Repeat("x", hugeBytes),
+		"owner":                   "team-db",
+	})
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "ci", Operation: metav1.ManagedFieldsOperationApply}})
+	return obj
+}
+
+// spec 本身有 2MB，裁剪不掉
+func oversizedCluster(namespace, name, phase string) *unstructured.Unstructured {
+	obj := testCluster(namespace, name, phase)
+	obj.Object["spec"] = map[string]interface{}{"blob": strings.Repeat("y", hugeBytes)}
+	return obj
+}
+
+func oomPod(namespace, cluster string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: cluster + "-0", Labels: map[string]string{instanceLabel: cluster}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "mysql",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"}},
+		}}},
+	}
+}
+
+func TestTrimCluster(t *testing.T) {
+	obj := annotatedCluster("ns1", "db", "Failed")
+	annotations := obj.GetAnnotations()
+	annotations["kubectl.kubernetes.io/last-applied-configuration"] = "{}"
+	obj.SetAnnotations(annotations)
+
+	trimCluster(obj)
+	if size := objectSize(obj); size > maxAnnotationBytes {
+		t.Errorf("trimmed object is %d bytes", size)
+	}
+	if got := obj.GetAnnotations(); len(got) != 1 || got["owner"] != "team-db" {
+		t.Errorf("annotations = %v, want only owner", got)
+	}
+	if obj.GetManagedFields() != nil {
+		t.Errorf("managedFields kept: %v", obj.GetManagedFields())
+	}
+	if phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase"); phase != "Failed" {
+		t.Errorf("phase = %q after trimming", phase)
+	}
+
+	cached, err := trimClusterTransform(annotatedCluster("ns1", "db", "Failed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := objectSize(cached.(*unstructured.Unstructured)); size > maxAnnotationBytes {
+		t.Errorf("informer would cache %d bytes", size)
+	}
+}
+
+// 2MB 的集群照常告警：注解过大的裁剪后正常补充 Pod 信息，裁剪不掉的跳过补充；巡检结束后不保留这些对象
+func TestRunOnceHugeClusters(t *testing.T) {
+	dynamic := newTestDynamic(
+		annotatedCluster("ns1", "annotated", "Failed"),
+		oversizedCluster("ns1", "oversized", "Failed"),
+	)
+	cfg := DefaultConfig()
+	cfg.AlertAfterChecks = 1
+	env := newTestEnv(t, cfg, Deps{Dynamic: dynamic}, oomPod("ns1", "annotated"), oomPod("ns1", "oversized"))
+	ctx := context.Background()
+	if err := env.m.RefreshDebt(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	report, err := env.m.RunOnce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	entries := make(map[string]ReportEntry)
+	for _, e := range report.Entries {
+		entries[e.Name] = e
+	}
+	if e, ok := entries["annotated"]; !ok || e.Phase != "Failed" || e.Reason != reasonOOM {
+		t.Errorf("annotated cluster entry = %+v, want Failed with OOM reason", e)
+	}
+	if e, ok := entries["oversized"]; !ok || e.Phase != "Failed" || e.Reason != "" || len(e.Pods) != 0 {
+		t.Errorf("oversized cluster entry = %+v, want Failed without enrichment", e)
+	}
+	if sent := env.notifier.take(); len(sent) != 1 || len(sent[0].Entries) != 2 {
+		t.Errorf("sent %d reports, want one alert for both clusters", len(sent))
+	}
+	if grown := int64(after.HeapAlloc) - int64(before.HeapAlloc); grown > hugeBytes/2 {
+		t.Errorf("heap grew by %d bytes after the check", grown)
+	}
+}
@@ -0,0 +1,176 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// KubeBlocks 运维操作（重启、升级、变配等）的 GVR
+var opsRequestsGVR = schema.GroupVersionResource{
+	Group:    "apps.kubeblocks.io",
+	Version:  "v1alpha1",
+	Resource: "opsrequests",
+}
+
+// 结束超过该时长的失败操作不再提醒，避免首次启动时把历史上的失败全部发一遍
+const opsFailureMaxAge = 24 * time.Hour
+
+const (
+	opsProblemFailed = "failed"
+	opsProblemStuck  = "stuck"
+)
+
+// opsTracker 记录已提醒过的操作及其问题，同一操作的同一问题只提醒一次
+type opsTracker struct {
+	mu sync.Mutex
+	// key 为 namespace/name/uid
+	notified map[string]string
+}
+
+// opsProblem 一个失败或运行超时的运维操作
+type opsProblem struct {
+	key     string
+	problem string
+	line    string
+}
+
+// 列出所有运维操作，找出 Failed 的和 Running 超过 OpsRunningTimeout 的
+func (m *Monitor) auditOps(ctx context.Context) ([]opsProblem, map[string]bool, error) {
+	now := m.now()
+	var problems []opsProblem
+	seen := make(map[string]bool)
+	err := m.listAll(ctx, m.api().opsRequests, func(obj *unstructured.Unstructured) {
+		if !m.namespaceAllowed(obj.GetNamespace()) {
+			return
+		}
+		key := obj.GetNamespace() + "/" + obj.GetName() + "/" + string(obj.GetUID())
+		seen[key] = true
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		desc := describeOps(obj)
+		switch phase {
+		case "Failed":
+			if completed, ok := opsTime(obj, "completionTimestamp"); ok && now.Sub(completed) > opsFailureMaxAge {
+				return
+			}
+			line := desc + " failed"
+			if msg := opsMessage(obj); msg != "" {
+				line += ": " + msg
+			}
+			problems = append(problems, opsProblem{key: key, problem: opsProblemFailed, line: line})
+		case "Running":
+			started, ok := opsTime(obj, "startTimestamp")
+			if !ok {
+				started = obj.GetCreationTimestamp().Time
+			}
+			if running := now.Sub(started); running > m.cfg.OpsRunningTimeout {
+				problems = append(problems, opsProblem{key: key, problem: opsProblemStuck,
+					line: fmt.Sprintf("%s has been running for %s, longer than %s", desc, running.Round(time.Minute), m.cfg.OpsRunningTimeout)})
+			}
+		}
+	})
+	return problems, seen, err
+}
+
+// 例如 "OpsRequest ns1/restart-x (Restart of cluster mysql-a)"
+func describeOps(obj *unstructured.Unstructured) string {
+	opsType, _, _ := unstructured.NestedString(obj.Object, "spec", "type")
+	// KubeBlocks 0.9 起字段名为 clusterName，之前为 clusterRef
+	cluster, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterName")
+	if cluster == "" {
+		cluster, _, _ = unstructured.NestedString(obj.Object, "spec", "clusterRef")
+	}
+	desc := fmt.Sprintf("OpsRequest %s/%s", obj.GetNamespace(), obj.GetName())
+	switch {
+	case opsType != "" && cluster != "":
+		desc += fmt.Sprintf(" (%s of cluster %s)", opsType, cluster)
+	case opsType != "":
+		desc += fmt.Sprintf(" (%s)", opsType)
+	}
+	return desc
+}
+
+func opsTime(obj *unstructured.Unstructured, field string) (time.Time, bool) {
+	v, _, _ := unstructured.NestedString(obj.Object, "status", field)
+	t, err := time.Parse(time.RFC3339, v)
+	return t, err == nil
+}
+
+// 最后一个带消息的 condition，通常说明了失败原因
+func opsMessage(obj *unstructured.Unstructured) string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for i := len(conditions) - 1; i >= 0; i-- {
+		c, ok := conditions[i].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if msg, _ := c["message"].(string); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+// CheckOps 立即检查一次运维操作。Run 会定期检查；只调用 RunOnce 的嵌入方需要自行调用
+func (m *Monitor) CheckOps(ctx context.Context) error {
+	return m.checkOps(ctx)
+}
+
+// 检查一次运维操作，新出现的问题合并为一条通知发送
+func (m *Monitor) checkOps(ctx context.Context) error {
+	problems, seen, err := m.auditOps(ctx)
+	if err != nil {
+		return err
+	}
+	t := &m.ops
+	t.mu.Lock()
+	var lines []string
+	for _, p := range problems {
+		if t.notified[p.key] == p.problem {
+			continue
+		}
+		t.notified[p.key] = p.problem
+		lines = append(lines, p.line)
+	}
+	// 已删除的操作不再记录
+	for key := range t.notified {
+		if !seen[key] {
+			delete(t.notified, key)
+		}
+	}
+	t.mu.Unlock()
+
+	if len(lines) == 0 {
+		return nil
+	}
+	sort.Strings(lines)
+	m.log.Warn("OpsRequests need attention", "count", len(lines))
+	m.Notify(ctx, m.NewNotice("KubeBlocks operations need attention:\n"+strings.Join(lines, "\n")))
+	return nil
+}
+
+// 定期检查运维操作；未安装 OpsRequest CRD 时只打印一次日志
+func (m *Monitor) startOpsLoop(ctx context.Context) {
+	if m.cfg.OpsCheckInterval <= 0 {
+		return
+	}
+	missingLogged := false
+	m.until(ctx, m.withCycleTimeout("ops", func(ctx context.Context) {
+		err := m.checkOps(ctx)
+		switch {
+		case err != nil && isMissingCRD(err):
+			if !missingLogged {
+				missingLogged = true
+				m.log.Info("KubeBlocks OpsRequest CRD not installed, skipping operation checks")
+			}
+		case err != nil:
+			m.log.Error("Error checking OpsRequests", "err", err)
+		}
+	}), m.cfg.OpsCheckInterval)
+}
@@ -0,0 +1,58 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// 巡检失败后第一次重试的间隔，之后每次翻倍，不超过 CheckInterval
+const checkRetryBackoff = 5 * time.Second
+
+// apiOutage 巡检连续失败的情况，只由 Run 的主循环访问
+type apiOutage struct {
+	failures int
+	since    time.Time
+	alerted  bool
+}
+
+// 记录一次失败的巡检并返回重试前的等待时间。连续失败超过 APIOutageAlertAfter 时发送一次监控自身的告警，
+// 不再像以前那样退出进程：API server 短暂不可用时重启也无济于事
+func (m *Monitor) checkFailed(ctx context.Context, err error) time.Duration {
+	o := &m.outage
+	now := m.now()
+	if o.failures == 0 {
+		o.since = now
+	}
+	o.failures++
+	m.metrics.checkFailures.Inc()
+	delay := checkRetryBackoff << min(o.failures-1, 10)
+	if delay > m.cfg.CheckInterval {
+		delay = m.cfg.CheckInterval
+	}
+	m.log.Error("Error running check, retrying", "attempt", o.failures, "retryIn", delay, "err", err)
+	// 重试期间主循环仍在推进，存活探针不应重启进程
+	m.expectCheck(time.Now().Add(delay))
+
+	if !o.alerted && m.cfg.APIOutageAlertAfter > 0 && now.Sub(o.since) >= m.cfg.APIOutageAlertAfter {
+		o.alerted = true
+		m.log.Warn("Checks keep failing, sending a monitor alert", "since", o.since, "attempts", o.failures)
+		m.Notify(ctx, m.NewNotice(fmt.Sprintf("Monitor cannot reach the API server, checks have failed for %s (%d attempts)\nlast error: %v",
+			now.Sub(o.since).Round(time.Second), o.failures, err)))
+	}
+	return delay
+}
+
+// 巡检重新成功，发送过告警时通知恢复
+func (m *Monitor) checkSucceeded(ctx context.Context) {
+	o := &m.outage
+	if o.failures == 0 {
+		return
+	}
+	down := m.now().Sub(o.since).Round(time.Second)
+	m.log.Info("Check succeeded again", "failedFor", down, "attempts", o.failures)
+	if o.alerted {
+		m.Notify(ctx, m.NewNotice(fmt.Sprintf("Monitor reached the API server again, checks failed for %s", down)))
+	}
+	*o = apiOutage{}
+}
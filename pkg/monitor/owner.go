@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// namespaceOwners 从 ns 注解读取的负责人信息，每个 ns 最多每个 CheckInterval 读取一次
+type namespaceOwners struct {
+	mu   sync.Mutex
+	byNS map[string]namespaceOwner
+}
+
+type namespaceOwner struct {
+	owner    string
+	mentions []string
+	at       time.Time
+}
+
+// 给报告中的条目附上所在 ns 的负责人信息；读取失败时沿用上次的结果
+func (m *Monitor) annotateOwners(ctx context.Context, r *Report) {
+	if len(r.Entries) == 0 || len(m.cfg.OwnerAnnotations) == 0 && m.cfg.OwnerMentionAnnotation == "" {
+		return
+	}
+	o := &m.owners
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.byNS == nil {
+		o.byNS = make(map[string]namespaceOwner)
+	}
+	now := m.now()
+	for i := range r.Entries {
+		e := &r.Entries[i]
+		owner, ok := o.byNS[e.Namespace]
+		if !ok || now.Sub(owner.at) >= m.cfg.CheckInterval {
+			fresh, err := m.namespaceOwner(ctx, e.Namespace)
+			if err != nil {
+				m.log.Warn("Error reading namespace owner", "namespace", e.Namespace, "err", err)
+			} else {
+				fresh.at = now
+				owner = fresh
+				o.byNS[e.Namespace] = fresh
+			}
+		}
+		e.Owner, e.OwnerMentions = owner.owner, owner.mentions
+	}
+}
+
+// 按 OwnerAnnotations 的顺序读取 ns 的注解，没有该注解时读取同名标签，例如 sealos 的 user.sealos.io/owner。
+// OwnerMentionAnnotation 中的飞书 user ID 以逗号分隔；ns 不存在时为空
+func (m *Monitor) namespaceOwner(ctx context.Context, namespace string) (namespaceOwner, error) {
+	if err := m.budget.Wait(ctx); err != nil {
+		return namespaceOwner{}, err
+	}
+	ns, err := m.kube.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return namespaceOwner{}, nil
+	}
+	if err != nil {
+		return namespaceOwner{}, err
+	}
+	var parts []string
+	for _, key := range m.cfg.OwnerAnnotations {
+		value, ok := ns.Annotations[key]
+		if !ok {
+			value = ns.Labels[key]
+		}
+		if value != "" {
+			parts = append(parts, key+"="+value)
+		}
+	}
+	var owner namespaceOwner
+	owner.owner = strings.Join(parts, ", ")
+	if m.cfg.OwnerMentionAnnotation != "" {
+		for _, id := range strings.Split(ns.Annotations[m.cfg.OwnerMentionAnnotation], ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				owner.mentions = append(owner.mentions, id)
+			}
+		}
+		sort.Strings(owner.mentions)
+	}
+	return owner, nil
+}
@@ -0,0 +1,120 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KubeBlocks 给集群下的 Pod 打的实例标签
+const instanceLabel = "app.kubernetes.io/instance"
+
+// oomKill 一次容器 OOMKill
+type oomKill struct {
+	Pod         string
+	Container   string
+	FinishedAt  time.Time
+	MemoryLimit string
+	Restarts    int32
+}
+
+func (k oomKill) id() string {
+	return fmt.Sprintf("%s/%s@%d", k.Pod, k.Container, k.FinishedAt.Unix())
+}
+
+// podFindings 检查集群 Pod 得到的信息
+type podFindings struct {
+	OOMKills []oomKill
+}
+
+// oomSummary 返回用于告警行前缀的 OOMKill 描述，没有时返回空字符串
+func (f podFindings) oomSummary() string {
+	if len(f.OOMKills) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(f.OOMKills))
+	for _, k := range f.OOMKills {
+		limit := k.MemoryLimit
+		if limit == "" {
+			limit = "none"
+		}
+		parts = append(parts, fmt.Sprintf("%s/%s limit %s, %d restarts", k.Pod, k.Container, limit, k.Restarts))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// 汇总每个 Pod 的 phase、重启次数和最近一次容器终止原因，按名称排序
+func podStatuses(pods []corev1.Pod) []PodStatus {
+	statuses := make([]PodStatus, 0, len(pods))
+	for _, pod := range pods {
+		status := PodStatus{Name: pod.Name, Phase: string(pod.Status.Phase)}
+		if pod.DeletionTimestamp != nil {
+			status.Phase = "Terminating"
+		}
+		var last *corev1.ContainerStateTerminated
+		for _, cs := range pod.Status.ContainerStatuses {
+			status.Restarts += cs.RestartCount
+			for _, t := range []*corev1.ContainerStateTerminated{cs.LastTerminationState.Terminated, cs.State.Terminated} {
+				if t != nil && (last == nil || t.FinishedAt.After(last.FinishedAt.Time)) {
+					last = t
+				}
+			}
+		}
+		if last != nil {
+			reason := last.Reason
+			if reason == "" {
+				reason = "Terminated"
+			}
+			status.LastTermination = fmt.Sprintf("%s(exit %d)", reason, last.ExitCode)
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (m *Monitor) listClusterPods(ctx context.Context, namespace, name string) ([]corev1.Pod, error) {
+	if err := m.budget.Wait(ctx); err != nil {
+		return nil, err
+	}
+	pods, err := m.kube.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: instanceLabel + "=" + name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// 检查集群的 Pod，since 之后发生的 OOMKill 才会被统计
+func inspectPods(pods []corev1.Pod, since time.Time) podFindings {
+	var findings podFindings
+	for _, pod := range pods {
+		limits := make(map[string]string)
+		for _, c := range pod.Spec.Containers {
+			if q, ok := c.Resources.Limits[corev1.ResourceMemory]; ok {
+				limits[c.Name] = q.String()
+			}
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			for _, t := range []*corev1.ContainerStateTerminated{cs.LastTerminationState.Terminated, cs.State.Terminated} {
+				if t == nil || t.Reason != "OOMKilled" || t.FinishedAt.Time.Before(since) {
+					continue
+				}
+				findings.OOMKills = append(findings.OOMKills, oomKill{
+					Pod:         pod.Name,
+					Container:   cs.Name,
+					FinishedAt:  t.FinishedAt.Time,
+					MemoryLimit: limits[cs.Name],
+					Restarts:    cs.RestartCount,
+				})
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,58 @@
+package monitor
+
+// 严重程度
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// PhasePolicy 决定集群 phase 的含义
+type PhasePolicy interface {
+	// Healthy 返回处于该 phase 的集群是否无需关注
+	Healthy(phase string) bool
+	// Severity 返回该 phase 的严重程度
+	Severity(phase string) string
+}
+
+// DefaultPhasePolicy Running/Stopped 视为健康，Failed 为 critical，Abnormal 为 warning，其余为 info
+func DefaultPhasePolicy() PhasePolicy {
+	return defaultPhasePolicy{}
+}
+
+type defaultPhasePolicy struct{}
+
+func (defaultPhasePolicy) Healthy(phase string) bool {
+	return phase == "Running" || phase == "Stopped"
+}
+
+func (defaultPhasePolicy) Severity(phase string) string {
+	switch phaseClass(phase) {
+	case "failed":
+		return SeverityCritical
+	case "abnormal", "deleting":
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// 故障类的 phase，Creating、Updating 等过渡状态和删除中都不算
+func failurePhase(phase string) bool {
+	class := phaseClass(phase)
+	return class == "failed" || class == "abnormal"
+}
+
+// 把具体 phase 归为几类，事件身份只关心类别而不关心细节
+func phaseClass(phase string) string {
+	switch phase {
+	case "Failed":
+		return "failed"
+	case "Abnormal":
+		return "abnormal"
+	case "Deleting":
+		return "deleting"
+	default:
+		return "transitional"
+	}
+}
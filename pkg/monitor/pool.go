@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// evaluatePool 轮询模式下用 Workers 个 goroutine 并发评估集群，Pod 查询等 API 调用不再串行等待；
+// 并发的 API 调用仍然共享 budget 令牌桶。结果按提交顺序汇总，报告与串行评估时一致
+type evaluatePool struct {
+	jobs chan evaluateJob
+	wg   sync.WaitGroup
+
+	mu      sync.Mutex
+	results []evaluateResult
+	next    int
+}
+
+type evaluateJob struct {
+	seq     int
+	res     *monitoredResource
+	cluster *unstructured.Unstructured
+}
+
+type evaluateResult struct {
+	seq   int
+	entry ReportEntry
+}
+
+func (m *Monitor) newEvaluatePool(ctx context.Context) *evaluatePool {
+	workers := m.cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	// 队列长度和 worker 数相同，评估跟不上时阻塞分页 List，同时在内存中的集群对象有上限
+	p := &evaluatePool{jobs: make(chan evaluateJob, workers)}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer m.crashed()
+			defer p.wg.Done()
+			for job := range p.jobs {
+				if entry := m.evaluateCluster(ctx, job.res, job.cluster); entry != nil {
+					p.mu.Lock()
+					p.results = append(p.results, evaluateResult{seq: job.seq, entry: *entry})
+					p.mu.Unlock()
+				}
+			}
+		}()
+	}
+	return p
+}
+
+// submit 只由 List 的回调调用
+func (p *evaluatePool) submit(res *monitoredResource, cluster *unstructured.Unstructured) {
+	p.jobs <- evaluateJob{seq: p.next, res: res, cluster: cluster}
+	p.next++
+}
+
+// wait 等待所有集群评估完成，返回需要报告的条目
+func (p *evaluatePool) wait() []ReportEntry {
+	close(p.jobs)
+	p.wg.Wait()
+	sort.Slice(p.results, func(i, j int) bool { return p.results[i].seq < p.results[j].seq })
+	entries := make([]ReportEntry, 0, len(p.results))
+	for _, r := range p.results {
+		entries = append(entries, r.entry)
+	}
+	return entries
+}
@@ -0,0 +1,336 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"database-monitor/pkg/redact"
+)
+
+// 支持主动探测的数据库引擎
+const (
+	engineMySQL    = "mysql"
+	enginePostgres = "postgresql"
+)
+
+const (
+	probeProblemUnreachable = "unreachable"
+	probeProblemSlow        = "slow"
+)
+
+// 各引擎可用的 database/sql 驱动名，按顺序取第一个已注册的。
+// 驱动由 main 包的 drivers.go 或嵌入方以 blank import 注册；都没有注册时只检查 TCP 连接
+var probeDrivers = map[string][]string{
+	engineMySQL:    {"mysql"},
+	enginePostgres: {"pgx", "postgres"},
+}
+
+// probeTracker 记录已提醒过的集群及其问题，同一问题只提醒一次，探测恢复后清除
+type probeTracker struct {
+	mu       sync.Mutex
+	notified map[string]string
+}
+
+// probeTarget 一个待探测的集群
+type probeTarget struct {
+	Namespace string
+	Name      string
+	Engine    string
+	Host      string
+	Port      string
+	User      string
+	Password  string
+}
+
+// probeResult 一次探测的结果
+type probeResult struct {
+	target  probeTarget
+	latency time.Duration
+	// 使用的方式：驱动名或 tcp
+	method string
+	err    error
+	// 复制延迟检查发现的问题
+	replication []probeProblem
+}
+
+// probeProblem 探测发现的一个问题；key 为 namespace/name，复制问题再加上成员名
+type probeProblem struct {
+	key     string
+	problem string
+	line    string
+}
+
+// 由集群引用的 ClusterDefinition 或组件的 componentDef 判断引擎，不支持的返回空字符串
+func clusterEngine(api *kubeblocksAPI, cluster *unstructured.Unstructured) string {
+	refs := []string{}
+	if ref, _, _ := unstructured.NestedString(cluster.Object, api.clusterDefPath...); ref != "" {
+		refs = append(refs, ref)
+	}
+	comps, _, _ := unstructured.NestedSlice(cluster.Object, "spec", "componentSpecs")
+	for _, c := range comps {
+		if c, ok := c.(map[string]interface{}); ok {
+			if def, _ := c["componentDef"].(string); def != "" {
+				refs = append(refs, def)
+			}
+		}
+	}
+	for _, ref := range refs {
+		switch ref := strings.ToLower(ref); {
+		case strings.Contains(ref, "mysql"):
+			return engineMySQL
+		case strings.Contains(ref, "postgres"):
+			return enginePostgres
+		}
+	}
+	return ""
+}
+
+// 找出 Running 的 MySQL/PostgreSQL 集群，从连接凭据 Secret 和集群的 Service 得到探测地址
+func (m *Monitor) probeTargets(ctx context.Context) ([]probeTarget, error) {
+	var targets []probeTarget
+	api := m.api()
+	err := m.listAll(ctx, api.clusters, func(cluster *unstructured.Unstructured) {
+		if !m.namespaceAllowed(cluster.GetNamespace()) || cluster.GetDeletionTimestamp() != nil {
+			return
+		}
+		phase, _, _ := unstructured.NestedString(cluster.Object, "status", "phase")
+		if phase != "Running" {
+			return
+		}
+		if engine := clusterEngine(api, cluster); engine != "" {
+			targets = append(targets, probeTarget{Namespace: cluster.GetNamespace(), Name: cluster.GetName(), Engine: engine})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := targets[:0]
+	for _, t := range targets {
+		if err := m.resolveProbeTarget(ctx, &t); err != nil {
+			m.log.Warn("Unable to resolve probe target", "cluster", t.Name, "namespace", t.Namespace, "err", err)
+			continue
+		}
+		resolved = append(resolved, t)
+	}
+	return resolved, nil
+}
+
+// 从 <cluster>-conn-credential 读取账号和端口，地址取集群非 headless 的 Service
+func (m *Monitor) resolveProbeTarget(ctx context.Context, t *probeTarget) error {
+	if err := m.budget.Wait(ctx); err != nil {
+		return err
+	}
+	secret, err := m.kube.CoreV1().Secrets(t.Namespace).Get(ctx, t.Name+connCredentialSuffix, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	t.User, t.Password = string(secret.Data["username"]), string(secret.Data["password"])
+	t.Port = string(secret.Data["port"])
+
+	if err := m.budget.Wait(ctx); err != nil {
+		return err
+	}
+	services, err := m.kube.CoreV1().Services(t.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: instanceLabel + "=" + t.Name,
+	})
+	if err != nil {
+		return err
+	}
+	var svc *corev1.Service
+	for i := range services.Items {
+		s := &services.Items[i]
+		if s.Spec.ClusterIP == corev1.ClusterIPNone || len(s.Spec.Ports) == 0 {
+			continue
+		}
+		if svc == nil || servicePort(s, t.Port) != "" && servicePort(svc, t.Port) == "" {
+			svc = s
+		}
+	}
+	if svc == nil {
+		return fmt.Errorf("no service found for cluster %s", t.Name)
+	}
+	if t.Port = servicePort(svc, t.Port); t.Port == "" {
+		t.Port = strconv.Itoa(int(svc.Spec.Ports[0].Port))
+	}
+	t.Host = svc.Name + "." + svc.Namespace + ".svc"
+	return nil
+}
+
+// Service 上与 want 相同的端口，want 为空或没有时返回空字符串
+func servicePort(svc *corev1.Service, want string) string {
+	for _, p := range svc.Spec.Ports {
+		if port := strconv.Itoa(int(p.Port)); port == want {
+			return port
+		}
+	}
+	return ""
+}
+
+// 注册了驱动时连接并执行 SELECT 1，否则只建立 TCP 连接
+func (m *Monitor) probe(ctx context.Context, t probeTarget) probeResult {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.ProbeTimeout)
+	defer cancel()
+	result := probeResult{target: t, method: "tcp"}
+	start := time.Now()
+	driver, dsn := probeDSN(t, m.cfg.ProbeTimeout)
+	if driver == "" {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(t.Host, t.Port))
+		if err == nil {
+			conn.Close()
+		}
+		result.latency, result.err = time.Since(start), err
+		return result
+	}
+	result.method = driver
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		result.err = t.scrub(err)
+		return result
+	}
+	defer db.Close()
+	var one int
+	result.err = t.scrub(db.QueryRowContext(ctx, "SELECT 1").Scan(&one))
+	result.latency = time.Since(start)
+	return result
+}
+
+// 驱动的错误中可能带有连接串，只在这里隐藏本集群的密码及其转义形式；
+// 不登记到全局的 redactor，否则集群越多登记的值越多，且集群删除后也不会移除
+func (t probeTarget) scrub(err error) error {
+	if err == nil || t.Password == "" {
+		return err
+	}
+	r := redact.New()
+	r.Add(t.Password, url.QueryEscape(t.Password), url.PathEscape(t.Password),
+		strings.TrimPrefix(url.UserPassword("", t.Password).String(), ":"))
+	msg := err.Error()
+	if scrubbed := r.String(msg); scrubbed != msg {
+		return errors.New(scrubbed)
+	}
+	return err
+}
+
+// 引擎对应的已注册驱动及连接串，没有可用驱动时返回空字符串
+func probeDSN(t probeTarget, timeout time.Duration) (string, string) {
+	registered := make(map[string]bool)
+	for _, d := range sql.Drivers() {
+		registered[d] = true
+	}
+	for _, driver := range probeDrivers[t.Engine] {
+		if !registered[driver] {
+			continue
+		}
+		addr := net.JoinHostPort(t.Host, t.Port)
+		switch t.Engine {
+		case engineMySQL:
+			return driver, fmt.Sprintf("%s:%s@tcp(%s)/?timeout=%s", t.User, t.Password, addr, timeout)
+		case enginePostgres:
+			u := url.URL{Scheme: "postgres", User: url.UserPassword(t.User, t.Password), Host: addr, Path: "/postgres",
+				RawQuery: fmt.Sprintf("sslmode=prefer&connect_timeout=%d", int(timeout.Seconds()+0.5))}
+			return driver, u.String()
+		}
+	}
+	return "", ""
+}
+
+// 并发探测所有集群，新出现的连接失败、延迟过高和复制延迟合并为一条通知发送
+func (m *Monitor) probeClusters(ctx context.Context) error {
+	targets, err := m.probeTargets(ctx)
+	if err != nil {
+		return err
+	}
+	workers := m.cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]probeResult, len(targets))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, t probeTarget) {
+			defer m.crashed()
+			defer wg.Done()
+			results[i] = m.probe(ctx, t)
+			if results[i].err == nil && results[i].method != "tcp" && m.cfg.ReplicationLagThreshold > 0 {
+				results[i].replication = m.checkReplication(ctx, t)
+			}
+			<-sem
+		}(i, t)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var problems []probeProblem
+	for _, r := range results {
+		key := clusterKey(r.target.Namespace, r.target.Name)
+		m.metrics.probeLatency.WithLabelValues(r.target.Engine).Observe(r.latency.Seconds())
+		switch {
+		case r.err != nil:
+			problems = append(problems, probeProblem{key: key, problem: probeProblemUnreachable,
+				line: fmt.Sprintf("%s cluster %s is unreachable via %s (%s:%s): %v", r.target.Engine, key, r.method, r.target.Host, r.target.Port, r.err)})
+		case m.cfg.ProbeLatencyThreshold > 0 && r.latency > m.cfg.ProbeLatencyThreshold:
+			problems = append(problems, probeProblem{key: key, problem: probeProblemSlow,
+				line: fmt.Sprintf("%s cluster %s answered in %s via %s, slower than %s", r.target.Engine, key, r.latency.Round(time.Millisecond), r.method, m.cfg.ProbeLatencyThreshold)})
+		}
+		problems = append(problems, r.replication...)
+	}
+
+	pt := &m.probes
+	pt.mu.Lock()
+	var lines []string
+	current := make(map[string]bool, len(problems))
+	for _, p := range problems {
+		current[p.key] = true
+		if pt.notified[p.key] == p.problem {
+			continue
+		}
+		pt.notified[p.key] = p.problem
+		lines = append(lines, p.line)
+	}
+	// 已恢复的问题下次出现时重新提醒；不再 Running 或已删除的集群由巡检告警负责
+	for key := range pt.notified {
+		if !current[key] {
+			delete(pt.notified, key)
+		}
+	}
+	pt.mu.Unlock()
+
+	if len(lines) == 0 {
+		return nil
+	}
+	sort.Strings(lines)
+	m.log.Warn("Database probes failed", "count", len(lines))
+	m.Notify(ctx, m.NewNotice("Database connectivity probes failed:\n"+strings.Join(lines, "\n")))
+	return nil
+}
+
+// 定期探测；ProbeInterval 为 0 时不探测
+func (m *Monitor) startProbeLoop(ctx context.Context) {
+	if m.cfg.ProbeInterval <= 0 {
+		return
+	}
+	m.until(ctx, m.withCycleTimeout("probes", func(ctx context.Context) {
+		if err := m.probeClusters(ctx); err != nil && ctx.Err() == nil {
+			m.log.Error("Error probing database clusters", "err", err)
+		}
+	}), m.cfg.ProbeInterval)
+}
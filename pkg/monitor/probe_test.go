@@ -0,0 +1,231 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"database-monitor/pkg/redact"
+)
+
+// fakeSQL 以 mysql 为名注册的 database/sql 驱动，记录每个连接串上执行的查询，
+// 按 respond 返回结果。测试二进制中没有真实的 mysql 驱动
+var fakeSQL = &fakeSQLDriver{}
+
+func init() {
+	sql.Register("mysql", fakeSQL)
+}
+
+type fakeSQLDriver struct {
+	mu      sync.Mutex
+	queries []string
+	respond func(dsn, query string) ([]string, [][]driver.Value, error)
+}
+
+// 重置记录，respond 为 nil 时所有查询返回一行 1
+func (d *fakeSQLDriver) reset(respond func(dsn, query string) ([]string, [][]driver.Value, error)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries, d.respond = nil, respond
+}
+
+func (d *fakeSQLDriver) executed() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.queries...)
+}
+
+func (d *fakeSQLDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeSQLConn{driver: d, dsn: dsn}, nil
+}
+
+type fakeSQLConn struct {
+	driver *fakeSQLDriver
+	dsn    string
+}
+
+func (c *fakeSQLConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeSQLConn) Close() error                        { return nil }
+func (c *fakeSQLConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *fakeSQLConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	d := c.driver
+	d.mu.Lock()
+	d.queries = append(d.queries, query)
+	respond := d.respond
+	d.mu.Unlock()
+	if respond == nil {
+		return &fakeSQLRows{cols: []string{"1"}, rows: [][]driver.Value{{int64(1)}}}, nil
+	}
+	cols, rows, err := respond(c.dsn, query)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeSQLRows{cols: cols, rows: rows}, nil
+}
+
+type fakeSQLRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeSQLRows) Columns() []string { return r.cols }
+func (r *fakeSQLRows) Close() error      { return nil }
+
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// 一个 Running 的 MySQL 集群及其连接凭据、Service 和 Pod
+func mysqlProbeEnv(t *testing.T, cfg Config, deps Deps, podIPs ...string) *testEnv {
+	t.Helper()
+	cluster := testCluster("ns1", "db", "Running")
+	unstructured.SetNestedSlice(cluster.Object, []interface{}{
+		map[string]interface{}{"name": "mysql", "componentDef": "apecloud-mysql"},
+	}, "spec", "componentSpecs")
+	objects := []runtime.Object{
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "db" + connCredentialSuffix},
+			Data: map[string][]byte{"username": []byte("root"), "password": []byte("s3cr3t/pass"), "port": []byte("3306")}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "db-mysql", Labels: map[string]string{instanceLabel: "db"}},
+			Spec: corev1.ServiceSpec{ClusterIP: "10.0.0.1", Ports: []corev1.ServicePort{{Port: 3306}}}},
+	}
+	for i, ip := range podIPs {
+		objects = append(objects, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "db-mysql-" + string(rune('0'+i)), Labels: map[string]string{instanceLabel: "db"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: ip},
+		})
+	}
+	deps.Dynamic = newTestDynamic(cluster)
+	return newTestEnv(t, cfg, deps, objects...)
+}
+
+func TestProbeRunsSelectOneWithDriver(t *testing.T) {
+	fakeSQL.reset(nil)
+	env := mysqlProbeEnv(t, DefaultConfig(), Deps{})
+	targets, err := env.m.probeTargets(context.Background())
+	if err != nil || len(targets) != 1 {
+		t.Fatalf("probeTargets = %v, %v; want one target", targets, err)
+	}
+	result := env.m.probe(context.Background(), targets[0])
+	if result.err != nil {
+		t.Fatalf("probe error: %v", result.err)
+	}
+	if result.method != "mysql" {
+		t.Errorf("probe method = %q, want mysql", result.method)
+	}
+	if got := fakeSQL.executed(); len(got) != 1 || got[0] != "SELECT 1" {
+		t.Errorf("queries = %q, want [SELECT 1]", got)
+	}
+}
+
+func TestProbeReportsReplicationLag(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ReplicationLagThreshold = 30 * time.Second
+	fakeSQL.reset(func(dsn, query string) ([]string, [][]driver.Value, error) {
+		switch {
+		case query == "SELECT 1":
+			return []string{"1"}, [][]driver.Value{{int64(1)}}, nil
+		case strings.Contains(dsn, "10.1.0.2"):
+			// 副本落后 120 秒
+			return []string{"Replica_IO_Running", "Seconds_Behind_Source"}, [][]driver.Value{{"Yes", "120"}}, nil
+		case strings.Contains(dsn, "10.1.0.3"):
+			// 副本的复制线程已停止
+			return []string{"Replica_IO_Running", "Seconds_Behind_Source"}, [][]driver.Value{{"No", nil}}, nil
+		default:
+			// 主库没有复制状态
+			return []string{"Replica_IO_Running", "Seconds_Behind_Source"}, nil, nil
+		}
+	})
+	env := mysqlProbeEnv(t, cfg, Deps{}, "10.1.0.1", "10.1.0.2", "10.1.0.3")
+	if err := env.m.probeClusters(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	reports := env.notifier.take()
+	if len(reports) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(reports))
+	}
+	notice := reports[0].Notice
+	for _, want := range []string{
+		"mysql cluster ns1/db replica db-mysql-1 is 120s behind, more than 30s",
+		"mysql cluster ns1/db replica db-mysql-2 is not replicating",
+	} {
+		if !strings.Contains(notice, want) {
+			t.Errorf("notice %q does not contain %q", notice, want)
+		}
+	}
+	if strings.Contains(notice, "db-mysql-0") {
+		t.Errorf("notice %q mentions the primary", notice)
+	}
+
+	// 同一问题只提醒一次
+	if err := env.m.probeClusters(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if reports := env.notifier.take(); len(reports) != 0 {
+		t.Errorf("got %d notifications for unchanged problems, want 0", len(reports))
+	}
+}
+
+func TestProbeLagBelowThresholdIsQuiet(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ReplicationLagThreshold = 30 * time.Second
+	fakeSQL.reset(func(dsn, query string) ([]string, [][]driver.Value, error) {
+		if query == "SELECT 1" {
+			return []string{"1"}, [][]driver.Value{{int64(1)}}, nil
+		}
+		if strings.Contains(dsn, "10.1.0.2") {
+			return []string{"Seconds_Behind_Source"}, [][]driver.Value{{"5"}}, nil
+		}
+		return []string{"Seconds_Behind_Source"}, nil, nil
+	})
+	env := mysqlProbeEnv(t, cfg, Deps{}, "10.1.0.1", "10.1.0.2")
+	if err := env.m.probeClusters(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if reports := env.notifier.take(); len(reports) != 0 {
+		t.Errorf("got notice %q, want none", reports[0].Notice)
+	}
+}
+
+// 连接串中的密码不出现在探测结果和通知中，也不登记到全局的 redactor
+func TestProbeErrorHidesPasswordWithoutGlobalRedactor(t *testing.T) {
+	fakeSQL.reset(func(dsn, query string) ([]string, [][]driver.Value, error) {
+		return nil, nil, errors.New("connect " + dsn + " (" + url.QueryEscape("s3cr3t/pass") + "): refused")
+	})
+	redactor := redact.New()
+	env := mysqlProbeEnv(t, DefaultConfig(), Deps{Redactor: redactor})
+	if err := env.m.probeClusters(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	reports := env.notifier.take()
+	if len(reports) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(reports))
+	}
+	notice := reports[0].Notice
+	if !strings.Contains(notice, "unreachable via mysql") {
+		t.Errorf("notice %q does not report the failed probe", notice)
+	}
+	if strings.Contains(notice, "s3cr3t") {
+		t.Errorf("notice %q contains the password", notice)
+	}
+	if got := redactor.String("s3cr3t/pass"); got != "s3cr3t/pass" {
+		t.Errorf("cluster password registered in the global redactor: %q", got)
+	}
+}
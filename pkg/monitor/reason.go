@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// 故障原因类别。Pod 的原始 reason/message 变化很频繁，归一化后再比较，
+// 避免文案上的差异触发重复提醒
+const (
+	reasonScheduling = "scheduling"
+	reasonImage      = "image"
+	reasonStorage    = "storage"
+	reasonOOM        = "oom"
+	reasonCrash      = "crash"
+	reasonUnknown    = "unknown"
+)
+
+// 多个 Pod 原因不同时取优先级最高的类别，越靠前越优先
+var reasonPriority = []string{reasonOOM, reasonCrash, reasonImage, reasonStorage, reasonScheduling}
+
+// 容器等待或终止的 reason 到类别的映射
+var containerReasonCategory = map[string]string{
+	"OOMKilled":                  reasonOOM,
+	"CrashLoopBackOff":           reasonCrash,
+	"Error":                      reasonCrash,
+	"RunContainerError":          reasonCrash,
+	"CreateContainerError":       reasonCrash,
+	"ContainerCannotRun":         reasonCrash,
+	"ImagePullBackOff":           reasonImage,
+	"ErrImagePull":               reasonImage,
+	"ErrImageNeverPull":          reasonImage,
+	"InvalidImageName":           reasonImage,
+	"CreateContainerConfigError": reasonCrash,
+}
+
+// 调度失败信息中出现这些词时归为存储问题，例如 PVC 未绑定
+var storageHints = []string{"persistentvolumeclaim", "volume", "pvc", "storageclass"}
+
+// categorizeReason 返回集群 Pod 当前最主要的故障原因类别
+func categorizeReason(pods []corev1.Pod) string {
+	found := make(map[string]bool)
+	for i := range pods {
+		for _, category := range podReasons(&pods[i]) {
+			found[category] = true
+		}
+	}
+	for _, category := range reasonPriority {
+		if found[category] {
+			return category
+		}
+	}
+	return reasonUnknown
+}
+
+func podReasons(pod *corev1.Pod) []string {
+	var reasons []string
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse {
+			reasons = append(reasons, schedulingCategory(cond.Message))
+		}
+	}
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if w := cs.State.Waiting; w != nil {
+			if category, ok := containerReasonCategory[w.Reason]; ok {
+				reasons = append(reasons, category)
+			}
+			// CrashLoopBackOff 的具体原因看上一次终止
+			if w.Reason == "CrashLoopBackOff" && cs.LastTerminationState.Terminated != nil &&
+				cs.LastTerminationState.Terminated.Reason == "OOMKilled" {
+				reasons = append(reasons, reasonOOM)
+			}
+		}
+		if t := cs.State.Terminated; t != nil {
+			if category, ok := containerReasonCategory[t.Reason]; ok {
+				reasons = append(reasons, category)
+			}
+		}
+	}
+	return reasons
+}
+
+func schedulingCategory(message string) string {
+	message = strings.ToLower(message)
+	for _, hint := range storageHints {
+		if strings.Contains(message, hint) {
+			return reasonStorage
+		}
+	}
+	return reasonScheduling
+}
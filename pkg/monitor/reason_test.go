@@ -0,0 +1,70 @@
+package monitor
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func unschedulable(message string) corev1.Pod {
+	return corev1.Pod{Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+		{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable", Message: message},
+	}}}
+}
+
+func waiting(reason string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}}}
+}
+
+func terminated(reason string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: reason}}}
+}
+
+func withContainers(statuses ...corev1.ContainerStatus) corev1.Pod {
+	return corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: statuses}}
+}
+
+func TestCategorizeReason(t *testing.T) {
+	crashAfterOOM := waiting("CrashLoopBackOff")
+	crashAfterOOM.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{Reason: "OOMKilled"}
+	crashAfterError := waiting("CrashLoopBackOff")
+	crashAfterError.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}
+	initImage := corev1.Pod{Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{waiting("ErrImagePull")}}}
+
+	tests := []struct {
+		name string
+		pods []corev1.Pod
+		want string
+	}{
+		{"no pods", nil, reasonUnknown},
+		{"healthy pod", []corev1.Pod{withContainers(corev1.ContainerStatus{Ready: true})}, reasonUnknown},
+		{"insufficient cpu", []corev1.Pod{unschedulable("0/3 nodes are available: 3 Insufficient cpu.")}, reasonScheduling},
+		{"unbound pvc", []corev1.Pod{unschedulable(`0/3 nodes are available: pod has unbound immediate PersistentVolumeClaims.`)}, reasonStorage},
+		{"volume node affinity", []corev1.Pod{unschedulable("3 node(s) had volume node affinity conflict")}, reasonStorage},
+		{"missing storage class", []corev1.Pod{unschedulable(`storageclass.storage.k8s.io "fast" not found`)}, reasonStorage},
+		{"scheduled condition true", []corev1.Pod{{Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionTrue}}}}}, reasonUnknown},
+		{"image pull backoff", []corev1.Pod{withContainers(waiting("ImagePullBackOff"))}, reasonImage},
+		{"invalid image name", []corev1.Pod{withContainers(waiting("InvalidImageName"))}, reasonImage},
+		{"init container image", []corev1.Pod{initImage}, reasonImage},
+		{"crash loop", []corev1.Pod{withContainers(crashAfterError)}, reasonCrash},
+		{"crash loop after oom", []corev1.Pod{withContainers(crashAfterOOM)}, reasonOOM},
+		{"terminated oom", []corev1.Pod{withContainers(terminated("OOMKilled"))}, reasonOOM},
+		{"terminated error", []corev1.Pod{withContainers(terminated("Error"))}, reasonCrash},
+		{"config error", []corev1.Pod{withContainers(waiting("CreateContainerConfigError"))}, reasonCrash},
+		{"unmapped waiting reason", []corev1.Pod{withContainers(waiting("ContainerCreating"))}, reasonUnknown},
+		{"terminated completed", []corev1.Pod{withContainers(terminated("Completed"))}, reasonUnknown},
+		// 多个 Pod 原因不同时按 reasonPriority 取最靠前的类别
+		{"oom beats scheduling", []corev1.Pod{unschedulable("Insufficient memory"), withContainers(terminated("OOMKilled"))}, reasonOOM},
+		{"crash beats image", []corev1.Pod{withContainers(waiting("ErrImagePull")), withContainers(crashAfterError)}, reasonCrash},
+		{"image beats storage", []corev1.Pod{unschedulable("pvc not bound"), withContainers(waiting("ErrImagePull"))}, reasonImage},
+		{"storage beats scheduling", []corev1.Pod{unschedulable("Insufficient cpu"), unschedulable("pvc not bound")}, reasonStorage},
+		{"containers in one pod", []corev1.Pod{withContainers(waiting("ImagePullBackOff"), terminated("OOMKilled"))}, reasonOOM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := categorizeReason(tt.pods); got != tt.want {
+				t.Errorf("categorizeReason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
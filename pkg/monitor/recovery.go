@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// recovery 已告警过的集群恢复健康，等待发送恢复通知
+type recovery struct {
+	namespace, name string
+	// 故障期间最后的 phase 和恢复后的 phase
+	was, phase string
+	// 告警时的严重程度，恢复通知按它和 ns 路由到收到告警的后端
+	severity string
+	downtime time.Duration
+}
+
+// 告警过的事件在集群恢复时排队一条恢复通知，调用方需持有 m.mu
+func (m *Monitor) queueRecovery(namespace, name, phase string, at time.Time) {
+	inc, ok := m.openIncidents[clusterKey(namespace, name)]
+	if !ok || !inc.Alerted {
+		return
+	}
+	m.recoveries = append(m.recoveries, recovery{
+		namespace: namespace,
+		name:      name,
+		was:       inc.Phase,
+		phase:     phase,
+		severity:  m.policy.Severity(inc.Phase),
+		downtime:  at.Sub(inc.OpenedAt),
+	})
+	m.audit(AuditRecord{
+		Kind:            AuditRecovery,
+		Cluster:         name,
+		Namespace:       namespace,
+		OldPhase:        inc.Phase,
+		NewPhase:        phase,
+		Decision:        "recovered",
+		IncidentID:      inc.ID,
+		DowntimeSeconds: at.Sub(inc.OpenedAt).Seconds(),
+	})
+}
+
+// 报告中的集群已经通知过，事件恢复时需要发送恢复通知；事件第一次告警时记录一条 alert
+func (m *Monitor) markAlerted(r Report, destinations []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range r.Entries {
+		inc, ok := m.openIncidents[clusterKey(e.Namespace, e.Name)]
+		if !ok || inc.Alerted {
+			continue
+		}
+		inc.Alerted = true
+		m.emitOpenedEvent(inc)
+		m.audit(AuditRecord{
+			Kind:         AuditAlert,
+			Cluster:      e.Name,
+			Namespace:    e.Namespace,
+			OldPhase:     e.PreviousPhase,
+			NewPhase:     e.Phase,
+			Decision:     "alert",
+			Reason:       e.Reason,
+			Destinations: destinations,
+			IncidentID:   inc.ID,
+			Severity:     e.Severity,
+		})
+	}
+}
+
+// 发送排队的恢复通知，与故障告警分开，一轮中多个集群恢复时每个后端合并为一条。
+// 恢复通知与告警按相同的规则路由：团队和租户渠道收到自己 ns 的恢复，兜底渠道不会收到其他渠道的集群
+func (m *Monitor) sendRecoveries(ctx context.Context) {
+	m.mu.Lock()
+	pending := m.recoveries
+	m.recoveries = nil
+	m.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	namespaces := Report{}
+	for _, r := range pending {
+		namespaces.Entries = append(namespaces.Entries, ReportEntry{Namespace: r.namespace})
+		m.log.Info("Cluster recovered", "cluster", r.name, "namespace", r.namespace, "phase", r.phase, "downtime", r.downtime.Round(time.Second))
+	}
+	tenants := m.tenantNotifiers(ctx, namespaces)
+	accepts := m.routeFilter(tenants)
+	for _, n := range m.notifiers {
+		m.sendRecoveryNotice(ctx, n, pending, func(r recovery) bool { return accepts(n, r.namespace, r.severity) })
+	}
+	for _, namespace := range sortedNamespaces(tenants) {
+		m.sendRecoveryNotice(ctx, tenants[namespace], pending, func(r recovery) bool { return r.namespace == namespace })
+	}
+}
+
+// 把 keep 返回 true 的恢复合并为一条通知发送到 n，没有时不发送
+func (m *Monitor) sendRecoveryNotice(ctx context.Context, n Notifier, pending []recovery, keep func(recovery) bool) {
+	var lines []string
+	for _, r := range pending {
+		if keep(r) {
+			lines = append(lines, fmt.Sprintf("RECOVERED: %s in %s is %s again (was %s), downtime %s",
+				r.name, r.namespace, r.phase, r.was, r.downtime.Round(time.Second)))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	sort.Strings(lines)
+	m.sendTo(ctx, n, m.NewNotice(strings.Join(lines, "\n")))
+}
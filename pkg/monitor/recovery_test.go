@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// 告警没有送达的集群恢复时不发送恢复通知
+func TestNoRecoveryNoticeAfterFailedAlert(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	cfg.AlertAfterChecks = 1
+	cfg.NotifyAttempts = 1
+	env := newTestEnv(t, cfg, Deps{Dynamic: newTestDynamic(testCluster("ns1", "db", "Failed")), Store: &memStore{}})
+	if err := env.m.RefreshDebt(ctx); err != nil {
+		t.Fatal(err)
+	}
+	env.notifier.err = errors.New("webhook unavailable")
+	env.runOnce(t)
+	if got := env.notifier.take(); len(got) != 1 || len(got[0].Entries) != 1 {
+		t.Fatalf("rendered %+v, want the failed alert", got)
+	}
+
+	env.notifier.err = nil
+	env.now = env.now.Add(5 * time.Minute)
+	recovered := testCluster("ns1", "db", "Running")
+	if _, err := env.dynamic.Resource(clustersGVR).Namespace("ns1").Update(ctx, recovered, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	env.runOnce(t)
+	for _, notice := range env.notifier.notices() {
+		if strings.HasPrefix(notice, "RECOVERED") {
+			t.Errorf("recovery notice sent for an alert that was never delivered: %q", notice)
+		}
+	}
+}
+
+// scopedNotifier 只接收 namespace 中的条目，fallback 为 true 时是兜底渠道
+type scopedNotifier struct {
+	*testNotifier
+	namespace string
+	fallback  bool
+}
+
+func (n *scopedNotifier) AcceptsNamespace(namespace string) bool { return namespace == n.namespace }
+func (n *scopedNotifier) Fallback() bool                         { return n.fallback }
+
+// 恢复通知发送到收到告警的团队渠道，兜底渠道不会收到其他团队的集群
+func TestRecoveryRoutedLikeAlert(t *testing.T) {
+	ctx := context.Background()
+	team := &scopedNotifier{testNotifier: &testNotifier{name: "team-a"}, namespace: "team-a"}
+	fallback := &scopedNotifier{testNotifier: &testNotifier{name: "fallback"}, fallback: true}
+	cfg := DefaultConfig()
+	cfg.AlertAfterChecks = 1
+	env := newTestEnv(t, cfg, Deps{Dynamic: newTestDynamic(testCluster("team-a", "db", "Failed")), Store: &memStore{}, Notifiers: []Notifier{team, fallback}})
+	if err := env.m.RefreshDebt(ctx); err != nil {
+		t.Fatal(err)
+	}
+	env.runOnce(t)
+	if got := team.take(); len(got) != 1 || len(got[0].Entries) != 1 {
+		t.Fatalf("team received %+v, want the alert", got)
+	}
+
+	env.now = env.now.Add(5 * time.Minute)
+	recovered := testCluster("team-a", "db", "Running")
+	if _, err := env.dynamic.Resource(clustersGVR).Namespace("team-a").Update(ctx, recovered, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	env.runOnce(t)
+	if got := team.notices(); len(got) != 1 || !strings.HasPrefix(got[0], "RECOVERED: db in team-a") {
+		t.Errorf("team notices = %q, want the recovery", got)
+	}
+	for _, r := range fallback.take() {
+		if r.Notice != "" || len(r.Entries) > 0 {
+			t.Errorf("fallback received %+v for a namespace routed to the team", r)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"database-monitor/pkg/redact"
+)
+
+// payloadNotifier 把 Notice 作为消息内容，记录发出的内容；err 非空时发送失败
+type payloadNotifier struct {
+	name     string
+	err      error
+	mu       sync.Mutex
+	payloads []string
+}
+
+func (n *payloadNotifier) Name() string { return n.name }
+
+func (n *payloadNotifier) Render(r Report) ([]byte, error) { return []byte(r.Notice), nil }
+
+func (n *payloadNotifier) Send(_ context.Context, payload []byte) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.payloads = append(n.payloads, string(payload))
+	return n.err
+}
+
+// 通知后端的错误中带有 webhook token 时，日志和转发该错误的自身提醒中都看不到 token
+func TestLeakedTokenIsRedacted(t *testing.T) {
+	const token = "0f9a3c1e-5b7d-token"
+	webhook := "https://open.feishu.cn/open-apis/bot/v2/hook/" + token
+	redactor := redact.New()
+	redactor.AddURL(webhook)
+	var logs bytes.Buffer
+	sendErr := &url.Error{Op: "Post", URL: webhook, Err: errors.New("context deadline exceeded")}
+	failing := &payloadNotifier{name: "feishu", err: sendErr}
+	healthy := &payloadNotifier{name: "oncall"}
+	cfg := DefaultConfig()
+	cfg.NotifyAttempts = 2
+	cfg.NotifyBackoff = 0
+	env := newTestEnv(t, cfg, Deps{
+		Notifiers: []Notifier{failing, healthy},
+		Redactor:  redactor,
+		Logger:    slog.New(redactor.Handler(slog.NewTextHandler(&logs, nil))),
+	})
+	ctx := context.Background()
+
+	env.m.Notify(ctx, env.m.NewNotice("Ping"))
+	env.m.Notify(ctx, env.m.NewNotice("Notification to feishu failed: "+sendErr.Error()+" ("+url.QueryEscape(webhook)+")"))
+
+	if !strings.Contains(logs.String(), "Error sending notification") {
+		t.Fatalf("send failure not logged:\n%s", logs.String())
+	}
+	if strings.Contains(logs.String(), token) {
+		t.Errorf("log output contains the token:\n%s", logs.String())
+	}
+	if len(healthy.payloads) != 2 {
+		t.Fatalf("oncall got %d notifications, want 2", len(healthy.payloads))
+	}
+	for _, payload := range append(healthy.payloads, failing.payloads...) {
+		if strings.Contains(payload, token) {
+			t.Errorf("notification contains the token: %q", payload)
+		}
+	}
+	if !strings.Contains(healthy.payloads[1], "context deadline exceeded") {
+		t.Errorf("self alert lost the error: %q", healthy.payloads[1])
+	}
+}
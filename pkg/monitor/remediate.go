@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// 自动修复创建的 OpsRequest 带有该标签，便于清理和审计
+const managedByLabel = "app.kubernetes.io/managed-by"
+
+// remediationTarget 一个待重启的集群
+type remediationTarget struct {
+	namespace string
+	name      string
+	incident  string
+	failedFor time.Duration
+	attempt   int
+}
+
+// 持续 Failed 超过 RemediationAfter、不欠费且未被静默的 KubeBlocks 集群；
+// 同一事件最多尝试 RemediationMaxAttempts 次，两次之间至少间隔 RemediationAfter
+func (m *Monitor) remediationTargets() []remediationTarget {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var targets []remediationTarget
+	for _, inc := range m.openIncidents {
+		if inc.Phase != "Failed" || inc.Remediations >= m.cfg.RemediationMaxAttempts {
+			continue
+		}
+		if res, _ := m.resourceOf(inc.Name); res == nil || !res.kubeblocks {
+			continue
+		}
+		if now.Sub(inc.OpenedAt) < m.cfg.RemediationAfter ||
+			inc.LastRemediation != nil && now.Sub(*inc.LastRemediation) < m.cfg.RemediationAfter {
+			continue
+		}
+		if m.debt.inDebt(inc.Namespace) {
+			continue
+		}
+		if _, silenced := m.silenced(inc.Namespace, inc.Name, inc.Phase, now); silenced {
+			continue
+		}
+		targets = append(targets, remediationTarget{
+			namespace: inc.Namespace,
+			name:      inc.Name,
+			incident:  inc.ID,
+			failedFor: now.Sub(inc.OpenedAt),
+			attempt:   inc.Remediations + 1,
+		})
+	}
+	return targets
+}
+
+// Remediate 立即为符合条件的集群创建重启操作。Run 会定期执行；只调用 RunOnce 的嵌入方需要自行调用
+func (m *Monitor) Remediate(ctx context.Context) error {
+	return m.remediate(ctx)
+}
+
+// 为每个目标创建重启 OpsRequest，结果记入事件时间线并发送通知；单个集群失败不影响其他集群
+func (m *Monitor) remediate(ctx context.Context) error {
+	if m.cfg.RemediationAfter <= 0 {
+		return nil
+	}
+	for _, t := range m.remediationTargets() {
+		ops, err := m.createRestartOps(ctx, t.namespace, t.name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			m.log.Error("Error creating restart OpsRequest", "cluster", t.name, "namespace", t.namespace, "err", err)
+		}
+		now := m.now()
+		m.mu.Lock()
+		if inc, ok := m.openIncidents[clusterKey(t.namespace, t.name)]; ok && inc.ID == t.incident {
+			inc.Remediations = t.attempt
+			inc.LastRemediation = &now
+			msg := "auto-remediation created restart OpsRequest " + ops
+			if err != nil {
+				msg = "auto-remediation failed to create a restart OpsRequest: " + err.Error()
+			}
+			inc.Timeline = append(inc.Timeline, IncidentEvent{Time: now, Message: msg})
+		}
+		m.mu.Unlock()
+
+		text := fmt.Sprintf("Auto-remediation: created restart OpsRequest %s for %s in %s, Failed for %s (attempt %d of %d)",
+			ops, t.name, t.namespace, t.failedFor.Round(time.Second), t.attempt, m.cfg.RemediationMaxAttempts)
+		if err != nil {
+			text = fmt.Sprintf("Auto-remediation: failed to create a restart OpsRequest for %s in %s (attempt %d of %d): %v",
+				t.name, t.namespace, t.attempt, m.cfg.RemediationMaxAttempts, err)
+		}
+		m.log.Warn("Auto-remediation attempted", "cluster", t.name, "namespace", t.namespace, "ops", ops, "attempt", t.attempt)
+		m.Notify(ctx, m.NewNotice(text))
+	}
+	return nil
+}
+
+// 重启集群的所有组件，返回 OpsRequest 名称
+func (m *Monitor) createRestartOps(ctx context.Context, namespace, name string) (string, error) {
+	api := m.api()
+	if err := m.budget.Wait(ctx); err != nil {
+		return "", err
+	}
+	cluster, err := m.dynamic.Resource(api.clusters).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	var restart []interface{}
+	comps, _, _ := unstructured.NestedSlice(cluster.Object, "spec", "componentSpecs")
+	for _, c := range comps {
+		if c, ok := c.(map[string]interface{}); ok {
+			if comp, _ := c["name"].(string); comp != "" {
+				restart = append(restart, map[string]interface{}{"componentName": comp})
+			}
+		}
+	}
+	if len(restart) == 0 {
+		return "", fmt.Errorf("cluster has no components")
+	}
+
+	ops := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"clusterName": name,
+			"type":        "Restart",
+			"restart":     restart,
+		},
+	}}
+	if api.opsRequests == opsRequestsGVR {
+		// 0.9 之前的 OpsRequest 只有 clusterRef
+		unstructured.SetNestedField(ops.Object, name, "spec", "clusterRef")
+	}
+	ops.SetAPIVersion(api.opsRequests.GroupVersion().String())
+	ops.SetKind("OpsRequest")
+	ops.SetNamespace(namespace)
+	ops.SetName(fmt.Sprintf("%s-restart-%d", name, m.now().Unix()))
+	ops.SetLabels(map[string]string{instanceLabel: name, managedByLabel: "database-monitor"})
+	if m.cfg.DryRun {
+		m.log.Info("Dry run, restart OpsRequest not created", "cluster", name, "namespace", namespace, "ops", ops.GetName())
+		return ops.GetName(), nil
+	}
+	if err := m.budget.Wait(ctx); err != nil {
+		return "", err
+	}
+	if _, err := m.dynamic.Resource(api.opsRequests).Namespace(namespace).Create(ctx, ops, metav1.CreateOptions{}); err != nil {
+		return "", err
+	}
+	return ops.GetName(), nil
+}
+
+// 每个巡检周期检查一次是否需要自动修复
+func (m *Monitor) startRemediationLoop(ctx context.Context) {
+	if m.cfg.RemediationAfter <= 0 {
+		return
+	}
+	m.until(ctx, m.withCycleTimeout("remediation", func(ctx context.Context) {
+		if err := m.remediate(ctx); err != nil && ctx.Err() == nil {
+			m.log.Error("Error running auto-remediation", "err", err)
+		}
+	}), m.cfg.CheckInterval)
+}
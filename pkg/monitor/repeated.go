@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 统计反复短时故障的滚动窗口
+const repeatedIncidentWindow = 24 * time.Hour
+
+// 提醒过反复故障的集群记录一条这个决定的通知审计，窗口内不再重复提醒
+const repeatedIncidentDecision = "repeated-incidents"
+
+// 窗口内关闭的一个事件
+type closedIncident struct {
+	namespace, name string
+	downtime        time.Duration
+}
+
+// 单次故障时间都很短、但一天内反复出现的集群不会触发时长类阈值，这里基于事件历史单独提醒。
+// 与抖动检测不同，这里关注的是一天内已恢复事件的累计次数；只统计进入过故障类 phase 或告警过的事件，
+// 创建、变更等过渡状态不算故障。配置了审计历史时从历史库读取，重启后仍能累计
+func (m *Monitor) checkRepeatedIncidents(ctx context.Context) {
+	if m.cfg.RepeatedIncidentThreshold <= 0 {
+		return
+	}
+	since := m.now().Add(-repeatedIncidentWindow)
+
+	m.mu.Lock()
+	incidents, alreadyWarned := m.recentIncidents(since)
+	byCluster := make(map[string][]closedIncident)
+	for _, inc := range incidents {
+		key := clusterKey(inc.namespace, inc.name)
+		byCluster[key] = append(byCluster[key], inc)
+	}
+	var warnings []string
+	var warned []closedIncident
+	for key, incidents := range byCluster {
+		if len(incidents) <= m.cfg.RepeatedIncidentThreshold || alreadyWarned[key] {
+			continue
+		}
+		var downtime time.Duration
+		for _, inc := range incidents {
+			downtime += inc.downtime
+		}
+		latest := incidents[len(incidents)-1]
+		warned = append(warned, latest)
+		warnings = append(warnings, fmt.Sprintf("%s in %s has failed %d times today, total downtime %s",
+			latest.name, latest.namespace, len(incidents), downtime.Round(time.Minute)))
+	}
+	m.mu.Unlock()
+
+	if len(warnings) == 0 {
+		return
+	}
+	sort.Strings(warnings)
+	// 送达后才记录为已提醒，发送失败或被丢弃时下一次检查重新提醒
+	m.notifyDelivered(ctx, m.NewNotice("Repeated short incidents in the last 24h:\n"+strings.Join(warnings, "\n")), func(_ context.Context, delivered bool) {
+		if !delivered {
+			return
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for _, inc := range warned {
+			m.markRepeatWarned(inc)
+		}
+	})
+}
+
+// since 之后恢复的事件，以及窗口内已提醒过的集群；调用方需持有 m.mu
+func (m *Monitor) recentIncidents(since time.Time) ([]closedIncident, map[string]bool) {
+	var incidents []closedIncident
+	warned := make(map[string]bool)
+	if m.auditHistory != nil {
+		for _, r := range m.auditHistory.AuditRecords(AuditIncident, since) {
+			if r.Region == m.cfg.Region && r.Decision == resolutionRecovered {
+				incidents = append(incidents, closedIncident{namespace: r.Namespace, name: r.Cluster, downtime: time.Duration(r.DowntimeSeconds * float64(time.Second))})
+			}
+		}
+		for _, r := range m.auditHistory.AuditRecords(AuditNotification, since) {
+			if r.Region == m.cfg.Region && r.Decision == repeatedIncidentDecision {
+				warned[clusterKey(r.Namespace, r.Cluster)] = true
+			}
+		}
+		return incidents, warned
+	}
+	for _, inc := range m.incidentHistory {
+		if inc.Resolution != resolutionRecovered || inc.ClosedAt == nil || inc.ClosedAt.Before(since) || (!inc.Failed && !inc.Alerted) {
+			continue
+		}
+		incidents = append(incidents, closedIncident{namespace: inc.Namespace, name: inc.Name, downtime: inc.ClosedAt.Sub(inc.OpenedAt)})
+		if inc.RepeatWarned {
+			warned[clusterKey(inc.Namespace, inc.Name)] = true
+		}
+	}
+	return incidents, warned
+}
+
+// 记录已提醒过反复故障；没有审计历史时标记在最近的事件上。调用方需持有 m.mu
+func (m *Monitor) markRepeatWarned(inc closedIncident) {
+	if m.auditHistory != nil {
+		m.audit(AuditRecord{Kind: AuditNotification, Cluster: inc.name, Namespace: inc.namespace, Decision: repeatedIncidentDecision})
+		return
+	}
+	for i := len(m.incidentHistory) - 1; i >= 0; i-- {
+		if h := m.incidentHistory[i]; h.Namespace == inc.namespace && h.Name == inc.name {
+			h.RepeatWarned = true
+			return
+		}
+	}
+}
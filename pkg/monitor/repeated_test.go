@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memHistory 内存中的审计历史，同时作为 AuditSink，多个 Monitor 共用时模拟重启
+type memHistory struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (h *memHistory) Record(r AuditRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+}
+
+func (h *memHistory) AuditRecords(kind string, since time.Time) []AuditRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var records []AuditRecord
+	for _, r := range h.records {
+		if r.Kind == kind && !r.Time.Before(since) {
+			records = append(records, r)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records
+}
+
+// 打开并在两分钟后关闭 count 个事件，每个事件间隔一小时
+func flap(env *testEnv, name, phase string, count int) {
+	for i := 0; i < count; i++ {
+		env.m.mu.Lock()
+		env.m.openIncident("ns1", name, phase, env.now)
+		env.now = env.now.Add(2 * time.Minute)
+		env.m.closeIncident("ns1", name, resolutionRecovered, env.now)
+		env.m.mu.Unlock()
+		env.now = env.now.Add(time.Hour)
+	}
+}
+
+func repeatedWarnings(env *testEnv) []string {
+	env.m.checkRepeatedIncidents(context.Background())
+	var warnings []string
+	for _, r := range env.notifier.take() {
+		if strings.HasPrefix(r.Notice, "Repeated short incidents") {
+			warnings = append(warnings, strings.Split(r.Notice, "\n")[1:]...)
+		}
+	}
+	return warnings
+}
+
+func TestRepeatedIncidentsCountFailuresOnly(t *testing.T) {
+	env := newTestEnv(t, DefaultConfig(), Deps{})
+	flap(env, "db", "Failed", 4)
+	flap(env, "creating", "Creating", 6)
+	flap(env, "updating", "Updating", 2)
+	flap(env, "updating", "Abnormal", 4)
+
+	got := repeatedWarnings(env)
+	want := []string{
+		"db in ns1 has failed 4 times today, total downtime 8m0s",
+		"updating in ns1 has failed 4 times today, total downtime 8m0s",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+	if got := repeatedWarnings(env); len(got) != 0 {
+		t.Errorf("warned again in the same day: %q", got)
+	}
+}
+
+func TestRepeatedIncidentsAlertedTransitional(t *testing.T) {
+	env := newTestEnv(t, DefaultConfig(), Deps{})
+	for i := 0; i < 4; i++ {
+		env.m.mu.Lock()
+		env.m.openIncident("ns1", "db", "Updating", env.now)
+		env.m.mu.Unlock()
+		env.m.markAlerted(reportOf(testEntry("ns1", "db", "Updating", SeverityInfo)), nil)
+		env.now = env.now.Add(time.Minute)
+		env.m.mu.Lock()
+		env.m.closeIncident("ns1", "db", resolutionRecovered, env.now)
+		env.m.mu.Unlock()
+	}
+	if got := repeatedWarnings(env); len(got) != 1 {
+		t.Errorf("warnings = %q, want one for the alerted incidents", got)
+	}
+}
+
+// 事件历史来自共享的历史库，重启后仍累计之前的事件，也不会重复提醒
+func TestRepeatedIncidentsAcrossRestarts(t *testing.T) {
+	history := &memHistory{}
+	deps := Deps{Audit: history, History: history}
+	first := newTestEnv(t, DefaultConfig(), deps)
+	flap(first, "db", "Failed", 2)
+	if got := repeatedWarnings(first); len(got) != 0 {
+		t.Fatalf("warned below the threshold: %q", got)
+	}
+
+	second := newTestEnv(t, DefaultConfig(), deps)
+	second.now = first.now
+	flap(second, "db", "Failed", 2)
+	if got := repeatedWarnings(second); len(got) != 1 || !strings.HasPrefix(got[0], "db in ns1 has failed 4 times today") {
+		t.Fatalf("warnings after restart = %q, want one for 4 incidents", got)
+	}
+
+	third := newTestEnv(t, DefaultConfig(), deps)
+	third.now = second.now
+	if got := repeatedWarnings(third); len(got) != 0 {
+		t.Errorf("warned again after restart: %q", got)
+	}
+	// 窗口过去之后重新累计
+	third.now = third.now.Add(repeatedIncidentWindow)
+	flap(third, "db", "Failed", 4)
+	if got := repeatedWarnings(third); len(got) != 1 {
+		t.Errorf("warnings on the next day = %q, want one", got)
+	}
+}
+
+// 发送失败的反复故障提醒不记录为已提醒，下一次检查重新提醒
+func TestRepeatedIncidentsWarnedAfterDelivery(t *testing.T) {
+	env := newTestEnv(t, DefaultConfig(), Deps{})
+	env.m.cfg.NotifyAttempts = 1
+	flap(env, "db", "Failed", 4)
+	env.notifier.err = errors.New("webhook unavailable")
+	if got := repeatedWarnings(env); len(got) != 1 {
+		t.Fatalf("warnings = %q, want one attempted", got)
+	}
+	env.notifier.err = nil
+	if got := repeatedWarnings(env); len(got) != 1 {
+		t.Errorf("after the failed delivery warnings = %q, want the warning again", got)
+	}
+	if got := repeatedWarnings(env); len(got) != 0 {
+		t.Errorf("warned again after delivery: %q", got)
+	}
+}
@@ -0,0 +1,161 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	replicationProblemLagging = "lagging"
+	replicationProblemStopped = "stopped"
+	replicationProblemMissing = "missing"
+)
+
+// 多副本集群的复制状态：MySQL 逐个连接 Pod 执行 SHOW REPLICA STATUS，
+// PostgreSQL 通过 Service 连接主库查询 pg_stat_replication。查询失败只打日志，连通性由探测本身负责
+func (m *Monitor) checkReplication(ctx context.Context, t probeTarget) []probeProblem {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.ProbeTimeout)
+	defer cancel()
+	pods, err := m.listClusterPods(ctx, t.Namespace, t.Name)
+	if err != nil {
+		m.log.Warn("Error listing cluster pods for replication check", "cluster", t.Name, "namespace", t.Namespace, "err", err)
+		return nil
+	}
+	var members []corev1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != "" {
+			members = append(members, pod)
+		}
+	}
+	if len(members) < 2 {
+		return nil
+	}
+	switch t.Engine {
+	case engineMySQL:
+		return m.mysqlReplication(ctx, t, members)
+	case enginePostgres:
+		return m.postgresReplication(ctx, t, len(members))
+	}
+	return nil
+}
+
+func (m *Monitor) mysqlReplication(ctx context.Context, t probeTarget, members []corev1.Pod) []probeProblem {
+	key := clusterKey(t.Namespace, t.Name)
+	var problems []probeProblem
+	for _, pod := range members {
+		member := t
+		member.Host = pod.Status.PodIP
+		driver, dsn := probeDSN(member, m.cfg.ProbeTimeout)
+		lag, replica, err := mysqlReplicaLag(ctx, driver, dsn)
+		switch {
+		case err != nil:
+			m.log.Warn("Error reading replica status", "cluster", t.Name, "namespace", t.Namespace, "pod", pod.Name, "err", t.scrub(err))
+		case !replica:
+			// 主库没有复制状态
+		case !lag.Valid:
+			problems = append(problems, probeProblem{key: key + "/" + pod.Name, problem: replicationProblemStopped,
+				line: fmt.Sprintf("mysql cluster %s replica %s is not replicating", key, pod.Name)})
+		case time.Duration(lag.Int64)*time.Second > m.cfg.ReplicationLagThreshold:
+			problems = append(problems, probeProblem{key: key + "/" + pod.Name, problem: replicationProblemLagging,
+				line: fmt.Sprintf("mysql cluster %s replica %s is %ds behind, more than %s", key, pod.Name, lag.Int64, m.cfg.ReplicationLagThreshold)})
+		}
+	}
+	return problems
+}
+
+// 副本的 Seconds_Behind_Source，复制线程未运行时为 NULL；replica 为 false 表示该实例不是副本
+func mysqlReplicaLag(ctx context.Context, driver, dsn string) (lag sql.NullInt64, replica bool, err error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return lag, false, err
+	}
+	defer db.Close()
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		// 8.0.22 之前只有 SHOW SLAVE STATUS
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		return lag, false, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return lag, false, err
+	}
+	if !rows.Next() {
+		return lag, false, rows.Err()
+	}
+	values := make([]sql.NullString, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return lag, true, err
+	}
+	for i, col := range cols {
+		if col != "Seconds_Behind_Source" && col != "Seconds_Behind_Master" {
+			continue
+		}
+		if values[i].Valid {
+			err = lag.Scan(values[i].String)
+		}
+		return lag, true, err
+	}
+	return lag, true, fmt.Errorf("replica status has no Seconds_Behind_Source column")
+}
+
+func (m *Monitor) postgresReplication(ctx context.Context, t probeTarget, members int) []probeProblem {
+	key := clusterKey(t.Namespace, t.Name)
+	driver, dsn := probeDSN(t, m.cfg.ProbeTimeout)
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		m.log.Warn("Error reading replication status", "cluster", t.Name, "namespace", t.Namespace, "err", t.scrub(err))
+		return nil
+	}
+	defer db.Close()
+	var inRecovery bool
+	if err := db.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil || inRecovery {
+		// Service 指向备库时无法从这里看到复制状态
+		if err != nil {
+			m.log.Warn("Error reading replication status", "cluster", t.Name, "namespace", t.Namespace, "err", t.scrub(err))
+		}
+		return nil
+	}
+	rows, err := db.QueryContext(ctx,
+		"SELECT application_name, EXTRACT(EPOCH FROM COALESCE(replay_lag, interval '0')) FROM pg_stat_replication")
+	if err != nil {
+		m.log.Warn("Error reading replication status", "cluster", t.Name, "namespace", t.Namespace, "err", t.scrub(err))
+		return nil
+	}
+	defer rows.Close()
+	var problems []probeProblem
+	streaming := 0
+	for rows.Next() {
+		var name string
+		var lag float64
+		if err := rows.Scan(&name, &lag); err != nil {
+			m.log.Warn("Error reading replication status", "cluster", t.Name, "namespace", t.Namespace, "err", t.scrub(err))
+			return nil
+		}
+		streaming++
+		if d := time.Duration(lag * float64(time.Second)); d > m.cfg.ReplicationLagThreshold {
+			problems = append(problems, probeProblem{key: key + "/" + name, problem: replicationProblemLagging,
+				line: fmt.Sprintf("postgresql cluster %s standby %s is %s behind, more than %s", key, name, d.Round(time.Second), m.cfg.ReplicationLagThreshold)})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		m.log.Warn("Error reading replication status", "cluster", t.Name, "namespace", t.Namespace, "err", t.scrub(err))
+		return nil
+	}
+	if streaming < members-1 {
+		problems = append(problems, probeProblem{key: key + "/standbys", problem: replicationProblemMissing,
+			line: fmt.Sprintf("postgresql cluster %s has %d of %d standbys streaming", key, streaming, members-1)})
+	}
+	return problems
+}
@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Report 一轮巡检得到的结构化结果，各通知后端基于它渲染消息
+type Report struct {
+	GeneratedAt time.Time     `json:"generatedAt"`
+	Entries     []ReportEntry `json:"entries"`
+	// 监控自身的通知，例如异常重启
+	Notice string `json:"notice,omitempty"`
+	// 当前处于欠费状态的 ns
+	DebtNamespaces []string `json:"debtNamespaces,omitempty"`
+	// 生成报告时生效的配置版本
+	ConfigHash string `json:"configHash,omitempty"`
+	// 报告来源的区域，单集群时为空
+	Region string `json:"region,omitempty"`
+	// 需要 @ 的用户 ID，升级通知时设置，由支持的渠道渲染
+	Mentions []string `json:"mentions,omitempty"`
+}
+
+// ReportEntry 报告中的一行，即一个需要关注的数据库
+type ReportEntry struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Phase     string `json:"phase"`
+	// 进入当前 phase 之前的 phase，未观察到变化时为空
+	PreviousPhase string `json:"previousPhase,omitempty"`
+	Severity      string `json:"severity"`
+	// 事件打开的时间，即集群开始不健康的时间
+	Since time.Time `json:"since"`
+	// 集群所在 ns 是否欠费
+	InDebt bool `json:"inDebt,omitempty"`
+	// 附加说明，例如卡在删除中的时长
+	Note string `json:"note,omitempty"`
+	// 事件期间发生的 OOMKill 及容器内存限制
+	OOMKilled string `json:"oomKilled,omitempty"`
+	// 检查 Pod 得到的其他诊断信息
+	Findings []string `json:"findings,omitempty"`
+	// 归一化后的故障原因类别，例如 scheduling、storage、crash
+	Reason string `json:"reason,omitempty"`
+	// 所在 ns 注解中的负责人信息，例如 owner=alice, team=dba
+	Owner string `json:"owner,omitempty"`
+	// 负责人的飞书 user ID，飞书通知中 @ 这些用户
+	OwnerMentions []string `json:"ownerMentions,omitempty"`
+	// 集群在管理控制台中的页面，未配置 ConsoleURL 时为空
+	ConsoleURL string `json:"consoleURL,omitempty"`
+	// 多组件集群各组件的 phase 和副本就绪情况，便于看出是哪个组件出了问题
+	Components []ComponentStatus `json:"components,omitempty"`
+	// 集群各 Pod 的状态，便于不用 kubectl 就能初步判断
+	Pods []PodStatus `json:"pods,omitempty"`
+}
+
+// PodStatus 集群下一个 Pod 的概况
+type PodStatus struct {
+	Name     string `json:"name"`
+	Phase    string `json:"phase"`
+	Restarts int32  `json:"restarts"`
+	// 最近一次容器终止的原因，例如 OOMKilled、Error(exit 1)
+	LastTermination string `json:"lastTermination,omitempty"`
+}
+
+// String 单行描述，例如 mysql-0 Running, 3 restarts, last terminated: OOMKilled
+func (p PodStatus) String() string {
+	s := fmt.Sprintf("%s %s, %d restarts", p.Name, p.Phase, p.Restarts)
+	if p.LastTermination != "" {
+		s += ", last terminated: " + p.LastTermination
+	}
+	return s
+}
+
+// 事件身份：namespace/name/phase 类别
+func (e ReportEntry) incidentKey() string {
+	return clusterKey(e.Namespace, e.Name) + "/" + phaseClass(e.Phase)
+}
+
+// DisplayPhase 带附加说明的 phase，例如 Deleting(stuck 45m)
+func (e ReportEntry) DisplayPhase() string {
+	if e.Note == "" {
+		return e.Phase
+	}
+	return e.Phase + "(" + e.Note + ")"
+}
+
+// 文本类消息中每个集群最多列出的 Pod 数，其余只给出数量
+const maxPodLines = 10
+
+// PodLines 用于文本类消息的 Pod 概况，每个 Pod 一行
+func (e ReportEntry) PodLines() []string {
+	var lines []string
+	for i, p := range e.Pods {
+		if i == maxPodLines {
+			lines = append(lines, fmt.Sprintf("... and %d more pods", len(e.Pods)-maxPodLines))
+			break
+		}
+		lines = append(lines, p.String())
+	}
+	return lines
+}
+
+// 报告中所有事件的 key 及其严重程度
+func (r Report) incidentKeys() map[string]string {
+	keys := make(map[string]string, len(r.Entries))
+	for _, e := range r.Entries {
+		keys[e.incidentKey()] = e.Severity
+	}
+	return keys
+}
+
+// 调用方需持有 m.mu
+func (m *Monitor) newEntry(namespace, name, phase string) *ReportEntry {
+	e := &ReportEntry{Name: name, Namespace: namespace, Phase: phase, Severity: m.policy.Severity(phase), InDebt: m.debt.inDebt(namespace)}
+	if inc, ok := m.openIncidents[clusterKey(namespace, name)]; ok {
+		e.Since = inc.OpenedAt
+	}
+	e.ConsoleURL = m.consoleURL(namespace, name)
+	return e
+}
+
+// 按 ConsoleURL 模板生成集群在管理控制台中的地址，其他资源使用对象本身的名称
+func (m *Monitor) consoleURL(namespace, name string) string {
+	if m.cfg.ConsoleURL == "" {
+		return ""
+	}
+	_, base := m.resourceOf(name)
+	return strings.NewReplacer(
+		"{namespace}", url.PathEscape(namespace),
+		"{name}", url.PathEscape(base),
+		"{region}", url.PathEscape(m.cfg.Region),
+	).Replace(m.cfg.ConsoleURL)
+}
+
+func clusterKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// 报告中已知原因类别的事件 key 及其类别
+func (r Report) incidentReasons() map[string]string {
+	reasons := make(map[string]string)
+	for _, e := range r.Entries {
+		if e.Reason != "" && e.Reason != reasonUnknown {
+			reasons[e.incidentKey()] = e.Reason
+		}
+	}
+	return reasons
+}
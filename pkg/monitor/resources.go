@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// 默认的状态字段路径
+const defaultPhasePath = "status.phase"
+
+// MonitoredResource 被巡检的一种资源，例如其他 operator 的 CR：从 PhasePath 读取状态，按配置的取值判断是否健康
+type MonitoredResource struct {
+	// group/version/resource，例如 databases.spotahome.com/v1/redisfailovers
+	Resource string `json:"resource"`
+	// 资源的 Kind，在对象上创建 Event 时使用，例如 RedisFailover
+	Kind string `json:"kind"`
+	// 状态字段的路径，以 . 分隔，为空时为 status.phase
+	PhasePath string `json:"phasePath,omitempty"`
+	// 分别视为健康、故障（critical）和异常（warning）的状态值，巡检中对应 Running、Failed 和 Abnormal；
+	// 其他状态原样保留，视为过渡状态，持续不健康时按 info 告警
+	HealthyPhases  []string `json:"healthyPhases,omitempty"`
+	FailedPhases   []string `json:"failedPhases,omitempty"`
+	AbnormalPhases []string `json:"abnormalPhases,omitempty"`
+}
+
+// ValidateMonitoredResource 检查资源的 GVR 和 Kind
+func ValidateMonitoredResource(r MonitoredResource) error {
+	if _, err := parseResource(r.Resource); err != nil {
+		return err
+	}
+	if r.Kind == "" {
+		return fmt.Errorf("kind is required")
+	}
+	return nil
+}
+
+func parseResource(v string) (schema.GroupVersionResource, error) {
+	parts := strings.Split(v, "/")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return schema.GroupVersionResource{}, fmt.Errorf("invalid resource %q, want group/version/resource", v)
+	}
+	return schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]}, nil
+}
+
+// monitoredResource 解析后的 MonitoredResource
+type monitoredResource struct {
+	gvr  schema.GroupVersionResource
+	kind string
+	path []string
+	// 状态值到巡检 phase 的映射，没有映射的值原样使用
+	phases map[string]string
+	// KubeBlocks 集群：任意版本均按检测到的版本巡检，名称不加资源前缀，并补充 Pod、探测、备份等 KubeBlocks 专有的检查
+	kubeblocks bool
+}
+
+// 未配置 Resources 时只巡检 KubeBlocks 集群
+var kubeblocksResource = &monitoredResource{
+	gvr:        clustersGVR,
+	kind:       "Cluster",
+	path:       strings.Split(defaultPhasePath, "."),
+	kubeblocks: true,
+}
+
+func newMonitoredResource(r MonitoredResource) (*monitoredResource, error) {
+	if err := ValidateMonitoredResource(r); err != nil {
+		return nil, err
+	}
+	gvr, _ := parseResource(r.Resource)
+	path := r.PhasePath
+	if path == "" {
+		path = defaultPhasePath
+	}
+	res := &monitoredResource{
+		gvr:        gvr,
+		kind:       r.Kind,
+		path:       strings.Split(path, "."),
+		phases:     make(map[string]string),
+		kubeblocks: gvr.GroupResource() == clustersGVR.GroupResource(),
+	}
+	for phase, values := range map[string][]string{"Running": r.HealthyPhases, "Failed": r.FailedPhases, "Abnormal": r.AbnormalPhases} {
+		for _, v := range values {
+			res.phases[v] = phase
+		}
+	}
+	return res, nil
+}
+
+// 报告、状态和事件中使用的名称：其他资源加上资源名前缀，例如 redisfailovers/cache，避免和同名的集群冲突
+func (r *monitoredResource) trackedName(name string) string {
+	if r.kubeblocks {
+		return name
+	}
+	return r.gvr.Resource + "/" + name
+}
+
+// 对象的巡检 phase 和状态字段的原始值
+func (r *monitoredResource) phase(obj *unstructured.Unstructured) (phase, raw string, found bool, err error) {
+	raw, found, err = unstructured.NestedString(obj.Object, r.path...)
+	if err != nil || !found {
+		return "", "", found, err
+	}
+	if mapped, ok := r.phases[raw]; ok {
+		return mapped, raw, true, nil
+	}
+	return raw, raw, true, nil
+}
+
+// 由 trackedName 找到所属资源和对象本身的名称
+func (m *Monitor) resourceOf(name string) (*monitoredResource, string) {
+	prefix, base, ok := strings.Cut(name, "/")
+	if !ok {
+		return m.kubeblocks(), name
+	}
+	for _, r := range m.resources {
+		if !r.kubeblocks && r.gvr.Resource == prefix {
+			return r, base
+		}
+	}
+	return nil, name
+}
+
+// 实际 List 的资源，KubeBlocks 集群使用检测到的 API 版本
+func (m *Monitor) resourceGVR(r *monitoredResource) schema.GroupVersionResource {
+	if r.kubeblocks {
+		return m.api().clusters
+	}
+	return r.gvr
+}
+
+// 巡检的 KubeBlocks 集群资源，配置的 Resources 中没有 KubeBlocks 集群时为 nil
+func (m *Monitor) kubeblocks() *monitoredResource {
+	for _, r := range m.resources {
+		if r.kubeblocks {
+			return r
+		}
+	}
+	return nil
+}
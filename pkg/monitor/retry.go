@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 发送通知，失败时按指数退避加抖动重试，最多尝试 NotifyAttempts 次。
+// 非 2xx 响应和飞书等返回的业务错误码都由 Send 作为错误返回，同样重试。
+// 拆成多条的通知部分送达时（PartialSendError）只重试还未送达的部分
+func (m *Monitor) sendWithRetry(ctx context.Context, n Notifier, payload []byte) error {
+	attempts := m.cfg.NotifyAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	if m.cfg.DryRun {
+		m.log.Info("Dry run, notification not sent", "notifier", n.Name(), "payload", string(payload))
+		return nil
+	}
+	ctx, span := m.tracer.Start(ctx, "send", trace.WithAttributes(
+		attribute.String("notifier", n.Name()), attribute.Int("payload.bytes", len(payload))))
+	defer span.End()
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = m.sendOnce(ctx, n, payload); err == nil || attempt >= attempts {
+			span.SetAttributes(attribute.Int("attempts", attempt))
+			m.spanError(span, err)
+			return err
+		}
+		var partial *PartialSendError
+		if errors.As(err, &partial) {
+			payload = partial.Remaining
+		}
+		delay := m.retryDelay(attempt)
+		m.log.Warn("Error sending notification, retrying", "notifier", n.Name(), "attempt", attempt, "retryIn", delay.Round(time.Millisecond), "err", err)
+		m.metrics.notificationRetries.WithLabelValues(n.Name()).Inc()
+		select {
+		case <-ctx.Done():
+			span.SetAttributes(attribute.Int("attempts", attempt))
+			m.spanError(span, err)
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// 第 attempt 次失败后的等待时间：NotifyBackoff 每次翻倍，不超过 NotifyMaxBackoff，
+// 再上下浮动 NotifyJitter 的比例，避免多个副本或后端同时重试
+func (m *Monitor) retryDelay(attempt int) time.Duration {
+	backoff := m.cfg.NotifyBackoff
+	for i := 1; i < attempt && backoff < m.cfg.NotifyMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if m.cfg.NotifyMaxBackoff > 0 && backoff > m.cfg.NotifyMaxBackoff {
+		backoff = m.cfg.NotifyMaxBackoff
+	}
+	if j := m.cfg.NotifyJitter; j > 0 {
+		backoff = time.Duration(float64(backoff) * (1 + j*(2*rand.Float64()-1)))
+	}
+	return backoff
+}
+
+// 单次发送最多 NotifyTimeout，挂起的通知后端不会占住整轮巡检
+func (m *Monitor) sendOnce(ctx context.Context, n Notifier, payload []byte) error {
+	if m.cfg.NotifyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.cfg.NotifyTimeout)
+		defer cancel()
+	}
+	return n.Send(ctx, payload)
+}
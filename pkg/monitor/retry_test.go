@@ -0,0 +1,70 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// partsNotifier 逐条发送 payload 中的消息，failures 次发送第 failAt 条时失败
+type partsNotifier struct {
+	failAt    int
+	failures  int
+	delivered []string
+}
+
+func (n *partsNotifier) Name() string { return "parts" }
+
+func (n *partsNotifier) Render(Report) ([]byte, error) { return nil, nil }
+
+func (n *partsNotifier) Send(_ context.Context, payload []byte) error {
+	var parts []string
+	if err := json.Unmarshal(payload, &parts); err != nil {
+		return err
+	}
+	for i, part := range parts {
+		if len(n.delivered) == n.failAt && n.failures > 0 {
+			n.failures--
+			err := fmt.Errorf("sending part %d: webhook unavailable", len(n.delivered)+1)
+			if i == 0 {
+				return err
+			}
+			remaining, _ := json.Marshal(parts[i:])
+			return &PartialSendError{Remaining: remaining, Err: err}
+		}
+		n.delivered = append(n.delivered, part)
+	}
+	return nil
+}
+
+// 多条消息的第二条发送失败后只重试未送达的部分，已送达的消息不会重复发送
+func TestSendWithRetryResumesPartialSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		failAt   int
+		failures int
+	}{
+		{"first part fails", 0, 1},
+		{"middle part fails", 1, 1},
+		{"last part fails twice", 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.NotifyAttempts = 3
+			cfg.NotifyBackoff, cfg.NotifyMaxBackoff = time.Millisecond, time.Millisecond
+			env := newTestEnv(t, cfg, Deps{})
+			n := &partsNotifier{failAt: tt.failAt, failures: tt.failures}
+			payload, _ := json.Marshal([]string{"1/3", "2/3", "3/3"})
+			if err := env.m.sendWithRetry(context.Background(), n, payload); err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"1/3", "2/3", "3/3"}; !slices.Equal(n.delivered, want) {
+				t.Errorf("delivered = %q, want %q", n.delivered, want)
+			}
+		})
+	}
+}
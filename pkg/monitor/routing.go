@@ -0,0 +1,224 @@
+package monitor
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// routedDedup 记录每个按严重程度或 ns 过滤的通知后端上一次收到的条目
+type routedDedup struct {
+	mu   sync.Mutex
+	last map[string]routedView
+}
+
+type routedView struct {
+	keys map[string]string
+	at   time.Time
+}
+
+// 过滤后的内容与上次发送给该后端的相同且未到重复提醒间隔时不发送，
+// 避免只改变了其他严重程度的条目时重复打扰
+func (d *routedDedup) changed(name string, r Report, now time.Time, realert time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	prev, ok := d.last[name]
+	keys := r.incidentKeys()
+	if !ok {
+		return len(keys) > 0
+	}
+	if len(keys) != len(prev.keys) {
+		return true
+	}
+	for key, severity := range keys {
+		if prev.keys[key] != severity {
+			return true
+		}
+	}
+	return len(keys) > 0 && now.Sub(prev.at) >= realert
+}
+
+func (d *routedDedup) mark(name string, r Report, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.last == nil {
+		d.last = make(map[string]routedView)
+	}
+	d.last[name] = routedView{keys: r.incidentKeys(), at: now}
+}
+
+// 只保留 keep 返回 true 的条目
+func filterReport(r Report, keep func(ReportEntry) bool) Report {
+	filtered := r
+	filtered.Entries = nil
+	for _, e := range r.Entries {
+		if keep(e) {
+			filtered.Entries = append(filtered.Entries, e)
+		}
+	}
+	return filtered
+}
+
+// 把巡检报告发送到各通知后端。按严重程度或 ns 过滤的后端只收到它接收的条目，并各自去重；
+// ns 上有租户 webhook 注解时，该 ns 的条目发送到租户的渠道，兜底渠道不再接收
+func (m *Monitor) notifyRouted(ctx context.Context, r Report, now time.Time, g *deliveryGroup) {
+	tenants := m.tenantNotifiers(ctx, r)
+	accepts := m.routeFilter(tenants)
+	for _, n := range m.notifiers {
+		_, filtersSeverity := n.(SeverityFilter)
+		_, filtersNamespace := n.(NamespaceFilter)
+		if !filtersSeverity && !filtersNamespace {
+			m.sendNotification(ctx, heldNotification{notifier: n, route: n.Name(), report: r, sent: g.add()})
+			continue
+		}
+		view := filterReport(r, func(e ReportEntry) bool { return accepts(n, e.Namespace, e.Severity) })
+		m.sendRoute(ctx, n.Name(), n, view, now, g)
+	}
+	for _, namespace := range sortedNamespaces(tenants) {
+		view := filterReport(r, func(e ReportEntry) bool { return e.Namespace == namespace })
+		m.sendRoute(ctx, tenantRouteKey+namespace, tenants[namespace], view, now, g)
+		if len(view.Entries) == 0 {
+			m.forgetTenant(namespace)
+		}
+	}
+}
+
+// routeFilter 返回判断通知后端是否接收某个 ns 中某个严重程度的条目的函数。
+// 兜底渠道只接收没有被其他后端或 tenants 中的租户渠道认领的 ns
+func (m *Monitor) routeFilter(tenants map[string]Notifier) func(n Notifier, namespace, severity string) bool {
+	claimed := func(namespace string) bool {
+		if _, ok := tenants[namespace]; ok {
+			return true
+		}
+		for _, n := range m.notifiers {
+			if f, ok := n.(NamespaceFilter); ok && !f.Fallback() && f.AcceptsNamespace(namespace) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(n Notifier, namespace, severity string) bool {
+		if sf, ok := n.(SeverityFilter); ok && !sf.AcceptsSeverity(severity) {
+			return false
+		}
+		nf, ok := n.(NamespaceFilter)
+		return !ok || nf.AcceptsNamespace(namespace) || nf.Fallback() && !claimed(namespace)
+	}
+}
+
+func sortedNamespaces(tenants map[string]Notifier) []string {
+	namespaces := make([]string, 0, len(tenants))
+	for namespace := range tenants {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// 过滤后的内容有变化时发送，route 为去重记录的 key，送达后才记录
+func (m *Monitor) sendRoute(ctx context.Context, route string, n Notifier, view Report, now time.Time, g *deliveryGroup) {
+	if !m.routes.changed(route, view, now, m.cfg.RealertInterval) {
+		m.log.Info("Skipping notification: no changes in its entries", "notifier", n.Name())
+		return
+	}
+	sent := g.add()
+	m.sendNotification(ctx, heldNotification{notifier: n, route: route, report: view, sent: func(ctx context.Context, delivered bool) {
+		if delivered {
+			m.routes.mark(route, view, now)
+		}
+		sent(ctx, delivered)
+	}})
+}
+
+// 租户通知后端在 routedDedup 中的 key 前缀
+const tenantRouteKey = "namespace:"
+
+// tenantRoutes 由 ns 注解创建的租户通知后端，地址不变时复用
+type tenantRoutes struct {
+	mu   sync.Mutex
+	byNS map[string]tenantRoute
+}
+
+type tenantRoute struct {
+	url      string
+	notifier Notifier
+}
+
+// 报告中各 ns 的租户通知后端。上次发送过的 ns 即使已没有条目也会返回，让租户收到恢复后的报告
+func (m *Monitor) tenantNotifiers(ctx context.Context, r Report) map[string]Notifier {
+	if m.nsNotifier == nil || m.cfg.NamespaceWebhookAnnotation == "" {
+		return nil
+	}
+	t := &m.tenants
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	namespaces := make(map[string]bool)
+	for _, e := range r.Entries {
+		namespaces[e.Namespace] = true
+	}
+	for namespace := range t.byNS {
+		namespaces[namespace] = true
+	}
+
+	tenants := make(map[string]Notifier)
+	for namespace := range namespaces {
+		url, err := m.namespaceWebhook(ctx, namespace)
+		if err != nil {
+			m.log.Warn("Error reading namespace webhook annotation", "namespace", namespace, "err", err)
+			if prev, ok := t.byNS[namespace]; ok && prev.notifier != nil {
+				tenants[namespace] = prev.notifier
+			}
+			continue
+		}
+		prev, ok := t.byNS[namespace]
+		switch {
+		case url == "":
+			delete(t.byNS, namespace)
+			continue
+		case ok && prev.url == url:
+			tenants[namespace] = prev.notifier
+			continue
+		}
+		if m.redactor != nil {
+			m.redactor.AddURL(url)
+		}
+		n, err := m.nsNotifier(namespace, url)
+		if err != nil {
+			m.log.Warn("Ignoring namespace webhook annotation", "namespace", namespace, "err", err)
+			delete(t.byNS, namespace)
+			continue
+		}
+		t.byNS[namespace] = tenantRoute{url: url, notifier: n}
+		tenants[namespace] = n
+	}
+	return tenants
+}
+
+// ns 上租户 webhook 注解的值，ns 不存在时为空
+func (m *Monitor) namespaceWebhook(ctx context.Context, namespace string) (string, error) {
+	if err := m.budget.Wait(ctx); err != nil {
+		return "", err
+	}
+	ns, err := m.kube.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return ns.Annotations[m.cfg.NamespaceWebhookAnnotation], nil
+}
+
+// 租户已收到没有条目的报告后不再跟踪该 ns
+func (m *Monitor) forgetTenant(namespace string) {
+	m.tenants.mu.Lock()
+	delete(m.tenants.byNS, namespace)
+	m.tenants.mu.Unlock()
+	m.routes.mu.Lock()
+	delete(m.routes.last, tenantRouteKey+namespace)
+	m.routes.mu.Unlock()
+}
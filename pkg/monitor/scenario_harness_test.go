@@ -0,0 +1,536 @@
+package monitor_test
+
+// 场景测试用脚本化的时间线驱动完整的巡检循环：fake client、fake 时钟，
+// 逐步修改集群 phase、欠费配额等，并断言每一步发出的通知和做出的决策。
+// 阈值、冷却、欠费、删除等功能相互影响，新增功能时应在 scenario_test.go 中补充对应的场景。
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	"database-monitor/pkg/monitor"
+)
+
+var (
+	clustersGVR      = schema.GroupVersionResource{Group: "apps.kubeblocks.io", Version: "v1alpha1", Resource: "clusters"}
+	notificationsGVR = schema.GroupVersionResource{Group: "notification.sealos.io", Version: "v1", Resource: "notifications"}
+	opsRequestsGVR   = schema.GroupVersionResource{Group: "apps.kubeblocks.io", Version: "v1alpha1", Resource: "opsrequests"}
+	backupsGVR       = schema.GroupVersionResource{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backups"}
+	schedulesGVR     = schema.GroupVersionResource{Group: "dataprotection.kubeblocks.io", Version: "v1alpha1", Resource: "backupschedules"}
+	debtsGVR         = schema.GroupVersionResource{Group: "account.sealos.io", Version: "v1", Resource: "debts"}
+	redisGVR         = schema.GroupVersionResource{Group: "databases.spotahome.com", Version: "v1", Resource: "redisfailovers"}
+)
+
+// 场景时间线的起点
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Scenario 一条时间线
+type Scenario struct {
+	Name string
+	// 为空时使用 monitor.DefaultConfig
+	Config *monitor.Config
+	Steps  []Step
+}
+
+// Step 在 At 时刻执行 Actions，然后停止欠费集群、运行一轮巡检，检查一次运维操作和备份，并执行自动修复
+type Step struct {
+	At      time.Duration
+	Actions []Action
+	// 本轮发出的通知摘要，顺序必须一致，为空表示不应发出通知
+	Expect []string
+	// 本轮结束后各集群（namespace/name）的决策动作，只检查列出的集群
+	Decisions map[string]string
+}
+
+// Action 修改 fake 集群中的状态
+type Action func(ctx context.Context, w *world) error
+
+// Phase 创建集群或修改其 phase
+func Phase(namespace, name, phase string) Action {
+	return func(ctx context.Context, w *world) error {
+		return w.upsertCluster(ctx, namespace, name, func(obj *unstructured.Unstructured) {
+			unstructured.SetNestedField(obj.Object, phase, "status", "phase")
+		})
+	}
+}
+
+// Components 设置集群的组件，自动修复按组件重启
+func Components(namespace, name string, components ...string) Action {
+	return func(ctx context.Context, w *world) error {
+		return w.upsertCluster(ctx, namespace, name, func(obj *unstructured.Unstructured) {
+			specs := make([]interface{}, 0, len(components))
+			for _, c := range components {
+				specs = append(specs, map[string]interface{}{"name": c})
+			}
+			unstructured.SetNestedSlice(obj.Object, specs, "spec", "componentSpecs")
+		})
+	}
+}
+
+// Condition 设置集群的 status.conditions，只保留这一个条件
+func Condition(namespace, name, condType, status, reason string) Action {
+	return func(ctx context.Context, w *world) error {
+		return w.upsertCluster(ctx, namespace, name, func(obj *unstructured.Unstructured) {
+			unstructured.SetNestedSlice(obj.Object, []interface{}{
+				map[string]interface{}{"type": condType, "status": status, "reason": reason},
+			}, "status", "conditions")
+		})
+	}
+}
+
+// ComponentPhase 设置集群 status.components 中一个组件的 phase
+func ComponentPhase(namespace, name, component, phase string) Action {
+	return func(ctx context.Context, w *world) error {
+		return w.upsertCluster(ctx, namespace, name, func(obj *unstructured.Unstructured) {
+			unstructured.SetNestedField(obj.Object, phase, "status", "components", component, "phase")
+		})
+	}
+}
+
+// Ignore 在集群上设置忽略注解
+func Ignore(namespace, name string) Action {
+	return func(ctx context.Context, w *world) error {
+		return w.upsertCluster(ctx, namespace, name, func(obj *unstructured.Unstructured) {
+			annotations := obj.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[monitor.DefaultConfig().IgnoreAnnotation] = "true"
+			obj.SetAnnotations(annotations)
+		})
+	}
+}
+
+// Deleting 给集群打上 deletionTimestamp，时间为当前时刻
+func Deleting(namespace, name string) Action {
+	return func(ctx context.Context, w *world) error {
+		return w.upsertCluster(ctx, namespace, name, func(obj *unstructured.Unstructured) {
+			t := metav1.NewTime(w.now)
+			obj.SetDeletionTimestamp(&t)
+		})
+	}
+}
+
+// RemoveCluster 集群从 API 中消失
+func RemoveCluster(namespace, name string) Action {
+	return func(ctx context.Context, w *world) error {
+		return w.dynamic.Resource(clustersGVR).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	}
+}
+
+// RedisFailover 创建 RedisFailover 或修改其 status.state，用于配置了 Resources 的场景
+func RedisFailover(namespace, name, state string) Action {
+	return func(ctx context.Context, w *world) error {
+		if err := w.ensureNamespace(ctx, namespace); err != nil {
+			return err
+		}
+		client := w.dynamic.Resource(redisGVR).Namespace(namespace)
+		obj, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			obj = &unstructured.Unstructured{}
+			obj.SetAPIVersion("databases.spotahome.com/v1")
+			obj.SetKind("RedisFailover")
+			obj.SetNamespace(namespace)
+			obj.SetName(name)
+			unstructured.SetNestedField(obj.Object, state, "status", "state")
+			_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+			return err
+		}
+		unstructured.SetNestedField(obj.Object, state, "status", "state")
+		_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+		return err
+	}
+}
+
+// Acknowledge 确认集群当前的事件，与点击飞书卡片上的确认按钮相同
+func Acknowledge(namespace, name string) Action {
+	return func(ctx context.Context, w *world) error {
+		_, err := w.m.Acknowledge(ctx, namespace, name, "scenario")
+		return err
+	}
+}
+
+// Debt ns 出现 debt-limit0 配额
+func Debt(namespace string) Action {
+	return func(ctx context.Context, w *world) error {
+		quota := &corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "debt-limit0", Namespace: namespace}}
+		_, err := w.kube.CoreV1().ResourceQuotas(namespace).Create(ctx, quota, metav1.CreateOptions{})
+		return err
+	}
+}
+
+// PayDebt 删除 ns 的 debt-limit0 配额
+func PayDebt(namespace string) Action {
+	return func(ctx context.Context, w *world) error {
+		return w.kube.CoreV1().ResourceQuotas(namespace).Delete(ctx, "debt-limit0", metav1.DeleteOptions{})
+	}
+}
+
+// RemoveNamespace 删除 ns，模拟计费系统清理
+func RemoveNamespace(namespace string) Action {
+	return func(ctx context.Context, w *world) error {
+		return w.kube.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{})
+	}
+}
+
+// OpsRequest 创建运维操作或修改其 phase；首次创建时以当前时刻为开始时间
+func OpsRequest(namespace, name, opsType, cluster, phase string) Action {
+	return func(ctx context.Context, w *world) error {
+		client := w.dynamic.Resource(opsRequestsGVR).Namespace(namespace)
+		obj, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			obj = &unstructured.Unstructured{}
+			obj.SetAPIVersion("apps.kubeblocks.io/v1alpha1")
+			obj.SetKind("OpsRequest")
+			obj.SetNamespace(namespace)
+			obj.SetName(name)
+			obj.SetUID(types.UID(namespace + "-" + name))
+			unstructured.SetNestedField(obj.Object, opsType, "spec", "type")
+			unstructured.SetNestedField(obj.Object, cluster, "spec", "clusterName")
+			unstructured.SetNestedField(obj.Object, w.now.Format(time.RFC3339), "status", "startTimestamp")
+			unstructured.SetNestedField(obj.Object, phase, "status", "phase")
+			_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+			return err
+		}
+		unstructured.SetNestedField(obj.Object, phase, "status", "phase")
+		if phase == "Failed" || phase == "Succeed" {
+			unstructured.SetNestedField(obj.Object, w.now.Format(time.RFC3339), "status", "completionTimestamp")
+		}
+		_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+		return err
+	}
+}
+
+// BackupSchedule 为集群创建启用的备份计划，创建时间为当前时刻
+func BackupSchedule(namespace, cluster string) Action {
+	return func(ctx context.Context, w *world) error {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("dataprotection.kubeblocks.io/v1alpha1")
+		obj.SetKind("BackupSchedule")
+		obj.SetNamespace(namespace)
+		obj.SetName(cluster + "-schedule")
+		obj.SetLabels(map[string]string{"app.kubernetes.io/instance": cluster})
+		obj.SetCreationTimestamp(metav1.NewTime(w.now))
+		unstructured.SetNestedSlice(obj.Object, []interface{}{map[string]interface{}{"enabled": true}}, "spec", "schedules")
+		_, err := w.dynamic.Resource(schedulesGVR).Namespace(namespace).Create(ctx, obj, metav1.CreateOptions{})
+		return err
+	}
+}
+
+// Backup 集群的一次备份在当前时刻结束，phase 为 Completed 或 Failed
+func Backup(namespace, cluster, name, phase string) Action {
+	return func(ctx context.Context, w *world) error {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("dataprotection.kubeblocks.io/v1alpha1")
+		obj.SetKind("Backup")
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		obj.SetUID(types.UID(namespace + "-" + name))
+		obj.SetLabels(map[string]string{"app.kubernetes.io/instance": cluster})
+		obj.SetCreationTimestamp(metav1.NewTime(w.now))
+		unstructured.SetNestedField(obj.Object, phase, "status", "phase")
+		unstructured.SetNestedField(obj.Object, w.now.Format(time.RFC3339), "status", "completionTimestamp")
+		_, err := w.dynamic.Resource(backupsGVR).Namespace(namespace).Create(ctx, obj, metav1.CreateOptions{})
+		return err
+	}
+}
+
+// world 场景运行时的 fake 集群和时钟
+type world struct {
+	dynamic *dynamicfake.FakeDynamicClient
+	kube    *kubefake.Clientset
+	now     time.Time
+	m       *monitor.Monitor
+}
+
+func (w *world) upsertCluster(ctx context.Context, namespace, name string, mutate func(*unstructured.Unstructured)) error {
+	if err := w.ensureNamespace(ctx, namespace); err != nil {
+		return err
+	}
+	client := w.dynamic.Resource(clustersGVR).Namespace(namespace)
+	obj, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		obj = &unstructured.Unstructured{}
+		obj.SetAPIVersion("apps.kubeblocks.io/v1alpha1")
+		obj.SetKind("Cluster")
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		mutate(obj)
+		_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+		return err
+	}
+	mutate(obj)
+	_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+	return err
+}
+
+func (w *world) ensureNamespace(ctx context.Context, namespace string) error {
+	if _, err := w.kube.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err == nil {
+		return nil
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	_, err := w.kube.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+	return err
+}
+
+// recorder 记录发出的每条通知的摘要
+type recorder struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (r *recorder) Name() string {
+	return "scenario"
+}
+
+func (r *recorder) Render(report monitor.Report) ([]byte, error) {
+	return json.Marshal(report)
+}
+
+func (r *recorder) Send(_ context.Context, payload []byte) error {
+	var report monitor.Report
+	if err := json.Unmarshal(payload, &report); err != nil {
+		return err
+	}
+	r.record(summarize(report))
+	return nil
+}
+
+func (r *recorder) record(summary string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, summary)
+}
+
+// prefixedRecorder 租户或升级使用的通知后端，摘要前加上 prefix 记入同一个 recorder，@ 的用户附在最后
+type prefixedRecorder struct {
+	*recorder
+	name   string
+	prefix string
+}
+
+func (r *prefixedRecorder) Name() string {
+	return r.name
+}
+
+func (r *prefixedRecorder) Send(_ context.Context, payload []byte) error {
+	var report monitor.Report
+	if err := json.Unmarshal(payload, &report); err != nil {
+		return err
+	}
+	summary := r.prefix + summarize(report)
+	if len(report.Mentions) > 0 {
+		summary += " @" + strings.Join(report.Mentions, " @")
+	}
+	r.record(summary)
+	return nil
+}
+
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sent := r.sent
+	r.sent = nil
+	return sent
+}
+
+// SealosDebt 设置用户 sealos Debt 对象的 status.status，用户的 ns 为 ns-<user>。
+// 只在 DebtSource 为 crd 的场景中生效
+func SealosDebt(user, status string) Action {
+	return func(ctx context.Context, w *world) error {
+		client := w.dynamic.Resource(debtsGVR).Namespace("sealos-system")
+		obj, err := client.Get(ctx, "debt-"+user, metav1.GetOptions{})
+		if err != nil {
+			obj = &unstructured.Unstructured{}
+			obj.SetAPIVersion("account.sealos.io/v1")
+			obj.SetKind("Debt")
+			obj.SetNamespace("sealos-system")
+			obj.SetName("debt-" + user)
+			unstructured.SetNestedField(obj.Object, user, "spec", "userName")
+			unstructured.SetNestedField(obj.Object, status, "status", "status")
+			_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+			return err
+		}
+		unstructured.SetNestedField(obj.Object, status, "status", "status")
+		_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+		return err
+	}
+}
+
+// NamespaceWebhook 在 ns 上设置租户 webhook 注解，该 ns 的告警另外发送给以 tenant <ns>: 记录的租户后端
+func NamespaceWebhook(namespace, url string) Action {
+	return func(ctx context.Context, w *world) error {
+		if err := w.ensureNamespace(ctx, namespace); err != nil {
+			return err
+		}
+		ns, err := w.kube.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		metav1.SetMetaDataAnnotation(&ns.ObjectMeta, monitor.DefaultConfig().NamespaceWebhookAnnotation, url)
+		_, err = w.kube.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{})
+		return err
+	}
+}
+
+// IgnoreNamespace 在 ns 上设置忽略注解，该 ns 的集群不再告警
+func IgnoreNamespace(namespace string) Action {
+	return func(ctx context.Context, w *world) error {
+		if err := w.ensureNamespace(ctx, namespace); err != nil {
+			return err
+		}
+		ns, err := w.kube.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		metav1.SetMetaDataAnnotation(&ns.ObjectMeta, monitor.DefaultConfig().IgnoreAnnotation, "true")
+		_, err = w.kube.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{})
+		return err
+	}
+}
+
+// Silence 从当前时刻起静默集群 d，namespace 和 cluster 支持 glob
+func Silence(namespace, cluster string, d time.Duration) Action {
+	return func(ctx context.Context, w *world) error {
+		_, err := w.m.AddSilence(ctx, monitor.Silence{Namespace: namespace, Cluster: cluster, EndsAt: w.now.Add(d)})
+		return err
+	}
+}
+
+// SilencePhase 和 Silence 相同，但只静默处于 phase 的集群
+func SilencePhase(namespace, cluster, phase string, d time.Duration) Action {
+	return func(ctx context.Context, w *world) error {
+		_, err := w.m.AddSilence(ctx, monitor.Silence{Namespace: namespace, Cluster: cluster, Phase: phase, EndsAt: w.now.Add(d)})
+		return err
+	}
+}
+
+// 通知摘要：notice 取第一行，报告列出各条目的 namespace/name 和带说明的 phase
+func summarize(r monitor.Report) string {
+	if r.Notice != "" && len(r.Entries) == 0 {
+		return "notice: " + strings.SplitN(r.Notice, "\n", 2)[0]
+	}
+	if len(r.Entries) == 0 {
+		return "report: (empty)"
+	}
+	rows := make([]string, 0, len(r.Entries))
+	for _, e := range r.Entries {
+		rows = append(rows, e.Namespace+"/"+e.Name+" "+e.DisplayPhase())
+	}
+	return "report: " + strings.Join(rows, ", ")
+}
+
+// runScenario 执行场景，返回第一处与预期不符的地方
+func runScenario(ctx context.Context, s Scenario) error {
+	cfg := monitor.DefaultConfig()
+	if s.Config != nil {
+		cfg = *s.Config
+	}
+	// 场景中没有真实的 API server，不需要限速
+	cfg.APIQPS, cfg.APIBurst = 1e6, 1e6
+	// fake client 中 Debt CRD 总是"已安装"，auto 不会退回配额；Debt/PayDebt 动作针对的是配额
+	if cfg.DebtSource == monitor.DebtSourceAuto {
+		cfg.DebtSource = monitor.DebtSourceQuota
+	}
+
+	w := &world{
+		dynamic: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+			clustersGVR:      "ClusterList",
+			notificationsGVR: "NotificationList",
+			opsRequestsGVR:   "OpsRequestList",
+			backupsGVR:       "BackupList",
+			schedulesGVR:     "BackupScheduleList",
+			debtsGVR:         "DebtList",
+			redisGVR:         "RedisFailoverList",
+		}),
+		kube: kubefake.NewSimpleClientset(),
+		now:  epoch,
+	}
+	rec := &recorder{}
+	m := monitor.New(monitor.Deps{
+		Config:    cfg,
+		Dynamic:   w.dynamic,
+		Kube:      w.kube,
+		Notifiers: []monitor.Notifier{rec},
+		Now:       func() time.Time { return w.now },
+
+		NamespaceNotifier: func(namespace, _ string) (monitor.Notifier, error) {
+			return &prefixedRecorder{recorder: rec, name: "scenario-" + namespace, prefix: "tenant " + namespace + ": "}, nil
+		},
+		EscalationNotifiers: []monitor.Notifier{&prefixedRecorder{recorder: rec, name: "escalation", prefix: "escalation: "}},
+	})
+	w.m = m
+
+	for i, step := range s.Steps {
+		w.now = epoch.Add(step.At)
+		for _, action := range step.Actions {
+			if err := action(ctx, w); err != nil {
+				return fmt.Errorf("step %d (t=%s): action failed: %w", i, step.At, err)
+			}
+		}
+		m.FlushNotifications(ctx)
+		if err := m.RefreshDebt(ctx); err != nil {
+			return fmt.Errorf("step %d (t=%s): refresh debt: %w", i, step.At, err)
+		}
+		if err := m.StopDebtClusters(ctx); err != nil {
+			return fmt.Errorf("step %d (t=%s): stop debt clusters: %w", i, step.At, err)
+		}
+		if _, err := m.RunOnce(ctx); err != nil {
+			return fmt.Errorf("step %d (t=%s): run: %w", i, step.At, err)
+		}
+		if err := m.CheckOps(ctx); err != nil {
+			return fmt.Errorf("step %d (t=%s): check ops: %w", i, step.At, err)
+		}
+		if err := m.RefreshBackups(ctx); err != nil {
+			return fmt.Errorf("step %d (t=%s): refresh backups: %w", i, step.At, err)
+		}
+		if err := m.Remediate(ctx); err != nil {
+			return fmt.Errorf("step %d (t=%s): remediate: %w", i, step.At, err)
+		}
+
+		if got := rec.take(); !equal(got, step.Expect) {
+			return fmt.Errorf("step %d (t=%s): notifications = %q, want %q", i, step.At, got, step.Expect)
+		}
+		actions := make(map[string]string)
+		for _, d := range m.Decisions() {
+			actions[d.Namespace+"/"+d.Name] = d.Action
+		}
+		keys := make([]string, 0, len(step.Decisions))
+		for key := range step.Decisions {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if got := actions[key]; got != step.Decisions[key] {
+				return fmt.Errorf("step %d (t=%s): decision for %s = %q, want %q", i, step.At, key, got, step.Decisions[key])
+			}
+		}
+	}
+	return nil
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,350 @@
+package monitor_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+const minute = time.Minute
+
+func TestScenarios(t *testing.T) {
+	for _, s := range canonicalScenarios() {
+		t.Run(s.Name, func(t *testing.T) {
+			if err := runScenario(context.Background(), s); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// canonicalScenarios 覆盖各功能交互的基准场景
+func canonicalScenarios() []Scenario {
+	repeated := monitor.DefaultConfig()
+	repeated.RepeatedIncidentThreshold = 3
+	threeChecks := monitor.DefaultConfig()
+	threeChecks.AlertAfterChecks = 3
+	backupSLA := monitor.DefaultConfig()
+	backupSLA.BackupSLA = 24 * time.Hour
+	maintenance := monitor.DefaultConfig()
+	maintenance.MaintenanceWindows = []monitor.MaintenanceWindow{{Name: "nightly", Namespace: "ns1", Schedule: "CRON_TZ=UTC 0 0 * * *", Duration: 20 * minute}}
+	escalation := monitor.DefaultConfig()
+	escalation.Escalations = []monitor.EscalationTier{{Name: "oncall", After: 30 * minute, Notifiers: []string{"escalation"}, Mentions: []string{"ou_oncall"}}}
+	debtCRD := monitor.DefaultConfig()
+	debtCRD.DebtSource = monitor.DebtSourceCRD
+	remediation := monitor.DefaultConfig()
+	remediation.RemediationAfter = 20 * minute
+	remediation.RemediationMaxAttempts = 2
+	stopInDebt := monitor.DefaultConfig()
+	stopInDebt.StopInDebt = true
+	rateLimited := monitor.DefaultConfig()
+	rateLimited.NotifyRatePerMinute = 0.1
+	rateLimited.NotifyBurst = 1
+	resources := monitor.DefaultConfig()
+	resources.Resources = []monitor.MonitoredResource{
+		{Resource: "apps.kubeblocks.io/v1alpha1/clusters", Kind: "Cluster"},
+		{Resource: "databases.spotahome.com/v1/redisfailovers", Kind: "RedisFailover", PhasePath: "status.state",
+			HealthyPhases: []string{"Healthy"}, FailedPhases: []string{"Failed"}},
+	}
+	conditions := monitor.DefaultConfig()
+	conditions.ConditionRules = []monitor.ConditionRule{
+		{Type: "Ready", Reasons: []string{"ComponentsNotReady"}},
+		{Component: "*", ComponentPhases: []string{"Failed"}, Phase: "Failed"},
+	}
+
+	var flapping []Step
+	for i := 0; i < 4; i++ {
+		at := time.Duration(i) * 10 * minute
+		flapping = append(flapping,
+			Step{At: at, Actions: []Action{Phase("ns1", "a", "Failed")}},
+			Step{At: at + 5*minute, Actions: []Action{Phase("ns1", "a", "Running")}},
+		)
+	}
+	flapping[len(flapping)-1].Expect = []string{"notice: Repeated short incidents in the last 24h:"}
+
+	return []Scenario{
+		{
+			Name: "failed cluster alerts on the second observation",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed")}, Decisions: map[string]string{"ns1/a": "pending"}},
+				{At: 5 * minute, Expect: []string{"report: ns1/a Failed"}, Decisions: map[string]string{"ns1/a": "alert"}},
+			},
+		},
+		{
+			Name:   "a threshold of three checks ignores a short upgrade and alerts on the third",
+			Config: &threeChecks,
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Updating"), Phase("ns1", "b", "Abnormal")}},
+				{At: 5 * minute, Decisions: map[string]string{"ns1/a": "pending", "ns1/b": "pending"}},
+				{At: 10 * minute, Actions: []Action{Phase("ns1", "a", "Running")}, Expect: []string{"report: ns1/b Abnormal"},
+					Decisions: map[string]string{"ns1/a": "healthy", "ns1/b": "alert"}},
+			},
+		},
+		{
+			Name: "alerted cluster returning to Running sends a recovery notice",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed")}},
+				{At: 5 * minute, Expect: []string{"report: ns1/a Failed"}},
+				{At: 10 * minute, Actions: []Action{Phase("ns1", "a", "Running")}, Expect: []string{
+					"notice: RECOVERED: a in ns1 is Running again (was Failed), downtime 10m0s",
+					"report: (empty)",
+				}},
+			},
+		},
+		{
+			Name: "abnormal cluster that recovers before the second check never alerts",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Abnormal")}},
+				{At: 5 * minute, Actions: []Action{Phase("ns1", "a", "Running")}, Decisions: map[string]string{"ns1/a": "healthy"}},
+			},
+		},
+		{
+			Name: "unchanged incidents are not re-sent within the realert interval",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed")}},
+				{At: 5 * minute, Expect: []string{"report: ns1/a Failed"}},
+				{At: 10 * minute},
+				{At: 15 * minute},
+			},
+		},
+		{
+			Name: "unchanged incidents are re-sent after the realert interval",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed")}},
+				{At: 5 * minute, Expect: []string{"report: ns1/a Failed"}},
+				{At: 2*time.Hour + 10*minute, Expect: []string{"report: ns1/a Failed"}},
+			},
+		},
+		{
+			Name: "a new cluster failing re-sends the whole set",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed")}},
+				{At: 5 * minute, Actions: []Action{Phase("ns2", "b", "Failed")}, Expect: []string{"report: ns1/a Failed"}},
+				{At: 10 * minute, Expect: []string{"report: ns1/a Failed, ns2/b Failed"}},
+			},
+		},
+		{
+			Name: "failed cluster in a namespace in debt is suppressed",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Debt("ns1"), Phase("ns1", "a", "Failed")}, Decisions: map[string]string{"ns1/a": "suppress"}},
+				{At: 5 * minute, Decisions: map[string]string{"ns1/a": "suppress"}},
+			},
+		},
+		{
+			Name: "debt appearing mid-incident clears the alert",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed")}},
+				{At: 5 * minute, Expect: []string{"report: ns1/a Failed"}},
+				{At: 10 * minute, Actions: []Action{Debt("ns1")}, Expect: []string{"report: (empty)"}, Decisions: map[string]string{"ns1/a": "suppress"}},
+			},
+		},
+		{
+			Name: "paying the debt lets a still failed cluster alert again",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Debt("ns1"), Phase("ns1", "a", "Failed")}},
+				{At: 5 * minute, Actions: []Action{PayDebt("ns1")}, Expect: []string{"notice: Namespaces recovered from debt:"}, Decisions: map[string]string{"ns1/a": "pending"}},
+				{At: 10 * minute, Expect: []string{"report: ns1/a Failed"}},
+			},
+		},
+		{
+			Name:   "a sealos Debt outside the normal period suppresses the user's namespace until it is paid",
+			Config: &debtCRD,
+			Steps: []Step{
+				{At: 0, Actions: []Action{SealosDebt("u1", "WarningPeriod"), Phase("ns-u1", "a", "Failed")}, Decisions: map[string]string{"ns-u1/a": "suppress"}},
+				{At: 5 * minute, Actions: []Action{SealosDebt("u1", "NormalPeriod")}, Expect: []string{"notice: Namespaces recovered from debt:"}, Decisions: map[string]string{"ns-u1/a": "pending"}},
+				{At: 10 * minute, Expect: []string{"report: ns-u1/a Failed"}},
+			},
+		},
+		{
+			Name: "cluster removed by debt cleanup disappears without a notification",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Abnormal")}},
+				{At: 5 * minute, Actions: []Action{Debt("ns1")}, Expect: []string{"report: ns1/a Abnormal"}},
+				{At: 10 * minute, Actions: []Action{RemoveCluster("ns1", "a"), RemoveNamespace("ns1")}},
+			},
+		},
+		{
+			Name: "an acknowledged incident stops repeating and a new incident alerts again",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed")}},
+				{At: 5 * minute, Expect: []string{"report: ns1/a Failed"}},
+				{At: 10 * minute, Actions: []Action{Acknowledge("ns1", "a")}, Expect: []string{"report: (empty)"}, Decisions: map[string]string{"ns1/a": "suppress"}},
+				{At: 3 * time.Hour},
+				{At: 3*time.Hour + 5*minute, Actions: []Action{Phase("ns1", "a", "Running")},
+					Expect: []string{"notice: RECOVERED: a in ns1 is Running again (was Failed), downtime 3h5m0s"}},
+				{At: 3*time.Hour + 10*minute, Actions: []Action{Phase("ns1", "a", "Failed")}},
+				{At: 3*time.Hour + 15*minute, Expect: []string{"report: ns1/a Failed"}},
+			},
+		},
+		{
+			Name: "a silence suppresses a failed cluster until it expires",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Silence("ns1", "a", 30*minute), Phase("ns1", "a", "Failed")}, Decisions: map[string]string{"ns1/a": "pending"}},
+				{At: 5 * minute, Decisions: map[string]string{"ns1/a": "suppress"}},
+				{At: 30 * minute, Expect: []string{"report: ns1/a Failed"}, Decisions: map[string]string{"ns1/a": "alert"}},
+			},
+		},
+		{
+			Name:   "notifications over the rate limit are combined into one when tokens are available again",
+			Config: &rateLimited,
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed"), Phase("ns1", "b", "Failed")}},
+				{At: 5 * minute, Expect: []string{"report: ns1/a Failed, ns1/b Failed"}},
+				{At: 6 * minute, Actions: []Action{Phase("ns1", "a", "Running")}},
+				{At: 8 * minute, Actions: []Action{Phase("ns1", "b", "Running")}},
+				{At: 15 * minute, Expect: []string{"notice: Notification rate limit reached, 3 notifications were combined into this one:"}},
+			},
+		},
+		{
+			Name: "a silence with a phase matcher only suppresses clusters in that phase",
+			Steps: []Step{
+				{At: 0, Actions: []Action{SilencePhase("ns1", "*", "Abnormal", time.Hour), Phase("ns1", "a", "Abnormal"), Phase("ns1", "b", "Failed")}},
+				{At: 10 * minute, Expect: []string{"report: ns1/b Failed"}, Decisions: map[string]string{"ns1/a": "suppress", "ns1/b": "alert"}},
+				{At: 15 * minute, Actions: []Action{Phase("ns1", "a", "Failed")},
+					Expect: []string{"report: ns1/a Failed, ns1/b Failed"}, Decisions: map[string]string{"ns1/a": "alert"}},
+			},
+		},
+		{
+			Name:   "a maintenance window suppresses alerts in its namespace only while it is open",
+			Config: &maintenance,
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed"), Phase("ns2", "b", "Failed")}},
+				{At: 10 * minute, Expect: []string{"report: ns2/b Failed"}, Decisions: map[string]string{"ns1/a": "suppress", "ns2/b": "alert"}},
+				{At: 20 * minute, Expect: []string{"report: ns1/a Failed, ns2/b Failed"}, Decisions: map[string]string{"ns1/a": "alert"}},
+			},
+		},
+		{
+			Name: "alerts and recoveries of an annotated namespace also go to the tenant's webhook",
+			Steps: []Step{
+				{At: 0, Actions: []Action{NamespaceWebhook("ns1", "https://tenant.example/hook"), Phase("ns1", "a", "Failed"), Phase("ns2", "b", "Failed")}},
+				{At: 5 * minute, Expect: []string{"report: ns1/a Failed, ns2/b Failed", "tenant ns1: report: ns1/a Failed"}},
+				{At: 10 * minute, Actions: []Action{Phase("ns1", "a", "Running")}, Expect: []string{
+					"notice: RECOVERED: a in ns1 is Running again (was Failed), downtime 10m0s",
+					"tenant ns1: notice: RECOVERED: a in ns1 is Running again (was Failed), downtime 10m0s",
+					"report: ns2/b Failed",
+					"tenant ns1: report: (empty)",
+				}},
+			},
+		},
+		{
+			Name:   "a cluster failing past the escalation threshold is escalated once until it recovers",
+			Config: &escalation,
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed")}},
+				{At: 5 * minute, Expect: []string{"report: ns1/a Failed"}},
+				{At: 30 * minute, Expect: []string{"escalation: report: ns1/a Failed @ou_oncall"}},
+				{At: 35 * minute},
+				{At: 40 * minute, Actions: []Action{Phase("ns1", "a", "Running")}, Expect: []string{
+					"notice: RECOVERED: a in ns1 is Running again (was Failed), downtime 40m0s",
+					"escalation: report: (empty)",
+					"report: (empty)",
+				}},
+			},
+		},
+		{
+			Name: "deleting a failed cluster clears the alert",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed")}},
+				{At: 5 * minute, Expect: []string{"report: ns1/a Failed"}},
+				{At: 10 * minute, Actions: []Action{Deleting("ns1", "a")}, Expect: []string{"report: (empty)"}, Decisions: map[string]string{"ns1/a": "suppress"}},
+			},
+		},
+		{
+			Name: "cluster stuck deleting alerts after the threshold",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Deleting"), Deleting("ns1", "a")}, Decisions: map[string]string{"ns1/a": "suppress"}},
+				{At: 35 * minute, Expect: []string{"report: ns1/a Deleting(stuck 35m0s)"}, Decisions: map[string]string{"ns1/a": "alert"}},
+			},
+		},
+		{
+			Name: "failed and long-running OpsRequests notify once each",
+			Steps: []Step{
+				{At: 0, Actions: []Action{OpsRequest("ns1", "restart-a", "Restart", "a", "Running"), OpsRequest("ns1", "upgrade-b", "Upgrade", "b", "Running")}},
+				{At: 10 * minute, Actions: []Action{OpsRequest("ns1", "restart-a", "Restart", "a", "Failed")},
+					Expect: []string{"notice: KubeBlocks operations need attention:"}},
+				{At: 30 * minute},
+				{At: 70 * minute, Expect: []string{"notice: KubeBlocks operations need attention:"}},
+				{At: 80 * minute},
+			},
+		},
+		{
+			Name:   "failed and overdue backups notify once until they recover",
+			Config: &backupSLA,
+			Steps: []Step{
+				{At: 0, Actions: []Action{BackupSchedule("ns1", "a"), Backup("ns1", "a", "a-1", "Completed")}},
+				{At: time.Hour, Actions: []Action{Backup("ns1", "a", "a-2", "Failed")}, Expect: []string{"notice: Backups need attention:"}},
+				{At: 2 * time.Hour},
+				{At: 25 * time.Hour, Expect: []string{"notice: Backups need attention:"}},
+				{At: 26 * time.Hour},
+				{At: 27 * time.Hour, Actions: []Action{Backup("ns1", "a", "a-3", "Completed")}},
+				{At: 52 * time.Hour, Expect: []string{"notice: Backups need attention:"}},
+			},
+		},
+		{
+			Name:   "clusters in a namespace in debt are stopped once per debt",
+			Config: &stopInDebt,
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Running"), Components("ns1", "a", "mysql"), Phase("ns1", "b", "Stopped"), Components("ns1", "b", "redis")}},
+				{At: 5 * minute, Actions: []Action{Debt("ns1")}, Expect: []string{"notice: Stopped databases in namespaces in debt, start them again after the debt is paid:"}},
+				{At: 10 * minute, Actions: []Action{Phase("ns1", "a", "Failed")}, Decisions: map[string]string{"ns1/a": "suppress"}},
+				{At: 15 * minute, Actions: []Action{Phase("ns1", "a", "Stopped")}},
+			},
+		},
+		{
+			Name:   "a long failed cluster is restarted at most the configured number of times",
+			Config: &remediation,
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed"), Components("ns1", "a", "mysql"), Phase("ns2", "b", "Failed"), Components("ns2", "b", "redis"), Debt("ns2")}},
+				{At: 5 * minute, Expect: []string{"report: ns1/a Failed"}},
+				{At: 20 * minute, Expect: []string{"notice: Auto-remediation: created restart OpsRequest a-restart-1704068400 for a in ns1, Failed for 20m0s (attempt 1 of 2)"}},
+				{At: 30 * minute},
+				{At: 40 * minute, Expect: []string{"notice: Auto-remediation: created restart OpsRequest a-restart-1704069600 for a in ns1, Failed for 40m0s (attempt 2 of 2)"}},
+				{At: 60 * minute},
+			},
+		},
+		{
+			Name:   "configured custom resources are checked alongside clusters",
+			Config: &resources,
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Running"), RedisFailover("ns1", "a", "Failed")},
+					Decisions: map[string]string{"ns1/a": "healthy", "ns1/redisfailovers/a": "pending"}},
+				{At: 5 * minute, Expect: []string{"report: ns1/redisfailovers/a Failed"}},
+				{At: 10 * minute, Actions: []Action{RedisFailover("ns1", "a", "Healthy")}, Expect: []string{
+					"notice: RECOVERED: redisfailovers/a in ns1 is Running again (was Failed), downtime 10m0s",
+					"report: (empty)",
+				}},
+			},
+		},
+		{
+			Name:   "a running cluster alerts on a matching condition and a failed component",
+			Config: &conditions,
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Running"), Condition("ns1", "a", "Ready", "False", "ComponentsNotReady")},
+					Decisions: map[string]string{"ns1/a": "pending"}},
+				{At: 5 * minute, Expect: []string{"report: ns1/a Abnormal(Ready=False: ComponentsNotReady)"}},
+				{At: 10 * minute, Actions: []Action{ComponentPhase("ns1", "a", "mysql", "Failed")},
+					Expect: []string{"report: ns1/a Failed(component mysql Failed)"}},
+				{At: 15 * minute, Actions: []Action{Condition("ns1", "a", "Ready", "True", "ClusterReady"), ComponentPhase("ns1", "a", "mysql", "Running")},
+					Decisions: map[string]string{"ns1/a": "healthy"}, Expect: []string{
+						"notice: RECOVERED: a in ns1 is Running again (was Failed), downtime 15m0s",
+						"report: (empty)",
+					}},
+			},
+		},
+		{
+			Name: "clusters annotated to be ignored, or in an ignored namespace, never alert",
+			Steps: []Step{
+				{At: 0, Actions: []Action{Phase("ns1", "a", "Failed"), Ignore("ns1", "a"), Phase("ns2", "b", "Failed"), IgnoreNamespace("ns2")},
+					Decisions: map[string]string{"ns1/a": "suppress", "ns2/b": "suppress"}},
+				{At: 5 * minute, Decisions: map[string]string{"ns1/a": "suppress", "ns2/b": "suppress"}},
+			},
+		},
+		{
+			Name:   "repeated short incidents within a day produce one notice",
+			Config: &repeated,
+			Steps:  flapping,
+		},
+	}
+}
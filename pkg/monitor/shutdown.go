@@ -0,0 +1,35 @@
+package monitor
+
+import (
+	"context"
+	"time"
+)
+
+// detached 返回不随 ctx 取消的 context：ctx 结束后再等待 ShutdownTimeout 才取消，
+// 让收到退出信号时已经开始的通知发送完成，滚动更新时不丢告警
+func (m *Monitor) detached(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(m.cfg.ShutdownTimeout, cancel)
+	})
+	return detached, func() {
+		stop()
+		cancel()
+	}
+}
+
+// flush 在 Run 返回前发送被速率限制暂缓的通知，保存去重状态、巡检状态和未发送的回调，新副本从这里接着运行
+func (m *Monitor) flush(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.cfg.ShutdownTimeout)
+	defer cancel()
+	m.flushNotifications(ctx, true)
+	m.saveAlertState(ctx)
+	m.saveCheckpoint(ctx)
+	if m.cfg.ResolutionCallbackURL != "" {
+		m.callbacks.mu.Lock()
+		pending := append([]ResolutionCallback(nil), m.callbacks.pending...)
+		m.callbacks.mu.Unlock()
+		m.savePendingCallbacks(ctx, pending)
+	}
+	m.log.Info("Saved state before stopping")
+}
@@ -0,0 +1,246 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"database-monitor/pkg/schedule"
+)
+
+// 临时静默在 StateStore 中的 key
+const silencesKey = "silences"
+
+// MaintenanceWindow 周期性的维护窗口：Schedule 每次触发后的 Duration 内不告警匹配的集群
+type MaintenanceWindow struct {
+	Name string `json:"name"`
+	// ns 和集群名，支持 glob，为空表示全部
+	Namespace string `json:"namespace,omitempty"`
+	Cluster   string `json:"cluster,omitempty"`
+	// 窗口开始时间，格式与 checkSchedule 相同，可以用 CRON_TZ= 指定时区
+	Schedule string        `json:"schedule"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Silence 临时静默，EndsAt 之后自动失效
+type Silence struct {
+	ID string `json:"id"`
+	// 静默所属的区域，只在查询结果中设置
+	Region string `json:"region,omitempty"`
+	// ns、集群名和 phase，支持 glob，为空表示全部
+	Namespace string    `json:"namespace,omitempty"`
+	Cluster   string    `json:"cluster,omitempty"`
+	Phase     string    `json:"phase,omitempty"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	// 确认告警：只覆盖确认时已打开的事件，之后新打开的事件照常告警
+	Acknowledged bool `json:"acknowledged,omitempty"`
+}
+
+// 确认最长的有效期，事件一直不关闭时到期后重新告警
+const ackMaxDuration = 7 * 24 * time.Hour
+
+// 匹配 ns 和集群名，空模式匹配全部
+func matchScope(nsPattern, clusterPattern, namespace, name string) bool {
+	if nsPattern != "" {
+		if ok, _ := path.Match(nsPattern, namespace); !ok {
+			return false
+		}
+	}
+	if clusterPattern != "" {
+		if ok, _ := path.Match(clusterPattern, name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// maintenanceWindow 解析后的维护窗口
+type maintenanceWindow struct {
+	MaintenanceWindow
+	cron *schedule.Cron
+}
+
+// now 是否在窗口内：最近一次触发在 Duration 之内
+func (w maintenanceWindow) active(now time.Time) bool {
+	start := w.cron.Next(now.Add(-w.Duration))
+	return !start.IsZero() && !start.After(now)
+}
+
+// ValidateMaintenanceWindow 检查维护窗口的时间表和时长
+func ValidateMaintenanceWindow(w MaintenanceWindow) error {
+	if w.Duration <= 0 {
+		return fmt.Errorf("duration must be positive, got %s", w.Duration)
+	}
+	for _, p := range []string{w.Namespace, w.Cluster} {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	_, err := schedule.Parse(w.Schedule, nil)
+	return err
+}
+
+// silenceTracker 维护窗口和临时静默
+type silenceTracker struct {
+	mu       sync.Mutex
+	windows  []maintenanceWindow
+	silences []Silence
+}
+
+// 集群当前是否被维护窗口、静默或确认覆盖，返回原因；调用方需持有 m.mu
+func (m *Monitor) silenced(namespace, name, phase string, now time.Time) (string, bool) {
+	inc := m.openIncidents[clusterKey(namespace, name)]
+	t := &m.silences
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, w := range t.windows {
+		if matchScope(w.Namespace, w.Cluster, namespace, name) && w.active(now) {
+			return "maintenance window " + w.Name, true
+		}
+	}
+	for _, s := range t.silences {
+		if s.Acknowledged && (inc == nil || inc.OpenedAt.After(s.StartsAt)) {
+			continue
+		}
+		if s.Phase != "" {
+			if ok, _ := path.Match(s.Phase, phase); !ok {
+				continue
+			}
+		}
+		if matchScope(s.Namespace, s.Cluster, namespace, name) && !now.Before(s.StartsAt) && now.Before(s.EndsAt) {
+			if s.Acknowledged {
+				return "acknowledged by " + s.CreatedBy, true
+			}
+			reason := "silence " + s.ID
+			if s.Reason != "" {
+				reason += " (" + s.Reason + ")"
+			}
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+// Silences 未过期的静默，按开始时间排序
+func (m *Monitor) Silences() []Silence {
+	now := m.now()
+	t := &m.silences
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	silences := make([]Silence, 0, len(t.silences))
+	for _, s := range t.silences {
+		if now.Before(s.EndsAt) {
+			s.Region = m.cfg.Region
+			silences = append(silences, s)
+		}
+	}
+	sort.Slice(silences, func(i, j int) bool { return silences[i].StartsAt.Before(silences[j].StartsAt) })
+	return silences
+}
+
+// AddSilence 创建静默并保存。StartsAt 为空时立即生效；ID 由监控生成
+func (m *Monitor) AddSilence(ctx context.Context, s Silence) (Silence, error) {
+	now := m.now()
+	if s.StartsAt.IsZero() {
+		s.StartsAt = now
+	}
+	if !s.EndsAt.After(s.StartsAt) || !s.EndsAt.After(now) {
+		return Silence{}, fmt.Errorf("endsAt must be after startsAt and in the future")
+	}
+	for _, p := range []string{s.Namespace, s.Cluster, s.Phase} {
+		if _, err := path.Match(p, ""); err != nil {
+			return Silence{}, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Silence{}, err
+	}
+	s.ID, s.Region = hex.EncodeToString(id), ""
+
+	t := &m.silences
+	t.mu.Lock()
+	t.silences = append(t.silences, s)
+	t.mu.Unlock()
+	m.log.Info("Silence created", "id", s.ID, "namespace", s.Namespace, "cluster", s.Cluster, "phase", s.Phase, "endsAt", s.EndsAt, "reason", s.Reason)
+	return s, m.saveSilences(ctx)
+}
+
+// Acknowledge 确认集群当前的事件：该事件不再出现在报告中，也不再重复提醒和升级，事件关闭后自动失效。
+// 确认和静默一样保存在 StateStore 中，可以由非 leader 副本的管理接口创建
+func (m *Monitor) Acknowledge(ctx context.Context, namespace, name, by string) (Silence, error) {
+	m.mu.Lock()
+	if inc, ok := m.openIncidents[clusterKey(namespace, name)]; ok {
+		inc.Timeline = append(inc.Timeline, IncidentEvent{Time: m.now(), Message: "acknowledged by " + by})
+	}
+	m.mu.Unlock()
+	return m.AddSilence(ctx, Silence{Namespace: namespace, Cluster: name, EndsAt: m.now().Add(ackMaxDuration),
+		Reason: "acknowledged", CreatedBy: by, Acknowledged: true})
+}
+
+// DeleteSilence 提前结束静默，不存在时返回 false
+func (m *Monitor) DeleteSilence(ctx context.Context, id string) (bool, error) {
+	t := &m.silences
+	t.mu.Lock()
+	found := false
+	for i, s := range t.silences {
+		if s.ID == id {
+			t.silences = append(t.silences[:i], t.silences[i+1:]...)
+			found = true
+			break
+		}
+	}
+	t.mu.Unlock()
+	if !found {
+		return false, nil
+	}
+	m.log.Info("Silence deleted", "id", id)
+	return true, m.saveSilences(ctx)
+}
+
+// 从 StateStore 读取静默。多副本时静默可能由其他副本的管理接口创建，每轮巡检前重新读取
+func (m *Monitor) loadSilences(ctx context.Context) error {
+	if m.store == nil {
+		return nil
+	}
+	data, err := m.store.Get(ctx, silencesKey)
+	if err != nil || data == "" {
+		return err
+	}
+	var silences []Silence
+	if err := json.Unmarshal([]byte(data), &silences); err != nil {
+		return err
+	}
+	m.silences.mu.Lock()
+	m.silences.silences = silences
+	m.silences.mu.Unlock()
+	return nil
+}
+
+// 保存未过期的静默
+func (m *Monitor) saveSilences(ctx context.Context) error {
+	silences := m.Silences()
+	for i := range silences {
+		silences[i].Region = ""
+	}
+	m.silences.mu.Lock()
+	m.silences.silences = silences
+	m.silences.mu.Unlock()
+	if m.store == nil {
+		return nil
+	}
+	data, err := json.Marshal(silences)
+	if err != nil {
+		return err
+	}
+	return m.store.Set(ctx, silencesKey, string(data))
+}
@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ClusterState 集群当前的 phase 和告警决策，供状态查询接口使用
+type ClusterState struct {
+	Region        string `json:"region,omitempty"`
+	Namespace     string `json:"namespace"`
+	Name          string `json:"name"`
+	Phase         string `json:"phase"`
+	PreviousPhase string `json:"previousPhase,omitempty"`
+	Action        string `json:"action"`
+	Reason        string `json:"reason"`
+	InDebt        bool   `json:"inDebt"`
+	// 未关闭事件的 ID，没有事件时为空
+	IncidentID string    `json:"incidentId,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// ListClusters 直接从 apiserver 列出受监控的集群及其 phase，按 namespace/name 排序。
+// 不评估也不通知，Action 和 Reason 为空；欠费标记来自最近一次 RefreshDebt
+func (m *Monitor) ListClusters(ctx context.Context) ([]ClusterState, error) {
+	var clusters []ClusterState
+	for _, res := range m.resources {
+		err := m.listSelected(ctx, m.resourceGVR(res), m.cfg.ClusterSelector, func(cluster *unstructured.Unstructured) {
+			if !m.namespaceAllowed(cluster.GetNamespace()) {
+				return
+			}
+			phase, _, _, _ := res.phase(cluster)
+			clusters = append(clusters, ClusterState{
+				Region:    m.cfg.Region,
+				Namespace: cluster.GetNamespace(),
+				Name:      res.trackedName(cluster.GetName()),
+				Phase:     phase,
+				InDebt:    m.debt.inDebt(cluster.GetNamespace()),
+			})
+		})
+		if err != nil && (res == m.resources[0] || !isMissingCRD(err)) {
+			return nil, err
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusterKey(clusters[i].Namespace, clusters[i].Name) < clusterKey(clusters[j].Namespace, clusters[j].Name)
+	})
+	return clusters, nil
+}
+
+// RegionIncident 带区域名的事件，多区域时用于区分来源
+type RegionIncident struct {
+	Region string `json:"region,omitempty"`
+	Incident
+}
+
+// DebtNamespace 当前欠费的 ns
+type DebtNamespace struct {
+	Region    string `json:"region,omitempty"`
+	Namespace string `json:"namespace"`
+	// 最近一次观察到欠费的时间
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// Region 巡检的区域，单集群时为空
+func (m *Monitor) Region() string {
+	return m.cfg.Region
+}
+
+// Clusters 返回每个集群的当前状态，按 namespace/name 排序
+func (m *Monitor) Clusters() []ClusterState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clusters := make([]ClusterState, 0, len(m.decisions))
+	for key, d := range m.decisions {
+		c := ClusterState{
+			Region:        m.cfg.Region,
+			Namespace:     d.Namespace,
+			Name:          d.Name,
+			Phase:         d.Phase,
+			PreviousPhase: m.previousPhases[key],
+			Action:        d.Action,
+			Reason:        d.Reason,
+			InDebt:        m.debt.inDebt(d.Namespace),
+			UpdatedAt:     d.Time,
+		}
+		if inc, ok := m.openIncidents[key]; ok {
+			c.IncidentID = inc.ID
+		}
+		clusters = append(clusters, c)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusterKey(clusters[i].Namespace, clusters[i].Name) < clusterKey(clusters[j].Namespace, clusters[j].Name)
+	})
+	return clusters
+}
+
+// ActiveAlerts 返回所有未关闭的事件，按打开时间排序
+func (m *Monitor) ActiveAlerts() []RegionIncident {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	alerts := make([]RegionIncident, 0, len(m.openIncidents))
+	for _, inc := range m.openIncidents {
+		a := RegionIncident{Region: m.cfg.Region, Incident: *inc}
+		a.Timeline = append([]IncidentEvent(nil), inc.Timeline...)
+		alerts = append(alerts, a)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].OpenedAt.Before(alerts[j].OpenedAt) })
+	return alerts
+}
+
+// RecentIncidents 返回最近关闭的至多 limit 个事件，最新的在前；limit <= 0 时返回内存中保留的全部
+func (m *Monitor) RecentIncidents(limit int) []RegionIncident {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	history := m.incidentHistory
+	if limit > 0 && len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	incidents := make([]RegionIncident, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		inc := RegionIncident{Region: m.cfg.Region, Incident: *history[i]}
+		inc.Timeline = append([]IncidentEvent(nil), history[i].Timeline...)
+		incidents = append(incidents, inc)
+	}
+	return incidents
+}
+
+// DebtNamespaces 返回当前欠费的 ns，按名称排序
+func (m *Monitor) DebtNamespaces() []DebtNamespace {
+	m.debt.mu.RLock()
+	defer m.debt.mu.RUnlock()
+	namespaces := make([]DebtNamespace, 0, len(m.debt.record))
+	for ns := range m.debt.record {
+		namespaces = append(namespaces, DebtNamespace{Region: m.cfg.Region, Namespace: ns, LastSeen: m.debt.seen[ns]})
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Namespace < namespaces[j].Namespace })
+	return namespaces
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// 聚合状态在 StatusStore 中的 key
+const statusKey = "clusters"
+
+// ClusterStatus 发布给其他控制器的单个集群状态
+type ClusterStatus struct {
+	Phase string `json:"phase"`
+	// 当前事件打开的时间，即这次开始不健康的时间；健康时为空
+	UnhealthySince *time.Time `json:"unhealthySince,omitempty"`
+	IncidentID     string     `json:"incidentId,omitempty"`
+	InDebt         bool       `json:"inDebt,omitempty"`
+}
+
+// StatusSnapshot 每轮巡检后发布的聚合状态，Clusters 的 key 为 namespace/name
+type StatusSnapshot struct {
+	Region    string                   `json:"region,omitempty"`
+	UpdatedAt time.Time                `json:"updatedAt"`
+	Clusters  map[string]ClusterStatus `json:"clusters"`
+}
+
+// 当前所有集群的状态，调用方不能持有 m.mu
+func (m *Monitor) statusSnapshot() StatusSnapshot {
+	s := StatusSnapshot{Region: m.cfg.Region, UpdatedAt: m.now(), Clusters: make(map[string]ClusterStatus)}
+	// 与评估时的加锁顺序一致：先 m.mu 再 debt.mu
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, phase := range m.phases {
+		cs := ClusterStatus{Phase: phase}
+		if inc, ok := m.openIncidents[key]; ok {
+			opened := inc.OpenedAt
+			cs.UnhealthySince, cs.IncidentID = &opened, inc.ID
+		}
+		namespace, _, _ := strings.Cut(key, "/")
+		cs.InDebt = m.debt.inDebt(namespace)
+		s.Clusters[key] = cs
+	}
+	return s
+}
+
+// 每轮巡检后发布聚合状态。集群状态没有变化时最多每个 CheckInterval 写入一次，
+// 消费方可以根据 updatedAt 判断监控是否仍在运行
+func (m *Monitor) publishStatus(ctx context.Context) {
+	if m.statusStore == nil {
+		return
+	}
+	s := m.statusSnapshot()
+	clusters, err := json.Marshal(s.Clusters)
+	if err != nil {
+		m.log.Error("Error encoding cluster status", "err", err)
+		return
+	}
+	if string(clusters) == m.published.clusters && s.UpdatedAt.Sub(m.published.at) < m.cfg.CheckInterval {
+		return
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		m.log.Error("Error encoding cluster status", "err", err)
+		return
+	}
+	if err := m.statusStore.Set(ctx, statusKey, string(data)); err != nil {
+		m.log.Error("Error publishing cluster status", "err", err)
+		return
+	}
+	m.published.clusters, m.published.at = string(clusters), s.UpdatedAt
+}
@@ -0,0 +1,66 @@
+package monitor
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// StateStore 保存少量需要跨重启保留的状态
+type StateStore interface {
+	// Get 读取 key 对应的值，不存在时返回空字符串
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string) error
+}
+
+// NewConfigMapStore 返回把状态写入指定 ConfigMap 的 StateStore
+func NewConfigMapStore(kube kubernetes.Interface, namespace, name string) StateStore {
+	return &configMapStore{kube: kube, namespace: namespace, name: name}
+}
+
+type configMapStore struct {
+	kube      kubernetes.Interface
+	namespace string
+	name      string
+}
+
+func (s *configMapStore) Get(ctx context.Context, key string) (string, error) {
+	cm, err := s.kube.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return cm.Data[key], nil
+}
+
+// 多个副本或 key 可能同时写入同一个 ConfigMap，冲突时重新读取后重试
+func (s *configMapStore) Set(ctx context.Context, key, value string) error {
+	client := s.kube.CoreV1().ConfigMaps(s.namespace)
+	conflict := func(err error) bool { return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) }
+	return retry.OnError(retry.DefaultRetry, conflict, func() error {
+		cm, err := client.Get(ctx, s.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: s.name, Namespace: s.namespace},
+				Data:       map[string]string{key: value},
+			}
+			_, err = client.Create(ctx, cm, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[key] = value
+		_, err = client.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
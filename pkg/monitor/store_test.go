@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// 另一个写入者抢先更新了 ConfigMap：Set 重新读取后重试，不覆盖对方写入的 key
+func TestConfigMapStoreSetRetriesOnConflict(t *testing.T) {
+	ctx := context.Background()
+	kube := kubefake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "monitor", Name: "state"},
+		Data:       map[string]string{"a": "1"},
+	})
+	conflicts := 0
+	kube.PrependReactor("update", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicts > 0 {
+			return false, nil, nil
+		}
+		conflicts++
+		// 模拟并发写入：对方的更新先生效，本次更新基于旧版本而冲突
+		cm, err := kube.Tracker().Get(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, "monitor", "state")
+		if err != nil {
+			return true, nil, err
+		}
+		cm = cm.DeepCopyObject()
+		cm.(*corev1.ConfigMap).Data["b"] = "2"
+		if err := kube.Tracker().Update(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, cm, "monitor"); err != nil {
+			return true, nil, err
+		}
+		return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "state", nil)
+	})
+
+	store := NewConfigMapStore(kube, "monitor", "state")
+	if err := store.Set(ctx, "c", "3"); err != nil {
+		t.Fatal(err)
+	}
+	if conflicts != 1 {
+		t.Fatalf("got %d conflicts, want 1", conflicts)
+	}
+	for key, want := range map[string]string{"a": "1", "b": "2", "c": "3"} {
+		if got, err := store.Get(ctx, key); err != nil || got != want {
+			t.Errorf("Get(%q) = %q, %v, want %q", key, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package monitor
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var notificationGVR = schema.GroupVersionResource{
+	Group:    "notification.sealos.io",
+	Version:  "v1",
+	Resource: "notifications",
+}
+
+// 在租户 ns 下创建 sealos Notification，让用户在控制台看到数据库异常
+func (m *Monitor) createTenantNotification(ctx context.Context, namespace, name, status string) {
+	now := m.now().UTC().Unix()
+	message := "database : " + name + " is " + status + ". Please check in time."
+	notification := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "notification.sealos.io/v1",
+			"kind":       "Notification",
+			"metadata": map[string]interface{}{
+				"name": "database-monitor-notification",
+			},
+			"spec": map[string]interface{}{
+				"title":        "Database Exception",
+				"message":      message,
+				"timestamp":    now,
+				"from":         "database-monitor-cronjob",
+				"importance":   "High",
+				"desktopPopup": true,
+				"i18ns": map[string]interface{}{
+					"en": map[string]interface{}{
+						"title":   "English Title",
+						"message": "English Message",
+						"from":    "User A",
+					},
+				},
+			},
+		},
+	}
+
+	if m.cfg.DryRun {
+		m.log.Info("Dry run, tenant notification not created", "cluster", name, "namespace", namespace, "message", message)
+		return
+	}
+	if err := m.budget.Wait(ctx); err != nil {
+		m.log.Error("Error waiting for API budget", "err", err)
+		return
+	}
+	// 使用客户端和 GVR 创建 CRD
+	_, err := m.dynamic.Resource(notificationGVR).Namespace(namespace).Create(ctx, notification, metav1.CreateOptions{})
+	if err != nil {
+		m.log.Error("Error creating tenant notification", "cluster", name, "namespace", namespace, "err", err)
+	}
+}
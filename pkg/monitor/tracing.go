@@ -0,0 +1,20 @@
+package monitor
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 巡检、欠费刷新和通知发送的 span 使用的 instrumentation 名称
+const tracerName = "database-monitor/pkg/monitor"
+
+// 把错误记录到 span 上；错误中可能带有 webhook 地址等敏感信息，先脱敏
+func (m *Monitor) spanError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	msg := m.redactor.String(err.Error())
+	span.SetStatus(codes.Error, msg)
+	span.AddEvent("exception", trace.WithAttributes(attribute.String("exception.message", msg)))
+}
@@ -0,0 +1,203 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// volumeTracker 记录已提醒过的 PVC 及其使用率级别，级别升高时才再次提醒
+type volumeTracker struct {
+	mu sync.Mutex
+	// key 为 namespace/pvc，值为 SeverityWarning 或 SeverityCritical
+	notified map[string]string
+}
+
+// kubelet /stats/summary 中用到的部分
+type statsSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Volumes []struct {
+			PVCRef *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+			UsedBytes     *uint64 `json:"usedBytes"`
+			CapacityBytes *uint64 `json:"capacityBytes"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// volumeUsage 一个数据库 PVC 的使用情况
+type volumeUsage struct {
+	Namespace string
+	Cluster   string
+	PVC       string
+	Used      uint64
+	Capacity  uint64
+}
+
+func (u volumeUsage) percent() float64 {
+	return float64(u.Used) * 100 / float64(u.Capacity)
+}
+
+// 找出受监控集群的 Pod 所在节点，从各节点 kubelet 的统计中读取这些 Pod 挂载的 PVC 用量
+func (m *Monitor) auditVolumes(ctx context.Context) ([]volumeUsage, error) {
+	clusters := make(map[string]string)
+	nodes := make(map[string]bool)
+	opts := metav1.ListOptions{LabelSelector: kubeblocksResourceSelector, Limit: m.listPageSize()}
+	for {
+		if err := m.budget.Wait(ctx); err != nil {
+			return nil, err
+		}
+		pods, err := m.kube.CoreV1().Pods("").List(ctx, opts)
+		if token, ok := expiredContinue(err); ok {
+			opts.Continue = token
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, pod := range pods.Items {
+			if pod.Spec.NodeName == "" || !m.namespaceAllowed(pod.Namespace) {
+				continue
+			}
+			clusters[pod.Namespace+"/"+pod.Name] = pod.Labels[instanceLabel]
+			nodes[pod.Spec.NodeName] = true
+		}
+		if pods.Continue == "" {
+			break
+		}
+		opts.Continue = pods.Continue
+	}
+
+	names := make([]string, 0, len(nodes))
+	for node := range nodes {
+		names = append(names, node)
+	}
+	sort.Strings(names)
+	var usages []volumeUsage
+	for _, node := range names {
+		if err := m.budget.Wait(ctx); err != nil {
+			return nil, err
+		}
+		data, err := m.kube.CoreV1().RESTClient().Get().
+			AbsPath("/api/v1/nodes", node, "proxy", "stats", "summary").DoRaw(ctx)
+		if err != nil {
+			// 单个节点不可达不影响其他节点
+			m.log.Warn("Error reading kubelet stats", "node", node, "err", err)
+			continue
+		}
+		var summary statsSummary
+		if err := json.Unmarshal(data, &summary); err != nil {
+			m.log.Warn("Error decoding kubelet stats", "node", node, "err", err)
+			continue
+		}
+		for _, pod := range summary.Pods {
+			cluster, ok := clusters[pod.PodRef.Namespace+"/"+pod.PodRef.Name]
+			if !ok {
+				continue
+			}
+			for _, v := range pod.Volumes {
+				if v.PVCRef == nil || v.UsedBytes == nil || v.CapacityBytes == nil || *v.CapacityBytes == 0 {
+					continue
+				}
+				usages = append(usages, volumeUsage{
+					Namespace: v.PVCRef.Namespace,
+					Cluster:   cluster,
+					PVC:       v.PVCRef.Name,
+					Used:      *v.UsedBytes,
+					Capacity:  *v.CapacityBytes,
+				})
+			}
+		}
+	}
+	return usages, nil
+}
+
+// 使用率对应的级别，低于警告阈值时为空
+func (m *Monitor) volumeLevel(percent float64) string {
+	switch {
+	case m.cfg.VolumeCriticalPercent > 0 && percent >= m.cfg.VolumeCriticalPercent:
+		return SeverityCritical
+	case m.cfg.VolumeWarningPercent > 0 && percent >= m.cfg.VolumeWarningPercent:
+		return SeverityWarning
+	}
+	return ""
+}
+
+// 检查一次 PVC 使用率，新超过阈值或级别升高的卷合并为一条通知发送
+func (m *Monitor) checkVolumes(ctx context.Context) error {
+	usages, err := m.auditVolumes(ctx)
+	if err != nil {
+		return err
+	}
+	t := &m.volumes
+	t.mu.Lock()
+	var lines []string
+	current := make(map[string]bool, len(usages))
+	for _, u := range usages {
+		key := u.Namespace + "/" + u.PVC
+		level := m.volumeLevel(u.percent())
+		if level == "" {
+			continue
+		}
+		current[key] = true
+		previous := t.notified[key]
+		if previous == level || previous == SeverityCritical {
+			continue
+		}
+		t.notified[key] = level
+		lines = append(lines, fmt.Sprintf("[%s] pvc %s of cluster %s is %.0f%% full (%s of %s)",
+			level, key, u.Cluster, u.percent(), formatBytes(u.Used), formatBytes(u.Capacity)))
+	}
+	// 回落到阈值以下或已删除的卷再次超过阈值时重新提醒
+	for key := range t.notified {
+		if !current[key] {
+			delete(t.notified, key)
+		}
+	}
+	t.mu.Unlock()
+
+	if len(lines) == 0 {
+		return nil
+	}
+	sort.Strings(lines)
+	m.log.Warn("Database volumes filling up", "count", len(lines))
+	m.Notify(ctx, m.NewNotice("Database volumes are filling up:\n"+strings.Join(lines, "\n")))
+	return nil
+}
+
+// 例如 45.2Gi
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ci", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// 定期检查 PVC 使用率
+func (m *Monitor) startVolumeLoop(ctx context.Context) {
+	if m.cfg.VolumeCheckInterval <= 0 {
+		return
+	}
+	m.until(ctx, m.withCycleTimeout("volumes", func(ctx context.Context) {
+		if err := m.checkVolumes(ctx); err != nil {
+			m.log.Error("Error checking volume usage", "err", err)
+		}
+	}), m.cfg.VolumeCheckInterval)
+}
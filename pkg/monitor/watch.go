@@ -0,0 +1,235 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// watch 模式：informer 事件只把 key 放入限速队列，由固定数量的 worker 取出后按缓存中的最新状态评估。
+// 同一个 key 在队列中只会存在一份，短时间内的大量更新只会触发一次评估。
+// 报告中的条目变化时经过 WatchDebounce 合并后立即发送，CheckInterval 只用于 resync 和重复提醒。
+func (m *Monitor) watch(ctx context.Context) error {
+	changed := make(chan struct{}, 1)
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(m.dynamic, m.cfg.CheckInterval, metav1.NamespaceAll,
+		func(opts *metav1.ListOptions) { opts.LabelSelector = m.cfg.ClusterSelector })
+	queue := workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(), workqueue.RateLimitingQueueConfig{
+		Name:            "clusters",
+		MetricsProvider: workqueueMetricsProvider{m: m.metrics},
+	})
+	defer queue.ShutDown()
+
+	// 每种资源一个 informer，队列中的 key 与 watchEntries 相同，为 namespace/trackedName
+	indexers := make(map[*monitoredResource]cache.Indexer)
+	var synced []cache.InformerSynced
+	for _, res := range m.resources {
+		if res != m.resources[0] {
+			installed, err := m.resourceInstalled(ctx, m.resourceGVR(res))
+			if err != nil {
+				return err
+			}
+			if !installed {
+				m.log.Warn("Monitored resource not installed, skipping", "resource", m.resourceGVR(res).String())
+				continue
+			}
+		}
+		informer := factory.ForResource(m.resourceGVR(res)).Informer()
+		if err := informer.SetTransform(trimClusterTransform); err != nil {
+			return err
+		}
+		res := res
+		enqueue := func(obj interface{}) {
+			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			if err != nil {
+				m.log.Error("Error getting object key", "object", fmt.Sprintf("%v", obj), "err", err)
+				return
+			}
+			namespace, name, err := cache.SplitMetaNamespaceKey(key)
+			if err != nil || !m.namespaceAllowed(namespace) {
+				return
+			}
+			queue.Add(clusterKey(namespace, res.trackedName(name)))
+		}
+		_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    enqueue,
+			UpdateFunc: func(_, obj interface{}) { enqueue(obj) },
+			DeleteFunc: enqueue,
+		})
+		if err != nil {
+			return err
+		}
+		indexers[res] = informer.GetIndexer()
+		synced = append(synced, informer.HasSynced)
+	}
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return fmt.Errorf("failed to sync informers")
+	}
+
+	for i := 0; i < m.cfg.Workers; i++ {
+		m.until(ctx, func(ctx context.Context) {
+			for m.processNextItem(ctx, queue, indexers, changed) {
+			}
+		}, time.Second)
+	}
+	go m.watchQueueDepth(ctx, queue)
+
+	// 定期重新检查反复故障并发送报告，设置了时间表时按时间表
+	delay := m.nextCheckDelay()
+	ticker := time.NewTimer(delay)
+	defer ticker.Stop()
+	// 定时报告的预期时间，存活探针据此判断主循环是否卡住
+	due := time.Now().Add(delay)
+	m.expectCheck(due)
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+			if debounce == nil {
+				debounce = time.After(m.cfg.WatchDebounce)
+			}
+			continue
+		case <-debounce:
+			debounce = nil
+		case <-ticker.C:
+			delay := m.nextCheckDelay()
+			ticker.Reset(delay)
+			due = time.Now().Add(delay)
+			if err := m.loadSilences(ctx); err != nil {
+				m.log.Error("Error loading silences", "err", err)
+			}
+			m.checkRepeatedIncidents(ctx)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		reportCtx, span := m.tracer.Start(ctx, "report", trace.WithAttributes(attribute.String("region", m.cfg.Region)))
+		report := m.watchReport()
+		m.annotateOwners(reportCtx, &report)
+		span.SetAttributes(attribute.Int("entries", len(report.Entries)))
+		m.setLastReport(report)
+		m.notifyReport(reportCtx, report)
+		m.saveCheckpoint(reportCtx)
+		m.publishStatus(reportCtx)
+		span.End()
+		m.recordCheck(due)
+	}
+}
+
+// 条目的事件身份或严重程度变化时通知 changed
+func (m *Monitor) processNextItem(ctx context.Context, queue workqueue.RateLimitingInterface, indexers map[*monitoredResource]cache.Indexer, changed chan<- struct{}) bool {
+	item, shutdown := queue.Get()
+	if shutdown {
+		return false
+	}
+	defer queue.Done(item)
+
+	key := item.(string)
+	namespace, tracked, _ := strings.Cut(key, "/")
+	res, name := m.resourceOf(tracked)
+	indexer, ok := indexers[res]
+	if !ok {
+		queue.Forget(key)
+		return true
+	}
+	obj, exists, err := indexer.GetByKey(clusterKey(namespace, name))
+	if err != nil {
+		m.log.Error("Error fetching cluster from cache", "key", key, "err", err)
+		queue.AddRateLimited(key)
+		return true
+	}
+	queue.Forget(key)
+	ctx, cancel := m.cycleContext(ctx)
+	defer cancel()
+
+	if !exists {
+		m.forgetCluster(ctx, key)
+		signal(changed)
+		return true
+	}
+	cluster, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return true
+	}
+	entry := m.evaluateCluster(ctx, res, cluster)
+	m.mu.Lock()
+	prev, had := m.watchEntries[key]
+	if entry != nil {
+		m.watchEntries[key] = *entry
+	} else {
+		delete(m.watchEntries, key)
+	}
+	m.mu.Unlock()
+	if had != (entry != nil) || (entry != nil && (prev.incidentKey() != entry.incidentKey() || prev.Severity != entry.Severity)) {
+		signal(changed)
+	}
+	return true
+}
+
+func signal(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// 集群已被删除，清理相关状态
+func (m *Monitor) forgetCluster(ctx context.Context, key string) {
+	m.mu.Lock()
+	delete(m.watchEntries, key)
+	m.forgetDecision(key)
+	delete(m.lastStatus, key)
+	m.forgetClusterPhase(key)
+	inc, open := m.openIncidents[key]
+	m.mu.Unlock()
+	if open {
+		m.closeDisappeared(ctx, []*Incident{inc})
+	}
+}
+
+func (m *Monitor) watchReport() Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := m.newReport()
+	keys := make([]string, 0, len(m.watchEntries))
+	for key := range m.watchEntries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		report.Entries = append(report.Entries, m.watchEntries[key])
+	}
+	return report
+}
+
+// 队列积压超过高水位时告警，回落后再次越过才会重新告警
+func (m *Monitor) watchQueueDepth(ctx context.Context, queue workqueue.RateLimitingInterface) {
+	defer m.crashed()
+	above := false
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		depth := queue.Len()
+		switch {
+		case depth > m.cfg.QueueHighWatermark && !above:
+			above = true
+			m.log.Warn("Work queue depth exceeds high watermark", "depth", depth, "highWatermark", m.cfg.QueueHighWatermark)
+		case depth <= m.cfg.QueueHighWatermark && above:
+			above = false
+			m.log.Info("Work queue depth back below high watermark", "depth", depth)
+		}
+	}, 5*time.Second)
+}
@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// 等待 cond 成立，超时时测试失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 100 个集群在评估期间收到 1 万次更新，队列按 key 合并后每个集群只再评估一次
+func TestWatchCoalescesUpdateBurst(t *testing.T) {
+	const clusters, updates = 100, 10000
+	cfg := DefaultConfig()
+	cfg.Watch = true
+	var objects []runtime.Object
+	for i := 0; i < clusters; i++ {
+		objects = append(objects, testCluster("ns1", fmt.Sprintf("db-%d", i), "Running"))
+	}
+	dynamic := newTestDynamic(objects...)
+	events := watch.NewFakeWithChanSize(updates, false)
+	dynamic.PrependWatchReactor("clusters", func(k8stesting.Action) (bool, watch.Interface, error) {
+		return true, events, nil
+	})
+	env := newTestEnv(t, cfg, Deps{Dynamic: dynamic})
+	evaluations := func() int { return int(testutil.ToFloat64(env.m.metrics.evaluations)) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- env.m.watch(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+	waitFor(t, "initial evaluations", func() bool { return evaluations() == clusters })
+
+	// 持有 m.mu 使 worker 停在评估中，模拟评估跟不上事件的速度
+	env.m.mu.Lock()
+	for i := 0; i < updates; i++ {
+		obj := testCluster("ns1", fmt.Sprintf("db-%d", i%clusters), "Running")
+		obj.SetResourceVersion(strconv.Itoa(i + 1))
+		events.Modify(obj)
+	}
+	waitFor(t, "informer to consume updates", func() bool { return len(events.ResultChan()) == 0 })
+	time.Sleep(200 * time.Millisecond)
+	env.m.mu.Unlock()
+
+	last := -1
+	waitFor(t, "evaluations to settle", func() bool {
+		n := evaluations()
+		settled := n == last
+		last = n
+		if !settled {
+			time.Sleep(100 * time.Millisecond)
+		}
+		return settled
+	})
+	// 每个 key 在队列中只有一份；评估中的 key 被标记后最多再评估一次
+	got := last - clusters
+	if got < clusters || got > clusters+cfg.Workers {
+		t.Errorf("%d updates across %d clusters caused %d evaluations, want %d to %d", updates, clusters, got, clusters, clusters+cfg.Workers)
+	}
+}
+
+// watch 模式下同一个 CheckInterval 内的多次更新只计一次检查，AlertAfterChecks 仍表示连续巡检次数
+func TestWatchCountsOneCheckPerInterval(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Watch = true
+	cfg.AlertAfterChecks = 2
+	dynamic := newTestDynamic(testCluster("ns1", "a", "Failed"))
+	events := watch.NewFake()
+	dynamic.PrependWatchReactor("clusters", func(k8stesting.Action) (bool, watch.Interface, error) {
+		return true, events, nil
+	})
+	var mu sync.Mutex
+	now := testEpoch
+	env := newTestEnv(t, cfg, Deps{Dynamic: dynamic, Now: func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}})
+	evaluations := func() int { return int(testutil.ToFloat64(env.m.metrics.evaluations)) }
+	checks := func() int {
+		env.m.mu.Lock()
+		defer env.m.mu.Unlock()
+		return env.m.lastStatus[clusterKey("ns1", "a")].checks
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- env.m.watch(ctx) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+	waitFor(t, "initial evaluation", func() bool { return evaluations() == 1 })
+
+	modify := func(rv int) {
+		obj := testCluster("ns1", "a", "Failed")
+		obj.SetResourceVersion(strconv.Itoa(rv))
+		events.Modify(obj)
+		waitFor(t, "evaluation of update "+strconv.Itoa(rv), func() bool { return evaluations() == rv+1 })
+	}
+	for rv := 1; rv <= 3; rv++ {
+		modify(rv)
+	}
+	if got := checks(); got != 1 {
+		t.Fatalf("3 updates within one interval counted %d checks, want 1", got)
+	}
+
+	mu.Lock()
+	now = now.Add(cfg.CheckInterval)
+	mu.Unlock()
+	modify(4)
+	if got := checks(); got != 2 {
+		t.Errorf("update after one interval counted %d checks, want 2", got)
+	}
+}
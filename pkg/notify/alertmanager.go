@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+// 未恢复的告警保存在状态存储中的 key 前缀，后接通知后端名称
+const alertmanagerStateKey = "alertmanager-open-"
+
+// AlertmanagerAlert Alertmanager /api/v2/alerts 接口的一条告警
+type AlertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt,omitempty"`
+	EndsAt       time.Time         `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// alertmanagerBatch Render 的结果；Notice 为 true 时只是自监控通知，Alertmanager 不处理
+type alertmanagerBatch struct {
+	Notice bool                `json:"notice,omitempty"`
+	Alerts []AlertmanagerAlert `json:"alerts"`
+}
+
+// Alertmanager 把需要关注的集群作为告警转发给 Alertmanager，由已有的路由、静默和抑制规则处理。
+// 只在报告变化或重复提醒时发送，因此告警的 endsAt 设为 ttl 之后，避免在两次发送之间被自动恢复；
+// 集群不再出现在报告中时立即发送 endsAt 为当前时间的告警
+type Alertmanager struct {
+	name     string
+	client   *http.Client
+	endpoint string
+	ttl      time.Duration
+	store    monitor.StateStore
+
+	mu sync.Mutex
+	// 已发送且尚未恢复的告警，按标签指纹索引，保存在 store 中，重启或重新加载配置后仍能恢复；
+	// 第一次发送时从 store 加载，加载前为 nil
+	open map[string]AlertmanagerAlert
+}
+
+// NewAlertmanager 创建 Alertmanager 转发，baseURL 形如 http://alertmanager:9093，name 为空时为 alertmanager。
+// store 保存未恢复的告警，为空时只保存在内存中，重启前发送的告警要等 ttl 过后才自动恢复
+func NewAlertmanager(name, baseURL string, ttl time.Duration, store monitor.StateStore, client *http.Client) *Alertmanager {
+	if name == "" {
+		name = "alertmanager"
+	}
+	return &Alertmanager{
+		name:     name,
+		client:   clientOrDefault(client),
+		endpoint: strings.TrimSuffix(baseURL, "/") + "/api/v2/alerts",
+		ttl:      ttl,
+		store:    store,
+	}
+}
+
+func (n *Alertmanager) Name() string {
+	return n.name
+}
+
+func (n *Alertmanager) Render(r monitor.Report) ([]byte, error) {
+	batch := alertmanagerBatch{Notice: r.Notice != "" && len(r.Entries) == 0, Alerts: []AlertmanagerAlert{}}
+	for _, e := range r.Entries {
+		labels := map[string]string{
+			"alertname": "DatabaseClusterUnhealthy",
+			"cluster":   e.Name,
+			"namespace": e.Namespace,
+			"severity":  e.Severity,
+		}
+		if r.Region != "" {
+			labels["region"] = r.Region
+		}
+		annotations := map[string]string{
+			"summary": fmt.Sprintf("Database cluster %s/%s is %s", e.Namespace, e.Name, e.DisplayPhase()),
+			"phase":   e.Phase,
+		}
+		if e.PreviousPhase != "" {
+			annotations["previousPhase"] = e.PreviousPhase
+		}
+		if e.Reason != "" {
+			annotations["reason"] = e.Reason
+		}
+		if e.Owner != "" {
+			annotations["owner"] = e.Owner
+		}
+		description := append([]string(nil), e.Findings...)
+		if e.OOMKilled != "" {
+			description = append([]string{"OOMKilled (" + e.OOMKilled + ")"}, description...)
+		}
+		for _, c := range e.ComponentLines() {
+			description = append(description, "component "+c)
+		}
+		for _, p := range e.PodLines() {
+			description = append(description, "pod "+p)
+		}
+		if len(description) > 0 {
+			annotations["description"] = strings.Join(description, "\n")
+		}
+		batch.Alerts = append(batch.Alerts, AlertmanagerAlert{
+			Labels:       labels,
+			Annotations:  annotations,
+			EndsAt:       r.GeneratedAt.Add(n.ttl),
+			GeneratorURL: e.ConsoleURL,
+		})
+	}
+	return json.Marshal(batch)
+}
+
+// 告警的身份：区域、集群和严重程度
+func alertFingerprint(labels map[string]string) string {
+	return strings.Join([]string{labels["region"], labels["namespace"], labels["cluster"], labels["severity"]}, "/")
+}
+
+// Send 发送当前的告警，同时恢复上次发送而这次不再出现的告警
+func (n *Alertmanager) Send(ctx context.Context, payload []byte) error {
+	var batch alertmanagerBatch
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		return err
+	}
+	if batch.Notice {
+		return nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := n.loadOpen(ctx); err != nil {
+		return fmt.Errorf("loading open Alertmanager alerts: %w", err)
+	}
+	now := time.Now()
+	current := make(map[string]AlertmanagerAlert)
+	alerts := batch.Alerts
+	for i, alert := range alerts {
+		key := alertFingerprint(alert.Labels)
+		// 沿用首次发送的开始时间
+		if prev, ok := n.open[key]; ok {
+			alerts[i].StartsAt = prev.StartsAt
+		} else {
+			alerts[i].StartsAt = now
+		}
+		current[key] = alerts[i]
+	}
+	for key, alert := range n.open {
+		if _, ok := current[key]; !ok {
+			alert.EndsAt = now
+			alerts = append(alerts, alert)
+		}
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+	if err := n.post(ctx, alerts); err != nil {
+		return err
+	}
+	n.open = current
+	if err := n.saveOpen(ctx); err != nil {
+		return fmt.Errorf("saving open Alertmanager alerts: %w", err)
+	}
+	return nil
+}
+
+// 第一次发送前从 store 加载未恢复的告警，调用方持有 n.mu
+func (n *Alertmanager) loadOpen(ctx context.Context) error {
+	if n.open != nil {
+		return nil
+	}
+	open := make(map[string]AlertmanagerAlert)
+	if n.store != nil {
+		data, err := n.store.Get(ctx, alertmanagerStateKey+n.name)
+		if err != nil {
+			return err
+		}
+		if data != "" {
+			if err := json.Unmarshal([]byte(data), &open); err != nil {
+				return err
+			}
+		}
+	}
+	n.open = open
+	return nil
+}
+
+// 调用方持有 n.mu
+func (n *Alertmanager) saveOpen(ctx context.Context) error {
+	if n.store == nil {
+		return nil
+	}
+	data, err := json.Marshal(n.open)
+	if err != nil {
+		return err
+	}
+	return n.store.Set(ctx, alertmanagerStateKey+n.name, string(data))
+}
+
+func (n *Alertmanager) post(ctx context.Context, alerts []AlertmanagerAlert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending alerts to Alertmanager: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to send alerts to Alertmanager, status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+// alertmanagerServer 记录收到的告警，格式为 "firing|resolved namespace/cluster"
+func alertmanagerServer(t *testing.T) (string, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var alerts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []AlertmanagerAlert
+		json.NewDecoder(r.Body).Decode(&batch)
+		mu.Lock()
+		for _, a := range batch {
+			state := "firing"
+			if !a.EndsAt.After(time.Now()) {
+				state = "resolved"
+			}
+			alerts = append(alerts, state+" "+a.Labels["namespace"]+"/"+a.Labels["cluster"])
+		}
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		sent := alerts
+		alerts = nil
+		sort.Strings(sent)
+		return sent
+	}
+}
+
+func sendAlertmanager(t *testing.T, n *Alertmanager, r monitor.Report) {
+	t.Helper()
+	payload, err := n.Render(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 重启（或重新加载配置）后创建的 Alertmanager 从 store 得知之前发送的告警，集群恢复时立即恢复告警
+func TestAlertmanagerResolvesAfterRestart(t *testing.T) {
+	url, alerts := alertmanagerServer(t)
+	store := &memStore{}
+	report := func(entries ...monitor.ReportEntry) monitor.Report {
+		return monitor.Report{GeneratedAt: time.Now(), Entries: entries}
+	}
+	sendAlertmanager(t, NewAlertmanager("", url, time.Hour, store, nil), report(critical("ns1", "db"), critical("ns1", "cache")))
+	if got := alerts(); !slices.Equal(got, []string{"firing ns1/cache", "firing ns1/db"}) {
+		t.Fatalf("alerts = %q", got)
+	}
+
+	sendAlertmanager(t, NewAlertmanager("", url, time.Hour, store, nil), report(critical("ns1", "cache")))
+	if got := alerts(); !slices.Equal(got, []string{"firing ns1/cache", "resolved ns1/db"}) {
+		t.Errorf("alerts after restart = %q", got)
+	}
+	sendAlertmanager(t, NewAlertmanager("", url, time.Hour, store, nil), report())
+	if got := alerts(); !slices.Equal(got, []string{"resolved ns1/cache"}) {
+		t.Errorf("alerts after second restart = %q", got)
+	}
+}
@@ -0,0 +1,15 @@
+package notify
+
+import (
+	"net/http"
+
+	"database-monitor/pkg/httpclient"
+)
+
+// 调用方没有传入客户端时使用一个超时为 httpclient.DefaultTimeout 的新客户端
+func clientOrDefault(client *http.Client) *http.Client {
+	if client == nil {
+		return httpclient.New(httpclient.DefaultTimeout)
+	}
+	return client
+}
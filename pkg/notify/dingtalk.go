@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+type DingTalkMessage struct {
+	MsgType string `json:"msgtype"`
+	Text    struct {
+		Content string `json:"content"`
+	} `json:"text"`
+	// 被 @ 的用户，content 中也需要包含 @userId 才会高亮
+	At *DingTalkAt `json:"at,omitempty"`
+}
+
+type DingTalkAt struct {
+	AtUserIds []string `json:"atUserIds"`
+}
+
+// DingTalk 钉钉群机器人通知
+type DingTalk struct {
+	name       string
+	client     *http.Client
+	webhookURL string
+	// 机器人安全设置中的加签密钥，为空时不签名
+	secret string
+	format Format
+	// 自定义消息模板，为空时使用默认的文本表格
+	tmpl *Template
+}
+
+// NewDingTalk 创建钉钉通知，name 为空时为 dingtalk，tmpl 为空时使用默认的文本表格
+func NewDingTalk(name, webhookURL, secret string, tmpl *Template, format Format, client *http.Client) *DingTalk {
+	if name == "" {
+		name = "dingtalk"
+	}
+	return &DingTalk{name: name, client: clientOrDefault(client), webhookURL: webhookURL, secret: secret, format: format, tmpl: tmpl}
+}
+
+func (n *DingTalk) Name() string {
+	return n.name
+}
+
+func (n *DingTalk) Render(r monitor.Report) ([]byte, error) {
+	message := DingTalkMessage{MsgType: "text"}
+	message.Text.Content = FormatText(r, n.format)
+	if n.tmpl != nil {
+		var err error
+		if message.Text.Content, err = n.tmpl.Execute(r); err != nil {
+			return nil, err
+		}
+	}
+	if len(r.Mentions) > 0 {
+		message.At = &DingTalkAt{AtUserIds: r.Mentions}
+		message.Text.Content += "\n@" + strings.Join(r.Mentions, " @")
+	}
+	return json.Marshal(message)
+}
+
+func (n *DingTalk) Send(ctx context.Context, payload []byte) error {
+	// 签名带时间戳，钉钉只接受一小时内的签名，每次发送时重新计算
+	target, err := n.signedURL(time.Now())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending alert to DingTalk: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to send alert to DingTalk, status code: %d", resp.StatusCode)
+	}
+	// 签名错误等问题也返回 200，需要检查 errcode
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode DingTalk response: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("DingTalk rejected the alert: %d %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
+
+// signedURL 按钉钉加签规则在 webhook 地址后追加 timestamp 和 sign：
+// sign = base64(HmacSHA256(secret, timestamp + "\n" + secret))
+func (n *DingTalk) signedURL(now time.Time) (string, error) {
+	if n.secret == "" {
+		return n.webhookURL, nil
+	}
+	u, err := url.Parse(n.webhookURL)
+	if err != nil {
+		return "", err
+	}
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(n.secret))
+	mac.Write([]byte(timestamp + "\n" + n.secret))
+	q := u.Query()
+	q.Set("timestamp", timestamp)
+	q.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 签名与钉钉文档中的算法一致：sign = base64(HmacSHA256(secret, timestamp + "\n" + secret))，并做 URL 编码
+func TestDingTalkSignedURL(t *testing.T) {
+	const webhook = "https://oapi.dingtalk.com/robot/send?access_token=abc"
+	now := time.UnixMilli(1700000000001)
+	tests := []struct {
+		name   string
+		secret string
+		want   string
+	}{
+		{"unsigned", "", webhook},
+		{"signed", "SECtest", webhook + "&sign=r4CWp%2FDz%2BNg0sbTjH1vB0Fr%2BuQ2fn831mssaN6%2FC05I%3D&timestamp=1700000000001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := NewDingTalk("", webhook, tt.secret, nil, Format{}, nil)
+			got, err := n.signedURL(now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("signedURL = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 发送时带上当前时间的签名，钉钉返回 200 但 errcode 不为 0 时视为失败
+func TestDingTalkSend(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+	}))
+	defer srv.Close()
+	n := NewDingTalk("", srv.URL+"?access_token=abc", "SECtest", nil, Format{}, srv.Client())
+	before := time.Now()
+	err := n.Send(context.Background(), []byte(`{"msgtype":"text"}`))
+	if err == nil || !strings.Contains(err.Error(), "sign not match") {
+		t.Errorf("err = %v, want DingTalk's errmsg", err)
+	}
+	if query.Get("access_token") != "abc" || query.Get("sign") == "" {
+		t.Errorf("query = %v, want the access token kept and a sign added", query)
+	}
+	if ms, err := strconv.ParseInt(query.Get("timestamp"), 10, 64); err != nil || ms < before.UnixMilli() {
+		t.Errorf("timestamp = %q, want the time of sending", query.Get("timestamp"))
+	}
+}
@@ -0,0 +1,212 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html/template"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+// SMTP 连接的 TLS 方式
+const (
+	// 明文连接后用 STARTTLS 升级，通常为 587 端口
+	SMTPStartTLS = "starttls"
+	// 直接建立 TLS 连接，通常为 465 端口
+	SMTPTLS = "tls"
+	// 不加密，只适合本机或内网中继
+	SMTPPlain = "none"
+)
+
+// SMTPConfig 发送邮件使用的 SMTP 服务器和收发件人
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	TLS      string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Email 以 HTML 邮件发送报告，聊天工具之外也留有记录
+type Email struct {
+	name   string
+	smtp   SMTPConfig
+	format Format
+}
+
+// NewEmail 创建邮件通知，name 为空时为 email
+func NewEmail(name string, smtp SMTPConfig, format Format) *Email {
+	if name == "" {
+		name = "email"
+	}
+	return &Email{name: name, smtp: smtp, format: format}
+}
+
+func (n *Email) Name() string {
+	return n.name
+}
+
+var emailTemplate = template.Must(template.New("email").Parse(`<html><body style="font-family: sans-serif">
+{{- if .Region}}<p><b>{{.T.Region}}</b>: {{.Region}}</p>{{end}}
+{{- if .GeneratedAt}}<p>{{.T.GeneratedAt}}: {{.GeneratedAt}}</p>{{end}}
+{{- if .Notice}}<pre>{{.Notice}}</pre>{{end}}
+{{- if .Entries}}
+<table border="1" cellspacing="0" cellpadding="4" style="border-collapse: collapse">
+<tr><th>{{.T.DatabaseName}}</th><th>{{.T.Status}}</th><th>{{.T.Namespace}}</th></tr>
+{{- range .Entries}}
+<tr><td>{{if .ConsoleURL}}<a href="{{.ConsoleURL}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</td><td style="color: {{.Color}}">{{.Phase}}</td><td>{{.Namespace}}</td></tr>
+{{- if or .OOMKilled .Findings .Components .Pods}}
+<tr><td colspan="3"><ul>{{if .OOMKilled}}<li>OOMKilled ({{.OOMKilled}})</li>{{end}}{{range .Findings}}<li>{{.}}</li>{{end}}{{range .Components}}<li>component {{.}}</li>{{end}}{{range .Pods}}<li>pod {{.}}</li>{{end}}</ul></td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- end}}
+{{- if .Debt}}<p>{{.Debt}}</p>{{end}}
+{{- if .ConfigHash}}<p style="color: gray">{{.T.Config}}: {{.ConfigHash}}</p>{{end}}
+</body></html>
+`))
+
+type emailEntry struct {
+	Name, Phase, Namespace, Color, OOMKilled, ConsoleURL string
+	Findings, Components, Pods                           []string
+}
+
+// RenderHTML 把报告渲染为 HTML，集群状态为表格
+func RenderHTML(r monitor.Report, f Format) (string, error) {
+	data := struct {
+		T                                             struct{ Region, GeneratedAt, DatabaseName, Status, Namespace, Config string }
+		Region, GeneratedAt, Notice, Debt, ConfigHash string
+		Entries                                       []emailEntry
+	}{Region: r.Region, Notice: f.Notice(r.Notice), ConfigHash: r.ConfigHash}
+	data.T.Region, data.T.GeneratedAt = f.T("Region"), f.T("Generated at")
+	data.T.DatabaseName, data.T.Status, data.T.Namespace = f.T("DatabaseName"), f.T("Status"), f.T("Namespace")
+	data.T.Config = f.T("config")
+	if !r.GeneratedAt.IsZero() {
+		data.GeneratedAt = f.Time(r.GeneratedAt)
+	}
+	if len(r.DebtNamespaces) > 0 {
+		data.Debt = fmt.Sprintf(f.T("Namespaces in debt: %d"), len(r.DebtNamespaces))
+	}
+	for _, e := range r.Entries {
+		color := "black"
+		switch e.Severity {
+		case monitor.SeverityCritical:
+			color = "red"
+		case monitor.SeverityWarning:
+			color = "darkorange"
+		}
+		data.Entries = append(data.Entries, emailEntry{
+			Name: e.Name, Phase: e.DisplayPhase(), Namespace: e.Namespace, Color: color, ConsoleURL: e.ConsoleURL,
+			OOMKilled: e.OOMKilled, Findings: e.Findings, Components: e.ComponentLines(), Pods: e.PodLines(),
+		})
+	}
+	var buf strings.Builder
+	if err := emailTemplate.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// 邮件主题：通知取第一行，报告给出需要关注的集群数
+func emailSubject(r monitor.Report, f Format) string {
+	subject := f.T("Database monitor")
+	if r.Region != "" {
+		subject += " [" + r.Region + "]"
+	}
+	if r.Notice != "" && len(r.Entries) == 0 {
+		line, _, _ := strings.Cut(f.Notice(r.Notice), "\n")
+		return subject + ": " + line
+	}
+	return subject + ": " + fmt.Sprintf(f.T("%d clusters need attention"), len(r.Entries))
+}
+
+// Render 生成完整的 MIME 邮件，Send 原样交给 SMTP 服务器
+func (n *Email) Render(r monitor.Report) ([]byte, error) {
+	body, err := RenderHTML(r, n.format)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", n.smtp.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(n.smtp.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", emailSubject(r, n.format)))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(body)
+	return buf.Bytes(), nil
+}
+
+func (n *Email) Send(ctx context.Context, payload []byte) error {
+	c, err := n.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting to SMTP server: %w", err)
+	}
+	defer c.Close()
+
+	if n.smtp.TLS == SMTPStartTLS {
+		if err := c.StartTLS(&tls.Config{ServerName: n.smtp.Host}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS: %w", err)
+		}
+	}
+	if n.smtp.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.smtp.Username, n.smtp.Password, n.smtp.Host)); err != nil {
+			return fmt.Errorf("SMTP auth: %w", err)
+		}
+	}
+	if err := c.Mail(n.smtp.From); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM: %w", err)
+	}
+	for _, to := range n.smtp.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA: %w", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		return fmt.Errorf("writing email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("sending email: %w", err)
+	}
+	return c.Quit()
+}
+
+// dial 建立到 SMTP 服务器的连接，整个会话受 ctx 的截止时间限制
+func (n *Email) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(n.smtp.Host, fmt.Sprint(n.smtp.Port))
+	var conn net.Conn
+	var err error
+	if n.smtp.TLS == SMTPTLS {
+		d := &tls.Dialer{Config: &tls.Config{ServerName: n.smtp.Host}}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, n.smtp.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
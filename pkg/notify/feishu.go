@@ -0,0 +1,452 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+// 飞书机器人请求体不能超过 20KB，留出签名和 JSON 转义的余量
+const (
+	feishuMaxPayload = 18 << 10
+	// 文本按字符拆分，中文每个字符占 3 字节
+	feishuMaxText = 5000
+	// 一份报告最多拆成的消息数，其余集群只给出数量
+	feishuMaxParts = 5
+	// 连续发送多条消息的间隔，机器人每秒最多接收 5 条
+	feishuPartInterval = 250 * time.Millisecond
+)
+
+type FeishuMessage struct {
+	MsgType string `json:"msg_type"`
+	Content struct {
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// Feishu 飞书机器人通知
+type Feishu struct {
+	name       string
+	client     *http.Client
+	webhookURL string
+	// 机器人安全设置中的签名校验密钥，为空时不签名
+	secret string
+	format Format
+	// 是否发送消息卡片；使用自定义模板时总是发送文本
+	card bool
+	// 卡片中每个集群是否带确认和静默按钮，需要由配置了消息卡片请求网址的飞书应用发送
+	actions bool
+	// 自定义消息模板，为空时使用默认的文本表格
+	tmpl *Template
+}
+
+// NewFeishu 创建飞书通知，tmpl 为自定义消息模板，为空时按 card 发送消息卡片或默认文本表格。
+// name 为目的地名称，为空时为 feishu；secret 不为空时对每条消息签名；actions 为 true 时卡片带确认和静默按钮；
+// format 决定消息的语言和时区，client 用于发送请求
+func NewFeishu(name, webhookURL, secret string, tmpl *Template, card, actions bool, format Format, client *http.Client) *Feishu {
+	if name == "" {
+		name = "feishu"
+	}
+	return &Feishu{name: name, client: clientOrDefault(client), webhookURL: webhookURL, secret: secret, format: format, card: card, actions: actions, tmpl: tmpl}
+}
+
+func (n *Feishu) Name() string {
+	return n.name
+}
+
+// Render 返回按顺序发送的消息列表。超过飞书大小限制的报告拆成多条，每条带上 (i/n)
+func (n *Feishu) Render(r monitor.Report) ([]byte, error) {
+	var messages []interface{}
+	if n.card && n.tmpl == nil {
+		for _, card := range feishuCards(r, n.format, n.actions) {
+			messages = append(messages, feishuCardMessage{MsgType: "interactive", Card: card})
+		}
+		return json.Marshal(messages)
+	}
+	text := FormatText(r, n.format)
+	if n.tmpl != nil {
+		var err error
+		if text, err = n.tmpl.Execute(r); err != nil {
+			return nil, err
+		}
+	}
+
+	parts := splitMessage(text, feishuMaxText)
+	switch {
+	case len(parts) == 0:
+		parts = []string{""}
+	case len(parts) > feishuMaxParts:
+		parts = parts[:feishuMaxParts]
+		parts[len(parts)-1] += "\n" + n.format.T("(message truncated)")
+	}
+	for _, id := range feishuTextMentions(r) {
+		parts[len(parts)-1] += fmt.Sprintf("\n<at user_id=%q></at>", id)
+	}
+	for i, part := range parts {
+		if len(parts) > 1 {
+			part = fmt.Sprintf("(%d/%d)\n", i+1, len(parts)) + part
+		}
+		message := FeishuMessage{MsgType: "text"}
+		message.Content.Text = part
+		messages = append(messages, message)
+	}
+	return json.Marshal(messages)
+}
+
+func (n *Feishu) Send(ctx context.Context, payload []byte) error {
+	var messages []json.RawMessage
+	if err := json.Unmarshal(payload, &messages); err != nil {
+		return err
+	}
+	for i, message := range messages {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(feishuPartInterval):
+			}
+		}
+		if err := n.send(ctx, message); err != nil {
+			if len(messages) == 1 {
+				return err
+			}
+			return partialSendError(messages, i, fmt.Errorf("sending part %d/%d: %w", i+1, len(messages), err))
+		}
+	}
+	return nil
+}
+
+func (n *Feishu) send(ctx context.Context, payload []byte) error {
+	// 签名带时间戳，飞书只接受一小时内的签名，每次发送时重新计算
+	payload, err := n.sign(payload, time.Now())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// 发送 POST 请求到 Feishu Webhook
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending alert to Feishu: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 检查响应状态
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to send alert, status code: %d", resp.StatusCode)
+	}
+	// 签名校验失败等错误也返回 200，需要检查 code
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && result.Code != 0 {
+		return fmt.Errorf("Feishu rejected the alert: %d %s", result.Code, result.Msg)
+	}
+	return nil
+}
+
+// sign 按飞书签名规则在消息中加入 timestamp 和 sign：
+// sign = base64(HmacSHA256(key = timestamp + "\n" + secret, 空消息))
+func (n *Feishu) sign(payload []byte, now time.Time) ([]byte, error) {
+	if n.secret == "" {
+		return payload, nil
+	}
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+n.secret))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	message["timestamp"], _ = json.Marshal(timestamp)
+	message["sign"], _ = json.Marshal(signature)
+	return json.Marshal(message)
+}
+
+// FeishuCard 飞书消息卡片，比等宽文本表格更适合在手机上查看
+type FeishuCard struct {
+	Config struct {
+		WideScreenMode bool `json:"wide_screen_mode"`
+	} `json:"config"`
+	Header   FeishuCardHeader `json:"header"`
+	Elements []interface{}    `json:"elements"`
+}
+
+type FeishuCardHeader struct {
+	// 标题栏颜色：red、yellow、blue 等
+	Template string         `json:"template"`
+	Title    FeishuCardText `json:"title"`
+}
+
+type FeishuCardText struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content"`
+}
+
+type feishuCardField struct {
+	IsShort bool           `json:"is_short"`
+	Text    FeishuCardText `json:"text"`
+}
+
+type feishuCardDiv struct {
+	Tag    string            `json:"tag"`
+	Text   *FeishuCardText   `json:"text,omitempty"`
+	Fields []feishuCardField `json:"fields,omitempty"`
+}
+
+type feishuCardNote struct {
+	Tag      string           `json:"tag"`
+	Elements []FeishuCardText `json:"elements"`
+}
+
+type feishuCardAction struct {
+	Tag     string             `json:"tag"`
+	Actions []feishuCardButton `json:"actions"`
+}
+
+type feishuCardButton struct {
+	Tag  string         `json:"tag"`
+	Text FeishuCardText `json:"text"`
+	Type string         `json:"type"`
+	// 点击时原样回传给消息卡片请求网址
+	Value FeishuActionValue `json:"value"`
+}
+
+// FeishuActionValue 卡片按钮回传的内容
+type FeishuActionValue struct {
+	// ack 确认当前事件，silence 按 Duration 静默集群
+	Action    string `json:"action"`
+	Region    string `json:"region,omitempty"`
+	Namespace string `json:"namespace"`
+	Cluster   string `json:"cluster"`
+	Duration  string `json:"duration,omitempty"`
+}
+
+// 按钮的静默时长
+const feishuSilenceDuration = "2h"
+
+// 确认和静默按钮
+func feishuActions(r monitor.Report, e monitor.ReportEntry, f Format) feishuCardAction {
+	value := FeishuActionValue{Action: "ack", Region: r.Region, Namespace: e.Namespace, Cluster: e.Name}
+	silence := value
+	silence.Action, silence.Duration = "silence", feishuSilenceDuration
+	return feishuCardAction{Tag: "action", Actions: []feishuCardButton{
+		{Tag: "button", Text: FeishuCardText{Tag: "plain_text", Content: f.T("Acknowledge")}, Type: "primary", Value: value},
+		{Tag: "button", Text: FeishuCardText{Tag: "plain_text", Content: f.T("Silence 2h")}, Type: "default", Value: silence},
+	}}
+}
+
+type feishuCardMessage struct {
+	MsgType string     `json:"msg_type"`
+	Card    FeishuCard `json:"card"`
+}
+
+// 标题栏颜色：有 Failed 时为红色，有 Abnormal 或卡住的集群时为黄色，其余为蓝色
+func cardTemplate(r monitor.Report) string {
+	color := "blue"
+	for _, e := range r.Entries {
+		switch {
+		case e.Severity == monitor.SeverityCritical:
+			return "red"
+		case e.Severity == monitor.SeverityWarning || e.Note != "":
+			color = "yellow"
+		}
+	}
+	return color
+}
+
+// 文本消息最后 @ 的用户：升级的 @ 加上各条目 ns 的负责人，不重复
+func feishuTextMentions(r monitor.Report) []string {
+	ids := append([]string(nil), r.Mentions...)
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for _, e := range r.Entries {
+		for _, id := range e.OwnerMentions {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func lark(content string) FeishuCardText {
+	return FeishuCardText{Tag: "lark_md", Content: content}
+}
+
+// feishuCards 把报告渲染为一张或多张卡片，每张不超过飞书的大小限制。
+// 说明只放在第一张，@ 只放在最后一张，超过 feishuMaxParts 张时其余集群只给出数量
+func feishuCards(r monitor.Report, f Format, actions bool) []FeishuCard {
+	card := feishuCard(r, f, actions)
+	if jsonSize(card) <= feishuMaxPayload {
+		return []FeishuCard{card}
+	}
+	// 按每个集群单独渲染的增量估算大小，依次装入各张卡片
+	empty := r
+	empty.Entries, empty.Mentions = nil, nil
+	base := jsonSize(feishuCard(empty, f, actions))
+	var chunks [][]monitor.ReportEntry
+	used := base
+	for _, e := range r.Entries {
+		single := empty
+		single.Entries = []monitor.ReportEntry{e}
+		n := jsonSize(feishuCard(single, f, actions)) - base
+		if len(chunks) == 0 || used+n > feishuMaxPayload && len(chunks[len(chunks)-1]) > 0 {
+			chunks = append(chunks, nil)
+			used = base
+		}
+		chunks[len(chunks)-1] = append(chunks[len(chunks)-1], e)
+		used += n
+	}
+	omitted := 0
+	if len(chunks) > feishuMaxParts {
+		for _, c := range chunks[feishuMaxParts:] {
+			omitted += len(c)
+		}
+		chunks = chunks[:feishuMaxParts]
+	}
+
+	title := feishuTitle(r, f)
+	cards := make([]FeishuCard, 0, len(chunks))
+	for i, chunk := range chunks {
+		part := r
+		part.Entries = chunk
+		if i > 0 {
+			part.Notice = ""
+		}
+		last := i == len(chunks)-1
+		if !last {
+			part.Mentions = nil
+		}
+		card := feishuCard(part, f, actions)
+		card.Header.Template = cardTemplate(r)
+		card.Header.Title.Content = title + fmt.Sprintf(" (%d/%d)", i+1, len(chunks))
+		if last && omitted > 0 {
+			card.Elements = append(card.Elements, feishuCardDiv{Tag: "div", Text: &FeishuCardText{Tag: "plain_text",
+				Content: fmt.Sprintf(f.T("%d more clusters not shown"), omitted)}})
+		}
+		cards = append(cards, card)
+	}
+	return cards
+}
+
+// 序列化后的字节数
+func jsonSize(v interface{}) int {
+	data, _ := json.Marshal(v)
+	return len(data)
+}
+
+func feishuTitle(r monitor.Report, f Format) string {
+	title := f.T("Database monitor")
+	if r.Region != "" {
+		title += " [" + r.Region + "]"
+	}
+	if len(r.Entries) > 0 {
+		title += ": " + fmt.Sprintf(f.T("%d clusters need attention"), len(r.Entries))
+	}
+	return title
+}
+
+// feishuCard 把报告渲染为卡片：每个集群一行，名称、状态、命名空间分三列，actions 为 true 时下方带按钮
+func feishuCard(r monitor.Report, f Format, actions bool) FeishuCard {
+	var card FeishuCard
+	card.Config.WideScreenMode = true
+	card.Header = FeishuCardHeader{Template: cardTemplate(r), Title: FeishuCardText{Tag: "plain_text", Content: feishuTitle(r, f)}}
+
+	if r.Notice != "" {
+		card.Elements = append(card.Elements, feishuCardDiv{Tag: "div", Text: &FeishuCardText{Tag: "plain_text", Content: f.Notice(r.Notice)}})
+	}
+	for i, e := range r.Entries {
+		if i > 0 || r.Notice != "" {
+			card.Elements = append(card.Elements, map[string]string{"tag": "hr"})
+		}
+		status := e.DisplayPhase()
+		switch e.Severity {
+		case monitor.SeverityCritical:
+			status = "<font color='red'>" + status + "</font>"
+		case monitor.SeverityWarning:
+			status = "<font color='orange'>" + status + "</font>"
+		}
+		name := e.Name
+		if e.ConsoleURL != "" {
+			name = "[" + e.Name + "](" + e.ConsoleURL + ")"
+		}
+		card.Elements = append(card.Elements, feishuCardDiv{Tag: "div", Fields: []feishuCardField{
+			{IsShort: true, Text: lark("**" + f.T("DatabaseName") + "**\n" + name)},
+			{IsShort: true, Text: lark("**" + f.T("Status") + "**\n" + status)},
+			{IsShort: true, Text: lark("**" + f.T("Namespace") + "**\n" + e.Namespace)},
+		}})
+		var details []string
+		if e.Owner != "" {
+			details = append(details, f.T("Owner")+": "+e.Owner)
+		}
+		if e.OOMKilled != "" {
+			details = append(details, "OOMKilled ("+e.OOMKilled+")")
+		}
+		details = append(details, e.Findings...)
+		for _, c := range e.ComponentLines() {
+			details = append(details, "component "+c)
+		}
+		for _, p := range e.PodLines() {
+			details = append(details, "pod "+p)
+		}
+		if len(details) > 0 {
+			card.Elements = append(card.Elements, feishuCardDiv{Tag: "div", Text: &FeishuCardText{Tag: "plain_text", Content: strings.Join(details, "\n")}})
+		}
+		if len(e.OwnerMentions) > 0 {
+			var at strings.Builder
+			for _, id := range e.OwnerMentions {
+				fmt.Fprintf(&at, "<at id=%s></at>", id)
+			}
+			card.Elements = append(card.Elements, feishuCardDiv{Tag: "div", Text: &FeishuCardText{Tag: "lark_md", Content: at.String()}})
+		}
+		if actions {
+			card.Elements = append(card.Elements, feishuActions(r, e, f))
+		}
+	}
+
+	if len(r.Mentions) > 0 {
+		var at strings.Builder
+		for _, id := range r.Mentions {
+			fmt.Fprintf(&at, "<at id=%s></at>", id)
+		}
+		card.Elements = append(card.Elements, feishuCardDiv{Tag: "div", Text: &FeishuCardText{Tag: "lark_md", Content: at.String()}})
+	}
+
+	var footer []string
+	if len(r.DebtNamespaces) > 0 {
+		footer = append(footer, fmt.Sprintf(f.T("Namespaces in debt: %d"), len(r.DebtNamespaces)))
+	}
+	if !r.GeneratedAt.IsZero() {
+		footer = append(footer, f.T("Generated at")+": "+f.Time(r.GeneratedAt))
+	}
+	if r.ConfigHash != "" && r.Notice != "" && len(r.Entries) == 0 {
+		footer = append(footer, f.T("config")+": "+r.ConfigHash)
+	}
+	if len(footer) > 0 {
+		note := feishuCardNote{Tag: "note"}
+		for _, line := range footer {
+			note.Elements = append(note.Elements, FeishuCardText{Tag: "plain_text", Content: line})
+		}
+		card.Elements = append(card.Elements, note)
+	}
+	return card
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"database-monitor/pkg/monitor"
+)
+
+var feishuPart = regexp.MustCompile(`\((\d+/\d+)\)`)
+
+// flakyFeishu 记录收到的每条消息的 (i/n)，第 failAt 条请求返回 500
+func flakyFeishu(t *testing.T, failAt int) (string, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests int
+	var parts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests == failAt {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		data, _ := json.Marshal(body)
+		parts = append(parts, feishuPart.FindStringSubmatch(string(data))[1])
+		w.Write([]byte(`{"code":0}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return parts
+	}
+}
+
+func largeReport(entries int) monitor.Report {
+	r := monitor.Report{}
+	for i := 0; i < entries; i++ {
+		r.Entries = append(r.Entries, monitor.ReportEntry{
+			Namespace: "ns-tenant", Name: fmt.Sprintf("cluster-%03d-%s", i, strings.Repeat("x", 60)),
+			Phase: "Failed", Severity: monitor.SeverityCritical,
+		})
+	}
+	return r
+}
+
+// 拆成多条的文本消息或卡片中一条发送失败后，从失败的那条继续发送，已送达的消息不会重复
+func TestFeishuResumesFailedPart(t *testing.T) {
+	for _, card := range []bool{false, true} {
+		t.Run(fmt.Sprintf("card=%t", card), func(t *testing.T) {
+			url, received := flakyFeishu(t, 2)
+			n := NewFeishu("", url, "", nil, card, false, Format{}, nil)
+			payload, err := n.Render(largeReport(120))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var messages []json.RawMessage
+			json.Unmarshal(payload, &messages)
+			if len(messages) < 3 {
+				t.Fatalf("report rendered to %d messages, want at least 3", len(messages))
+			}
+
+			err = n.Send(context.Background(), payload)
+			var partial *monitor.PartialSendError
+			if !errors.As(err, &partial) {
+				t.Fatalf("err = %v, want a PartialSendError", err)
+			}
+			if err := n.Send(context.Background(), partial.Remaining); err != nil {
+				t.Fatal(err)
+			}
+			want := make([]string, 0, len(messages))
+			for i := range messages {
+				want = append(want, fmt.Sprintf("%d/%d", i+1, len(messages)))
+			}
+			if got := received(); !slices.Equal(got, want) {
+				t.Errorf("received parts %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// 第一条就失败时没有送达任何消息，返回普通错误
+func TestFeishuFirstPartFails(t *testing.T) {
+	url, received := flakyFeishu(t, 1)
+	n := NewFeishu("", url, "", nil, false, false, Format{}, nil)
+	payload, err := n.Render(largeReport(120))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Send(context.Background(), payload)
+	var partial *monitor.PartialSendError
+	if err == nil || errors.As(err, &partial) {
+		t.Errorf("err = %v, want an error without remaining parts", err)
+	}
+	if got := received(); len(got) != 0 {
+		t.Errorf("received parts %q after the first part failed", got)
+	}
+}
@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 支持的语言，未知语言按英文输出；zh-en 为中英双语，中文在前
+const (
+	LocaleEnglish   = "en"
+	LocaleChinese   = "zh"
+	LocaleBilingual = "zh-en"
+)
+
+// ValidLocale 是否为支持的语言
+func ValidLocale(locale string) bool {
+	switch locale {
+	case LocaleEnglish, LocaleChinese, LocaleBilingual:
+		return true
+	}
+	return false
+}
+
+// 消息中与语言相关的固定文本
+var catalog = map[string]map[string]string{
+	LocaleChinese: {
+		"DatabaseName":               "数据库名称",
+		"Status":                     "状态",
+		"Namespace":                  "命名空间",
+		"Region":                     "区域",
+		"Generated at":               "生成时间",
+		"Namespaces in debt: %d":     "欠费命名空间: %d",
+		"config":                     "配置",
+		"Database monitor":           "数据库巡检",
+		"%d clusters need attention": "%d 个集群需要关注",
+		"%d more clusters not shown": "另有 %d 个集群未列出",
+		"(message truncated)":        "（消息过长，已截断）",
+		"Acknowledge":                "确认",
+		"Silence 2h":                 "静默 2 小时",
+		"Daily digest":               "每日摘要",
+		"Console":                    "控制台",
+		"Owner":                      "负责人",
+	},
+}
+
+// noticeCatalog 监控自身通知的翻译，按行匹配，未匹配的行原样输出
+var noticeCatalog = []struct {
+	pattern *regexp.Regexp
+	zh      string
+}{
+	{regexp.MustCompile(`^RECOVERED: (\S+) in (\S+) is (\S+) again \(was (\S+)\), downtime (\S+)$`), "已恢复：${2} 中的 ${1} 已恢复为 ${3}（之前为 ${4}），中断 ${5}"},
+	{regexp.MustCompile(`^ESCALATION (\S+): (\d+) cluster\(s\) failing for more than (\S+)$`), "升级 ${1}：${2} 个集群故障超过 ${3}"},
+	{regexp.MustCompile(`^(\S+) CRD detected, monitoring started$`), "检测到 ${1} CRD，开始巡检"},
+	{regexp.MustCompile(`^Backups need attention:$`), "备份需要关注："},
+	{regexp.MustCompile(`^KubeBlocks operations need attention:$`), "KubeBlocks 运维操作需要关注："},
+	{regexp.MustCompile(`^Database connectivity probes failed:$`), "数据库连通性探测失败："},
+	{regexp.MustCompile(`^Database volumes are filling up:$`), "数据库存储卷即将写满："},
+	{regexp.MustCompile(`^Namespaces recovered from debt:$`), "以下命名空间已结清欠费："},
+	{regexp.MustCompile(`^Stopped databases in namespaces in debt, start them again after the debt is paid:$`), "已停止欠费命名空间中的数据库，结清欠费后需要自行启动："},
+	{regexp.MustCompile(`^Repeated short incidents in the last 24h:$`), "最近 24 小时内反复出现的短时故障："},
+	{regexp.MustCompile(`^Daily digest$`), "每日摘要"},
+	{regexp.MustCompile(`^Monitor cannot reach the API server, checks have failed for (\S+) \((\d+) attempts\)$`), "监控无法访问 API server，巡检已连续失败 ${1}（${2} 次）"},
+	{regexp.MustCompile(`^Monitor reached the API server again, checks failed for (\S+)$`), "监控已恢复访问 API server，此前巡检连续失败 ${1}"},
+	{regexp.MustCompile(`^Notification rate limit reached, (\d+) notifications were combined into this one(:?)$`), "通知超过速率限制，${1} 条通知合并为这一条${2}"},
+}
+
+// Format 每个通知目的地各自的语言和显示时区，渲染时应用于同一份结构化报告
+type Format struct {
+	Locale string
+	// 为空时使用本地时区
+	Location *time.Location
+}
+
+// ParseFormat 按语言和 IANA 时区名构造 Format，时区为空时使用本地时区
+func ParseFormat(locale, timezone string) (Format, error) {
+	f := Format{Locale: locale}
+	if timezone == "" {
+		return f, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return Format{}, fmt.Errorf("load timezone %q: %w", timezone, err)
+	}
+	f.Location = loc
+	return f, nil
+}
+
+// 带格式化动词的双语文本只有一个参数，两种语言引用同一个参数
+var verbPattern = regexp.MustCompile(`%([a-z])`)
+
+// T 返回 key 在目标语言下的文本，没有翻译时原样返回；双语时为"中文 / English"
+func (f Format) T(key string) string {
+	if f.Locale == LocaleBilingual {
+		s, ok := catalog[LocaleChinese][key]
+		if !ok || s == key {
+			return key
+		}
+		return verbPattern.ReplaceAllString(s+" / "+key, "%[1]$1")
+	}
+	if s, ok := catalog[f.Locale][key]; ok {
+		return s
+	}
+	return key
+}
+
+// Notice 翻译监控自身通知中已知的行，双语时在中文行之后保留英文原文
+func (f Format) Notice(text string) string {
+	if f.Locale != LocaleChinese && f.Locale != LocaleBilingual {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		translated := line
+		for _, c := range noticeCatalog {
+			if c.pattern.MatchString(line) {
+				translated = c.pattern.ReplaceAllString(line, c.zh)
+				break
+			}
+		}
+		out = append(out, translated)
+		if f.Locale == LocaleBilingual && translated != line {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// Time 按目标时区格式化时间
+func (f Format) Time(t time.Time) string {
+	loc := f.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	return t.In(loc).Format("2006-01-02 15:04:05 MST")
+}
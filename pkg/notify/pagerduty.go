@@ -0,0 +1,228 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"database-monitor/pkg/monitor"
+)
+
+// PagerDuty Events API v2 的地址
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// 已触发的事件保存在状态存储中的 key 前缀，后接通知后端名称
+const pagerDutyStateKey = "pagerduty-open-"
+
+// PagerDutyEvent Events API v2 的一个事件
+type PagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *PagerDutyPayload `json:"payload,omitempty"`
+	Links       []PagerDutyLink   `json:"links,omitempty"`
+}
+
+// PagerDutyLink 事件上的链接，例如集群在管理控制台中的页面
+type PagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text,omitempty"`
+}
+
+type PagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// pagerDutyBatch Render 的结果：当前需要呼叫的集群。Notice 为 true 时只是自监控通知，不触发也不恢复
+type pagerDutyBatch struct {
+	Notice   bool             `json:"notice,omitempty"`
+	Region   string           `json:"region,omitempty"`
+	Triggers []PagerDutyEvent `json:"triggers"`
+}
+
+// PagerDuty 对严重（critical）的集群触发 PagerDuty 事件，按集群的 namespace/name 去重；
+// 集群不再出现在报告中或不再严重时自动 resolve
+type PagerDuty struct {
+	name       string
+	client     *http.Client
+	routingKey string
+	eventsURL  string
+	store      monitor.StateStore
+
+	mu sync.Mutex
+	// 已触发且尚未恢复的事件 dedup key 到报告的区域，保存在 store 中，重启或重新加载配置后仍能恢复；
+	// 第一次发送时从 store 加载，加载前为 nil
+	open map[string]string
+}
+
+// NewPagerDuty 创建 PagerDuty 通知，routingKey 为服务集成的 Integration Key，name 为空时为 pagerduty。
+// store 保存已触发的事件，为空时只保存在内存中，重启前打开的事件需要手动恢复
+func NewPagerDuty(name, routingKey string, store monitor.StateStore, client *http.Client) *PagerDuty {
+	if name == "" {
+		name = "pagerduty"
+	}
+	return &PagerDuty{name: name, client: clientOrDefault(client), routingKey: routingKey, eventsURL: pagerDutyEventsURL, store: store}
+}
+
+func (n *PagerDuty) Name() string {
+	return n.name
+}
+
+func (n *PagerDuty) Render(r monitor.Report) ([]byte, error) {
+	batch := pagerDutyBatch{Notice: r.Notice != "" && len(r.Entries) == 0, Region: r.Region, Triggers: []PagerDutyEvent{}}
+	for _, e := range r.Entries {
+		if e.Severity != monitor.SeverityCritical {
+			continue
+		}
+		// 与监控的事件 key 相同，同一集群重复触发时 PagerDuty 合并为一个事件
+		key := e.Namespace + "/" + e.Name
+		if r.Region != "" {
+			key = r.Region + "/" + key
+		}
+		details := map[string]string{"phase": e.DisplayPhase()}
+		if e.Reason != "" {
+			details["reason"] = e.Reason
+		}
+		if e.OOMKilled != "" {
+			details["oomKilled"] = e.OOMKilled
+		}
+		if e.Owner != "" {
+			details["owner"] = e.Owner
+		}
+		for i, f := range e.Findings {
+			details[fmt.Sprintf("finding_%d", i+1)] = f
+		}
+		for i, c := range e.ComponentLines() {
+			details[fmt.Sprintf("component_%d", i+1)] = c
+		}
+		for i, p := range e.PodLines() {
+			details[fmt.Sprintf("pod_%d", i+1)] = p
+		}
+		var links []PagerDutyLink
+		if e.ConsoleURL != "" {
+			links = []PagerDutyLink{{Href: e.ConsoleURL, Text: "Console"}}
+		}
+		batch.Triggers = append(batch.Triggers, PagerDutyEvent{
+			RoutingKey:  n.routingKey,
+			EventAction: "trigger",
+			DedupKey:    "database-monitor/" + key,
+			Payload: &PagerDutyPayload{
+				Summary:       fmt.Sprintf("Database cluster %s is %s", key, e.DisplayPhase()),
+				Source:        key,
+				Severity:      "critical",
+				Component:     e.Name,
+				Group:         e.Namespace,
+				CustomDetails: details,
+			},
+			Links: links,
+		})
+	}
+	return json.Marshal(batch)
+}
+
+// Send 触发报告中的事件，并恢复同一区域上次触发而这次不再出现的事件。
+// 触发按 dedup key 幂等，每次发送都会重复触发仍在报告中的事件
+func (n *PagerDuty) Send(ctx context.Context, payload []byte) (err error) {
+	var batch pagerDutyBatch
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		return err
+	}
+	if batch.Notice {
+		return nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := n.loadOpen(ctx); err != nil {
+		return fmt.Errorf("loading open PagerDuty incidents: %w", err)
+	}
+	// 中途失败时也保存已经成功触发或恢复的事件
+	defer func() {
+		if saveErr := n.saveOpen(ctx); saveErr != nil && err == nil {
+			err = fmt.Errorf("saving open PagerDuty incidents: %w", saveErr)
+		}
+	}()
+	current := make(map[string]bool)
+	for _, event := range batch.Triggers {
+		current[event.DedupKey] = true
+		if err := n.post(ctx, event); err != nil {
+			return err
+		}
+		n.open[event.DedupKey] = batch.Region
+	}
+	for key, region := range n.open {
+		if current[key] || region != batch.Region {
+			continue
+		}
+		event := PagerDutyEvent{RoutingKey: n.routingKey, EventAction: "resolve", DedupKey: key}
+		if err := n.post(ctx, event); err != nil {
+			return err
+		}
+		delete(n.open, key)
+	}
+	return nil
+}
+
+// 第一次发送前从 store 加载已触发的事件，调用方持有 n.mu
+func (n *PagerDuty) loadOpen(ctx context.Context) error {
+	if n.open != nil {
+		return nil
+	}
+	open := make(map[string]string)
+	if n.store != nil {
+		data, err := n.store.Get(ctx, pagerDutyStateKey+n.name)
+		if err != nil {
+			return err
+		}
+		if data != "" {
+			if err := json.Unmarshal([]byte(data), &open); err != nil {
+				return err
+			}
+		}
+	}
+	n.open = open
+	return nil
+}
+
+// 调用方持有 n.mu
+func (n *PagerDuty) saveOpen(ctx context.Context) error {
+	if n.store == nil {
+		return nil
+	}
+	data, err := json.Marshal(n.open)
+	if err != nil {
+		return err
+	}
+	return n.store.Set(ctx, pagerDutyStateKey+n.name, string(data))
+}
+
+func (n *PagerDuty) post(ctx context.Context, event PagerDutyEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.eventsURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending %s event to PagerDuty: %w", event.EventAction, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to send %s event to PagerDuty, status code: %d", event.EventAction, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"database-monitor/pkg/monitor"
+)
+
+// memStore 内存中的 StateStore
+type memStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (s *memStore) Get(_ context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key], nil
+}
+
+func (s *memStore) Set(_ context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make(map[string]string)
+	}
+	s.values[key] = value
+	return nil
+}
+
+// pagerDutyServer 记录收到的事件，格式为 "动作 dedup key"
+func pagerDutyServer(t *testing.T) (string, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var events []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e PagerDutyEvent
+		json.NewDecoder(r.Body).Decode(&e)
+		mu.Lock()
+		events = append(events, e.EventAction+" "+e.DedupKey)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		sent := events
+		events = nil
+		return sent
+	}
+}
+
+func newTestPagerDuty(url string, store monitor.StateStore) *PagerDuty {
+	n := NewPagerDuty("", "routing-key", store, nil)
+	n.eventsURL = url
+	return n
+}
+
+func sendReport(t *testing.T, n *PagerDuty, r monitor.Report) {
+	t.Helper()
+	payload, err := n.Render(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func critical(namespace, name string) monitor.ReportEntry {
+	return monitor.ReportEntry{Namespace: namespace, Name: name, Phase: "Failed", Severity: monitor.SeverityCritical}
+}
+
+// 重启（或重新加载配置）后创建的 PagerDuty 从 store 得知之前触发的事件，集群恢复时照常 resolve
+func TestPagerDutyResolvesAfterRestart(t *testing.T) {
+	url, events := pagerDutyServer(t)
+	store := &memStore{}
+	before := newTestPagerDuty(url, store)
+	sendReport(t, before, monitor.Report{Entries: []monitor.ReportEntry{critical("ns1", "db"), critical("ns1", "cache")}})
+	if got := events(); !slices.Equal(got, []string{"trigger database-monitor/ns1/db", "trigger database-monitor/ns1/cache"}) {
+		t.Fatalf("events = %q", got)
+	}
+
+	after := newTestPagerDuty(url, store)
+	sendReport(t, after, monitor.Report{Entries: []monitor.ReportEntry{critical("ns1", "cache")}})
+	if got := events(); !slices.Equal(got, []string{"trigger database-monitor/ns1/cache", "resolve database-monitor/ns1/db"}) {
+		t.Errorf("events after restart = %q", got)
+	}
+	sendReport(t, newTestPagerDuty(url, store), monitor.Report{Entries: []monitor.ReportEntry{}})
+	if got := events(); !slices.Equal(got, []string{"resolve database-monitor/ns1/cache"}) {
+		t.Errorf("events after second restart = %q", got)
+	}
+}
+
+func TestPagerDutySend(t *testing.T) {
+	warning := critical("ns1", "db")
+	warning.Severity = monitor.SeverityWarning
+	tests := []struct {
+		name    string
+		reports []monitor.Report
+		want    []string
+	}{
+		{"severity downgraded", []monitor.Report{
+			{Entries: []monitor.ReportEntry{critical("ns1", "db")}},
+			{Entries: []monitor.ReportEntry{warning}},
+		}, []string{"trigger database-monitor/ns1/db", "resolve database-monitor/ns1/db"}},
+		{"notice does not resolve", []monitor.Report{
+			{Entries: []monitor.ReportEntry{critical("ns1", "db")}},
+			{Notice: "Monitor restarted"},
+		}, []string{"trigger database-monitor/ns1/db"}},
+		// 各区域的报告只恢复本区域的事件
+		{"other region untouched", []monitor.Report{
+			{Region: "a", Entries: []monitor.ReportEntry{critical("ns1", "db")}},
+			{Region: "b", Entries: []monitor.ReportEntry{critical("ns1", "db")}},
+			{Region: "b", Entries: []monitor.ReportEntry{}},
+		}, []string{"trigger database-monitor/a/ns1/db", "trigger database-monitor/b/ns1/db", "resolve database-monitor/b/ns1/db"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, events := pagerDutyServer(t)
+			n := newTestPagerDuty(url, nil)
+			for _, r := range tt.reports {
+				sendReport(t, n, r)
+			}
+			if got := events(); !slices.Equal(got, tt.want) {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
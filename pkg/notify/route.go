@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"path"
+
+	"database-monitor/pkg/monitor"
+)
+
+// Routed 只接收部分严重程度的通知后端，例如 critical 发送到 PagerDuty，warning 只发送到聊天群
+type Routed struct {
+	monitor.Notifier
+	// 为空时接收全部严重程度
+	severities map[string]bool
+}
+
+// Route 让 n 只接收 severities 中的严重程度，severities 为空时返回 n 本身
+func Route(n monitor.Notifier, severities []string) monitor.Notifier {
+	if len(severities) == 0 {
+		return n
+	}
+	r := &Routed{Notifier: n, severities: make(map[string]bool, len(severities))}
+	for _, s := range severities {
+		r.severities[s] = true
+	}
+	return r
+}
+
+func (r *Routed) AcceptsSeverity(severity string) bool {
+	return r.severities == nil || r.severities[severity]
+}
+
+// Scoped 只接收部分 ns 的通知后端，例如各租户团队自己的群，也可以是接收其余 ns 的兜底渠道
+type Scoped struct {
+	*Routed
+	namespaces []string
+	fallback   bool
+}
+
+// RouteNamespaces 让 n 只接收 namespaces（支持 glob）中的 ns 的条目；fallback 为 true 时
+// 只接收没有被其他后端认领的 ns 的条目。两者都为空时返回 n 本身
+func RouteNamespaces(n monitor.Notifier, namespaces []string, fallback bool) monitor.Notifier {
+	if len(namespaces) == 0 && !fallback {
+		return n
+	}
+	r, ok := n.(*Routed)
+	if !ok {
+		r = &Routed{Notifier: n}
+	}
+	return &Scoped{Routed: r, namespaces: namespaces, fallback: fallback}
+}
+
+func (s *Scoped) AcceptsNamespace(namespace string) bool {
+	for _, p := range s.namespaces {
+		if ok, _ := path.Match(p, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scoped) Fallback() bool {
+	return s.fallback
+}
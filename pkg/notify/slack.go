@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"database-monitor/pkg/monitor"
+)
+
+// SlackMessage Slack incoming webhook 的消息体
+type SlackMessage struct {
+	Text string `json:"text"`
+}
+
+// Slack 通过 incoming webhook 发送到 Slack 频道
+type Slack struct {
+	name       string
+	client     *http.Client
+	webhookURL string
+	format     Format
+	// 自定义消息模板，为空时使用默认的文本表格
+	tmpl *Template
+}
+
+// NewSlack 创建 Slack 通知，name 为空时为 slack，tmpl 为空时使用默认的文本表格
+func NewSlack(name, webhookURL string, tmpl *Template, format Format, client *http.Client) *Slack {
+	if name == "" {
+		name = "slack"
+	}
+	return &Slack{name: name, client: clientOrDefault(client), webhookURL: webhookURL, format: format, tmpl: tmpl}
+}
+
+func (n *Slack) Name() string {
+	return n.name
+}
+
+func (n *Slack) Render(r monitor.Report) ([]byte, error) {
+	// 表格按列对齐，放进代码块中避免 Slack 的比例字体打乱对齐
+	text := "```\n" + FormatText(r, n.format) + "\n```"
+	if n.tmpl != nil {
+		var err error
+		if text, err = n.tmpl.Execute(r); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range r.Mentions {
+		text += " <@" + id + ">"
+	}
+	return json.Marshal(SlackMessage{Text: text})
+}
+
+func (n *Slack) Send(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending alert to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to send alert to Slack, status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+// 以下字段名是下游日志管道（Loki LogQL）依赖的稳定格式，只能新增字段，不要改名或删除
+
+// stdoutPod 条目中的一个 Pod
+type stdoutPod struct {
+	Name            string `json:"name"`
+	Phase           string `json:"phase"`
+	Restarts        int32  `json:"restarts"`
+	LastTermination string `json:"last_termination,omitempty"`
+}
+
+func stdoutPods(pods []monitor.PodStatus) []stdoutPod {
+	var out []stdoutPod
+	for _, p := range pods {
+		out = append(out, stdoutPod{Name: p.Name, Phase: p.Phase, Restarts: p.Restarts, LastTermination: p.LastTermination})
+	}
+	return out
+}
+
+// stdoutEntry 报告中的每个条目输出一行
+type stdoutEntry struct {
+	Type       string      `json:"type"`
+	Time       string      `json:"time"`
+	Cluster    string      `json:"cluster"`
+	Namespace  string      `json:"namespace"`
+	Phase      string      `json:"phase"`
+	Severity   string      `json:"severity"`
+	Note       string      `json:"note,omitempty"`
+	ConsoleURL string      `json:"console_url,omitempty"`
+	Owner      string      `json:"owner,omitempty"`
+	OOMKilled  string      `json:"oom_killed,omitempty"`
+	Findings   []string    `json:"findings,omitempty"`
+	Components []string    `json:"components,omitempty"`
+	Pods       []stdoutPod `json:"pods,omitempty"`
+	Region     string      `json:"region,omitempty"`
+}
+
+// stdoutSummary 每轮巡检最后输出一行汇总
+type stdoutSummary struct {
+	Type           string `json:"type"`
+	Time           string `json:"time"`
+	Entries        int    `json:"entries"`
+	DebtNamespaces int    `json:"debt_namespaces"`
+	ConfigHash     string `json:"config_hash,omitempty"`
+	Notice         string `json:"notice,omitempty"`
+	Region         string `json:"region,omitempty"`
+}
+
+const (
+	stdoutTypeEntry   = "database_monitor_entry"
+	stdoutTypeSummary = "database_monitor_summary"
+)
+
+// Stdout 把报告以 NDJSON 写到标准输出，供日志系统采集
+type Stdout struct {
+	name string
+	mu   sync.Mutex
+}
+
+// NewStdout 创建标准输出通知，name 为空时为 stdout
+func NewStdout(name string) *Stdout {
+	if name == "" {
+		name = "stdout"
+	}
+	return &Stdout{name: name}
+}
+
+func (n *Stdout) Name() string {
+	return n.name
+}
+
+func (n *Stdout) ContentType() string {
+	return "application/x-ndjson"
+}
+
+func (n *Stdout) Render(r monitor.Report) ([]byte, error) {
+	ts := r.GeneratedAt.UTC().Format(time.RFC3339)
+	entries := append([]monitor.ReportEntry(nil), r.Entries...)
+	// 同一轮内的输出顺序固定
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Namespace != entries[j].Namespace {
+			return entries[i].Namespace < entries[j].Namespace
+		}
+		return entries[i].Name < entries[j].Name
+	})
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		err := enc.Encode(stdoutEntry{
+			Type:       stdoutTypeEntry,
+			Time:       ts,
+			Cluster:    e.Name,
+			Namespace:  e.Namespace,
+			Phase:      e.Phase,
+			Severity:   e.Severity,
+			Note:       e.Note,
+			ConsoleURL: e.ConsoleURL,
+			Owner:      e.Owner,
+			OOMKilled:  e.OOMKilled,
+			Findings:   e.Findings,
+			Components: e.ComponentLines(),
+			Pods:       stdoutPods(e.Pods),
+			Region:     r.Region,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := enc.Encode(stdoutSummary{
+		Type:           stdoutTypeSummary,
+		Time:           ts,
+		Entries:        len(entries),
+		DebtNamespaces: len(r.DebtNamespaces),
+		ConfigHash:     r.ConfigHash,
+		Notice:         r.Notice,
+		Region:         r.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (n *Stdout) Send(_ context.Context, payload []byte) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err := os.Stdout.Write(payload)
+	return err
+}
@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+// go test ./pkg/notify -update 重新生成 testdata 中的期望输出
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// 与 testdata/name 中的内容比较
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s mismatch:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+var testTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestStdoutName(t *testing.T) {
+	if got := NewStdout("").Name(); got != "stdout" {
+		t.Errorf("default name = %q, want stdout", got)
+	}
+	if got := NewStdout("loki").Name(); got != "loki" {
+		t.Errorf("name = %q, want loki", got)
+	}
+}
+
+// 字段名是下游日志管道依赖的格式，输出变化需要同时更新 golden 文件并确认兼容
+func TestStdoutRenderGolden(t *testing.T) {
+	tests := []struct {
+		golden string
+		report monitor.Report
+	}{
+		{"stdout_empty.ndjson", monitor.Report{GeneratedAt: testTime, ConfigHash: "abc123"}},
+		{"stdout_notice.ndjson", monitor.Report{GeneratedAt: testTime, Notice: "Database monitor restarted", Region: "cn-north"}},
+		{"stdout_entries.ndjson", monitor.Report{
+			GeneratedAt:    testTime,
+			Region:         "cn-north",
+			DebtNamespaces: []string{"ns-debt"},
+			Entries: []monitor.ReportEntry{
+				{Name: "redis", Namespace: "ns-b", Phase: "Deleting", Severity: "warning", Note: "stuck 45m"},
+				{
+					Name: "mysql", Namespace: "ns-a", Phase: "Failed", Severity: "critical",
+					OOMKilled:  "mysql-0 (limit 1Gi)",
+					Findings:   []string{"0/3 nodes are available: insufficient memory"},
+					Owner:      "owner=alice",
+					ConsoleURL: "https://console.example.com/ns-a/mysql",
+					Components: []monitor.ComponentStatus{{Name: "mysql", Phase: "Failed", Ready: 1, Replicas: 3}, {Name: "proxy", Phase: "Running", Ready: 2, Replicas: 2}},
+					Pods:       []monitor.PodStatus{{Name: "mysql-0", Phase: "Running", Restarts: 4, LastTermination: "OOMKilled"}},
+				},
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			got, err := NewStdout("").Render(tt.report)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.golden, got)
+		})
+	}
+}
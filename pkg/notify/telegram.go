@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"database-monitor/pkg/monitor"
+)
+
+// Telegram 单条消息的长度上限（字符）
+const telegramMaxMessage = 4096
+
+type TelegramMessage struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+// Telegram 通过 Bot API 发送到群组或频道，过长的报告拆成多条消息
+type Telegram struct {
+	name     string
+	client   *http.Client
+	botToken string
+	chatID   string
+	format   Format
+	apiURL   string
+	// 自定义消息模板，为空时使用默认的文本表格
+	tmpl *Template
+}
+
+// NewTelegram 创建 Telegram 通知，name 为空时为 telegram，tmpl 为空时使用默认的文本表格
+func NewTelegram(name, botToken, chatID string, tmpl *Template, format Format, client *http.Client) *Telegram {
+	if name == "" {
+		name = "telegram"
+	}
+	return &Telegram{name: name, client: clientOrDefault(client), botToken: botToken, chatID: chatID, format: format, apiURL: "https://api.telegram.org", tmpl: tmpl}
+}
+
+func (n *Telegram) Name() string {
+	return n.name
+}
+
+// Render 返回按顺序发送的消息列表
+func (n *Telegram) Render(r monitor.Report) ([]byte, error) {
+	text := FormatText(r, n.format)
+	if n.tmpl != nil {
+		var err error
+		if text, err = n.tmpl.Execute(r); err != nil {
+			return nil, err
+		}
+	}
+	var messages []TelegramMessage
+	for _, part := range splitMessage(text, telegramMaxMessage) {
+		messages = append(messages, TelegramMessage{ChatID: n.chatID, Text: part})
+	}
+	return json.Marshal(messages)
+}
+
+// splitMessage 按字符数拆分文本，尽量在换行处断开，单行过长时强制截断
+func splitMessage(text string, limit int) []string {
+	var parts []string
+	runes := []rune(text)
+	for len(runes) > limit {
+		cut := limit
+		for i := limit - 1; i > 0; i-- {
+			if runes[i] == '\n' {
+				cut = i + 1
+				break
+			}
+		}
+		parts = append(parts, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	if len(runes) > 0 {
+		parts = append(parts, string(runes))
+	}
+	return parts
+}
+
+func (n *Telegram) Send(ctx context.Context, payload []byte) error {
+	var messages []TelegramMessage
+	if err := json.Unmarshal(payload, &messages); err != nil {
+		return err
+	}
+	for i, message := range messages {
+		if err := n.send(ctx, message); err != nil {
+			return partialSendError(messages, i, fmt.Errorf("sending part %d/%d: %w", i+1, len(messages), err))
+		}
+	}
+	return nil
+}
+
+// partialSendError 在 messages 中前 sent 条已经送达时带上剩余的消息，重试时从失败的那条开始
+func partialSendError[T any](messages []T, sent int, err error) error {
+	if sent == 0 {
+		return err
+	}
+	remaining, merr := json.Marshal(messages[sent:])
+	if merr != nil {
+		return err
+	}
+	return &monitor.PartialSendError{Remaining: remaining, Err: err}
+}
+
+func (n *Telegram) send(ctx context.Context, message TelegramMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	endpoint := n.apiURL + "/bot" + n.botToken + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending alert to Telegram: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var result struct {
+			Description string `json:"description"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return fmt.Errorf("failed to send alert to Telegram, status code: %d %s", resp.StatusCode, result.Description)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"database-monitor/pkg/monitor"
+)
+
+func TestSplitMessage(t *testing.T) {
+	line := strings.Repeat("数据库", 10) + "\n" // 31 个字符
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		// 各部分的字符数
+		want []int
+	}{
+		{"empty", "", 10, nil},
+		{"exactly the limit in Chinese", strings.Repeat("库", telegramMaxMessage), telegramMaxMessage, []int{telegramMaxMessage}},
+		{"one rune over the limit in Chinese", strings.Repeat("库", telegramMaxMessage+1), telegramMaxMessage, []int{telegramMaxMessage, 1}},
+		{"breaks after the last newline", strings.Repeat(line, 3), 70, []int{62, 31}},
+		{"newline exactly at the limit", strings.Repeat(line, 2), 31, []int{31, 31}},
+		{"single line longer than the limit", strings.Repeat("集群", 25), 20, []int{20, 20, 10}},
+		{"long line after a short one", "短行\n" + strings.Repeat("长", 30), 20, []int{3, 20, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := splitMessage(tt.text, tt.limit)
+			var got []int
+			for _, p := range parts {
+				if !utf8.ValidString(p) {
+					t.Errorf("part %q is not valid UTF-8", p)
+				}
+				got = append(got, utf8.RuneCountInString(p))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("part lengths = %v, want %v", got, tt.want)
+			}
+			if joined := strings.Join(parts, ""); joined != tt.text {
+				t.Errorf("joined parts differ from the original text")
+			}
+		})
+	}
+}
+
+// flakyTelegram 记录收到的消息，第 failAt 条请求返回 429
+func flakyTelegram(t *testing.T, failAt int) (string, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests int
+	var texts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message TelegramMessage
+		json.NewDecoder(r.Body).Decode(&message)
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests == failAt {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"ok":false,"description":"Too Many Requests: retry after 1"}`))
+			return
+		}
+		texts = append(texts, message.Text)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return texts
+	}
+}
+
+// 拆成多条的消息中第二条发送失败时返回剩余的消息，重试只发送未送达的部分
+func TestTelegramResumesFailedPart(t *testing.T) {
+	url, received := flakyTelegram(t, 2)
+	n := NewTelegram("", "token", "chat", nil, Format{}, nil)
+	n.apiURL = url
+	payload, err := n.Render(largeReport(120))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []TelegramMessage
+	json.Unmarshal(payload, &messages)
+	if len(messages) < 3 {
+		t.Fatalf("report rendered to %d messages, want at least 3", len(messages))
+	}
+
+	err = n.Send(context.Background(), payload)
+	var partial *monitor.PartialSendError
+	if !errors.As(err, &partial) {
+		t.Fatalf("err = %v, want a PartialSendError", err)
+	}
+	if !strings.Contains(err.Error(), "sending part 2/") || !strings.Contains(err.Error(), "Too Many Requests") {
+		t.Errorf("err = %v, want the failed part and Telegram's description", err)
+	}
+	if err := n.Send(context.Background(), partial.Remaining); err != nil {
+		t.Fatal(err)
+	}
+	got := received()
+	if len(got) != len(messages) {
+		t.Fatalf("received %d messages, want %d", len(got), len(messages))
+	}
+	for i, m := range messages {
+		if got[i] != m.Text {
+			t.Errorf("message %d differs from the rendered part", i+1)
+		}
+	}
+}
+
+// 第一条就发送失败时没有已送达的部分，返回普通错误，重试整份报告
+func TestTelegramFirstPartFails(t *testing.T) {
+	url, received := flakyTelegram(t, 1)
+	n := NewTelegram("", "token", "chat", nil, Format{}, nil)
+	n.apiURL = url
+	payload, err := n.Render(largeReport(120))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Send(context.Background(), payload)
+	var partial *monitor.PartialSendError
+	if err == nil || errors.As(err, &partial) {
+		t.Fatalf("err = %v, want a plain error", err)
+	}
+	if got := received(); len(got) != 0 {
+		t.Errorf("received %d messages after the first failed", len(got))
+	}
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+// Template 用户自定义的 Go text/template 消息模板，数据为 monitor.Report。
+// 除标准函数外可用 tr（按语言翻译）、notice（翻译监控自身的通知）、localTime（按时区格式化时间）、
+// duration（两个时间之间的时长，取整到分钟，例如 {{duration .Since $.GeneratedAt}}）和 join
+type Template struct {
+	tmpl *template.Template
+}
+
+// ParseTemplateFile 读取并解析模板文件，format 决定 tr 和 localTime 的语言和时区
+func ParseTemplateFile(path string, format Format) (*Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read template: %w", err)
+	}
+	return ParseTemplate(path, string(content), format)
+}
+
+// ParseTemplate 解析模板内容，name 用于错误信息
+func ParseTemplate(name, content string, format Format) (*Template, error) {
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"tr":        format.T,
+		"notice":    format.Notice,
+		"localTime": format.Time,
+		"duration":  templateDuration,
+		"join":      strings.Join,
+	}).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Execute 渲染报告，执行失败时返回 *monitor.TemplateError
+func (t *Template) Execute(r monitor.Report) (string, error) {
+	var buf strings.Builder
+	if err := t.tmpl.Execute(&buf, r); err != nil {
+		return "", &monitor.TemplateError{Err: err}
+	}
+	return buf.String(), nil
+}
+
+// 起始时间未知时为空
+func templateDuration(since, until time.Time) string {
+	if since.IsZero() || until.Before(since) {
+		return ""
+	}
+	return until.Sub(since).Round(time.Minute).String()
+}
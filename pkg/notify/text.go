@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"fmt"
+
+	"database-monitor/pkg/monitor"
+)
+
+// Text 默认的纯文本表格格式，英文、本地时区
+func Text(r monitor.Report) string {
+	return FormatText(r, Format{})
+}
+
+// FormatText 按目的地的语言和时区渲染文本表格
+func FormatText(r monitor.Report, f Format) string {
+	text := ""
+	if r.Region != "" {
+		text = f.T("Region") + ": " + r.Region + "\n"
+	}
+	if !r.GeneratedAt.IsZero() {
+		text += f.T("Generated at") + ": " + f.Time(r.GeneratedAt) + "\n"
+	}
+	if r.Notice != "" && len(r.Entries) == 0 {
+		return text + f.Notice(r.Notice) + noticeFooter(r, f)
+	}
+	if r.Notice != "" {
+		text += f.Notice(r.Notice) + "\n\n"
+	}
+	text += fmt.Sprintf("%-50s %-50s %-50s\n", f.T("DatabaseName"), f.T("Status"), f.T("Namespace"))
+	for _, e := range r.Entries {
+		if e.OOMKilled != "" {
+			text += "OOMKilled (" + e.OOMKilled + ")\n"
+		}
+		text += fmt.Sprintf("%-50s %-50s %-50s\n", e.Name, e.DisplayPhase(), e.Namespace)
+		if e.Owner != "" {
+			text += "    " + f.T("Owner") + ": " + e.Owner + "\n"
+		}
+		if e.ConsoleURL != "" {
+			text += "    " + f.T("Console") + ": " + e.ConsoleURL + "\n"
+		}
+		for _, f := range e.Findings {
+			text += "    " + f + "\n"
+		}
+		for _, c := range e.ComponentLines() {
+			text += "    component " + c + "\n"
+		}
+		for _, p := range e.PodLines() {
+			text += "    pod " + p + "\n"
+		}
+	}
+	if len(r.DebtNamespaces) > 0 {
+		text += fmt.Sprintf("\n"+f.T("Namespaces in debt: %d")+"\n", len(r.DebtNamespaces))
+	}
+	return text
+}
+
+// 自监控通知的页脚，标明生效的配置版本
+func noticeFooter(r monitor.Report, f Format) string {
+	if r.ConfigHash == "" {
+		return ""
+	}
+	return "\n\n" + f.T("config") + ": " + r.ConfigHash
+}
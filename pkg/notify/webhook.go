@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"database-monitor/pkg/monitor"
+)
+
+// WebhookSchemaVersion 通用 webhook 消息格式的版本，字段不兼容变化时递增
+const WebhookSchemaVersion = 1
+
+// WebhookMessage 通用 webhook 发送的 JSON，供下游自动化直接消费
+type WebhookMessage struct {
+	SchemaVersion int `json:"schemaVersion"`
+	// report 为巡检报告，notice 为监控自身的通知
+	Kind        string         `json:"kind"`
+	Region      string         `json:"region,omitempty"`
+	GeneratedAt time.Time      `json:"generatedAt"`
+	ConfigHash  string         `json:"configHash,omitempty"`
+	Notice      string         `json:"notice,omitempty"`
+	Mentions    []string       `json:"mentions,omitempty"`
+	Alerts      []WebhookAlert `json:"alerts"`
+}
+
+// WebhookAlert 一个需要关注的集群
+type WebhookAlert struct {
+	Cluster       string                    `json:"cluster"`
+	Namespace     string                    `json:"namespace"`
+	Phase         string                    `json:"phase"`
+	PreviousPhase string                    `json:"previousPhase,omitempty"`
+	Severity      string                    `json:"severity"`
+	Reason        string                    `json:"reason,omitempty"`
+	Note          string                    `json:"note,omitempty"`
+	ConsoleURL    string                    `json:"consoleURL,omitempty"`
+	Owner         string                    `json:"owner,omitempty"`
+	OwnerMentions []string                  `json:"ownerMentions,omitempty"`
+	OOMKilled     string                    `json:"oomKilled,omitempty"`
+	Findings      []string                  `json:"findings,omitempty"`
+	Components    []monitor.ComponentStatus `json:"components,omitempty"`
+	Pods          []monitor.PodStatus       `json:"pods,omitempty"`
+	InDebt        bool                      `json:"inDebt"`
+	Timestamp     time.Time                 `json:"timestamp"`
+}
+
+// Webhook 把结构化报告 POST 到任意地址，可附加自定义请求头（例如鉴权）
+type Webhook struct {
+	name    string
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+// NewWebhook 创建通用 webhook 通知，name 为空时为 webhook
+func NewWebhook(name, url string, headers map[string]string, client *http.Client) *Webhook {
+	if name == "" {
+		name = "webhook"
+	}
+	return &Webhook{name: name, client: clientOrDefault(client), url: url, headers: headers}
+}
+
+func (n *Webhook) Name() string {
+	return n.name
+}
+
+func (n *Webhook) Render(r monitor.Report) ([]byte, error) {
+	message := WebhookMessage{
+		SchemaVersion: WebhookSchemaVersion,
+		Kind:          "report",
+		Region:        r.Region,
+		GeneratedAt:   r.GeneratedAt,
+		ConfigHash:    r.ConfigHash,
+		Notice:        r.Notice,
+		Mentions:      r.Mentions,
+		Alerts:        []WebhookAlert{},
+	}
+	if r.Notice != "" && len(r.Entries) == 0 {
+		message.Kind = "notice"
+	}
+	debt := make(map[string]bool, len(r.DebtNamespaces))
+	for _, ns := range r.DebtNamespaces {
+		debt[ns] = true
+	}
+	for _, e := range r.Entries {
+		message.Alerts = append(message.Alerts, WebhookAlert{
+			Cluster:       e.Name,
+			Namespace:     e.Namespace,
+			Phase:         e.Phase,
+			PreviousPhase: e.PreviousPhase,
+			Severity:      e.Severity,
+			Reason:        e.Reason,
+			Note:          e.Note,
+			ConsoleURL:    e.ConsoleURL,
+			Owner:         e.Owner,
+			OwnerMentions: e.OwnerMentions,
+			OOMKilled:     e.OOMKilled,
+			Findings:      e.Findings,
+			Components:    e.Components,
+			Pods:          e.Pods,
+			InDebt:        debt[e.Namespace],
+			Timestamp:     r.GeneratedAt,
+		})
+	}
+	return json.Marshal(message)
+}
+
+func (n *Webhook) Send(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending alert to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send alert to webhook, status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"database-monitor/pkg/monitor"
+)
+
+// 企业微信 markdown 消息内容的长度上限（字节）
+const wecomMaxContent = 4096
+
+type WeComMessage struct {
+	MsgType  string `json:"msgtype"`
+	Markdown struct {
+		Content string `json:"content"`
+	} `json:"markdown"`
+}
+
+// WeCom 企业微信群机器人通知，使用 markdown 消息
+type WeCom struct {
+	name       string
+	client     *http.Client
+	webhookURL string
+	format     Format
+	// 自定义 markdown 模板，为空时每个集群一行
+	tmpl *Template
+}
+
+// NewWeCom 创建企业微信通知，name 为空时为 wecom，tmpl 为空时使用默认的 markdown 格式
+func NewWeCom(name, webhookURL string, tmpl *Template, format Format, client *http.Client) *WeCom {
+	if name == "" {
+		name = "wecom"
+	}
+	return &WeCom{name: name, client: clientOrDefault(client), webhookURL: webhookURL, format: format, tmpl: tmpl}
+}
+
+func (n *WeCom) Name() string {
+	return n.name
+}
+
+func (n *WeCom) Render(r monitor.Report) ([]byte, error) {
+	message := WeComMessage{MsgType: "markdown"}
+	message.Markdown.Content = FormatMarkdown(r, n.format)
+	if n.tmpl != nil {
+		text, err := n.tmpl.Execute(r)
+		if err != nil {
+			return nil, err
+		}
+		message.Markdown.Content = truncateMarkdown(text)
+	}
+	for _, id := range r.Mentions {
+		message.Markdown.Content += "<@" + id + ">"
+	}
+	return json.Marshal(message)
+}
+
+// FormatMarkdown 渲染企业微信 markdown：不支持表格，每个集群一行，按严重程度着色
+func FormatMarkdown(r monitor.Report, f Format) string {
+	var b strings.Builder
+	if r.Region != "" {
+		fmt.Fprintf(&b, "**%s**: %s\n", f.T("Region"), r.Region)
+	}
+	if !r.GeneratedAt.IsZero() {
+		fmt.Fprintf(&b, "> %s: %s\n", f.T("Generated at"), f.Time(r.GeneratedAt))
+	}
+	if r.Notice != "" {
+		b.WriteString(f.Notice(r.Notice) + "\n")
+	}
+	if r.Notice != "" && len(r.Entries) == 0 {
+		return truncateMarkdown(b.String() + noticeFooter(r, f))
+	}
+	if len(r.Entries) > 0 {
+		fmt.Fprintf(&b, "**%s** | %s | %s\n", f.T("DatabaseName"), f.T("Status"), f.T("Namespace"))
+	}
+	for _, e := range r.Entries {
+		color := "comment"
+		switch e.Severity {
+		case monitor.SeverityCritical:
+			color = "warning"
+		case monitor.SeverityWarning:
+			color = "info"
+		}
+		name := e.Name
+		if e.ConsoleURL != "" {
+			name = "[" + e.Name + "](" + e.ConsoleURL + ")"
+		}
+		fmt.Fprintf(&b, "**%s** | <font color=\"%s\">%s</font> | %s\n", name, color, e.DisplayPhase(), e.Namespace)
+		if e.Owner != "" {
+			b.WriteString("> " + f.T("Owner") + ": " + e.Owner + "\n")
+		}
+		if e.OOMKilled != "" {
+			b.WriteString("> OOMKilled (" + e.OOMKilled + ")\n")
+		}
+		for _, finding := range e.Findings {
+			b.WriteString("> " + finding + "\n")
+		}
+		for _, c := range e.ComponentLines() {
+			b.WriteString("> component " + c + "\n")
+		}
+		for _, p := range e.PodLines() {
+			b.WriteString("> pod " + p + "\n")
+		}
+	}
+	if len(r.DebtNamespaces) > 0 {
+		fmt.Fprintf(&b, "\n"+f.T("Namespaces in debt: %d")+"\n", len(r.DebtNamespaces))
+	}
+	return truncateMarkdown(b.String())
+}
+
+// 超过上限时按行截断，避免整条消息被拒绝
+func truncateMarkdown(s string) string {
+	if len(s) <= wecomMaxContent {
+		return s
+	}
+	const suffix = "\n..."
+	cut := strings.LastIndex(s[:wecomMaxContent-len(suffix)], "\n")
+	if cut < 0 {
+		cut = wecomMaxContent - len(suffix)
+	}
+	return s[:cut] + suffix
+}
+
+func (n *WeCom) Send(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending alert to WeCom: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to send alert to WeCom, status code: %d", resp.StatusCode)
+	}
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode WeCom response: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("WeCom rejected the alert: %d %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Package redact 把日志和通知内容中已知的敏感值替换为 ***。
+// 敏感值来自实际加载的配置和 Secret，而不是靠正则猜测，因此不会漏掉格式不规则的 token。
+package redact
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask 替换敏感值使用的字符串
+const Mask = "***"
+
+// 过短的值替换后会误伤正常内容，不予登记
+const minSecretLen = 6
+
+// Redactor 记录需要隐藏的敏感值，可并发使用
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets map[string]bool
+	// 按长度倒序排列，保证先替换较长的值
+	sorted []string
+}
+
+func New() *Redactor {
+	return &Redactor{secrets: make(map[string]bool)}
+}
+
+// Add 登记敏感值，同时登记它在 URL 中转义后的形式
+func (r *Redactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range secrets {
+		if len(s) < minSecretLen {
+			continue
+		}
+		for _, form := range []string{s, url.QueryEscape(s), url.PathEscape(s), escapeUserinfo(s)} {
+			if !r.secrets[form] {
+				r.secrets[form] = true
+				r.sorted = append(r.sorted, form)
+			}
+		}
+	}
+	sort.Slice(r.sorted, func(i, j int) bool { return len(r.sorted[i]) > len(r.sorted[j]) })
+}
+
+// 作为 userinfo 中的密码时的转义形式
+func escapeUserinfo(s string) string {
+	return strings.TrimPrefix(url.UserPassword("", s).String(), ":")
+}
+
+// 参数名包含这些词的 query 参数视为凭据，例如钉钉的 access_token、企业微信的 key
+var tokenParams = []string{"token", "key", "secret", "sign", "auth", "pass", "code"}
+
+// 已知 webhook 地址中 token 所在的路径：host 匹配（为空时不限）时，prefix 之后的各段都是凭据
+var tokenPaths = []struct {
+	host   string
+	prefix string
+}{
+	// 飞书、Lark 及私有部署：/open-apis/bot/v2/hook/<token>
+	{"", "/open-apis/bot/v2/hook/"},
+	// Slack：/services/<team>/<bot>/<secret>
+	{"hooks.slack.com", "/services/"},
+	// Discord：/api/webhooks/<id>/<token>
+	{"", "/api/webhooks/"},
+}
+
+// AddURL 登记 URL 及其中的凭据部分：userinfo 中的密码、名称像凭据的 query 参数，以及已知 webhook 格式路径中的 token。
+// 其他路径段和参数（例如 /api/v1/alerts）不登记，避免普通单词在所有日志中被替换
+func (r *Redactor) AddURL(raw string) {
+	r.Add(raw)
+	u, err := url.Parse(raw)
+	if err != nil {
+		return
+	}
+	if u.User != nil {
+		if p, ok := u.User.Password(); ok {
+			r.Add(p)
+		}
+	}
+	for name, values := range u.Query() {
+		if tokenParam(name) {
+			r.Add(values...)
+		}
+	}
+	for _, p := range tokenPaths {
+		if p.host != "" && u.Hostname() != p.host {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(u.Path, p.prefix); ok {
+			for _, segment := range strings.Split(rest, "/") {
+				r.Add(segment)
+			}
+		}
+	}
+	// Telegram：/bot<token>/sendMessage
+	for _, segment := range strings.Split(u.Path, "/") {
+		if strings.HasPrefix(segment, "bot") && strings.Contains(segment, ":") {
+			r.Add(segment, strings.TrimPrefix(segment, "bot"))
+		}
+	}
+}
+
+func tokenParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range tokenParams {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// String 返回隐藏了所有已登记敏感值的字符串；r 为 nil 时原样返回
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, secret := range r.sorted {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	return s
+}
+
+// Bytes 同 String
+func (r *Redactor) Bytes(b []byte) []byte {
+	if r == nil {
+		return b
+	}
+	return []byte(r.String(string(b)))
+}
@@ -0,0 +1,65 @@
+package redact
+
+import (
+	"context"
+	"log/slog"
+)
+
+// handler 在写出日志前隐藏消息和字段值中的敏感值
+type handler struct {
+	r    *Redactor
+	next slog.Handler
+}
+
+// Handler 包装 next，输出前对消息和所有字段值脱敏；错误等非字符串值按其文本脱敏
+func (r *Redactor) Handler(next slog.Handler) slog.Handler {
+	return &handler{r: r, next: next}
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, rec slog.Record) error {
+	out := slog.NewRecord(rec.Time, rec.Level, h.r.String(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.attr(a)
+	}
+	return &handler{r: h.r, next: h.next.WithAttrs(redacted)}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{r: h.r, next: h.next.WithGroup(name)}
+}
+
+func (h *handler) attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.r.String(v.String()))
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return slog.String(a.Key, h.r.String(x.Error()))
+		case interface{ String() string }:
+			return slog.String(a.Key, h.r.String(x.String()))
+		}
+	case slog.KindGroup:
+		group := v.Group()
+		attrs := make([]any, len(group))
+		for i, g := range group {
+			attrs[i] = h.attr(g)
+		}
+		return slog.Group(a.Key, attrs...)
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
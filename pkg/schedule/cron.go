@@ -0,0 +1,176 @@
+// Package schedule 解析标准的 5 段 cron 表达式（分 时 日 月 周），用于按时间表巡检，
+// 例如只在工作时间巡检，或高峰期每分钟巡检。
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron 解析后的 cron 表达式，每段为允许值的位图
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// 日和周都不是 * 时，两者满足其一即可，与标准 cron 一致
+	domStar, dowStar bool
+	// 小时为 * 时夏令时结束重复的那一小时照常执行，否则只执行第一次
+	hourStar bool
+	loc      *time.Location
+}
+
+type field struct {
+	min, max int
+	// 从 min 开始对应的名称，例如 jan、sun
+	names []string
+}
+
+var fields = []field{
+	{min: 0, max: 59}, // 分
+	{min: 0, max: 23}, // 时
+	{min: 1, max: 31}, // 日
+	{min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// 周，0 和 7 都表示周日
+	{min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// Parse 解析 5 段 cron 表达式，支持 *、列表（1,5）、范围（9-18）、步长（*/5、0-30/10）
+// 以及月和周的英文缩写（jan、mon-fri，不区分大小写）。
+// 时间按 loc 计算，loc 为 nil 时使用本地时区；表达式可以用 CRON_TZ=Asia/Shanghai 前缀指定时区
+func Parse(expr string, loc *time.Location) (*Cron, error) {
+	parts := strings.Fields(expr)
+	if len(parts) > 0 && strings.HasPrefix(parts[0], "CRON_TZ=") {
+		tz, err := time.LoadLocation(strings.TrimPrefix(parts[0], "CRON_TZ="))
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		loc, parts = tz, parts[1:]
+	}
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	c := &Cron{loc: loc, domStar: parts[2] == "*", dowStar: parts[4] == "*", hourStar: parts[1] == "*"}
+	targets := []*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+	for i, part := range parts {
+		bits, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		*targets[i] = bits
+	}
+	// 7 与 0 都是周日
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+func parseField(s string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		rng, step := item, 1
+		if r, st, ok := strings.Cut(item, "/"); ok {
+			n, err := strconv.Atoi(st)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+			rng, step = r, n
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, fmt.Errorf("invalid value %q", item)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(b); err != nil {
+					return 0, fmt.Errorf("invalid value %q", item)
+				}
+			} else if step > 1 {
+				// 5/10 表示从 5 开始每 10 个
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("value %q out of range %d-%d", item, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// 数字或名称对应的值
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	return strconv.Atoi(s)
+}
+
+// Next 返回 t 之后（不含 t 所在的分钟）第一个满足表达式的时间
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.In(c.loc)
+	after := wallClock(t)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// 最多向后找 5 年，覆盖 2 月 29 日这类很少出现的时间
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = later(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc))
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = later(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc))
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = later(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.loc))
+			continue
+		}
+		// 夏令时结束时钟回拨，指定了小时的表达式不在重复的那一小时再执行一次
+		if c.minute&(1<<uint(t.Minute())) == 0 || !c.hourStar && !wallClock(t).After(after) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// 跳到 next；夏令时开始时不存在的时刻（例如 2:00）会被 time.Date 换算到跳变之前，
+// 这时逐分钟前进，避免停在原地
+func later(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Minute)
+}
+
+// 与时区偏移无关的墙上时间，用于比较回拨前后的同一时刻
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,117 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"too few fields", "* * * *"},
+		{"too many fields", "* * * * * *"},
+		{"minute out of range", "60 * * * *"},
+		{"hour out of range", "* 24 * * *"},
+		{"day of month zero", "* * 0 * *"},
+		{"month out of range", "* * * 13 *"},
+		{"day of week out of range", "* * * * 8"},
+		{"reversed range", "* 18-9 * * *"},
+		{"open range", "* 9- * * *"},
+		{"zero step", "*/0 * * * *"},
+		{"negative step", "*/-5 * * * *"},
+		{"non-numeric step", "*/x * * * *"},
+		{"unknown month name", "* * * foo *"},
+		{"unknown weekday name", "* * * * monday"},
+		{"weekday name in month field", "* * * mon *"},
+		{"month name in hour field", "* jan * * *"},
+		{"unknown time zone", "CRON_TZ=Mars/Olympus 0 9 * * *"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.expr, time.UTC); err == nil {
+				t.Errorf("Parse(%q) succeeded, want an error", tt.expr)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	utc := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	ny := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, newYork)
+	}
+	tests := []struct {
+		name string
+		expr string
+		loc  *time.Location
+		from time.Time
+		want []time.Time
+	}{
+		{"every minute skips the current minute", "* * * * *", time.UTC, utc(2024, 1, 1, 10, 0).Add(30 * time.Second),
+			[]time.Time{utc(2024, 1, 1, 10, 1), utc(2024, 1, 1, 10, 2)}},
+		{"step rolls over the hour", "*/20 * * * *", time.UTC, utc(2024, 1, 1, 10, 45),
+			[]time.Time{utc(2024, 1, 1, 11, 0), utc(2024, 1, 1, 11, 20)}},
+		{"offset step", "5/30 * * * *", time.UTC, utc(2024, 1, 1, 10, 0),
+			[]time.Time{utc(2024, 1, 1, 10, 5), utc(2024, 1, 1, 10, 35), utc(2024, 1, 1, 11, 5)}},
+		{"hour range rolls over the day", "0 9-10 * * *", time.UTC, utc(2024, 1, 1, 10, 0),
+			[]time.Time{utc(2024, 1, 2, 9, 0), utc(2024, 1, 2, 10, 0)}},
+		{"day of month rolls over the month", "0 0 31 * *", time.UTC, utc(2024, 1, 31, 0, 0),
+			[]time.Time{utc(2024, 3, 31, 0, 0), utc(2024, 5, 31, 0, 0)}},
+		{"month rolls over the year", "0 0 1 jan,JUL *", time.UTC, utc(2024, 7, 1, 0, 0),
+			[]time.Time{utc(2025, 1, 1, 0, 0), utc(2025, 7, 1, 0, 0)}},
+		{"leap day", "0 0 29 2 *", time.UTC, utc(2024, 3, 1, 0, 0),
+			[]time.Time{utc(2028, 2, 29, 0, 0)}},
+		{"weekday names", "0 9 * * mon-fri", time.UTC, utc(2024, 1, 5, 9, 0), // 周五
+			[]time.Time{utc(2024, 1, 8, 9, 0), utc(2024, 1, 9, 9, 0)}},
+		{"sunday as 7", "0 0 * * 7", time.UTC, utc(2024, 1, 1, 0, 0),
+			[]time.Time{utc(2024, 1, 7, 0, 0), utc(2024, 1, 14, 0, 0)}},
+		{"day of month or day of week", "0 0 13 * fri", time.UTC, utc(2024, 9, 1, 0, 0), // 9 月 6 日是周五
+			[]time.Time{utc(2024, 9, 6, 0, 0), utc(2024, 9, 13, 0, 0), utc(2024, 9, 20, 0, 0)}},
+		{"day of week only when day of month is *", "0 0 * * fri", time.UTC, utc(2024, 9, 6, 0, 0),
+			[]time.Time{utc(2024, 9, 13, 0, 0), utc(2024, 9, 20, 0, 0)}},
+		{"time zone prefix", "CRON_TZ=Asia/Shanghai 0 9 * * *", time.UTC, utc(2024, 1, 1, 0, 0),
+			[]time.Time{utc(2024, 1, 1, 1, 0), utc(2024, 1, 2, 1, 0)}},
+		{"spring forward skips the missing hour", "30 2 * * *", newYork, ny(2024, 3, 9, 3, 0),
+			[]time.Time{ny(2024, 3, 11, 2, 30)}},
+		{"hourly across spring forward", "0 * * * *", newYork, ny(2024, 3, 10, 0, 30),
+			[]time.Time{ny(2024, 3, 10, 1, 0), ny(2024, 3, 10, 3, 0)}},
+		{"fall back runs a fixed hour once", "0 1 * * *", newYork, ny(2024, 11, 2, 12, 0),
+			[]time.Time{ny(2024, 11, 3, 1, 0), ny(2024, 11, 4, 1, 0)}},
+		{"hourly across fall back runs the repeated hour", "0 * * * *", newYork, ny(2024, 11, 3, 0, 30),
+			[]time.Time{ny(2024, 11, 3, 1, 0), ny(2024, 11, 3, 1, 0).Add(time.Hour), ny(2024, 11, 3, 2, 0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse(tt.expr, tt.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			at := tt.from
+			for _, want := range tt.want {
+				at = c.Next(at)
+				if !at.Equal(want) {
+					t.Fatalf("Next = %v, want %v", at, want)
+				}
+			}
+		})
+	}
+}
+
+// 永远不会出现的日期返回零值，而不是一直查找
+func TestNextNever(t *testing.T) {
+	c, err := Parse("0 0 30 2 *", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := c.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); !next.IsZero() {
+		t.Errorf("Next = %v, want zero", next)
+	}
+}
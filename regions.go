@@ -0,0 +1,509 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"database-monitor/pkg/monitor"
+)
+
+// 区域巡检异常退出后重启前的等待时间
+const regionRestartDelay = time.Minute
+
+// 访问区域 API server 的请求超时，避免一个区域不可达时卡住
+const regionRequestTimeout = 30 * time.Second
+
+// region 注册表中的一个区域及其巡检 goroutine
+type region struct {
+	name     string
+	endpoint string
+	// 端点和凭据的指纹，变化（例如凭据轮换）时重启巡检
+	fingerprint string
+	cancel      context.CancelFunc
+	// loop 返回（巡检已保存状态）后关闭，未启动巡检时为 nil
+	done     chan struct{}
+	m        *monitor.Monitor
+	registry *prometheus.Registry
+
+	mu        sync.Mutex
+	startedAt time.Time
+	lastError string
+	restarts  int
+}
+
+// regionStatus 状态接口中每个区域的健康情况
+type regionStatus struct {
+	Name      string    `json:"name"`
+	Endpoint  string    `json:"endpoint,omitempty"`
+	Running   bool      `json:"running"`
+	Ready     bool      `json:"ready"`
+	Reason    string    `json:"reason,omitempty"`
+	StartedAt time.Time `json:"startedAt,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+	Restarts  int       `json:"restarts"`
+}
+
+// regionManager 监听主集群中的区域注册表，按条目增删改动态启停各区域的巡检；
+// 或者启动时按 kubeconfig 中的 context 固定启动各区域。
+// 每个区域使用独立的客户端和 Monitor，一个区域的 API 故障不会影响其他区域
+type regionManager struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	// 非空时不使用注册表
+	contexts []string
+
+	mu      sync.Mutex
+	regions map[string]*region
+	synced  func() bool
+}
+
+// 解析 group/version/resource 形式的资源名
+func parseGVR(v string) (schema.GroupVersionResource, error) {
+	parts := strings.Split(v, "/")
+	if len(parts) != 3 {
+		return schema.GroupVersionResource{}, fmt.Errorf("invalid resource %q, want group/version/resource", v)
+	}
+	return schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]}, nil
+}
+
+func newRegionManager(gvr schema.GroupVersionResource, namespace string) *regionManager {
+	return &regionManager{gvr: gvr, namespace: namespace, regions: make(map[string]*region)}
+}
+
+func newContextRegions(contexts []string) *regionManager {
+	return &regionManager{contexts: contexts, regions: make(map[string]*region)}
+}
+
+// run 监听注册表直到 ctx 结束。除注册表事件外，每隔 CheckInterval 重新核对一次，以发现凭据 Secret 的轮换
+func (rm *regionManager) run(ctx context.Context) error {
+	if len(rm.contexts) > 0 {
+		return rm.runContexts(ctx)
+	}
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, cfg.CheckInterval, rm.namespace, nil)
+	informer := factory.ForResource(rm.gvr).Informer()
+	trigger := make(chan struct{}, 1)
+	notify := func(interface{}) {
+		select {
+		case trigger <- struct{}{}:
+		default:
+		}
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    notify,
+		UpdateFunc: func(_, obj interface{}) { notify(obj) },
+		DeleteFunc: notify,
+	})
+	if err != nil {
+		return err
+	}
+	rm.mu.Lock()
+	rm.synced = informer.HasSynced
+	rm.mu.Unlock()
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return fmt.Errorf("failed to sync region registry informer")
+	}
+
+	ticker := time.NewTicker(cfg.CheckInterval)
+	defer ticker.Stop()
+	for {
+		rm.reconcile(ctx, informer.GetStore().List())
+		select {
+		case <-ctx.Done():
+			rm.stopAll()
+			return nil
+		case <-trigger:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (rm *regionManager) reconcile(ctx context.Context, items []interface{}) {
+	desired := make(map[string]bool)
+	for _, item := range items {
+		entry, ok := item.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		name := entry.GetName()
+		desired[name] = true
+		endpoint, _, _ := unstructured.NestedString(entry.Object, "spec", "endpoint")
+		restConfig, fingerprint, err := regionRESTConfig(ctx, entry)
+
+		rm.mu.Lock()
+		existing := rm.regions[name]
+		rm.mu.Unlock()
+		if err != nil {
+			slog.Error("Error loading region credentials", "region", name, "err", err)
+			if existing == nil {
+				existing = &region{name: name, endpoint: endpoint}
+				rm.mu.Lock()
+				rm.regions[name] = existing
+				rm.mu.Unlock()
+			}
+			existing.setError(err)
+			continue
+		}
+		if existing != nil && existing.fingerprint == fingerprint {
+			continue
+		}
+		if existing != nil {
+			slog.Info("Region changed, restarting its monitor", "region", name)
+			existing.stop()
+		} else {
+			slog.Info("Region added, starting its monitor", "region", name)
+		}
+		r, err := startRegion(ctx, name, endpoint, fingerprint, restConfig)
+		if err != nil {
+			slog.Error("Error starting region", "region", name, "err", err)
+			r = &region{name: name, endpoint: endpoint}
+			r.setError(err)
+		}
+		rm.mu.Lock()
+		rm.regions[name] = r
+		rm.mu.Unlock()
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	for name, r := range rm.regions {
+		if !desired[name] {
+			slog.Info("Region removed, stopping its monitor", "region", name)
+			r.stop()
+			delete(rm.regions, name)
+		}
+	}
+}
+
+// 为每个 context 启动一个区域直到 ctx 结束；加载失败的 context 只记录错误，不影响其他区域
+func (rm *regionManager) runContexts(ctx context.Context) error {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if cfg.Kubeconfig != "" {
+		rules.ExplicitPath = cfg.Kubeconfig
+	}
+	for _, name := range rm.contexts {
+		restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules,
+			&clientcmd.ConfigOverrides{CurrentContext: name}).ClientConfig()
+		var r *region
+		if err == nil {
+			redactor.Add(restConfig.BearerToken)
+			restConfig.QPS = float32(cfg.APIQPS)
+			restConfig.Burst = cfg.APIBurst
+			restConfig.Timeout = regionRequestTimeout
+			slog.Info("Starting monitor for kubeconfig context", "region", name, "endpoint", restConfig.Host)
+			r, err = startRegion(ctx, name, restConfig.Host, "", restConfig)
+		}
+		if err != nil {
+			slog.Error("Error starting region", "region", name, "err", err)
+			r = &region{name: name}
+			r.setError(err)
+		}
+		rm.mu.Lock()
+		rm.regions[name] = r
+		rm.mu.Unlock()
+	}
+	rm.mu.Lock()
+	rm.synced = func() bool { return true }
+	rm.mu.Unlock()
+
+	<-ctx.Done()
+	rm.stopAll()
+	return nil
+}
+
+// 停止所有区域并等待它们保存状态；配置重新加载后 run 按新配置重新启动各区域
+func (rm *regionManager) stopAll() {
+	rm.mu.Lock()
+	stopped := make([]*region, 0, len(rm.regions))
+	for _, r := range rm.regions {
+		r.stop()
+		stopped = append(stopped, r)
+	}
+	rm.regions = make(map[string]*region)
+	rm.mu.Unlock()
+	for _, r := range stopped {
+		// 凭据加载失败的区域没有运行巡检
+		if r.done != nil {
+			<-r.done
+		}
+	}
+}
+
+// 注册表 informer 同步完成即就绪，单个区域不可用不影响整体就绪
+func (rm *regionManager) ready() (bool, string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	if rm.synced == nil || !rm.synced() {
+		return false, "region registry not synced"
+	}
+	return true, ""
+}
+
+func (rm *regionManager) status() []regionStatus {
+	rm.mu.Lock()
+	regions := make([]*region, 0, len(rm.regions))
+	for _, r := range rm.regions {
+		regions = append(regions, r)
+	}
+	rm.mu.Unlock()
+
+	statuses := make([]regionStatus, 0, len(regions))
+	for _, r := range regions {
+		statuses = append(statuses, r.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// 正在巡检的各区域的 Monitor，按区域名排序
+func (rm *regionManager) monitors() []*monitor.Monitor {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	names := make([]string, 0, len(rm.regions))
+	for name, r := range rm.regions {
+		if r.m != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	monitors := make([]*monitor.Monitor, 0, len(names))
+	for _, name := range names {
+		monitors = append(monitors, rm.regions[name].m)
+	}
+	return monitors
+}
+
+// Gather 汇总各区域独立注册的指标，已停止的区域不会残留
+func (rm *regionManager) Gather() ([]*dto.MetricFamily, error) {
+	rm.mu.Lock()
+	var gatherers prometheus.Gatherers
+	for _, r := range rm.regions {
+		if r.registry != nil {
+			gatherers = append(gatherers, r.registry)
+		}
+	}
+	rm.mu.Unlock()
+	return gatherers.Gather()
+}
+
+// 从注册表条目引用的 Secret 构造区域的客户端配置。Secret 中有 kubeconfig 时直接使用，
+// 否则使用条目的 endpoint 加 Secret 中的 token 和 ca.crt
+func regionRESTConfig(ctx context.Context, entry *unstructured.Unstructured) (*rest.Config, string, error) {
+	endpoint, _, _ := unstructured.NestedString(entry.Object, "spec", "endpoint")
+	secretName, _, _ := unstructured.NestedString(entry.Object, "spec", "credentialSecretRef", "name")
+	secretNamespace, _, _ := unstructured.NestedString(entry.Object, "spec", "credentialSecretRef", "namespace")
+	if secretNamespace == "" {
+		secretNamespace = entry.GetNamespace()
+	}
+	if secretName == "" {
+		return nil, "", fmt.Errorf("spec.credentialSecretRef.name is empty")
+	}
+	secret, err := clientset.CoreV1().Secrets(secretNamespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(endpoint))
+	keys := make([]string, 0, len(secret.Data))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write(secret.Data[key])
+	}
+	fingerprint := hex.EncodeToString(h.Sum(nil))
+
+	var restConfig *rest.Config
+	if kubeconfig, ok := secret.Data["kubeconfig"]; ok {
+		restConfig, err = clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+		if err != nil {
+			return nil, "", err
+		}
+		if endpoint != "" {
+			restConfig.Host = endpoint
+		}
+	} else {
+		if endpoint == "" {
+			return nil, "", fmt.Errorf("spec.endpoint is empty and secret %s has no kubeconfig", secretName)
+		}
+		restConfig = &rest.Config{
+			Host:            endpoint,
+			BearerToken:     string(secret.Data["token"]),
+			TLSClientConfig: rest.TLSClientConfig{CAData: secret.Data["ca.crt"]},
+		}
+	}
+	redactor.Add(restConfig.BearerToken)
+	restConfig.QPS = float32(cfg.APIQPS)
+	restConfig.Burst = cfg.APIBurst
+	restConfig.Timeout = regionRequestTimeout
+	return restConfig, fingerprint, nil
+}
+
+func startRegion(ctx context.Context, name, endpoint, fingerprint string, restConfig *rest.Config) (*region, error) {
+	traceKubeRequests(restConfig)
+	dyn, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	kube, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	regionConfig := cfg.Config
+	regionConfig.Region = name
+	r := &region{
+		name:        name,
+		endpoint:    endpoint,
+		fingerprint: fingerprint,
+		registry:    prometheus.NewRegistry(),
+	}
+	r.m = monitor.New(monitor.Deps{
+		Config:        regionConfig,
+		Dynamic:       dyn,
+		Kube:          kube,
+		Notifiers:     notifiers,
+		Registerer:    prometheus.WrapRegistererWith(prometheus.Labels{"region": name}, r.registry),
+		ConfigVersion: currentConfigHash(),
+		Redactor:      redactor,
+		Store:         prefixStore{store: store, prefix: "region-" + storeKeyPart(name) + "-"},
+		StatusStore:   regionStatusStore(name),
+		Audit:         auditSink(),
+		History:       auditHistory(),
+		Logger:        slog.Default().With("region", name),
+
+		NamespaceNotifier:   namespaceNotifier,
+		EscalationNotifiers: escalationNotifiers,
+		HTTPClient:          currentHTTPClient(),
+		TracerProvider:      otel.GetTracerProvider(),
+		OnPanic:             recordPanic,
+	})
+
+	regionCtx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+	r.startedAt = time.Now()
+	r.done = make(chan struct{})
+	go r.loop(regionCtx)
+	return r, nil
+}
+
+// 巡检出错或 panic 时记录错误并在稍后重启，只影响本区域
+func (r *region) loop(ctx context.Context) {
+	defer close(r.done)
+	for {
+		err := r.runOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("monitor stopped unexpectedly")
+		}
+		slog.Error("Region monitor failed", "region", r.name, "err", err)
+		r.setError(err)
+		r.mu.Lock()
+		r.restarts++
+		r.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(regionRestartDelay):
+		}
+	}
+}
+
+func (r *region) runOnce(ctx context.Context) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return r.m.Run(ctx)
+}
+
+func (r *region) stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+func (r *region) setError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastError = redactor.String(err.Error())
+}
+
+func (r *region) status() regionStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := regionStatus{
+		Name:      r.name,
+		Endpoint:  r.endpoint,
+		Running:   r.m != nil,
+		StartedAt: r.startedAt,
+		LastError: r.lastError,
+		Restarts:  r.restarts,
+	}
+	if r.m != nil {
+		s.Ready, s.Reason = r.m.Ready()
+	} else {
+		s.Reason = "not started"
+	}
+	return s
+}
+
+// ConfigMap 的 key 只允许字母、数字和 -._，context 名称中常见的 : 和 / 等替换为 -
+func storeKeyPart(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.', r == '_':
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+// 各区域的集群状态写入同一个 ConfigMap 中以区域名为前缀的 key，未启用时返回 nil
+func regionStatusStore(name string) monitor.StateStore {
+	if statusStore == nil {
+		return nil
+	}
+	return prefixStore{store: statusStore, prefix: "region-" + storeKeyPart(name) + "-"}
+}
+
+// prefixStore 给 key 加上前缀，多个区域共用同一个 ConfigMap 时互不覆盖
+type prefixStore struct {
+	store  monitor.StateStore
+	prefix string
+}
+
+func (s prefixStore) Get(ctx context.Context, key string) (string, error) {
+	return s.store.Get(ctx, s.prefix+key)
+}
+
+func (s prefixStore) Set(ctx context.Context, key, value string) error {
+	return s.store.Set(ctx, s.prefix+key, value)
+}
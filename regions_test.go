@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+var testRegistryGVR = schema.GroupVersionResource{Group: "cluster.sealos.io", Version: "v1", Resource: "regions"}
+
+// memStore 内存中的 StateStore
+type memStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (s *memStore) Get(_ context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key], nil
+}
+
+func (s *memStore) Set(_ context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make(map[string]string)
+	}
+	s.values[key] = value
+	return nil
+}
+
+// registryEnv 用 fake client 代替主集群，注册表条目的端点不可达，各区域停在等待 CRD
+type registryEnv struct {
+	dynamic *dynamicfake.FakeDynamicClient
+	kube    *kubefake.Clientset
+	rm      *regionManager
+}
+
+func newRegistryEnv(t *testing.T) *registryEnv {
+	t.Helper()
+	prevCfg, prevDynamic, prevKube, prevStore := cfg, dynamicClient, clientset, store
+	t.Cleanup(func() { cfg, dynamicClient, clientset, store = prevCfg, prevDynamic, prevKube, prevStore })
+	env := &registryEnv{
+		dynamic: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{testRegistryGVR: "RegionList"}),
+		kube: kubefake.NewSimpleClientset(),
+		rm:   newRegionManager(testRegistryGVR, "sealos-system"),
+	}
+	cfg = defaultConfig()
+	cfg.CheckInterval = time.Hour
+	cfg.CRDPollInterval = time.Hour
+	dynamicClient, clientset, store = env.dynamic, env.kube, &memStore{}
+	return env
+}
+
+// 创建或更新区域的凭据 Secret 和注册表条目
+func (e *registryEnv) setRegion(t *testing.T, name, token string) {
+	t.Helper()
+	ctx := context.Background()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name + "-credentials", Namespace: "sealos-system"},
+		Data:       map[string][]byte{"token": []byte(token)},
+	}
+	if _, err := e.kube.CoreV1().Secrets("sealos-system").Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		if _, err := e.kube.CoreV1().Secrets("sealos-system").Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entry := &unstructured.Unstructured{}
+	entry.SetAPIVersion("cluster.sealos.io/v1")
+	entry.SetKind("Region")
+	entry.SetNamespace("sealos-system")
+	entry.SetName(name)
+	unstructured.SetNestedField(entry.Object, "https://127.0.0.1:1", "spec", "endpoint")
+	unstructured.SetNestedField(entry.Object, name+"-credentials", "spec", "credentialSecretRef", "name")
+	// 修改注解让 informer 收到更新事件，不必等待定期核对
+	entry.SetAnnotations(map[string]string{"token": token})
+	client := e.dynamic.Resource(testRegistryGVR).Namespace("sealos-system")
+	if _, err := client.Update(ctx, entry, metav1.UpdateOptions{}); err != nil {
+		if _, err := client.Create(ctx, entry, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func (e *registryEnv) removeRegion(t *testing.T, name string) {
+	t.Helper()
+	if err := e.dynamic.Resource(testRegistryGVR).Namespace("sealos-system").Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (e *registryEnv) region(name string) *region {
+	e.rm.mu.Lock()
+	defer e.rm.mu.Unlock()
+	return e.rm.regions[name]
+}
+
+// 等待正在巡检的区域变为 want
+func (e *registryEnv) waitForRegions(t *testing.T, want ...string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var running []string
+		for _, s := range e.rm.status() {
+			if s.Running {
+				running = append(running, s.Name)
+			}
+		}
+		if slices.Equal(running, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("running regions = %q, want %q", running, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func waitClosed(t *testing.T, done chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s did not stop", what)
+	}
+}
+
+func TestRegionLifecycle(t *testing.T) {
+	env := newRegistryEnv(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if err := env.rm.run(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	env.setRegion(t, "eu", "token-1")
+	env.setRegion(t, "us", "token-1")
+	env.waitForRegions(t, "eu", "us")
+	if ready, reason := env.rm.ready(); !ready {
+		t.Errorf("not ready with the registry synced: %s", reason)
+	}
+	// 每个区域的指标注册在独立的注册表中，同名指标不会冲突
+	if _, err := env.rm.Gather(); err != nil {
+		t.Errorf("gather: %v", err)
+	}
+
+	// 凭据轮换后重启该区域的巡检，其他区域不受影响
+	eu, us := env.region("eu"), env.region("us")
+	env.setRegion(t, "eu", "token-2")
+	waitClosed(t, eu.done, "region with rotated credentials")
+	deadline := time.Now().Add(5 * time.Second)
+	for env.region("eu") == eu {
+		if time.Now().After(deadline) {
+			t.Fatal("region eu was not restarted after the credential rotation")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	env.waitForRegions(t, "eu", "us")
+	if env.region("us") != us {
+		t.Error("region us was restarted although it did not change")
+	}
+
+	env.removeRegion(t, "us")
+	waitClosed(t, us.done, "removed region")
+	env.waitForRegions(t, "eu")
+
+	// 删除后重新注册的区域使用新的注册表，重复注册指标不会 panic
+	env.setRegion(t, "us", "token-3")
+	env.waitForRegions(t, "eu", "us")
+	if _, err := env.rm.Gather(); err != nil {
+		t.Errorf("gather after re-adding a region: %v", err)
+	}
+
+	eu, us = env.region("eu"), env.region("us")
+	cancel()
+	<-stopped
+	for _, r := range []*region{eu, us} {
+		waitClosed(t, r.done, "region "+r.name+" after the manager stopped")
+	}
+	if got := env.rm.status(); len(got) != 0 {
+		t.Errorf("regions after stop = %+v", got)
+	}
+}
+
+// 凭据无法加载的区域记录错误，不影响其他区域，修复后开始巡检
+func TestRegionCredentialError(t *testing.T) {
+	env := newRegistryEnv(t)
+	broken := &unstructured.Unstructured{}
+	broken.SetNamespace("sealos-system")
+	broken.SetName("broken")
+	unstructured.SetNestedField(broken.Object, "https://127.0.0.1:1", "spec", "endpoint")
+	env.setRegion(t, "eu", "token-1")
+	eu, err := env.dynamic.Resource(testRegistryGVR).Namespace("sealos-system").Get(context.Background(), "eu", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	env.rm.reconcile(ctx, []interface{}{eu, broken})
+	defer env.rm.stopAll()
+	statuses := env.rm.status()
+	if len(statuses) != 2 || statuses[0].Name != "broken" || statuses[0].Running || statuses[0].LastError == "" || !statuses[1].Running {
+		t.Fatalf("statuses = %+v, want broken not running with an error and eu running", statuses)
+	}
+
+	unstructured.SetNestedField(broken.Object, "eu-credentials", "spec", "credentialSecretRef", "name")
+	env.rm.reconcile(ctx, []interface{}{eu, broken})
+	if s := env.rm.status()[0]; !s.Running {
+		t.Errorf("fixed region is not running: %+v", s)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"database-monitor/pkg/httpclient"
+	"database-monitor/pkg/monitor"
+)
+
+// 启动后需要重启进程才能生效的设置，重新加载配置时保留原值
+var restartOnlyFields = []string{
+	"Kubeconfig", "AdminAddr", "ConfigReloadInterval", "StateNamespace", "StateConfigMap", "StatusConfigMap",
+	"EmitEvents", "RegistryResource", "RegistryNamespace", "KubeContexts", "LeaderElect", "LeaderElectionLease",
+	"AuditLog", "AuditMaxBytes", "AuditBackups", "AuditKinds", "HistoryPath", "HistoryRetention",
+	"OTLPEndpoint", "TraceSampleRatio", "LogLevel", "LogFormat",
+}
+
+var (
+	// 保护 cfg、notifiers 和 escalationNotifiers 的替换。替换只在巡检停止时进行，
+	// 巡检中可以直接读取，管理接口等巡检之外的读取需要加读锁
+	configMu sync.RWMutex
+	// 启动时的命令行参数，重新加载时再次解析，命令行参数仍然优先于配置文件
+	commandArgs []string
+	// 主集群 Monitor 的指标
+	monitorMetrics generationGatherer
+)
+
+// reloadedConfig 重新加载并校验通过的配置，以及按它创建的通知后端
+type reloadedConfig struct {
+	config     Config
+	hash       string
+	notifiers  []monitor.Notifier
+	escalation []monitor.Notifier
+	httpClient *http.Client
+}
+
+// generationGatherer 读取最新一个 Monitor 的注册表，重新加载后旧 Monitor 的指标不再导出
+type generationGatherer struct {
+	reg atomic.Pointer[prometheus.Registry]
+}
+
+func (g *generationGatherer) Gather() ([]*dto.MetricFamily, error) {
+	if reg := g.reg.Load(); reg != nil {
+		return reg.Gather()
+	}
+	return nil, nil
+}
+
+// 巡检之外读取配置时使用
+func currentConfig() Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return cfg
+}
+
+// 与 currentConfig 一起替换的 HTTP 客户端
+func currentHTTPClient() *http.Client {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return httpClient
+}
+
+// watchConfig 每隔 ConfigReloadInterval 检查配置文件的内容，变化或收到 SIGHUP 时重新加载，校验通过后发送到返回的 channel。
+// 挂载的 ConfigMap 更新时 kubelet 替换符号链接，同样按内容发现变化。channel 只保留最新的一份，
+// 非 leader 副本开始巡检时使用最新的配置
+func watchConfig(ctx context.Context) <-chan reloadedConfig {
+	path := configFileFromArgs(commandArgs)
+	reloads := make(chan reloadedConfig, 1)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	var tick <-chan time.Time
+	if (path != "" || hasSecretRefs(cfg)) && cfg.ConfigReloadInterval > 0 {
+		ticker := time.NewTicker(cfg.ConfigReloadInterval)
+		tick = ticker.C
+		go func() {
+			<-ctx.Done()
+			ticker.Stop()
+		}()
+	}
+	last := fileHash(path)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick:
+				// 引用了 Secret 时每次都重新加载，只有 Secret 变化时才会生效
+				sum := fileHash(path)
+				if sum == last && !hasSecretRefs(currentConfig()) {
+					continue
+				}
+				last = sum
+			case <-hup:
+				slog.Info("Received SIGHUP, reloading configuration")
+				last = fileHash(path)
+			}
+			next, ok := reloadConfig()
+			if !ok {
+				continue
+			}
+			select {
+			case <-reloads:
+			default:
+			}
+			reloads <- next
+		}
+	}()
+	return reloads
+}
+
+// 文件内容的哈希，读取失败时为空，下次读取成功时视为变化
+func fileHash(path string) string {
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return string(sum[:])
+}
+
+// 按启动时的方式重新读取配置文件、命令行参数和引用的 Secret 并创建通知后端；配置无效或与当前配置相同时返回 false，继续使用当前配置
+func reloadConfig() (reloadedConfig, bool) {
+	cur := currentConfig()
+	next := defaultConfig()
+	if path := configFileFromArgs(commandArgs); path != "" {
+		if err := next.loadConfigFile(path); err != nil {
+			slog.Error("Error reloading configuration, keeping the current one", "err", err)
+			return reloadedConfig{}, false
+		}
+	}
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	next.bindFlags(fs)
+	if err := fs.Parse(commandArgs); err != nil {
+		slog.Error("Error reloading configuration, keeping the current one", "err", err)
+		return reloadedConfig{}, false
+	}
+	if err := next.validate(); err != nil {
+		slog.Error("Invalid configuration, keeping the current one", "err", err)
+		return reloadedConfig{}, false
+	}
+	if changed := keepRestartOnly(&next, cur); len(changed) > 0 {
+		slog.Warn("Configuration changes that need a restart were not applied", "settings", strings.Join(changed, ","))
+	}
+	// 哈希只包含 Secret 的引用，不包含读取到的值
+	hash := configHash(next)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := resolveSecretRefs(ctx, &next); err != nil {
+		slog.Error("Error reading secrets of the reloaded configuration, keeping the current one", "err", err)
+		return reloadedConfig{}, false
+	}
+	if reflect.DeepEqual(next, cur) {
+		slog.Debug("Configuration unchanged after reload", "hash", hash)
+		return reloadedConfig{}, false
+	}
+	redactConfig(next)
+	client := httpclient.New(next.NotifyTimeout)
+	all, escalation, err := buildNotifiers(next, client)
+	if err != nil {
+		slog.Error("Error creating notifiers from the reloaded configuration, keeping the current one", "err", err)
+		return reloadedConfig{}, false
+	}
+	return reloadedConfig{config: next, hash: hash, notifiers: all, escalation: escalation, httpClient: client}, true
+}
+
+// 把 next 中只在启动时生效的设置恢复为 cur 的值，返回有变化的设置
+func keepRestartOnly(next *Config, cur Config) []string {
+	var changed []string
+	nv, cv := reflect.ValueOf(next).Elem(), reflect.ValueOf(cur)
+	for _, name := range restartOnlyFields {
+		field, _ := nv.Type().FieldByName(name)
+		if !reflect.DeepEqual(nv.FieldByName(name).Interface(), cv.FieldByName(name).Interface()) {
+			changed = append(changed, field.Tag.Get("json"))
+			nv.FieldByName(name).Set(cv.FieldByName(name))
+		}
+	}
+	return changed
+}
+
+// 在巡检停止后替换配置和通知后端
+func applyConfig(next reloadedConfig) {
+	configMu.Lock()
+	cfg, notifiers, escalationNotifiers, httpClient = next.config, next.notifiers, next.escalation, next.httpClient
+	configMu.Unlock()
+	recordConfigVersion(next.hash)
+	slog.Info("Configuration reloaded", "hash", next.hash, "notifiers", len(next.notifiers))
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"database-monitor/pkg/history"
+	"database-monitor/pkg/monitor"
+	"database-monitor/pkg/notify"
+	"database-monitor/pkg/schedule"
+)
+
+// 可用性报告中最多列出的 ns 和集群数
+const slaReportTopN = 20
+
+// 处于 critical 严重程度的 phase（默认即 Failed）计为不可用
+func slaDown(phase string) bool {
+	return monitor.DefaultPhasePolicy().Severity(phase) == monitor.SeverityCritical
+}
+
+// 按 SLAReportSchedule 定期把最近 SLAReportPeriod 的可用性发送到 SLAReportNotifiers；未启用历史库时不发送
+func runSLAReports(ctx context.Context, m *monitor.Monitor) {
+	if cfg.SLAReportSchedule == "" || historyStore == nil {
+		return
+	}
+	sched, err := schedule.Parse(cfg.SLAReportSchedule, nil)
+	if err != nil {
+		slog.Error("Ignoring SLA report schedule", "err", err)
+		return
+	}
+	for {
+		next := sched.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		text, err := slaReportText(next.Add(-cfg.SLAReportPeriod), next)
+		if err != nil {
+			slog.Error("Error building SLA report", "err", err)
+			continue
+		}
+		m.NotifyNamed(ctx, cfg.SLAReportNotifiers, m.NewNotice(text))
+	}
+}
+
+// 可用性报告的文本：整体可用性，以及有不可用时长的 ns 和集群
+func slaReportText(since, until time.Time) (string, error) {
+	format, err := notify.ParseFormat(cfg.Locale, cfg.Timezone)
+	if err != nil {
+		return "", err
+	}
+	clusters := historyStore.Availability(since, until, slaDown)
+	var fleet history.Availability
+	for _, c := range clusters {
+		fleet.Observed += c.Observed
+		fleet.Downtime += c.Downtime
+		fleet.Outages += c.Outages
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Availability report %s - %s:\n", format.Time(since), format.Time(until))
+	fmt.Fprintf(&b, "%d clusters, %s\n", len(clusters), slaLine(fleet))
+	sections := []struct {
+		title string
+		list  []history.Availability
+	}{
+		{"Namespaces", history.ByNamespace(clusters)},
+		{"Clusters", clusters},
+	}
+	for _, s := range sections {
+		var lines []string
+		for _, a := range s.list {
+			if a.Downtime == 0 || len(lines) == slaReportTopN {
+				break
+			}
+			lines = append(lines, "  "+slaName(a)+": "+slaLine(a))
+		}
+		if len(lines) > 0 {
+			b.WriteString("\n" + s.title + " with downtime:\n" + strings.Join(lines, "\n") + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func slaName(a history.Availability) string {
+	name := a.Namespace
+	if a.Cluster != "" {
+		name += "/" + a.Cluster
+	}
+	if a.Region != "" {
+		name = "[" + a.Region + "] " + name
+	}
+	return name
+}
+
+func slaLine(a history.Availability) string {
+	return fmt.Sprintf("%.3f%% available, downtime %s, %d outage(s)", a.Percent(), a.Downtime.Round(time.Minute), a.Outages)
+}
+
+// 以 CSV 写出可用性，时长单位为秒
+func writeAvailabilityCSV(w io.Writer, list []history.Availability) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"region", "namespace", "cluster", "observed_seconds", "downtime_seconds", "availability_percent", "outages"})
+	for _, a := range list {
+		out.Write([]string{
+			a.Region,
+			a.Namespace,
+			a.Cluster,
+			strconv.FormatInt(int64(a.Observed.Seconds()), 10),
+			strconv.FormatInt(int64(a.Downtime.Seconds()), 10),
+			strconv.FormatFloat(a.Percent(), 'f', 4, 64),
+			strconv.Itoa(a.Outages),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// 启动阶段的初始化失败时返回错误，由 main 记录后退出，而不是 panic
+func TestInitErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing", "dir")
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		init   func() error
+	}{
+		{"log level", func(c *Config) { c.LogLevel = "verbose" }, initLogger},
+		{"audit log", func(c *Config) { c.AuditLog = filepath.Join(missing, "audit.log") }, initAudit},
+		{"history", func(c *Config) { c.HistoryPath = filepath.Join(missing, "history.db") }, initAudit},
+		{"notifier", func(c *Config) { c.Notifiers = []string{"carrier-pigeon"} }, initNotifiers},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prevCfg, prevNotifiers, prevEscalation := cfg, notifiers, escalationNotifiers
+			t.Cleanup(func() { cfg, notifiers, escalationNotifiers = prevCfg, prevNotifiers, prevEscalation })
+			cfg = defaultConfig()
+			tt.modify(&cfg)
+			if err := tt.init(); err == nil {
+				t.Error("init succeeded, want an error")
+			}
+		})
+	}
+}